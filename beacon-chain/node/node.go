@@ -152,7 +152,7 @@ func NewBeaconNode(cliCtx *cli.Context) (*BeaconNode, error) {
 		params.OverrideBeaconNetworkConfig(c)
 	}
 
-	registry := shared.NewServiceRegistry()
+	registry := shared.NewServiceRegistry(debug.RegistryOptions(cliCtx)...)
 
 	beacon := &BeaconNode{
 		cliCtx:            cliCtx,
@@ -244,7 +244,9 @@ func (b *BeaconNode) Start() {
 		"version": version.GetVersion(),
 	}).Info("Starting beacon node")
 
-	b.services.StartAll()
+	if err := b.services.StartAll(); err != nil {
+		log.WithError(err).Fatal("Could not start services")
+	}
 
 	stop := b.stop
 	b.lock.Unlock()
@@ -256,7 +258,7 @@ func (b *BeaconNode) Start() {
 		<-sigc
 		log.Info("Got interrupt, shutting down...")
 		debug.Exit(b.cliCtx) // Ensure trace and CPU profile data are flushed.
-		go b.Close()
+		go b.shutdown(shared.ShutdownSignal)
 		for i := 10; i > 0; i-- {
 			<-sigc
 			if i > 1 {
@@ -266,17 +268,41 @@ func (b *BeaconNode) Start() {
 		panic("Panic closing the beacon node")
 	}()
 
-	// Wait for stop channel to be closed.
-	<-stop
+	// Wait for stop channel to be closed, or for a service to report a fatal
+	// error, in which case the process exits with a non-zero code once the
+	// services are stopped.
+	select {
+	case <-stop:
+	case err := <-b.services.FatalErrors():
+		log.WithError(err).Error("Fatal error reported by a service, shutting down...")
+		b.shutdown(shared.ShutdownFatal)
+		log.WithError(err).Fatal("Beacon node stopped after a fatal error")
+	}
 }
 
 // Close handles graceful shutdown of the system.
 func (b *BeaconNode) Close() {
+	b.shutdown(shared.ShutdownNormal)
+}
+
+// shutdown is like Close, but stops the services with the given reason.
+func (b *BeaconNode) shutdown(reason shared.ShutdownReason) {
 	b.lock.Lock()
 	defer b.lock.Unlock()
+	select {
+	case <-b.stop:
+		// Already shut down, by a signal or a fatal error.
+		return
+	default:
+	}
 
 	log.Info("Stopping beacon node")
-	b.services.StopAll()
+	if err := b.services.StopAll(reason); err != nil {
+		log.WithError(err).Error("Could not stop all services")
+	}
+	if err := b.services.VerifyNoLeaks(); err != nil && !errors.Is(err, shared.ErrLeakCheckDisabled) {
+		log.WithError(err).Warn("Services left goroutines running")
+	}
 	if err := b.db.Close(); err != nil {
 		log.Errorf("Failed to close database: %v", err)
 	}
@@ -636,6 +662,7 @@ func (b *BeaconNode) registerRPCService() error {
 		StateGen:                b.stateGen,
 		EnableDebugRPCEndpoints: enableDebugRPCEndpoints,
 		MaxMsgSize:              maxMsgSize,
+		ServiceLister:           b.services,
 	})
 
 	return b.services.RegisterService(rpcService)
@@ -665,6 +692,7 @@ func (b *BeaconNode) registerPrometheusService(cliCtx *cli.Context) error {
 	}
 
 	additionalHandlers = append(additionalHandlers, prometheus.Handler{Path: "/tree", Handler: c.TreeHandler})
+	additionalHandlers = append(additionalHandlers, prometheus.Handler{Path: "/debug/services.dot", Handler: b.services.DependencyGraphHandler().ServeHTTP})
 
 	service := prometheus.NewService(
 		fmt.Sprintf("%s:%d", b.cliCtx.String(cmd.MonitoringHostFlag.Name), b.cliCtx.Int(flags.MonitoringPortFlag.Name)),
@@ -673,13 +701,14 @@ func (b *BeaconNode) registerPrometheusService(cliCtx *cli.Context) error {
 	)
 	hook := prometheus.NewLogrusCollector()
 	logrus.AddHook(hook)
-	return b.services.RegisterService(service)
+	if err := b.services.RegisterHealthCollector(); err != nil {
+		log.WithError(err).Error("Could not register service health metrics")
+	}
+	// The node keeps running without its metrics if the monitoring port is in use.
+	return b.services.RegisterServiceV2(service, nil, &shared.ServiceConfig{Optional: true})
 }
 
 func (b *BeaconNode) registerGRPCGateway() error {
-	if b.cliCtx.Bool(flags.DisableGRPCGateway.Name) {
-		return nil
-	}
 	gatewayPort := b.cliCtx.Int(flags.GRPCGatewayPort.Name)
 	gatewayHost := b.cliCtx.String(flags.GRPCGatewayHost.Name)
 	rpcHost := b.cliCtx.String(flags.RPCHost.Name)
@@ -687,7 +716,9 @@ func (b *BeaconNode) registerGRPCGateway() error {
 	gatewayAddress := fmt.Sprintf("%s:%d", gatewayHost, gatewayPort)
 	allowedOrigins := strings.Split(b.cliCtx.String(flags.GPRCGatewayCorsDomain.Name), ",")
 	enableDebugRPCEndpoints := b.cliCtx.Bool(flags.EnableDebugRPCEndpoints.Name)
-	return b.services.RegisterService(
+	return b.services.RegisterServiceIfFlag(
+		flags.DisableGRPCGateway.Name,
+		!b.cliCtx.Bool(flags.DisableGRPCGateway.Name),
 		gateway.New(
 			b.ctx,
 			selfAddress,
@@ -697,6 +728,7 @@ func (b *BeaconNode) registerGRPCGateway() error {
 			enableDebugRPCEndpoints,
 			b.cliCtx.Uint64(cmd.GrpcMaxCallRecvMsgSizeFlag.Name),
 		),
+		nil,
 	)
 }
 
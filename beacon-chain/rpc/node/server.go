@@ -34,6 +34,7 @@ type Server struct {
 	PeerManager        p2p.PeerManager
 	GenesisTimeFetcher blockchain.TimeFetcher
 	GenesisFetcher     blockchain.GenesisFetcher
+	ServiceLister      ServiceLister
 }
 
 // GetSyncStatus checks the current network sync status of the node.
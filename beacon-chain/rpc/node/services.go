@@ -0,0 +1,54 @@
+package node
+
+import (
+	"context"
+
+	ptypes "github.com/gogo/protobuf/types"
+	pbrpc "github.com/prysmaticlabs/prysm/proto/beacon/rpc/v1"
+	"github.com/prysmaticlabs/prysm/shared"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ServiceLister lists the services running in the beacon node, along with
+// their status.
+type ServiceLister interface {
+	ListServices() []shared.ServiceInfo
+}
+
+// GetServiceStatuses returns the status of every service registered in the
+// beacon node, in order of registration. Status checks are bounded by the
+// service registry, so a service whose Status method hangs is reported as
+// such rather than blocking the request.
+func (ns *Server) GetServiceStatuses(_ context.Context, _ *ptypes.Empty) (*pbrpc.ServiceStatusesResponse, error) {
+	if ns.ServiceLister == nil {
+		return nil, status.Error(codes.Unavailable, "Service registry is not available")
+	}
+	infos := ns.ServiceLister.ListServices()
+	statuses := make([]*pbrpc.ServiceStatus, len(infos))
+	for i, info := range infos {
+		statuses[i] = &pbrpc.ServiceStatus{
+			Name:          info.Name,
+			State:         info.State.String(),
+			UptimeSeconds: info.Uptime.Seconds(),
+			Severity:      serviceSeverity(info.Status),
+		}
+		if info.Status != nil {
+			statuses[i].Error = info.Status.Error()
+		}
+	}
+	return &pbrpc.ServiceStatusesResponse{
+		Statuses: statuses,
+	}, nil
+}
+
+func serviceSeverity(err error) pbrpc.ServiceStatus_Severity {
+	switch shared.StatusSeverity(err) {
+	case shared.SeverityOK:
+		return pbrpc.ServiceStatus_OK
+	case shared.SeverityDegraded:
+		return pbrpc.ServiceStatus_DEGRADED
+	default:
+		return pbrpc.ServiceStatus_CRITICAL
+	}
+}
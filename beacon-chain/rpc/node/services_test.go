@@ -0,0 +1,76 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	ptypes "github.com/gogo/protobuf/types"
+	pbrpc "github.com/prysmaticlabs/prysm/proto/beacon/rpc/v1"
+	"github.com/prysmaticlabs/prysm/shared"
+	"github.com/prysmaticlabs/prysm/shared/testutil"
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestNodeServer_GetServiceStatuses(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	registry := testutil.NewRegistry(t)
+	require.NoError(t, registry.RegisterNamedService("healthy", &testutil.FakeService{}, nil))
+	require.NoError(t, registry.RegisterNamedService("failing", (&testutil.FakeService{}).WithStatuses(errors.New("no peers")), nil))
+	require.NoError(t, registry.RegisterOptionalService((&testutil.FakeService{}).WithStatuses(errors.New("no metrics"))))
+	require.NoError(t, registry.RegisterNamedService("hung", (&testutil.FakeService{}).WithBlockingStatus(release), nil))
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	pbrpc.RegisterHealthServer(server, &Server{ServiceLister: registry})
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			t.Log(err)
+		}
+	}()
+	defer server.Stop()
+	conn, err := grpc.DialContext(context.Background(), "bufnet", grpc.WithInsecure(), grpc.WithContextDialer(
+		func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		},
+	))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, conn.Close())
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	res, err := pbrpc.NewHealthClient(conn).GetServiceStatuses(ctx, &ptypes.Empty{})
+	require.NoError(t, err, "Hung status call should not block the request")
+	require.Equal(t, 4, len(res.Statuses))
+
+	assert.Equal(t, "healthy", res.Statuses[0].Name)
+	assert.Equal(t, shared.StateRegistered.String(), res.Statuses[0].State)
+	assert.Equal(t, "", res.Statuses[0].Error)
+	assert.Equal(t, pbrpc.ServiceStatus_OK, res.Statuses[0].Severity)
+
+	assert.Equal(t, "failing", res.Statuses[1].Name)
+	assert.Equal(t, "no peers", res.Statuses[1].Error)
+	assert.Equal(t, pbrpc.ServiceStatus_CRITICAL, res.Statuses[1].Severity)
+
+	assert.Equal(t, "testutil.FakeService", res.Statuses[2].Name)
+	assert.Equal(t, pbrpc.ServiceStatus_DEGRADED, res.Statuses[2].Severity)
+
+	assert.Equal(t, "hung", res.Statuses[3].Name)
+	assert.NotEqual(t, "", res.Statuses[3].Error)
+	assert.Equal(t, pbrpc.ServiceStatus_CRITICAL, res.Statuses[3].Severity)
+	assert.Equal(t, float64(0), res.Statuses[3].UptimeSeconds)
+}
+
+func TestNodeServer_GetServiceStatuses_NoRegistry(t *testing.T) {
+	ns := &Server{}
+	_, err := ns.GetServiceStatuses(context.Background(), &ptypes.Empty{})
+	assert.ErrorContains(t, "Service registry is not available", err)
+}
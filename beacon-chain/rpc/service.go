@@ -97,6 +97,7 @@ type Service struct {
 	connectedRPCClients     map[net.Addr]bool
 	clientConnectionLock    sync.Mutex
 	maxMsgSize              int
+	serviceLister           node.ServiceLister
 }
 
 // Config options for the beacon node RPC server.
@@ -132,6 +133,7 @@ type Config struct {
 	OperationNotifier       opfeed.Notifier
 	StateGen                *stategen.State
 	MaxMsgSize              int
+	ServiceLister           node.ServiceLister
 }
 
 // NewService instantiates a new RPC service instance that will
@@ -175,6 +177,7 @@ func NewService(ctx context.Context, cfg *Config) *Service {
 		enableDebugRPCEndpoints: cfg.EnableDebugRPCEndpoints,
 		connectedRPCClients:     make(map[net.Addr]bool),
 		maxMsgSize:              cfg.MaxMsgSize,
+		serviceLister:           cfg.ServiceLister,
 	}
 }
 
@@ -257,6 +260,7 @@ func (s *Service) Start() {
 		PeersFetcher:       s.peersFetcher,
 		PeerManager:        s.peerManager,
 		GenesisFetcher:     s.genesisFetcher,
+		ServiceLister:      s.serviceLister,
 	}
 	beaconChainServer := &beacon.Server{
 		Ctx:                         s.ctx,
@@ -298,6 +302,7 @@ func (s *Service) Start() {
 		SyncChecker:         s.syncService,
 	}
 	ethpb.RegisterNodeServer(s.grpcServer, nodeServer)
+	pbrpc.RegisterHealthServer(s.grpcServer, nodeServer)
 	ethpb.RegisterBeaconChainServer(s.grpcServer, beaconChainServer)
 	ethpbv1.RegisterBeaconChainServer(s.grpcServer, beaconChainServerV1)
 	if s.enableDebugRPCEndpoints {
@@ -79,6 +79,8 @@ var appHelpFlagGroups = []flagGroup{
 			debug.MemProfileRateFlag,
 			debug.CPUProfileFlag,
 			debug.TraceFlag,
+			debug.LeakCheckFlag,
+			debug.ShuffleStartOrderFlag,
 		},
 	},
 	{
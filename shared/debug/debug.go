@@ -35,6 +35,7 @@ import (
 	"time"
 
 	"github.com/fjl/memsize/memsizeui"
+	"github.com/prysmaticlabs/prysm/shared"
 	log "github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v2"
 )
@@ -78,8 +79,38 @@ var (
 		Name:  "trace",
 		Usage: "Write execution trace to the given file",
 	}
+	// LeakCheckFlag to report the goroutines left behind by services once they are stopped.
+	LeakCheckFlag = &cli.BoolFlag{
+		Name:  "debug-goroutine-leaks",
+		Usage: "Report the goroutines which services leave running once the node is stopped",
+	}
+	// ShuffleStartOrderFlag to shuffle the start order of services, to reveal hidden ordering dependencies.
+	ShuffleStartOrderFlag = &cli.Int64Flag{
+		Name:  "debug-shuffle-start-order",
+		Usage: "Shuffle the start order of services which do not depend on each other with the given seed, " +
+			"which is logged at startup so that an order can be reproduced. A negative seed picks a random seed, " +
+			"and 0, the default, keeps the registration order",
+	}
 )
 
+// RegistryOptions returns the service registry options configured by the
+// debug flags: LeakCheckFlag enables the goroutine leak check, and a non-zero
+// ShuffleStartOrderFlag shuffles the start order with the given seed, or with
+// a random seed if it is negative.
+func RegistryOptions(ctx *cli.Context) []shared.RegistryOption {
+	var opts []shared.RegistryOption
+	if ctx.Bool(LeakCheckFlag.Name) {
+		opts = append(opts, shared.WithLeakCheck())
+	}
+	switch seed := ctx.Int64(ShuffleStartOrderFlag.Name); {
+	case seed < 0:
+		opts = append(opts, shared.WithRandomShuffledStartOrder())
+	case seed > 0:
+		opts = append(opts, shared.WithShuffledStartOrder(seed))
+	}
+	return opts
+}
+
 // HandlerT implements the debugging API.
 // Do not create values of this type, use the one
 // in the Handler variable instead.
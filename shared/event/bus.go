@@ -0,0 +1,94 @@
+package event
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+// Bus is a lightweight publish/subscribe bus for notifications between
+// services. Unlike Feed, a Bus carries values of any number of types, each
+// delivered to the subscribed channels of that type only, and publishing
+// never blocks: values published while the buffer of a subscribed channel is
+// full are dropped for that subscriber only. Subscribers must therefore size
+// the buffer of their channel according to how far behind they may fall.
+//
+// The zero value is ready to use.
+type Bus struct {
+	lock sync.RWMutex
+	subs map[*busSub]struct{}
+}
+
+type busSub struct {
+	ch    reflect.Value
+	etype reflect.Type
+}
+
+// Subscribe adds a channel to the bus, which receives every value published
+// from now on which can be assigned to its element type: a chan NewHead only
+// receives the NewHead values, while a chan interface{} receives every value.
+// The channel must be a sendable channel, or Subscribe panics. The returned
+// function cancels the subscription, and can be called any number of times.
+// The channel is never closed, as it belongs to the subscriber, who must not
+// close it before cancelling the subscription.
+func (b *Bus) Subscribe(channel interface{}) func() {
+	ch := reflect.ValueOf(channel)
+	if ch.Kind() != reflect.Chan || ch.Type().ChanDir()&reflect.SendDir == 0 {
+		panic(errBadChannel)
+	}
+	sub := &busSub{ch: ch, etype: ch.Type().Elem()}
+	b.lock.Lock()
+	if b.subs == nil {
+		b.subs = make(map[*busSub]struct{})
+	}
+	b.subs[sub] = struct{}{}
+	b.lock.Unlock()
+
+	return func() {
+		b.lock.Lock()
+		delete(b.subs, sub)
+		b.lock.Unlock()
+	}
+}
+
+// SubscribeWithContext is like Subscribe, but the subscription is also
+// cancelled when the context is done. Services subscribing with their
+// service context are thus unsubscribed when they are stopped.
+func (b *Bus) SubscribeWithContext(ctx context.Context, channel interface{}) func() {
+	unsubscribe := b.Subscribe(channel)
+	done := make(chan struct{})
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			unsubscribe()
+			close(done)
+		})
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancel()
+		case <-done:
+		}
+	}()
+	return cancel
+}
+
+// Publish delivers a value to every subscribed channel of a matching type with
+// room left in its buffer, and returns the number of channels the value was
+// delivered to.
+func (b *Bus) Publish(value interface{}) int {
+	if value == nil {
+		return 0
+	}
+	v := reflect.ValueOf(value)
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	var sent int
+	for sub := range b.subs {
+		if v.Type().AssignableTo(sub.etype) && sub.ch.TrySend(v) {
+			sent++
+		}
+	}
+	return sent
+}
@@ -0,0 +1,129 @@
+package event
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+func TestBus_PublishDeliversToAllSubscribers(t *testing.T) {
+	var bus Bus
+	ch1 := make(chan string, 1)
+	defer bus.Subscribe(ch1)()
+	ch2 := make(chan string, 1)
+	defer bus.Subscribe(ch2)()
+
+	assert.Equal(t, 2, bus.Publish("head"))
+	assert.Equal(t, "head", <-ch1)
+	assert.Equal(t, "head", <-ch2)
+}
+
+func TestBus_PublishDeliversByType(t *testing.T) {
+	var bus Bus
+	texts := make(chan string, 1)
+	defer bus.Subscribe(texts)()
+	ints := make(chan int, 1)
+	defer bus.Subscribe(ints)()
+	all := make(chan interface{}, 2)
+	defer bus.Subscribe(all)()
+
+	assert.Equal(t, 2, bus.Publish("head"))
+	assert.Equal(t, 2, bus.Publish(1))
+	assert.Equal(t, "head", <-texts)
+	assert.Equal(t, 1, <-ints)
+	assert.Equal(t, "head", <-all)
+	assert.Equal(t, 1, <-all)
+	assert.Equal(t, 0, bus.Publish(nil))
+}
+
+func TestBus_SubscribeBadChannel(t *testing.T) {
+	var bus Bus
+	for _, channel := range []interface{}{1, make(<-chan int)} {
+		func() {
+			defer func() {
+				assert.Equal(t, errBadChannel, recover())
+			}()
+			bus.Subscribe(channel)
+		}()
+	}
+}
+
+func TestBus_PublishDropsWhenBufferFull(t *testing.T) {
+	var bus Bus
+	ch := make(chan int, 1)
+	defer bus.Subscribe(ch)()
+
+	assert.Equal(t, 1, bus.Publish(1))
+	assert.Equal(t, 0, bus.Publish(2), "Expected full subscriber to be skipped")
+	assert.Equal(t, 1, <-ch)
+	assert.Equal(t, 1, bus.Publish(3))
+	assert.Equal(t, 3, <-ch)
+}
+
+func TestBus_Cancel(t *testing.T) {
+	var bus Bus
+	cancel := bus.Subscribe(make(chan int, 1))
+	cancel()
+	cancel()
+
+	assert.Equal(t, 0, bus.Publish(1))
+}
+
+func TestBus_SubscribeWithContext(t *testing.T) {
+	var bus Bus
+	ctx, cancel := context.WithCancel(context.Background())
+	bus.SubscribeWithContext(ctx, make(chan int, 1))
+	cancel()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for bus.Publish(1) != 0 {
+		require.Equal(t, true, time.Now().Before(deadline), "Subscription was not cancelled with its context")
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBus_SubscribeWithContext_CancelEndsGoroutine(t *testing.T) {
+	var bus Bus
+	before := runtime.NumGoroutine()
+	for i := 0; i < 100; i++ {
+		bus.SubscribeWithContext(context.Background(), make(chan int, 1))()
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before {
+		require.Equal(t, true, time.Now().Before(deadline), "Cancelled subscriptions left goroutines running")
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, 0, bus.Publish(1))
+}
+
+func TestBus_ConcurrentPublish(t *testing.T) {
+	var bus Bus
+	ch := make(chan int, 100)
+	cancel := bus.Subscribe(ch)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				bus.Publish(i*10 + j)
+			}
+		}(i)
+	}
+	wg.Wait()
+	cancel()
+	close(ch)
+
+	var received int
+	for range ch {
+		received++
+	}
+	require.Equal(t, 100, received)
+}
@@ -0,0 +1,39 @@
+package event_test
+
+import (
+	"fmt"
+
+	"github.com/prysmaticlabs/prysm/shared/event"
+)
+
+func ExampleBus() {
+	// This example shows a service notifying others through a bus. Each
+	// subscriber only receives the values of the type of its channel, and
+	// sizes its buffer so that it never misses a notification here.
+	type newHead struct {
+		slot uint64
+	}
+	type finalized struct {
+		epoch uint64
+	}
+	var bus event.Bus
+
+	heads := make(chan newHead, 10)
+	defer bus.Subscribe(heads)()
+	checkpoints := make(chan finalized, 10)
+	defer bus.Subscribe(checkpoints)()
+
+	for slot := uint64(1); slot <= 3; slot++ {
+		bus.Publish(newHead{slot: slot})
+	}
+	bus.Publish(finalized{epoch: 1})
+	for i := 0; i < 3; i++ {
+		fmt.Println("new head at slot", (<-heads).slot)
+	}
+	fmt.Println("finalized epoch", (<-checkpoints).epoch)
+	// Output:
+	// new head at slot 1
+	// new head at slot 2
+	// new head at slot 3
+	// finalized epoch 1
+}
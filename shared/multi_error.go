@@ -0,0 +1,56 @@
+package shared
+
+import (
+	"errors"
+	"strings"
+)
+
+// MultiError gathers the errors of several services, such as those which
+// failed to stop in StopAll.
+type MultiError struct {
+	Errors []error
+}
+
+// Error returns the messages of every gathered error.
+func (m *MultiError) Error() string {
+	msgs := make([]string, len(m.Errors))
+	for i, err := range m.Errors {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Is reports whether any of the gathered errors matches the target.
+func (m *MultiError) Is(target error) bool {
+	for _, err := range m.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first gathered error matching the target.
+func (m *MultiError) As(target interface{}) bool {
+	for _, err := range m.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MultiError) add(err error) {
+	if err != nil {
+		m.Errors = append(m.Errors, err)
+	}
+}
+
+// errorOrNil returns nil when no error was gathered, so that callers can
+// compare the result against nil.
+func (m *MultiError) errorOrNil() error {
+	if len(m.Errors) == 0 {
+		return nil
+	}
+	return m
+}
@@ -0,0 +1,24 @@
+package shared
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+)
+
+func TestMultiError(t *testing.T) {
+	errs := &MultiError{}
+	assert.NoError(t, errs.errorOrNil())
+
+	first := errors.New("first")
+	errs.add(first)
+	errs.add(nil)
+	errs.add(&UnknownServiceError{})
+	err := errs.errorOrNil()
+	assert.ErrorContains(t, "first; unknown service: <nil>", err)
+	assert.Equal(t, true, errors.Is(err, first))
+	var unknown *UnknownServiceError
+	assert.Equal(t, true, errors.As(err, &unknown))
+	assert.Equal(t, false, errors.Is(err, ErrServicePaused))
+}
@@ -1,6 +1,7 @@
 package prometheus_test
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -27,7 +28,7 @@ func TestLogrusCollector(t *testing.T) {
 	service := prometheus.NewService(addr, nil)
 	hook := prometheus.NewLogrusCollector()
 	log.AddHook(hook)
-	go service.Start()
+	require.NoError(t, service.Start(context.Background()))
 	defer func() {
 		err := service.Stop()
 		require.NoError(t, err)
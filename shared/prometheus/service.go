@@ -65,9 +65,9 @@ func (s *Service) healthzHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	var hasError bool
 	var statuses []serviceStatus
-	for k, v := range s.svcRegistry.Statuses() {
+	for k, v := range s.svcRegistry.StatusesByName() {
 		s := serviceStatus{
-			Name:   k.String(),
+			Name:   k,
 			Status: true,
 		}
 		if v != nil {
@@ -121,27 +121,20 @@ func (s *Service) goroutinezHandler(w http.ResponseWriter, _ *http.Request) {
 	}
 }
 
-// Start the prometheus service.
-func (s *Service) Start() {
+// Start the prometheus service, failing if its address is already in use.
+func (s *Service) Start(_ context.Context) error {
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return fmt.Errorf("could not listen to host:port %s: %w", s.server.Addr, err)
+	}
+	log.WithField("address", s.server.Addr).Debug("Starting prometheus service")
 	go func() {
-		// See if the port is already used.
-		conn, err := net.DialTimeout("tcp", s.server.Addr, time.Second)
-		if err == nil {
-			if err := conn.Close(); err != nil {
-				log.WithError(err).Error("Failed to close connection")
-			}
-			// Something on the port; we cannot use it.
-			log.WithField("address", s.server.Addr).Warn("Port already in use; cannot start prometheus service")
-		} else {
-			// Nothing on that port; we can use it.
-			log.WithField("address", s.server.Addr).Debug("Starting prometheus service")
-			err := s.server.ListenAndServe()
-			if err != nil && err != http.ErrServerClosed {
-				log.Errorf("Could not listen to host:port :%s: %v", s.server.Addr, err)
-				s.failStatus = err
-			}
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Errorf("Could not serve on host:port %s: %v", s.server.Addr, err)
+			s.failStatus = err
 		}
 	}()
+	return nil
 }
 
 // Stop the service gracefully.
@@ -1,10 +1,13 @@
 package prometheus
 
 import (
+	"context"
 	"errors"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -22,9 +25,7 @@ func init() {
 
 func TestLifecycle(t *testing.T) {
 	prometheusService := NewService(":2112", nil)
-	prometheusService.Start()
-	// Give service time to start.
-	time.Sleep(time.Second)
+	require.NoError(t, prometheusService.Start(context.Background()))
 
 	// Query the service to ensure it really started.
 	resp, err := http.Get("http://localhost:2112/metrics")
@@ -41,6 +42,27 @@ func TestLifecycle(t *testing.T) {
 	assert.NotNil(t, err, "Service still running after Stop()")
 }
 
+func TestStart_PortInUseIsDegraded(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, listener.Close())
+	}()
+	registry := shared.NewServiceRegistry()
+	other := &mockService{}
+	require.NoError(t, registry.RegisterService(other))
+	prometheusService := NewService(listener.Addr().String(), registry)
+	require.NoError(t, registry.RegisterServiceV2(prometheusService, nil, &shared.ServiceConfig{Optional: true}))
+
+	require.NoError(t, registry.StartAll())
+	statuses := registry.Statuses()
+	err = statuses[reflect.TypeOf(prometheusService)]
+	assert.ErrorContains(t, "could not listen to host:port", err)
+	assert.Equal(t, shared.SeverityDegraded, shared.StatusSeverity(err))
+	assert.NoError(t, statuses[reflect.TypeOf(other)])
+	require.NoError(t, registry.StopAll())
+}
+
 type mockService struct {
 	status error
 }
@@ -75,7 +97,7 @@ func TestHealthz(t *testing.T) {
 	}
 
 	body := rr.Body.String()
-	if !strings.Contains(body, "*prometheus.mockService: OK") {
+	if !strings.Contains(body, "prometheus.mockService: OK") {
 		t.Errorf("Expected body to contain mockService status, but got %v", body)
 	}
 
@@ -91,7 +113,7 @@ func TestHealthz(t *testing.T) {
 	body = rr.Body.String()
 	if !strings.Contains(
 		body,
-		"*prometheus.mockService: ERROR something really bad has happened",
+		"prometheus.mockService: ERROR something really bad has happened",
 	) {
 		t.Errorf("Expected body to contain mockService status, but got %v", body)
 	}
@@ -122,7 +144,7 @@ func TestContentNegotiation(t *testing.T) {
 		handler.ServeHTTP(rr, req)
 
 		body := rr.Body.String()
-		if !strings.Contains(body, "*prometheus.mockService: OK") {
+		if !strings.Contains(body, "prometheus.mockService: OK") {
 			t.Errorf("Expected body to contain mockService status, but got %q", body)
 		}
 
@@ -132,7 +154,7 @@ func TestContentNegotiation(t *testing.T) {
 		handler.ServeHTTP(rr, req)
 
 		body = rr.Body.String()
-		expectedJSON := "{\"error\":\"\",\"data\":[{\"service\":\"*prometheus.mockService\",\"status\":true,\"error\":\"\"}]}"
+		expectedJSON := "{\"error\":\"\",\"data\":[{\"service\":\"prometheus.mockService\",\"status\":true,\"error\":\"\"}]}"
 		if !strings.Contains(body, expectedJSON) {
 			t.Errorf("Unexpected data, want: %q got %q", expectedJSON, body)
 		}
@@ -153,7 +175,7 @@ func TestContentNegotiation(t *testing.T) {
 		handler.ServeHTTP(rr, req)
 
 		body := rr.Body.String()
-		if !strings.Contains(body, "*prometheus.mockService: ERROR something is wrong") {
+		if !strings.Contains(body, "prometheus.mockService: ERROR something is wrong") {
 			t.Errorf("Expected body to contain mockService status, but got %q", body)
 		}
 
@@ -163,7 +185,7 @@ func TestContentNegotiation(t *testing.T) {
 		handler.ServeHTTP(rr, req)
 
 		body = rr.Body.String()
-		expectedJSON := "{\"error\":\"\",\"data\":[{\"service\":\"*prometheus.mockService\",\"status\":false,\"error\":\"something is wrong\"}]}"
+		expectedJSON := "{\"error\":\"\",\"data\":[{\"service\":\"prometheus.mockService\",\"status\":false,\"error\":\"something is wrong\"}]}"
 		if !strings.Contains(body, expectedJSON) {
 			t.Errorf("Unexpected data, want: %q got %q", expectedJSON, body)
 		}
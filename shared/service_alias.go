@@ -0,0 +1,73 @@
+package shared
+
+import (
+	"fmt"
+	"reflect"
+	"sync/atomic"
+)
+
+// RegisterServiceAs registers a service like RegisterServiceWithConfig, and
+// also as each of the given interfaces, passed as nil pointers to them such
+// as (*HeadFetcher)(nil), so that FetchService with a pointer to one of the
+// interfaces resolves to the service even if other registered services
+// implement it as well. The service is registered once: it is started,
+// stopped and checked for its status once, whatever the number of
+// interfaces. Registering a service as an interface another service was
+// registered as fails with ErrServiceAlreadyRegistered, and nothing is
+// registered if any of the interfaces is invalid.
+func (s *ServiceRegistry) RegisterServiceAs(service Service, ctx *ServiceContext, as ...interface{}) error {
+	if service == nil {
+		return errNilService
+	}
+	kind := reflect.TypeOf(service)
+	aliases := make([]reflect.Type, 0, len(as))
+	for _, a := range as {
+		t := reflect.TypeOf(a)
+		if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Interface {
+			return fmt.Errorf("could not register service %s as %T: not a pointer to an interface", typeName(kind), a)
+		}
+		if !kind.Implements(t.Elem()) {
+			return fmt.Errorf("could not register service %s as %s: interface not implemented", typeName(kind), typeName(t.Elem()))
+		}
+		aliases = append(aliases, t.Elem())
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i, alias := range aliases {
+		if registered, exists := s.aliases[alias]; exists {
+			return fmt.Errorf("%w: %s is registered as %s", ErrServiceAlreadyRegistered, typeName(registered), typeName(alias))
+		}
+		for _, other := range aliases[:i] {
+			if other == alias {
+				return fmt.Errorf("could not register service %s as %s twice", typeName(kind), typeName(alias))
+			}
+		}
+	}
+	if err := s.register(service, ctx, nil); err != nil {
+		return err
+	}
+	for _, alias := range aliases {
+		s.aliases[alias] = kind
+	}
+	return nil
+}
+
+// fetchByAlias sets the element to the service registered with the given
+// type, as the interface type of the element. The caller must hold the
+// registry lock.
+func (s *ServiceRegistry) fetchByAlias(element reflect.Value, kind reflect.Type) error {
+	entry, ok := s.services[kind]
+	if !ok {
+		return &UnknownServiceError{Kind: element.Type()}
+	}
+	if !reflect.TypeOf(entry.service).Implements(element.Type()) {
+		return fmt.Errorf("service %v is overridden by %s, which does not implement %s", entry, typeName(reflect.TypeOf(entry.service)), typeName(element.Type()))
+	}
+	if entry.state == StateStopped {
+		s.log.Warnf("Fetching stopped service %v", entry)
+	}
+	atomic.AddInt32(&entry.fetches, 1)
+	element.Set(reflect.ValueOf(entry.service))
+	return nil
+}
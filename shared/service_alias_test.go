@@ -0,0 +1,70 @@
+package shared
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+type finalizationFetcher interface {
+	FinalizedEpoch() uint64
+}
+
+type chainService struct {
+	startCountingService
+}
+
+func (s *chainService) HeadSlot() uint64 {
+	return 32
+}
+
+func (s *chainService) FinalizedEpoch() uint64 {
+	return 1
+}
+
+func TestRegisterServiceAs(t *testing.T) {
+	registry := NewServiceRegistry()
+	chain := &chainService{}
+	require.NoError(t, registry.RegisterServiceAs(chain, nil, (*headFetcher)(nil), (*finalizationFetcher)(nil)))
+	require.NoError(t, registry.RegisterService(&chainInfoService{}))
+
+	var head headFetcher
+	require.NoError(t, registry.FetchService(&head))
+	assert.Equal(t, uint64(32), head.HeadSlot())
+	var finalization finalizationFetcher
+	require.NoError(t, registry.FetchService(&finalization))
+	assert.Equal(t, chain, finalization.(*chainService))
+	var fetched *chainService
+	require.NoError(t, registry.FetchService(&fetched))
+	assert.Equal(t, chain, fetched)
+
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, reflect.TypeOf(chain), StateRunning)
+	assert.Equal(t, 2, len(registry.ListServices()))
+	assert.Equal(t, 2, len(registry.Statuses()))
+	require.NoError(t, registry.StopAll())
+	chain.lock.Lock()
+	assert.Equal(t, 1, chain.starts)
+	chain.lock.Unlock()
+}
+
+func TestRegisterServiceAs_DuplicateAlias(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterServiceAs(&chainService{}, nil, (*headFetcher)(nil)))
+
+	err := registry.RegisterServiceAs(&chainInfoService{}, nil, (*headFetcher)(nil))
+	assert.Equal(t, true, errors.Is(err, ErrServiceAlreadyRegistered))
+	assert.ErrorContains(t, "shared.chainService is registered as shared.headFetcher", err)
+	assert.Equal(t, 1, len(registry.ListServices()))
+}
+
+func TestRegisterServiceAs_InvalidAlias(t *testing.T) {
+	registry := NewServiceRegistry()
+	assert.ErrorContains(t, "not a pointer to an interface", registry.RegisterServiceAs(&mockService{}, nil, &mockService{}))
+	assert.ErrorContains(t, "interface not implemented", registry.RegisterServiceAs(&mockService{}, nil, (*headFetcher)(nil)))
+	assert.ErrorContains(t, "twice", registry.RegisterServiceAs(&chainInfoService{}, nil, (*headFetcher)(nil), (*headFetcher)(nil)))
+	assert.Equal(t, 0, len(registry.ListServices()))
+}
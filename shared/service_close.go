@@ -0,0 +1,48 @@
+package shared
+
+import (
+	"errors"
+	"reflect"
+)
+
+// ErrRegistryClosed is returned, or wrapped, by the registrations, starts and
+// fetches attempted once Close was called.
+var ErrRegistryClosed = errors.New("registry is closed")
+
+// Close finalizes the registry: it runs StopAll unless it already ran,
+// cancels the root context, and releases the registered services, so that a
+// registry which is no longer used leaks neither goroutines nor contexts.
+// Every later registration, start or fetch fails with ErrRegistryClosed.
+// Close is idempotent and safe for concurrent use: every call returns the
+// result of the StopAll run by the first one, once it completed.
+func (s *ServiceRegistry) Close() error {
+	s.closeOnce.Do(func() {
+		s.lock.Lock()
+		s.closed = true
+		stopping := s.stopping
+		s.lock.Unlock()
+		if !stopping {
+			s.closeErr = s.StopAll()
+		}
+		s.cancelRoot()
+
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.services = make(map[reflect.Type]*serviceEntry)
+		s.named = make(map[string]*serviceEntry)
+		s.aliases = make(map[reflect.Type]reflect.Type)
+		s.entries = nil
+		s.disabled = nil
+		s.startedHooks = make(map[reflect.Type][]func())
+		s.stoppedHooks = make(map[reflect.Type][]func())
+		s.shutdownHooks = nil
+	})
+	return s.closeErr
+}
+
+// isClosed returns whether Close was called.
+func (s *ServiceRegistry) isClosed() bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.closed
+}
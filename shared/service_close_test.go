@@ -0,0 +1,74 @@
+package shared
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+func releasedStopService() *countingStopService {
+	svc := &countingStopService{release: make(chan struct{})}
+	close(svc.release)
+	return svc
+}
+
+func TestClose(t *testing.T) {
+	registry := NewServiceRegistry()
+	svc := releasedStopService()
+	require.NoError(t, registry.RegisterService(svc))
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, reflect.TypeOf(svc), StateRunning)
+
+	require.NoError(t, registry.Close())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&svc.stops))
+	assert.NotNil(t, registry.RootContext().Err(), "Root context was not cancelled")
+	assert.Equal(t, 0, len(registry.ListServices()))
+
+	var fetched *countingStopService
+	assert.Equal(t, true, errors.Is(registry.FetchService(&fetched), ErrRegistryClosed))
+	assert.Equal(t, true, errors.Is(registry.FetchNamedService("beacon", &fetched), ErrRegistryClosed))
+	assert.Equal(t, true, errors.Is(registry.RegisterService(&mockService{}), ErrRegistryClosed))
+	assert.Equal(t, true, errors.Is(registry.RegisterNamedService("beacon", &mockService{}, nil), ErrRegistryClosed))
+	assert.Equal(t, true, errors.Is(registry.StartAll(), ErrRegistryClosed))
+	assert.Equal(t, true, errors.Is(registry.RestartService(reflect.TypeOf(svc)), ErrRegistryClosed))
+	assert.Equal(t, true, errors.Is(registry.StartStatusPoller(1), ErrRegistryClosed))
+}
+
+func TestClose_AfterStopAll(t *testing.T) {
+	registry := NewServiceRegistry()
+	svc := releasedStopService()
+	require.NoError(t, registry.RegisterService(svc))
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, reflect.TypeOf(svc), StateRunning)
+	require.NoError(t, registry.StopAll())
+
+	require.NoError(t, registry.Close())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&svc.stops), "Close stopped the services again")
+}
+
+func TestClose_Concurrent(t *testing.T) {
+	registry := NewServiceRegistry()
+	svc := releasedStopService()
+	require.NoError(t, registry.RegisterService(&failingStopService{err: errors.New("db locked")}))
+	require.NoError(t, registry.RegisterService(svc))
+
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = registry.Close()
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&svc.stops))
+	for _, err := range errs {
+		assert.ErrorContains(t, "db locked", err, "Every Close call should report the StopAll error")
+	}
+}
@@ -0,0 +1,264 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+)
+
+// ServiceContext ties the lifetime of a registered service to the registry.
+// It is cancelled by the registry once the service has stopped, or once it has
+// exceeded its stop timeout, so that any goroutines spawned by the service
+// with this context are terminated either way. A context shared by several
+// services is only cancelled once none of them is active anymore.
+type ServiceContext struct {
+	context.Context
+	// Log is a logger whose prefix is the name of the service. The registry
+	// sets it when the service is registered, unless it was already set.
+	// Services should fall back to their package logger when it is nil.
+	Log *logrus.Entry
+	// logger is the logger of Log when the registry set it, whose level can
+	// be changed by SetServiceLogLevel.
+	logger *logrus.Logger
+	cancel context.CancelFunc
+	// parent is the context the service context derives from, which a
+	// renewed service context derives from as well.
+	parent context.Context
+	lock   sync.RWMutex
+	// reason is why the registry is stopping the service, empty until then.
+	reason ShutdownReason
+	// service is the name of the service the context was registered with,
+	// and report delivers the errors passed to Fatalf to the registry.
+	service string
+	report  func(error)
+	// group tracks the goroutines spawned with Go, and groupCtx is the
+	// context they are given. Both are created by the first call to Go.
+	group    *errgroup.Group
+	groupCtx context.Context
+}
+
+// NewServiceContext returns a cancellable service context derived from
+// context.Background(). Use the NewServiceContext method of a registry to
+// derive it from the root context of the registry instead.
+func NewServiceContext() *ServiceContext {
+	return newServiceContext(context.Background())
+}
+
+func newServiceContext(parent context.Context) *ServiceContext {
+	ctx, cancel := context.WithCancel(parent)
+	return &ServiceContext{
+		Context: ctx,
+		cancel:  cancel,
+		parent:  parent,
+	}
+}
+
+// Cancel cancels the service context, signalling every goroutine using it
+// to terminate.
+func (c *ServiceContext) Cancel() {
+	c.cancel()
+}
+
+// Value returns the reason the service is being stopped for under the key
+// read by ShutdownReasonFromContext, and the value of the context the service
+// context derives from otherwise.
+func (c *ServiceContext) Value(key interface{}) interface{} {
+	if _, ok := key.(shutdownReasonKey); ok {
+		c.lock.RLock()
+		reason := c.reason
+		c.lock.RUnlock()
+		if reason != "" {
+			return reason
+		}
+	}
+	return c.Context.Value(key)
+}
+
+// Go runs the function on a new goroutine tracked by the service context. The
+// function is given a context derived from the service context, which is
+// also cancelled once a function returns an error. Once the service has
+// stopped and its context is cancelled, StopAll waits for the goroutines to
+// return within the stop timeout of the service, and reports the first error
+// they returned other than the cancellation of their context. A panic in the
+// function is recovered and reported as its error.
+func (c *ServiceContext) Go(f func(ctx context.Context) error) {
+	c.lock.Lock()
+	if c.group == nil {
+		c.group, c.groupCtx = errgroup.WithContext(c)
+	}
+	group, ctx := c.group, c.groupCtx
+	c.lock.Unlock()
+	group.Go(func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("goroutine panicked: %v", r)
+			}
+		}()
+		return f(ctx)
+	})
+}
+
+// Wait blocks until every goroutine spawned with Go has returned, and returns
+// the first error they returned, if any.
+func (c *ServiceContext) Wait() error {
+	c.lock.RLock()
+	group := c.group
+	c.lock.RUnlock()
+	if group == nil {
+		return nil
+	}
+	return group.Wait()
+}
+
+// hasGoroutines returns whether Go was called on the service context.
+func (c *ServiceContext) hasGoroutines() bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.group != nil
+}
+
+// setShutdownReason records why the service is being stopped, unless a
+// reason was already recorded.
+func (c *ServiceContext) setShutdownReason(reason ShutdownReason) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.reason == "" {
+		c.reason = reason
+	}
+}
+
+// renew returns a fresh service context to be used by a restarted service,
+// keeping the logger of the service.
+func (c *ServiceContext) renew() *ServiceContext {
+	ctx := newServiceContext(c.parent)
+	ctx.Log = c.Log
+	ctx.logger = c.logger
+	ctx.service = c.service
+	ctx.report = c.report
+	return ctx
+}
+
+// setLogger sets the logger of the service context, unless it was already
+// set, using the given service name as its prefix. The logger has its own
+// level, initially the level of the standard logger, and otherwise writes
+// like the standard logger.
+func (c *ServiceContext) setLogger(name string) {
+	if c.Log != nil {
+		return
+	}
+	std := logrus.StandardLogger()
+	c.logger = &logrus.Logger{
+		Out:          std.Out,
+		Hooks:        std.Hooks,
+		Formatter:    std.Formatter,
+		ReportCaller: std.ReportCaller,
+		Level:        std.GetLevel(),
+		ExitFunc:     std.ExitFunc,
+	}
+	c.Log = c.logger.WithField("prefix", name)
+}
+
+// rootContext is the root context of a registry, derived from the context
+// given to NewServiceRegistryWithContext. Once StartAllWithContext linked it
+// to its own context, the values and deadline of that context are visible
+// through the root context as well, including from the service contexts
+// created before StartAllWithContext was called.
+type rootContext struct {
+	context.Context
+	lock  sync.RWMutex
+	start context.Context
+}
+
+func (c *rootContext) link(ctx context.Context) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.start = ctx
+}
+
+func (c *rootContext) startContext() context.Context {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.start
+}
+
+// Deadline returns the earliest of the deadlines of the root context and of
+// the linked context.
+func (c *rootContext) Deadline() (time.Time, bool) {
+	deadline, ok := c.Context.Deadline()
+	if start := c.startContext(); start != nil {
+		if d, set := start.Deadline(); set && (!ok || d.Before(deadline)) {
+			return d, true
+		}
+	}
+	return deadline, ok
+}
+
+// Value returns the value of the linked context for the key, if any, or the
+// value of the root context otherwise.
+func (c *rootContext) Value(key interface{}) interface{} {
+	if start := c.startContext(); start != nil {
+		if v := start.Value(key); v != nil {
+			return v
+		}
+	}
+	return c.Context.Value(key)
+}
+
+// linkStartContext links the root context to the context given to
+// StartAllWithContext, and cancels the root context along with every service
+// context once that context is done, unless StopAll was called first.
+func (s *ServiceRegistry) linkStartContext(ctx context.Context) {
+	if ctx == context.Background() {
+		return
+	}
+	s.root.link(ctx)
+	if ctx.Done() == nil {
+		return
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-s.shutdown:
+			return
+		}
+		// Both may be done by the time the goroutine runs.
+		select {
+		case <-s.shutdown:
+			return
+		default:
+		}
+		s.log.WithError(ctx.Err()).Warn("Start context of the registry is done")
+		s.CancelRoot()
+	}()
+}
+
+// NewServiceContext returns a cancellable service context derived from the
+// root context of the registry.
+func (s *ServiceRegistry) NewServiceContext() *ServiceContext {
+	return newServiceContext(s.root)
+}
+
+// RootContext returns the context every service context created by the
+// registry derives from.
+func (s *ServiceRegistry) RootContext() context.Context {
+	return s.root
+}
+
+// CancelRoot cancels the root context of the registry, and with it every
+// service context, whether it derives from the root context or not. It is
+// meant for emergency shutdowns: services are not stopped, and StopAll still
+// cancels the context of each service once it has stopped.
+func (s *ServiceRegistry) CancelRoot() {
+	s.log.Warn("Cancelling the context of every service")
+	s.cancelRoot()
+	for _, entry := range s.snapshot() {
+		s.lock.RLock()
+		ctx := entry.ctx
+		s.lock.RUnlock()
+		ctx.Cancel()
+	}
+}
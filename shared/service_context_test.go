@@ -0,0 +1,180 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+type nodeIDKey struct{}
+
+func TestNewServiceRegistryWithContext_ValuesVisibleToServices(t *testing.T) {
+	registry := NewServiceRegistryWithContext(context.WithValue(context.Background(), nodeIDKey{}, "node-1"))
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	ctx := registry.NewServiceContext()
+	require.NoError(t, registry.RegisterServiceWithConfig(&secondMockService{}, ctx, nil))
+
+	assert.Equal(t, "node-1", ctx.Value(nodeIDKey{}))
+	assert.Equal(t, "node-1", registry.snapshot()[0].ctx.Value(nodeIDKey{}))
+	assert.Equal(t, "node-1", registry.RootContext().Value(nodeIDKey{}))
+
+	// A restarted service keeps deriving from the root context.
+	require.NoError(t, registry.StartAll())
+	kind := reflect.TypeOf(&mockService{})
+	waitForState(t, registry, kind, StateRunning)
+	require.NoError(t, registry.RestartService(kind))
+	assert.Equal(t, "node-1", registry.snapshot()[0].ctx.Value(nodeIDKey{}))
+	require.NoError(t, registry.StopAll())
+	// Stopping services does not cancel the root context.
+	require.NoError(t, registry.RootContext().Err())
+}
+
+func TestCancelRoot(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	derived := registry.NewServiceContext()
+	require.NoError(t, registry.RegisterServiceWithConfig(&secondMockService{}, derived, nil))
+	independent := NewServiceContext()
+	require.NoError(t, registry.RegisterServiceWithConfig(&thirdMockService{}, independent, nil))
+	unregistered := registry.NewServiceContext()
+
+	registry.CancelRoot()
+	assert.ErrorContains(t, "context canceled", registry.RootContext().Err())
+	for _, entry := range registry.snapshot() {
+		assert.ErrorContains(t, "context canceled", entry.ctx.Err(), "Context of %v not cancelled", entry)
+	}
+	assert.ErrorContains(t, "context canceled", unregistered.Err())
+	assert.ErrorContains(t, "context canceled", independent.Err())
+}
+
+func TestStartAllWithContext_ValuesAndCancellation(t *testing.T) {
+	registry := NewServiceRegistry()
+	ctx := registry.NewServiceContext()
+	require.NoError(t, registry.RegisterServiceWithConfig(&mockService{}, ctx, nil))
+
+	deadline := time.Now().Add(time.Hour)
+	parent, cancel := context.WithDeadline(context.WithValue(context.Background(), nodeIDKey{}, "node-1"), deadline)
+	defer cancel()
+	require.NoError(t, registry.StartAllWithContext(parent))
+	assert.Equal(t, "node-1", ctx.Value(nodeIDKey{}))
+	assert.Equal(t, "node-1", registry.NewServiceContext().Value(nodeIDKey{}))
+	d, ok := ctx.Deadline()
+	assert.Equal(t, true, ok)
+	assert.Equal(t, deadline, d)
+
+	cancel()
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Service context not cancelled with the start context")
+	}
+	assert.ErrorContains(t, "context canceled", registry.RootContext().Err())
+	require.NoError(t, registry.StopAll())
+}
+
+func TestStartAllWithContext_StopAllStopsWatching(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	parent, cancel := context.WithCancel(context.Background())
+	require.NoError(t, registry.StartAllWithContext(parent))
+	require.NoError(t, registry.StopAll())
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, registry.RootContext().Err())
+}
+
+// goroutineService spawns the given functions with the Go method of its
+// service context when started.
+type goroutineService struct {
+	ctx   *ServiceContext
+	funcs []func(ctx context.Context) error
+}
+
+func (s *goroutineService) Start() {
+	for _, f := range s.funcs {
+		s.ctx.Go(f)
+	}
+}
+
+func (s *goroutineService) Stop() error {
+	return nil
+}
+
+func (s *goroutineService) Status() error {
+	return nil
+}
+
+func TestServiceContextGo_StopAllWaitsForGoroutines(t *testing.T) {
+	registry := NewServiceRegistry(WithoutPreStop())
+	ctx := registry.NewServiceContext()
+	returned := make(chan struct{}, 2)
+	worker := func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		returned <- struct{}{}
+		return ctx.Err()
+	}
+	svc := &goroutineService{ctx: ctx, funcs: []func(context.Context) error{worker, worker}}
+	require.NoError(t, registry.RegisterServiceWithConfig(svc, ctx, nil))
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, reflect.TypeOf(svc), StateRunning)
+
+	require.NoError(t, registry.StopAll())
+	assert.Equal(t, 2, len(returned), "StopAll returned before the goroutines")
+}
+
+func TestServiceContextGo_ReportsGoroutineErrors(t *testing.T) {
+	registry := NewServiceRegistry(WithoutPreStop())
+	ctx := registry.NewServiceContext()
+	failed := make(chan struct{})
+	svc := &goroutineService{ctx: ctx, funcs: []func(context.Context) error{
+		func(ctx context.Context) error {
+			defer close(failed)
+			return errors.New("could not dial peer")
+		},
+		func(ctx context.Context) error {
+			// The context of the other goroutines is cancelled by the error.
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}}
+	require.NoError(t, registry.RegisterServiceWithConfig(svc, ctx, nil))
+	require.NoError(t, registry.StartAll())
+	<-failed
+
+	err := registry.StopAll()
+	assert.ErrorContains(t, "goroutine of the service failed: could not dial peer", err)
+}
+
+func TestServiceContextGo_GoroutinesNotReturning(t *testing.T) {
+	registry := NewServiceRegistry(WithoutPreStop())
+	ctx := registry.NewServiceContext()
+	release := make(chan struct{})
+	defer close(release)
+	svc := &goroutineService{ctx: ctx, funcs: []func(context.Context) error{
+		func(ctx context.Context) error {
+			<-release
+			return nil
+		},
+	}}
+	require.NoError(t, registry.RegisterServiceWithConfig(svc, ctx, &ServiceConfig{StopTimeout: 50 * time.Millisecond}))
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, reflect.TypeOf(svc), StateRunning)
+
+	err := registry.StopAll()
+	assert.ErrorContains(t, "goroutines of the service did not return within 50ms", err)
+}
+
+func TestServiceContextWait(t *testing.T) {
+	ctx := NewServiceContext()
+	require.NoError(t, ctx.Wait())
+	ctx.Go(func(context.Context) error {
+		panic("boom")
+	})
+	assert.ErrorContains(t, "goroutine panicked: boom", ctx.Wait())
+}
@@ -0,0 +1,88 @@
+package shared
+
+import (
+	"sync"
+	"time"
+)
+
+// ServiceCounters counts the restarts and failures of a service since the
+// registry was created. They are kept when the service is restarted, so that
+// a service failing over and over again stands out.
+type ServiceCounters struct {
+	// Restarts counts the restarts of the service, by RestartService or the
+	// watchdog.
+	Restarts int
+	// Failures counts the failed starts and stops of the service, including
+	// the starts retried, and the unhealthy statuses which made the
+	// watchdog restart it or give up on it.
+	Failures int
+	// LastFailure is when the service last failed, or the zero time if it
+	// never did.
+	LastFailure time.Time
+	// LastFailureErr is the error of the last failure, if any.
+	LastFailureErr error
+	// Panics counts the panics of the service recovered by the registry,
+	// and LastPanic is the last of them, if any.
+	Panics    int
+	LastPanic *ServicePanic
+	// StatusTimeouts counts the status checks of the service which did not
+	// return within the status timeout of the registry.
+	StatusTimeouts int
+}
+
+// serviceCounters holds the counters of a service, safe for concurrent use
+// without holding the registry lock.
+type serviceCounters struct {
+	lock     sync.Mutex
+	counters ServiceCounters
+}
+
+func (c *serviceCounters) restarted() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.counters.Restarts++
+}
+
+func (c *serviceCounters) failed(err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.counters.Failures++
+	c.counters.LastFailure = time.Now()
+	c.counters.LastFailureErr = err
+}
+
+func (c *serviceCounters) panicked(p *ServicePanic) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.counters.Panics++
+	c.counters.LastPanic = p
+}
+
+func (c *serviceCounters) statusTimedOut() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.counters.StatusTimeouts++
+}
+
+func (c *serviceCounters) get() ServiceCounters {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.counters
+}
+
+// Counters returns the restart, failure and panic counters of every service,
+// keyed by name.
+func (s *ServiceRegistry) Counters() map[string]ServiceCounters {
+	entries := s.snapshot()
+	counters := make(map[string]ServiceCounters, len(entries))
+	for _, entry := range entries {
+		counters[entry.String()] = entry.counters.get()
+	}
+	return counters
+}
+
+// failed records a failure of a service and emits a ServiceFailed event.
+func (s *ServiceRegistry) failed(entry *serviceEntry, err error) {
+	entry.counters.failed(err)
+	s.emit(ServiceFailed, entry, err)
+}
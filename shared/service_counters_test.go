@@ -0,0 +1,81 @@
+package shared
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+func TestServiceCounters_RestartsAndFailures(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.RegisterService(&failingStopService{err: errors.New("db locked")}))
+	require.NoError(t, registry.StartAll())
+	mockKind, failingKind := reflect.TypeOf(&mockService{}), reflect.TypeOf(&failingStopService{})
+	waitForState(t, registry, mockKind, StateRunning)
+	waitForState(t, registry, failingKind, StateRunning)
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, registry.RestartService(mockKind))
+		waitForState(t, registry, mockKind, StateRunning)
+	}
+	before := time.Now()
+	assert.ErrorContains(t, "db locked", registry.RestartService(failingKind))
+
+	counters := registry.Counters()
+	assert.DeepEqual(t, ServiceCounters{Restarts: 2}, counters["shared.mockService"])
+	failing := counters["shared.failingStopService"]
+	assert.Equal(t, 0, failing.Restarts)
+	assert.Equal(t, 1, failing.Failures)
+	assert.ErrorContains(t, "db locked", failing.LastFailureErr)
+	assert.Equal(t, false, failing.LastFailure.Before(before))
+
+	infos := registry.ListServices()
+	assert.Equal(t, 2, infos[0].Counters.Restarts)
+	assert.Equal(t, 1, infos[1].Counters.Failures)
+
+	statuses := registry.DetailedStatuses()
+	assert.Equal(t, 2, statuses["shared.mockService"].Restarts)
+	assert.Equal(t, (*time.Time)(nil), statuses["shared.mockService"].LastFailure)
+	status := statuses["shared.failingStopService"]
+	assert.Equal(t, 1, status.Failures)
+	assert.Equal(t, "db locked", status.LastFailureError)
+	assert.Equal(t, failing.LastFailure, *status.LastFailure)
+}
+
+func TestServiceHealthCollector_Counters(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.RegisterService(&failingStopService{err: errors.New("db locked")}))
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, reflect.TypeOf(&failingStopService{}), StateRunning)
+	require.NotNil(t, registry.RestartService(reflect.TypeOf(&failingStopService{})))
+
+	promRegistry := prometheus.NewRegistry()
+	require.NoError(t, promRegistry.Register(NewServiceHealthCollector(registry)))
+	families, err := promRegistry.Gather()
+	require.NoError(t, err)
+	values := make(map[string]map[string]float64)
+	for _, family := range families {
+		values[family.GetName()] = make(map[string]float64)
+		for _, metric := range family.GetMetric() {
+			values[family.GetName()][labelValue(metric, "service")] = metricValue(metric)
+		}
+	}
+	assert.DeepEqual(t, map[string]float64{
+		"shared.mockService":        0,
+		"shared.failingStopService": 1,
+	}, values["service_failures_total"])
+	assert.DeepEqual(t, map[string]float64{
+		"shared.mockService":        0,
+		"shared.failingStopService": 0,
+	}, values["service_restarts_total"])
+	assert.Equal(t, float64(0), values["service_last_failure_timestamp_seconds"]["shared.mockService"])
+	lastFailure := registry.Counters()["shared.failingStopService"].LastFailure
+	assert.Equal(t, lastFailure.Unix(), int64(values["service_last_failure_timestamp_seconds"]["shared.failingStopService"]))
+}
@@ -0,0 +1,97 @@
+package shared
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// defaultCrashLoopRestarts is the number of restarts within the crash
+	// loop window after which the watchdog stops restarting a service.
+	defaultCrashLoopRestarts = 5
+	// defaultCrashLoopWindow is the sliding window in which restarts are
+	// counted to detect a crash loop.
+	defaultCrashLoopWindow = 10 * time.Minute
+)
+
+// ErrServiceCrashLooping is reported as the status of a service the watchdog
+// gave up restarting, because it was restarted too often.
+var ErrServiceCrashLooping = errors.New("service is crash looping")
+
+// CrashLoopPolicy configures when the watchdog considers a service it keeps
+// restarting to be crash looping. It then stops restarting the service, which
+// reports ErrServiceCrashLooping as its status until it is restarted with
+// RestartService.
+type CrashLoopPolicy struct {
+	// MaxRestarts is the number of restarts allowed within the window. It
+	// defaults to 5.
+	MaxRestarts int
+	// Window is the sliding window in which restarts are counted. It
+	// defaults to 10 minutes.
+	Window time.Duration
+	// ResetAfter is how long the service must stay healthy for its restarts
+	// to be forgotten. It defaults to the window.
+	ResetAfter time.Duration
+}
+
+// crashLoopPolicy returns the crash loop policy of a service, with defaults
+// applied.
+func crashLoopPolicy(entry *serviceEntry) CrashLoopPolicy {
+	var p CrashLoopPolicy
+	if entry.cfg.CrashLoop != nil {
+		p = *entry.cfg.CrashLoop
+	}
+	if p.MaxRestarts <= 0 {
+		p.MaxRestarts = defaultCrashLoopRestarts
+	}
+	if p.Window <= 0 {
+		p.Window = defaultCrashLoopWindow
+	}
+	if p.ResetAfter <= 0 {
+		p.ResetAfter = p.Window
+	}
+	return p
+}
+
+// healthy forgets the restarts of a service once it has been healthy for long
+// enough.
+func (w *watchdog) healthy(entry *serviceEntry, now time.Time) {
+	if len(w.restarts[entry]) == 0 {
+		return
+	}
+	since, ok := w.healthySince[entry]
+	if !ok {
+		w.healthySince[entry] = now
+		return
+	}
+	if now.Sub(since) >= crashLoopPolicy(entry).ResetAfter {
+		delete(w.restarts, entry)
+		delete(w.healthySince, entry)
+	}
+}
+
+// allowRestart records a restart of a service, unless the restarts within the
+// window reached the limit, in which case the service is marked as crash
+// looping and false is returned.
+func (w *watchdog) allowRestart(entry *serviceEntry, now time.Time) bool {
+	p := crashLoopPolicy(entry)
+	restarts := w.restarts[entry][:0]
+	for _, t := range w.restarts[entry] {
+		if now.Sub(t) < p.Window {
+			restarts = append(restarts, t)
+		}
+	}
+	if len(restarts) >= p.MaxRestarts {
+		w.restarts[entry] = restarts
+		w.crashLooping[entry] = true
+		err := fmt.Errorf("%w: restarted %d times within %v", ErrServiceCrashLooping, len(restarts), p.Window)
+		w.registry.lock.Lock()
+		entry.startErr = err
+		w.registry.lock.Unlock()
+		w.registry.log.WithError(err).Errorf("Service %v keeps failing after being restarted, it will not be restarted anymore", entry)
+		return false
+	}
+	w.restarts[entry] = append(restarts, now)
+	return true
+}
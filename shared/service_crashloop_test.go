@@ -0,0 +1,81 @@
+package shared
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+	logTest "github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestWatchdog_StopsRestartingCrashLoopingService(t *testing.T) {
+	hook := logTest.NewGlobal()
+	registry := NewServiceRegistry()
+	svc := &statusFuncService{status: func() error { return errors.New("stuck") }}
+	require.NoError(t, registry.RegisterServiceWithConfig(svc, nil, &ServiceConfig{
+		CrashLoop: &CrashLoopPolicy{MaxRestarts: 2, Window: time.Minute},
+	}))
+	require.NoError(t, registry.StartAll())
+
+	restarted := make(chan string, 10)
+	require.NoError(t, registry.StartWatchdog(&WatchdogConfig{
+		Interval:         10 * time.Millisecond,
+		FailureThreshold: 1,
+		OnRestart:        func(service string, _ error) { restarted <- service },
+	}))
+	kind := reflect.TypeOf(svc)
+	for i := 0; i < 100 && !errors.Is(registry.Statuses()[kind], ErrServiceCrashLooping); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.ErrorContains(t, "service is crash looping: restarted 2 times within 1m0s", registry.Statuses()[kind])
+	// Give the watchdog a chance to restart the service again.
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 2, len(restarted))
+	assert.Equal(t, 1, countLogs(hook, "Service shared.statusFuncService keeps failing after being restarted, it will not be restarted anymore"))
+
+	// Restarting the service by hand gives it another chance.
+	require.NoError(t, registry.RestartService(kind))
+	select {
+	case <-restarted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Service restarted by hand was not restarted by the watchdog")
+	}
+	require.NoError(t, registry.StopAll())
+}
+
+func TestWatchdog_CrashLoopWindow(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterServiceWithConfig(&mockService{}, nil, &ServiceConfig{
+		CrashLoop: &CrashLoopPolicy{MaxRestarts: 2, Window: time.Minute, ResetAfter: 5 * time.Minute},
+	}))
+	entry := registry.snapshot()[0]
+	w := &watchdog{
+		registry:     registry,
+		restarts:     make(map[*serviceEntry][]time.Time),
+		healthySince: make(map[*serviceEntry]time.Time),
+		crashLooping: make(map[*serviceEntry]bool),
+	}
+	now := time.Now()
+
+	// Restarts older than the window are not counted.
+	assert.Equal(t, true, w.allowRestart(entry, now))
+	assert.Equal(t, true, w.allowRestart(entry, now.Add(30*time.Second)))
+	assert.Equal(t, true, w.allowRestart(entry, now.Add(61*time.Second)))
+	assert.Equal(t, 2, len(w.restarts[entry]))
+
+	// A healthy period resets the restarts.
+	w.healthy(entry, now.Add(2*time.Minute))
+	w.healthy(entry, now.Add(6*time.Minute))
+	assert.Equal(t, 2, len(w.restarts[entry]))
+	w.healthy(entry, now.Add(7*time.Minute))
+	assert.Equal(t, 0, len(w.restarts[entry]))
+
+	assert.Equal(t, true, w.allowRestart(entry, now.Add(8*time.Minute)))
+	assert.Equal(t, true, w.allowRestart(entry, now.Add(8*time.Minute)))
+	assert.Equal(t, false, w.allowRestart(entry, now.Add(8*time.Minute)))
+	assert.Equal(t, true, w.crashLooping[entry])
+	assert.ErrorContains(t, "restarted 2 times within 1m0s", registry.Statuses()[reflect.TypeOf(&mockService{})])
+}
@@ -0,0 +1,63 @@
+package shared
+
+import (
+	"fmt"
+)
+
+// defaultCriticalThreshold is the number of consecutive unhealthy polls after
+// which a critical service terminates the node.
+const defaultCriticalThreshold = 3
+
+// WithCriticalThreshold sets after how many consecutive polls of the status
+// poller a running critical service which is unhealthy is reported as a
+// fatal error. It defaults to 3, and together with the interval given to
+// StartStatusPoller bounds how long a critical service can be unhealthy
+// before the node is stopped, so that a service briefly flapping does not
+// stop it.
+func WithCriticalThreshold(polls int) RegistryOption {
+	return func(s *ServiceRegistry) {
+		if polls > 0 {
+			s.criticalThreshold = polls
+		}
+	}
+}
+
+// RegisterCriticalService registers a service the node cannot run without,
+// such as its database: a failed start of the service, or an unhealthy
+// status reported to the status poller the given number of consecutive
+// times, is reported as a fatal error, making Run stop every service.
+func (s *ServiceRegistry) RegisterCriticalService(service Service, ctx *ServiceContext) error {
+	return s.RegisterServiceWithConfig(service, ctx, &ServiceConfig{Critical: true})
+}
+
+// checkCritical counts the consecutive unhealthy polls of a running critical
+// service, and reports a fatal error once they reach the critical threshold.
+// Services paused or stopped on request are not counted.
+func (p *statusPoller) checkCritical(entry *serviceEntry, err error, threshold int) {
+	if !entry.cfg.Critical {
+		return
+	}
+	if err == nil || isIntentional(err) || p.registry.stateOf(entry) != StateRunning {
+		delete(p.unhealthy, entry)
+		return
+	}
+	p.unhealthy[entry]++
+	if p.unhealthy[entry] != threshold {
+		return
+	}
+	p.registry.log.WithError(err).Errorf("Critical service %v is unhealthy, stopping the node", entry)
+	p.registry.reportFatal(&FatalError{
+		Service: entry.String(),
+		Err:     fmt.Errorf("critical service unhealthy for %d consecutive status checks: %w", threshold, err),
+	})
+}
+
+// criticalStartFailed reports the failed start of a critical service as a
+// fatal error, unless the registry is stopping.
+func (s *ServiceRegistry) criticalStartFailed(entry *serviceEntry, err error) {
+	if !entry.cfg.Critical || s.stopping {
+		return
+	}
+	s.log.WithError(err).Errorf("Critical service %v could not start, stopping the node", entry)
+	s.reportFatal(&FatalError{Service: entry.String(), Err: fmt.Errorf("critical service could not start: %w", err)})
+}
@@ -0,0 +1,106 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+func runAsync(registry *ServiceRegistry) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		done <- registry.Run(context.Background())
+	}()
+	return done
+}
+
+func TestCriticalService_UnhealthyStopsRun(t *testing.T) {
+	registry := NewServiceRegistry(WithCriticalThreshold(3))
+	var polls int32
+	svc := &statusFuncService{status: func() error {
+		atomic.AddInt32(&polls, 1)
+		return errors.New("database corrupted")
+	}}
+	require.NoError(t, registry.RegisterCriticalService(svc, nil))
+	require.NoError(t, registry.StartStatusPoller(10*time.Millisecond))
+
+	select {
+	case err := <-runAsync(registry):
+		var fatal *FatalError
+		require.Equal(t, true, errors.As(err, &fatal))
+		assert.Equal(t, "shared.statusFuncService", fatal.Service)
+		assert.ErrorContains(t, "critical service unhealthy for 3 consecutive status checks: database corrupted", err)
+		assert.Equal(t, true, atomic.LoadInt32(&polls) >= 3)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after a critical service stayed unhealthy")
+	}
+}
+
+func TestCriticalService_FlappingDoesNotStopRun(t *testing.T) {
+	registry := NewServiceRegistry(WithCriticalThreshold(2))
+	var polls int32
+	svc := &statusFuncService{status: func() error {
+		// Fails every other poll, never twice in a row.
+		if atomic.AddInt32(&polls, 1)%2 == 0 {
+			return errors.New("no peers")
+		}
+		return nil
+	}}
+	require.NoError(t, registry.RegisterCriticalService(svc, nil))
+	require.NoError(t, registry.StartStatusPoller(10*time.Millisecond))
+	done := runAsync(registry)
+
+	select {
+	case err := <-done:
+		t.Fatalf("Run returned while the critical service was flapping: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+	registry.reportFatal(errors.New("stop"))
+	assert.ErrorContains(t, "stop", <-done)
+}
+
+func TestCriticalService_NonCriticalKeepsRunning(t *testing.T) {
+	registry := NewServiceRegistry(WithCriticalThreshold(1))
+	require.NoError(t, registry.RegisterService(&statusFuncService{status: func() error {
+		return errors.New("database corrupted")
+	}}))
+	require.NoError(t, registry.StartStatusPoller(10*time.Millisecond))
+	done := runAsync(registry)
+
+	select {
+	case err := <-done:
+		t.Fatalf("Run returned because of a non-critical service: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	registry.reportFatal(errors.New("stop"))
+	assert.ErrorContains(t, "stop", <-done)
+}
+
+func TestCriticalService_StartFailureStopsRun(t *testing.T) {
+	registry := NewServiceRegistry(WithLegacyStartWait())
+	require.NoError(t, registry.RegisterCriticalService(&panickingStartService{started: make(chan struct{})}, nil))
+
+	select {
+	case err := <-runAsync(registry):
+		assert.ErrorContains(t, "could not start service shared.panickingStartService: service panicked during start: could not bind port", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after a critical service failed to start")
+	}
+	select {
+	case err := <-registry.fatal:
+		assert.ErrorContains(t, "service shared.panickingStartService: critical service could not start", err)
+	default:
+		t.Fatal("No fatal error reported")
+	}
+}
+
+func TestCriticalService_CannotBeOptional(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterServiceWithConfig(&mockService{}, nil, &ServiceConfig{Critical: true, Optional: true}))
+	assert.ErrorContains(t, "service shared.mockService cannot be both optional and critical", registry.Validate())
+}
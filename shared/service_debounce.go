@@ -0,0 +1,97 @@
+package shared
+
+import (
+	"sync"
+)
+
+// StatusDebouncePolicy keeps transient failures of the Status method of a
+// service, such as a single failed peer dial, from making it unhealthy. The
+// debounced status is the one reported by Statuses, the health endpoints, the
+// watchdog and the status poller, while the Raw status option reports the
+// result of every Status call as is.
+type StatusDebouncePolicy struct {
+	// UnhealthyAfter is the number of consecutive failed Status calls after
+	// which a healthy service is reported unhealthy. It defaults to 1.
+	UnhealthyAfter int
+	// HealthyAfter is the number of consecutive successful Status calls
+	// after which an unhealthy service is reported healthy again. It
+	// defaults to 1.
+	HealthyAfter int
+}
+
+// Raw makes Statuses and StatusesByName report the result of the Status
+// method of every service as is, neither cached nor debounced, which is
+// meant for debugging.
+func Raw() StatusOption {
+	return func(opts *statusOptions) {
+		opts.raw = true
+	}
+}
+
+// rawStatus is like status, but calls the Status method of the service
+// without debouncing its result.
+func (s *ServiceRegistry) rawStatus(entry *serviceEntry) error {
+	s.lock.RLock()
+	startErr, state := entry.startErr, entry.state
+	s.lock.RUnlock()
+	return entryStatusWith(entry, startErr, state, entry.callStatus)
+}
+
+// checkStatus calls the Status method of the service, and debounces its
+// result according to the StatusDebounce policy of the service.
+func (e *serviceEntry) checkStatus() error {
+	err := e.callStatus()
+	if e.cfg.StatusDebounce == nil {
+		return err
+	}
+	return e.debounce.observe(e.cfg.StatusDebounce, err)
+}
+
+// statusDebouncer holds the consecutive results of the Status method of a
+// service.
+type statusDebouncer struct {
+	lock      sync.Mutex
+	unhealthy bool
+	err       error // last error reported by the service while unhealthy.
+	failures  int
+	successes int
+}
+
+// observe records the result of a Status call, and returns the status to
+// report: a healthy service stays healthy until it failed the given number of
+// consecutive times, and an unhealthy service keeps reporting its last error
+// until it succeeded the given number of consecutive times.
+func (d *statusDebouncer) observe(policy *StatusDebouncePolicy, err error) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if err != nil {
+		d.failures++
+		d.successes = 0
+		if d.unhealthy || d.failures >= atLeastOne(policy.UnhealthyAfter) {
+			d.unhealthy, d.err = true, err
+			return err
+		}
+		return nil
+	}
+	d.successes++
+	d.failures = 0
+	if d.unhealthy && d.successes < atLeastOne(policy.HealthyAfter) {
+		return d.err
+	}
+	d.unhealthy, d.err = false, nil
+	return nil
+}
+
+// reset forgets the results recorded so far, once the service is started.
+func (d *statusDebouncer) reset() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.unhealthy, d.err, d.failures, d.successes = false, nil, 0, 0
+}
+
+func atLeastOne(n int) int {
+	if n < 1 {
+		return 1
+	}
+	return n
+}
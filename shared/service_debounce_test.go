@@ -0,0 +1,61 @@
+package shared
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+func TestStatusDebounce(t *testing.T) {
+	registry := NewServiceRegistry()
+	svc := &statusFuncService{status: func() error { return nil }}
+	cfg := &ServiceConfig{StatusDebounce: &StatusDebouncePolicy{UnhealthyAfter: 3, HealthyAfter: 2}}
+	require.NoError(t, registry.RegisterServiceWithConfig(svc, nil, cfg))
+	kind := reflect.TypeOf(svc)
+	status := func() error {
+		return registry.Statuses(ForceRefresh())[kind]
+	}
+	failing := func() error { return errors.New("could not dial peer") }
+	healthy := func() error { return nil }
+
+	svc.setStatus(failing)
+	assert.NoError(t, status())
+	assert.NoError(t, status())
+	assert.ErrorContains(t, "could not dial peer", registry.Statuses(Raw())[kind])
+	// A success in between starts the count of failures over.
+	svc.setStatus(healthy)
+	assert.NoError(t, status())
+	svc.setStatus(failing)
+	assert.NoError(t, status())
+	assert.NoError(t, status())
+	assert.ErrorContains(t, "could not dial peer", status())
+
+	svc.setStatus(healthy)
+	assert.ErrorContains(t, "could not dial peer", status())
+	assert.NoError(t, registry.StatusesByName(Raw())["shared.statusFuncService"])
+	assert.NoError(t, status())
+}
+
+func TestStatusDebounce_Disabled(t *testing.T) {
+	registry := NewServiceRegistry()
+	svc := &statusFuncService{status: func() error { return errors.New("database locked") }}
+	require.NoError(t, registry.RegisterService(svc))
+	assert.ErrorContains(t, "database locked", registry.Statuses(ForceRefresh())[reflect.TypeOf(svc)])
+}
+
+func TestStatusDebounce_ResetOnStart(t *testing.T) {
+	registry := NewServiceRegistry()
+	svc := &statusFuncService{status: func() error { return errors.New("database locked") }}
+	cfg := &ServiceConfig{StatusDebounce: &StatusDebouncePolicy{UnhealthyAfter: 1, HealthyAfter: 5}}
+	require.NoError(t, registry.RegisterServiceWithConfig(svc, nil, cfg))
+	kind := reflect.TypeOf(svc)
+	assert.ErrorContains(t, "database locked", registry.Statuses(ForceRefresh())[kind])
+
+	svc.setStatus(func() error { return nil })
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, kind, StateRunning)
+	assert.NoError(t, registry.Statuses(ForceRefresh())[kind])
+}
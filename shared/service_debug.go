@@ -0,0 +1,108 @@
+package shared
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// debugDump is the JSON document produced by DebugJSON.
+type debugDump struct {
+	// OrderError is set when the start order cannot be computed, in which
+	// case services are listed in order of registration.
+	OrderError string         `json:"order_error,omitempty"`
+	Services   []debugService `json:"services"`
+}
+
+// debugService describes a registered service in the output of DebugJSON.
+type debugService struct {
+	Name         string   `json:"name"`
+	Type         string   `json:"type"`
+	StartOrder   int      `json:"start_order"`
+	State        string   `json:"state"`
+	Dependencies []string `json:"dependencies"`
+	// Error is the last status error of the service, if any.
+	Error string `json:"error,omitempty"`
+	// Uptime is how long the service has been running, in seconds, and is
+	// zero for services which are not running.
+	Uptime   float64          `json:"uptime_seconds"`
+	Metadata *ServiceMetadata `json:"metadata,omitempty"`
+	// Disabled tells why the service is disabled, if it is, in which case
+	// its start order is -1.
+	Disabled string `json:"disabled,omitempty"`
+	// Panics counts the panics of the service recovered by the registry,
+	// and LastPanic is the last of them.
+	Panics    int           `json:"panics,omitempty"`
+	LastPanic *ServicePanic `json:"last_panic,omitempty"`
+}
+
+// DebugJSON returns a JSON dump of the registry for debugging purposes,
+// describing every registered service in start order with its lifecycle
+// state, dependencies, last status error, uptime, metadata and last panic,
+// followed by the disabled services along with why they are disabled.
+func (s *ServiceRegistry) DebugJSON() ([]byte, error) {
+	dump := debugDump{}
+	order, err := s.startOrder()
+	if err != nil {
+		dump.OrderError = err.Error()
+		order = s.snapshot()
+	}
+
+	type stateCopy struct {
+		entry     *serviceEntry
+		startErr  error
+		state     ServiceState
+		startedAt time.Time
+		deps      []string
+	}
+	s.lock.RLock()
+	copies := make([]stateCopy, len(order))
+	for i, entry := range order {
+		deps := make([]string, len(entry.cfg.Dependencies))
+		for j, dep := range entry.cfg.Dependencies {
+			if depEntry, ok := s.services[dep]; ok {
+				deps[j] = depEntry.String()
+			} else {
+				deps[j] = typeName(dep)
+			}
+		}
+		copies[i] = stateCopy{
+			entry:     entry,
+			startErr:  entry.startErr,
+			state:     entry.state,
+			startedAt: entry.startedAt,
+			deps:      deps,
+		}
+	}
+	s.lock.RUnlock()
+
+	dump.Services = make([]debugService, len(copies))
+	for i, c := range copies {
+		c := c
+		service := debugService{
+			Name:         c.entry.String(),
+			Type:         typeName(c.entry.kind),
+			StartOrder:   i,
+			State:        c.state.String(),
+			Dependencies: c.deps,
+			Metadata:     c.entry.metadata,
+		}
+		if err := checkStatus(func() error { return entryStatus(c.entry, c.startErr, c.state) }, healthzStatusTimeout); err != nil {
+			service.Error = err.Error()
+		}
+		service.Uptime = uptime(c.startedAt, c.state).Seconds()
+		counters := c.entry.counters.get()
+		service.Panics, service.LastPanic = counters.Panics, counters.LastPanic
+		dump.Services[i] = service
+	}
+	for _, d := range s.disabledSnapshot() {
+		dump.Services = append(dump.Services, debugService{
+			Name:         d.name,
+			Type:         typeName(d.kind),
+			StartOrder:   -1,
+			State:        StateDisabled.String(),
+			Dependencies: []string{},
+			Disabled:     d.reason(),
+		})
+	}
+	return json.Marshal(dump)
+}
@@ -0,0 +1,87 @@
+package shared
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+func TestDebugJSON(t *testing.T) {
+	registry := NewServiceRegistry()
+	// Do not wait for the unhealthy dependency.
+	registry.SetDependencyTimeout(time.Millisecond, false)
+	require.NoError(t, registry.RegisterServiceWithDeps(&secondMockService{}, reflect.TypeOf(&mockService{})))
+	require.NoError(t, registry.RegisterService(&mockService{status: errors.New("no peers")}))
+	require.NoError(t, registry.RegisterService(&thirdMockService{}))
+	require.NoError(t, registry.StartAll())
+	for _, kind := range []reflect.Type{reflect.TypeOf(&mockService{}), reflect.TypeOf(&secondMockService{}), reflect.TypeOf(&thirdMockService{})} {
+		waitForState(t, registry, kind, StateRunning)
+	}
+	defer func() {
+		require.NoError(t, registry.StopAll())
+	}()
+
+	b, err := registry.DebugJSON()
+	require.NoError(t, err)
+	dump := &debugDump{}
+	require.NoError(t, json.Unmarshal(b, dump))
+	assert.Equal(t, "", dump.OrderError)
+	require.Equal(t, 3, len(dump.Services))
+
+	first := dump.Services[0]
+	assert.Equal(t, "shared.mockService", first.Name)
+	assert.Equal(t, "shared.mockService", first.Type)
+	assert.Equal(t, 0, first.StartOrder)
+	assert.Equal(t, "running", first.State)
+	assert.Equal(t, "no peers", first.Error)
+	assert.DeepEqual(t, []string{}, first.Dependencies)
+	assert.Equal(t, true, first.Uptime >= 0)
+
+	second := dump.Services[1]
+	assert.Equal(t, "shared.secondMockService", second.Name)
+	assert.Equal(t, 1, second.StartOrder)
+	assert.DeepEqual(t, []string{"shared.mockService"}, second.Dependencies)
+	assert.Equal(t, "", second.Error)
+	assert.Equal(t, "shared.thirdMockService", dump.Services[2].Name)
+}
+
+func TestDebugJSON_Schema(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&mockService{}))
+
+	b, err := registry.DebugJSON()
+	require.NoError(t, err)
+	var dump map[string][]map[string]interface{}
+	require.NoError(t, json.Unmarshal(b, &dump))
+	require.Equal(t, 1, len(dump["services"]))
+	keys := make([]string, 0)
+	for k := range dump["services"][0] {
+		keys = append(keys, k)
+	}
+	wanted := []string{"dependencies", "name", "start_order", "state", "type", "uptime_seconds"}
+	assert.Equal(t, len(wanted), len(keys), "Unexpected keys %v", keys)
+	for _, k := range wanted {
+		_, ok := dump["services"][0][k]
+		assert.Equal(t, true, ok, "Missing key %s", k)
+	}
+	assert.Equal(t, "registered", dump["services"][0]["state"])
+	assert.Equal(t, float64(0), dump["services"][0]["uptime_seconds"])
+}
+
+func TestDebugJSON_UnorderableRegistry(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterServiceWithDeps(&mockService{}, reflect.TypeOf(&secondMockService{})))
+
+	b, err := registry.DebugJSON()
+	require.NoError(t, err)
+	dump := &debugDump{}
+	require.NoError(t, json.Unmarshal(b, dump))
+	assert.Equal(t, "service shared.mockService depends on unregistered service: shared.secondMockService", dump.OrderError)
+	require.Equal(t, 1, len(dump.Services))
+	assert.DeepEqual(t, []string{"shared.secondMockService"}, dump.Services[0].Dependencies)
+}
@@ -0,0 +1,112 @@
+package shared
+
+import (
+	"fmt"
+	"time"
+)
+
+// defaultDependencyTimeout is how long a service waits for its dependencies
+// to be ready before being started anyway.
+const defaultDependencyTimeout = time.Minute
+
+// SetDependencyTimeout configures how long StartAll lets a service wait for
+// its declared dependencies to be ready before starting it. Once the timeout
+// elapses, the service is started anyway with a warning or, if fatal is set,
+// marked as failed and reported as a fatal error to Run. Optional
+// dependencies are not waited for.
+func (s *ServiceRegistry) SetDependencyTimeout(timeout time.Duration, fatal bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.dependencyTimeout = timeout
+	s.dependencyFatal = fatal
+}
+
+// launchAfterDependencies launches a service, right away if it has no
+// dependencies, or once its dependencies are ready otherwise.
+func (s *ServiceRegistry) launchAfterDependencies(entry *serviceEntry) {
+	if len(entry.cfg.Dependencies) == 0 {
+		s.launch(entry)
+		return
+	}
+	go s.waitForDependencies(entry)
+}
+
+// waitForDependencies polls the readiness of the dependencies of a service
+// until they are all running and ready, then launches the service once a
+// start slot is free, unless StopAll began in the meantime.
+func (s *ServiceRegistry) waitForDependencies(entry *serviceEntry) {
+	s.lock.RLock()
+	interval, timeout, fatal := s.readyPoll, s.dependencyTimeout, s.dependencyFatal
+	deps := make([]*serviceEntry, 0, len(entry.cfg.Dependencies))
+	for _, kind := range entry.cfg.Dependencies {
+		if dep, ok := s.services[kind]; ok && !dep.cfg.Optional {
+			deps = append(deps, dep)
+		}
+	}
+	s.lock.RUnlock()
+	if interval <= 0 {
+		interval = defaultReadyPollInterval
+	}
+	if timeout <= 0 {
+		timeout = defaultDependencyTimeout
+	}
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		dep, err := s.unreadyDependency(deps)
+		if dep == nil {
+			break
+		}
+		select {
+		case <-ticker.C:
+			continue
+		case <-s.shutdown:
+			return
+		case <-deadline.C:
+		}
+		err = fmt.Errorf("dependency %v not ready after %v: %w", dep, timeout, err)
+		if fatal {
+			s.log.WithError(err).Errorf("Could not start service %v", entry)
+			s.lock.Lock()
+			entry.startErr = err
+			entry.state = StateStopped
+			waiter := s.starting
+			s.lock.Unlock()
+			s.failed(entry, err)
+			waiter.settle(entry, err)
+			s.reportFatal(fmt.Errorf("%v: %w", entry, err))
+			return
+		}
+		s.log.WithError(err).Warnf("Starting service %v although its dependencies are not ready", entry)
+		break
+	}
+	if !s.acquireStartSlot() {
+		return
+	}
+	go s.releaseStartSlot(entry)
+	s.lock.Lock()
+	if s.stopping {
+		s.lock.Unlock()
+		return
+	}
+	entry.state = StateStarting
+	s.lock.Unlock()
+	go s.startService(entry)
+}
+
+// unreadyDependency returns the first of the given services which is not
+// running or not ready, along with the reason.
+func (s *ServiceRegistry) unreadyDependency(deps []*serviceEntry) (*serviceEntry, error) {
+	for _, dep := range deps {
+		if state := s.stateOf(dep); state != StateRunning && state != StatePaused {
+			return dep, fmt.Errorf("service is %v", state)
+		}
+		dep := dep
+		if err := checkStatus(func() error { return s.readiness(dep) }, healthzStatusTimeout); err != nil {
+			return dep, err
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,87 @@
+package shared
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+	logTest "github.com/sirupsen/logrus/hooks/test"
+)
+
+func registerGatedServices(t *testing.T, registry *ServiceRegistry) (*statusFuncService, *startCountingService) {
+	registry.SetReadyPollInterval(5 * time.Millisecond)
+	dep := &statusFuncService{status: func() error { return errors.New("syncing") }}
+	dependent := &startCountingService{}
+	require.NoError(t, registry.RegisterServiceWithDeps(dependent, reflect.TypeOf(dep)))
+	require.NoError(t, registry.RegisterService(dep))
+	return dep, dependent
+}
+
+func TestStartAll_WaitsForDependencies(t *testing.T) {
+	registry := NewServiceRegistry()
+	dep, dependent := registerGatedServices(t, registry)
+	done := startAsync(registry)
+
+	waitForState(t, registry, reflect.TypeOf(dep), StateRunning)
+	time.Sleep(50 * time.Millisecond)
+	state, err := registry.State(reflect.TypeOf(dependent))
+	require.NoError(t, err)
+	assert.Equal(t, StateRegistered, state, "Expected the dependent service to wait for its dependency")
+
+	dep.setStatus(func() error { return nil })
+	require.NoError(t, <-done)
+	waitForState(t, registry, reflect.TypeOf(dependent), StateRunning)
+	require.NoError(t, registry.StopAll())
+}
+
+func TestStartAll_DependencyTimeout(t *testing.T) {
+	hook := logTest.NewGlobal()
+	registry := NewServiceRegistry()
+	_, dependent := registerGatedServices(t, registry)
+	registry.SetDependencyTimeout(20*time.Millisecond, false)
+	require.NoError(t, registry.StartAll())
+
+	waitForState(t, registry, reflect.TypeOf(dependent), StateRunning)
+	require.LogsContain(t, hook, "Starting service shared.startCountingService although its dependencies are not ready")
+	require.LogsContain(t, hook, "dependency shared.statusFuncService not ready after 20ms: syncing")
+	require.NoError(t, registry.StopAll())
+}
+
+func TestStartAll_DependencyTimeoutFatal(t *testing.T) {
+	registry := NewServiceRegistry(WithLegacyStartWait())
+	_, dependent := registerGatedServices(t, registry)
+	registry.SetDependencyTimeout(20*time.Millisecond, true)
+	assert.ErrorContains(t, "could not start service shared.startCountingService: dependency shared.statusFuncService not ready after 20ms", registry.StartAll())
+
+	select {
+	case err := <-registry.fatal:
+		assert.ErrorContains(t, "shared.startCountingService: dependency shared.statusFuncService not ready after 20ms: syncing", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("No fatal error reported")
+	}
+	kind := reflect.TypeOf(dependent)
+	waitForState(t, registry, kind, StateStopped)
+	assert.ErrorContains(t, "not ready after 20ms", registry.Statuses()[kind])
+	require.NoError(t, registry.StopAll())
+	dependent.lock.Lock()
+	defer dependent.lock.Unlock()
+	assert.Equal(t, 0, dependent.starts)
+}
+
+func TestStartAll_StopAllWhileWaitingForDependencies(t *testing.T) {
+	registry := NewServiceRegistry(WithLegacyStartWait())
+	dep, dependent := registerGatedServices(t, registry)
+	done := startAsync(registry)
+	waitForState(t, registry, reflect.TypeOf(dep), StateRunning)
+	require.NoError(t, registry.StopAll())
+	assert.ErrorContains(t, "registry was stopped before every service started", <-done)
+
+	dep.setStatus(func() error { return nil })
+	time.Sleep(50 * time.Millisecond)
+	dependent.lock.Lock()
+	defer dependent.lock.Unlock()
+	assert.Equal(t, 0, dependent.starts, "Expected the dependent service not to start once StopAll began")
+}
@@ -0,0 +1,117 @@
+package shared
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// DetailedStatusReporter is optionally implemented by services which report
+// structured details about their status, such as the current slot and peer
+// count of a sync service. The details must be cheap to compute.
+type DetailedStatusReporter interface {
+	DetailedStatus() map[string]interface{}
+}
+
+// ServiceStatus is the status of a service along with its details, as
+// reported by DetailedStatuses and OrderedStatuses. StartedAt is when the
+// service last finished starting, and Uptime how long it has been running
+// since, in seconds; both are zero if it never started, and Uptime is zero
+// once it stopped. Restarts, Failures and Panics are the counters of the
+// service, LastFailure and LastFailureError describe its last failure, if
+// any, and LastPanic its last recovered panic.
+type ServiceStatus struct {
+	Name             string                 `json:"name"`
+	State            ServiceState           `json:"state"`
+	Error            string                 `json:"error,omitempty"`
+	Details          map[string]interface{} `json:"details,omitempty"`
+	StartedAt        time.Time              `json:"started_at"`
+	Uptime           float64                `json:"uptime_seconds"`
+	Restarts         int                    `json:"restarts,omitempty"`
+	Failures         int                    `json:"failures,omitempty"`
+	LastFailure      *time.Time             `json:"last_failure,omitempty"`
+	LastFailureError string                 `json:"last_failure_error,omitempty"`
+	Panics           int                    `json:"panics,omitempty"`
+	LastPanic        *ServicePanic          `json:"last_panic,omitempty"`
+}
+
+// DetailedStatuses returns the status of every service, keyed by name, along
+// with the details reported by the services implementing
+// DetailedStatusReporter. The result can always be serialized to JSON.
+func (s *ServiceRegistry) DetailedStatuses() map[string]*ServiceStatus {
+	statuses := s.OrderedStatuses()
+	m := make(map[string]*ServiceStatus, len(statuses))
+	for i := range statuses {
+		m[statuses[i].Name] = &statuses[i]
+	}
+	return m
+}
+
+// OrderedStatuses is like DetailedStatuses, but returns the statuses in order
+// of registration, so that they are logged and rendered the same way every
+// time and two snapshots can be compared.
+func (s *ServiceRegistry) OrderedStatuses() []ServiceStatus {
+	entries := s.snapshot()
+	statuses := make([]ServiceStatus, len(entries))
+	for i, entry := range entries {
+		entry := entry
+		startedAt, up := s.startTime(entry)
+		statuses[i] = ServiceStatus{
+			Name:      entry.String(),
+			State:     s.stateOf(entry),
+			Details:   s.serviceDetails(entry),
+			StartedAt: startedAt,
+			Uptime:    up.Seconds(),
+		}
+		statuses[i].setCounters(entry.counters.get())
+		if err := checkStatus(func() error { return s.status(entry) }, healthzStatusTimeout); err != nil {
+			statuses[i].Error = err.Error()
+		}
+	}
+	return statuses
+}
+
+// setCounters copies the counters of a service to its status.
+func (st *ServiceStatus) setCounters(c ServiceCounters) {
+	st.Restarts = c.Restarts
+	st.Failures = c.Failures
+	if !c.LastFailure.IsZero() {
+		at := c.LastFailure
+		st.LastFailure = &at
+	}
+	if c.LastFailureErr != nil {
+		st.LastFailureError = c.LastFailureErr.Error()
+	}
+	st.Panics = c.Panics
+	st.LastPanic = c.LastPanic
+}
+
+// serviceDetails collects the details of a service, if it reports any. A
+// panic is reported as a detail, and values which cannot be serialized to
+// JSON are replaced by their string representation.
+func (s *ServiceRegistry) serviceDetails(entry *serviceEntry) (details map[string]interface{}) {
+	reporter, ok := entry.service.(DetailedStatusReporter)
+	if !ok {
+		return nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			s.log.WithField("service", entry.String()).Errorf("Detailed status panicked: %v", r)
+			s.recordPanic(entry, r, debug.Stack())
+			details = map[string]interface{}{"panic": fmt.Sprint(r)}
+		}
+	}()
+	reported := reporter.DetailedStatus()
+	if len(reported) == 0 {
+		return nil
+	}
+	details = make(map[string]interface{}, len(reported))
+	for k, v := range reported {
+		if _, err := json.Marshal(v); err != nil {
+			v = fmt.Sprintf("%v", v)
+		}
+		details[k] = v
+	}
+	return details
+}
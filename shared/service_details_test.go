@@ -0,0 +1,94 @@
+package shared
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+type detailedService struct {
+	mockService
+	details func() map[string]interface{}
+}
+
+func (s *detailedService) DetailedStatus() map[string]interface{} {
+	return s.details()
+}
+
+type secondDetailedService struct {
+	detailedService
+}
+
+type thirdDetailedService struct {
+	detailedService
+}
+
+func TestDetailedStatuses(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&detailedService{
+		mockService: mockService{status: errors.New("syncing")},
+		details: func() map[string]interface{} {
+			return map[string]interface{}{"slot": 42, "peers": 3, "done": make(chan struct{})}
+		},
+	}))
+	require.NoError(t, registry.RegisterService(&secondDetailedService{detailedService{
+		details: func() map[string]interface{} { return nil },
+	}}))
+	require.NoError(t, registry.RegisterService(&thirdDetailedService{detailedService{
+		details: func() map[string]interface{} { panic("database closed") },
+	}}))
+	require.NoError(t, registry.RegisterService(&mockService{}))
+
+	statuses := registry.DetailedStatuses()
+	require.Equal(t, 4, len(statuses))
+	first := statuses["shared.detailedService"]
+	assert.Equal(t, "syncing", first.Error)
+	assert.Equal(t, 42, first.Details["slot"])
+	_, isString := first.Details["done"].(string)
+	assert.Equal(t, true, isString, "Expected a value which cannot be serialized to be stringified")
+	assert.Equal(t, true, statuses["shared.secondDetailedService"].Details == nil)
+	assert.Equal(t, "database closed", statuses["shared.thirdDetailedService"].Details["panic"])
+	assert.DeepEqual(t, &ServiceStatus{Name: "shared.mockService", State: StateRegistered}, statuses["shared.mockService"])
+
+	_, err := json.Marshal(statuses)
+	require.NoError(t, err)
+}
+
+func TestHealthzHandler_Details(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&detailedService{
+		details: func() map[string]interface{} { return map[string]interface{}{"size": "1GB"} },
+	}))
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.StartAll())
+
+	_, resp := serveHealthz(t, registry)
+	assert.DeepEqual(t, map[string]map[string]interface{}{"shared.detailedService": {"size": "1GB"}}, resp.Details)
+}
+
+func TestOrderedStatuses(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&thirdMockService{}))
+	require.NoError(t, registry.RegisterService(&mockService{status: errors.New("no peers")}))
+	require.NoError(t, registry.RegisterNamedService("beacon", &secondMockService{}, nil))
+	require.NoError(t, registry.RegisterService(&secondMockService{status: errors.New("syncing")}))
+
+	golden := `[` +
+		`{"name":"shared.thirdMockService","state":"registered","started_at":"0001-01-01T00:00:00Z","uptime_seconds":0},` +
+		`{"name":"shared.mockService","state":"registered","error":"no peers","started_at":"0001-01-01T00:00:00Z","uptime_seconds":0},` +
+		`{"name":"beacon","state":"registered","started_at":"0001-01-01T00:00:00Z","uptime_seconds":0},` +
+		`{"name":"shared.secondMockService","state":"registered","error":"syncing","started_at":"0001-01-01T00:00:00Z","uptime_seconds":0}` +
+		`]`
+	for i := 0; i < 10; i++ {
+		b, err := json.Marshal(registry.OrderedStatuses())
+		require.NoError(t, err)
+		assert.Equal(t, golden, string(b))
+	}
+
+	var decoded []ServiceStatus
+	require.NoError(t, json.Unmarshal([]byte(golden), &decoded))
+	assert.DeepEqual(t, registry.OrderedStatuses(), decoded)
+}
@@ -0,0 +1,82 @@
+package shared
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// disabledService is a service registered while disabled, listed by
+// ListServices and DebugJSON but never started, stopped or depended upon.
+type disabledService struct {
+	kind reflect.Type
+	name string
+	// flag is the flag which disabled the service, if any.
+	flag string
+}
+
+// reason returns why the service is disabled.
+func (d *disabledService) reason() string {
+	if d.flag == "" {
+		return "disabled"
+	}
+	return fmt.Sprintf("disabled by flag --%s", d.flag)
+}
+
+// RegisterServiceIf registers the service with the given service context if
+// enabled is set, as RegisterServiceWithConfig does. Otherwise the service is
+// only recorded as disabled: it is listed by ListServices and DebugJSON so
+// that operators can tell it exists, but it is neither started nor stopped,
+// and services depending on it fail validation.
+func (s *ServiceRegistry) RegisterServiceIf(enabled bool, service Service, ctx *ServiceContext) error {
+	return s.RegisterServiceIfFlag("", enabled, service, ctx)
+}
+
+// RegisterServiceIfFlag is like RegisterServiceIf, but records the name of
+// the command line flag enabling or disabling the service, without its
+// leading dashes, which is reported along with a disabled service.
+func (s *ServiceRegistry) RegisterServiceIfFlag(flag string, enabled bool, service Service, ctx *ServiceContext) error {
+	if enabled {
+		return s.RegisterServiceWithConfig(service, ctx, nil)
+	}
+	if service == nil {
+		return errNilService
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return fmt.Errorf("could not register service %s: %w", serviceName(service), ErrRegistryClosed)
+	}
+	if s.phase.startAllCalled() {
+		return fmt.Errorf("could not register service %s: %w", serviceName(service), ErrAlreadyStarted)
+	}
+	kind := reflect.TypeOf(service)
+	if _, exists := s.services[kind]; exists || s.disabledOf(kind) != nil {
+		return fmt.Errorf("%w: %s", ErrServiceAlreadyRegistered, typeName(kind))
+	}
+	entry := &serviceEntry{kind: kind, service: service, cfg: &ServiceConfig{}}
+	d := &disabledService{kind: kind, name: entry.String(), flag: flag}
+	s.disabled = append(s.disabled, d)
+	s.log.WithField("service", d.name).Debugf("Service is %s", d.reason())
+	return nil
+}
+
+// disabledOf returns the disabled service of the given type, or nil. The
+// caller must hold the registry lock.
+func (s *ServiceRegistry) disabledOf(kind reflect.Type) *disabledService {
+	for _, d := range s.disabled {
+		if d.kind == kind {
+			return d
+		}
+	}
+	return nil
+}
+
+// disabledSnapshot returns a copy of the disabled services, in order of
+// registration.
+func (s *ServiceRegistry) disabledSnapshot() []*disabledService {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	disabled := make([]*disabledService, len(s.disabled))
+	copy(disabled, s.disabled)
+	return disabled
+}
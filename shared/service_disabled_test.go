@@ -0,0 +1,67 @@
+package shared
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+func TestRegisterServiceIf(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterServiceIf(true, &mockService{}, nil))
+	require.NoError(t, registry.RegisterServiceIfFlag("disable-grpc-gateway", false, &secondMockService{}, nil))
+	require.NoError(t, registry.RegisterServiceIf(false, &thirdMockService{}, nil))
+
+	assert.ErrorContains(t, ErrServiceAlreadyRegistered.Error(), registry.RegisterServiceIf(false, &secondMockService{}, nil))
+	var second *secondMockService
+	assert.Equal(t, true, errors.Is(registry.FetchService(&second), ErrServiceNotFound))
+
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, reflect.TypeOf(&mockService{}), StateRunning)
+	infos := registry.ListServices()
+	require.Equal(t, 3, len(infos))
+	assert.Equal(t, "", infos[0].Disabled)
+	assert.Equal(t, "shared.secondMockService", infos[1].Name)
+	assert.Equal(t, StateDisabled, infos[1].State)
+	assert.Equal(t, true, infos[1].Healthy)
+	assert.Equal(t, "disabled by flag --disable-grpc-gateway", infos[1].Disabled)
+	assert.Equal(t, "disabled", infos[2].Disabled)
+
+	dump, err := registry.DebugJSON()
+	require.NoError(t, err)
+	var decoded struct {
+		Services []debugService `json:"services"`
+	}
+	require.NoError(t, json.Unmarshal(dump, &decoded))
+	require.Equal(t, 3, len(decoded.Services))
+	assert.Equal(t, "running", decoded.Services[0].State)
+	assert.Equal(t, "disabled", decoded.Services[1].State)
+	assert.Equal(t, -1, decoded.Services[1].StartOrder)
+	assert.Equal(t, "disabled by flag --disable-grpc-gateway", decoded.Services[1].Disabled)
+
+	require.NoError(t, registry.StopAll())
+	assert.Equal(t, StateStopped, registry.ListServices()[0].State)
+}
+
+func TestRegisterServiceIf_DisabledDependency(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterServiceIfFlag("disable-grpc-gateway", false, &secondMockService{}, nil))
+	require.NoError(t, registry.RegisterServiceWithConfig(&mockService{}, nil, &ServiceConfig{
+		Dependencies: []reflect.Type{reflect.TypeOf(&secondMockService{})},
+	}))
+
+	err := registry.StartAll()
+	assert.ErrorContains(t, "service shared.mockService depends on service shared.secondMockService, which is disabled by flag --disable-grpc-gateway", err)
+}
+
+func TestStateDisabled_Text(t *testing.T) {
+	text, err := StateDisabled.MarshalText()
+	require.NoError(t, err)
+	var state ServiceState
+	require.NoError(t, state.UnmarshalText(text))
+	assert.Equal(t, StateDisabled, state)
+}
@@ -0,0 +1,74 @@
+package shared
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	serviceStartDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "service_start_duration_seconds",
+			Help:    "Time from the start of a service until it is first ready, in seconds.",
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 14),
+		},
+		[]string{"service"},
+	)
+	serviceStopDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "service_stop_duration_seconds",
+			Help:    "Duration of the Stop call of a service, in seconds.",
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 14),
+		},
+		[]string{"service"},
+	)
+)
+
+// observeStartup waits for a service started at the given time to be ready,
+// recording how long it took, then calls done with the error which prevented
+// the service from being ready, if any.
+func (s *ServiceRegistry) observeStartup(entry *serviceEntry, start time.Time, done func(err error)) {
+	err := s.waitUntilReady(entry)
+	if err == nil {
+		serviceStartDuration.WithLabelValues(entry.String()).Observe(time.Since(start).Seconds())
+		s.emit(ServiceReady, entry, nil)
+	}
+	done(err)
+}
+
+// waitUntilReady polls the readiness of a service until it is running and
+// ready, returning the last readiness error if the service stops or the
+// registry shuts down first.
+func (s *ServiceRegistry) waitUntilReady(entry *serviceEntry) error {
+	s.lock.RLock()
+	interval := s.readyPoll
+	s.lock.RUnlock()
+	if interval <= 0 {
+		interval = defaultReadyPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		// A service is only ready once its Start method returned.
+		state := s.stateOf(entry)
+		err := checkStatus(func() error { return s.readiness(entry) }, healthzStatusTimeout)
+		switch {
+		case err == nil && (state == StateRunning || state == StatePaused):
+			return nil
+		case err == nil:
+			err = fmt.Errorf("service is %v", state)
+		}
+		if state == StateStopping || state == StateStopped {
+			return err
+		}
+		select {
+		case <-ticker.C:
+		case <-s.shutdown:
+			return errors.New("registry shut down before the service was ready: " + err.Error())
+		}
+	}
+}
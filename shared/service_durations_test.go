@@ -0,0 +1,51 @@
+package shared
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+func histogramCount(t *testing.T, vec *prometheus.HistogramVec, service string) uint64 {
+	metric := &dto.Metric{}
+	require.NoError(t, vec.WithLabelValues(service).(prometheus.Histogram).Write(metric))
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func TestStartAll_ObservesStartDuration(t *testing.T) {
+	ready := histogramCount(t, serviceStartDuration, "start-duration-ready")
+	syncing := histogramCount(t, serviceStartDuration, "start-duration-syncing")
+	registry := NewServiceRegistry()
+	registry.SetReadyPollInterval(10 * time.Millisecond)
+	svc := &statusFuncService{}
+	svc.setStatus(func() error { return errors.New("syncing") })
+	require.NoError(t, registry.RegisterNamedService("start-duration-ready", &mockService{}, nil))
+	require.NoError(t, registry.RegisterNamedService("start-duration-syncing", svc, nil))
+	require.NoError(t, registry.StartAll())
+
+	for i := 0; i < 100 && histogramCount(t, serviceStartDuration, "start-duration-ready") == ready; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, ready+1, histogramCount(t, serviceStartDuration, "start-duration-ready"))
+	// Services which never became ready are not observed.
+	require.NoError(t, registry.StopAll())
+	assert.Equal(t, syncing, histogramCount(t, serviceStartDuration, "start-duration-syncing"))
+}
+
+func TestStopAll_ObservesStopDuration(t *testing.T) {
+	stopped := histogramCount(t, serviceStopDuration, "stop-duration")
+	failed := histogramCount(t, serviceStopDuration, "stop-duration-failing")
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterNamedService("stop-duration", &mockService{}, nil))
+	require.NoError(t, registry.RegisterNamedService("stop-duration-failing", &failingStopService{err: errors.New("db locked")}, nil))
+	require.NoError(t, registry.StartAll())
+	assert.ErrorContains(t, "db locked", registry.StopAll())
+
+	assert.Equal(t, stopped+1, histogramCount(t, serviceStopDuration, "stop-duration"))
+	assert.Equal(t, failed+1, histogramCount(t, serviceStopDuration, "stop-duration-failing"))
+}
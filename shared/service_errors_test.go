@@ -0,0 +1,49 @@
+package shared
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+func TestRegistryErrors(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.RegisterNamedService("beacon", &secondMockService{}, nil))
+	unknown := reflect.TypeOf(&thirdMockService{})
+	var third *thirdMockService
+	var p pauser
+
+	tests := []struct {
+		name   string
+		err    error
+		target error
+	}{
+		{name: "duplicate type", err: registry.RegisterService(&mockService{}), target: ErrServiceAlreadyRegistered},
+		{name: "duplicate name", err: registry.RegisterNamedService("beacon", &secondMockService{}, nil), target: ErrServiceAlreadyRegistered},
+		{name: "fetch unknown type", err: registry.FetchService(&third), target: ErrServiceNotFound},
+		{name: "fetch unknown interface", err: registry.FetchService(&p), target: ErrServiceNotFound},
+		{name: "fetch unknown name", err: registry.FetchNamedService("validator", &third), target: ErrServiceNotFound},
+		{name: "fetch value", err: registry.FetchService(mockService{}), target: ErrNotPointer},
+		{name: "fetch named value", err: registry.FetchNamedService("beacon", secondMockService{}), target: ErrNotPointer},
+		{name: "restart unknown", err: registry.RestartService(unknown), target: ErrServiceNotFound},
+		{name: "stop unknown", err: registry.StopService(unknown), target: ErrServiceNotFound},
+		{name: "unregister unknown", err: registry.UnregisterService(unknown), target: ErrServiceNotFound},
+		{name: "replace unknown", err: registry.ReplaceService(&thirdMockService{}), target: ErrServiceNotFound},
+		{name: "log level of unknown", err: registry.SetServiceLogLevel("validator", 0), target: ErrServiceNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, true, errors.Is(tt.err, tt.target), "Unexpected error %v", tt.err)
+		})
+	}
+
+	require.NoError(t, registry.StartAll())
+	err := registry.RegisterService(&thirdMockService{})
+	assert.Equal(t, true, errors.Is(err, ErrAlreadyStarted), "Unexpected error %v", err)
+	assert.Equal(t, true, errors.Is(registry.StartAll(), ErrAlreadyStarted))
+	require.NoError(t, registry.StopAll())
+}
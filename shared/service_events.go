@@ -0,0 +1,101 @@
+package shared
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// registryEventsBuffer is the capacity of the channel returned by Events.
+const registryEventsBuffer = 256
+
+// RegistryEventType is the kind of a RegistryEvent.
+type RegistryEventType int
+
+const (
+	// ServiceRegistered is emitted when a service is registered.
+	ServiceRegistered RegistryEventType = iota
+	// ServiceStarted is emitted when the Start method of a service returned.
+	ServiceStarted
+	// ServiceReady is emitted when a service started by StartAll is first
+	// ready.
+	ServiceReady
+	// ServiceStopped is emitted when a service stopped.
+	ServiceStopped
+	// ServiceFailed is emitted when a service could not be constructed,
+	// started or stopped.
+	ServiceFailed
+)
+
+// String returns the name of the event type.
+func (t RegistryEventType) String() string {
+	switch t {
+	case ServiceRegistered:
+		return "registered"
+	case ServiceStarted:
+		return "started"
+	case ServiceReady:
+		return "ready"
+	case ServiceStopped:
+		return "stopped"
+	case ServiceFailed:
+		return "failed"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(t))
+	}
+}
+
+// RegistryEvent describes a change in the lifecycle of a service.
+type RegistryEvent struct {
+	Type RegistryEventType
+	// Service is the name of the service.
+	Service string
+	Time    time.Time
+	// Err is why the service failed, for ServiceFailed events.
+	Err error
+}
+
+// registryEvents is the channel of events returned by Events, closed once
+// StopAll completed.
+type registryEvents struct {
+	lock    sync.Mutex
+	ch      chan RegistryEvent
+	closed  bool
+	dropped int
+}
+
+// Events returns the channel on which the registry emits the lifecycle
+// events of its services. The channel is buffered, and events emitted while
+// its buffer is full are dropped, so that a slow reader never blocks the
+// registry. The channel is closed once StopAll completed, so that readers can
+// range over it.
+func (s *ServiceRegistry) Events() <-chan RegistryEvent {
+	return s.events.ch
+}
+
+// emit sends an event about a service, unless the channel is closed or full.
+func (s *ServiceRegistry) emit(t RegistryEventType, entry *serviceEntry, err error) {
+	e := &s.events
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.closed {
+		return
+	}
+	select {
+	case e.ch <- RegistryEvent{Type: t, Service: entry.String(), Time: time.Now(), Err: err}:
+	default:
+		e.dropped++
+		s.log.WithField("dropped", e.dropped).Debugf("Dropping %v event of service %v, the events channel is full", t, entry)
+	}
+}
+
+// closeEvents closes the events channel, once.
+func (s *ServiceRegistry) closeEvents() {
+	e := &s.events
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if !e.closed {
+		e.closed = true
+		close(e.ch)
+	}
+}
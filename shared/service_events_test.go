@@ -0,0 +1,97 @@
+package shared
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+// eventTypes returns the types of the events of every service, in the order
+// they were emitted.
+func eventTypes(events []RegistryEvent) map[string][]RegistryEventType {
+	types := make(map[string][]RegistryEventType)
+	for _, e := range events {
+		types[e.Service] = append(types[e.Service], e.Type)
+	}
+	return types
+}
+
+func TestEvents_Lifecycle(t *testing.T) {
+	registry := NewServiceRegistry()
+	registry.SetReadyPollInterval(5 * time.Millisecond)
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.RegisterNamedService("gateway", &secondMockService{}, nil))
+	require.NoError(t, registry.StartAll())
+
+	var events []RegistryEvent
+	ready := 0
+	for ready < 2 {
+		select {
+		case e := <-registry.Events():
+			events = append(events, e)
+			if e.Type == ServiceReady {
+				ready++
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Services did not become ready")
+		}
+	}
+	require.NoError(t, registry.StopAll())
+	// The channel is closed once StopAll completed.
+	for e := range registry.Events() {
+		events = append(events, e)
+	}
+
+	want := []RegistryEventType{ServiceRegistered, ServiceStarted, ServiceReady, ServiceStopped}
+	assert.DeepEqual(t, map[string][]RegistryEventType{
+		"shared.mockService": want,
+		"gateway":            want,
+	}, eventTypes(events))
+	for _, e := range events {
+		assert.Equal(t, false, e.Time.IsZero())
+	}
+}
+
+func TestEvents_Failures(t *testing.T) {
+	registry := NewServiceRegistry(WithLegacyStartWait())
+	require.NoError(t, registry.RegisterService(&panickingStartService{started: make(chan struct{})}))
+	require.NoError(t, registry.RegisterService(&failingStopService{err: errors.New("db locked")}))
+	// The failed start stops the other service.
+	assert.ErrorContains(t, "could not bind port", registry.StartAll())
+
+	var failures []RegistryEvent
+	for e := range registry.Events() {
+		if e.Type == ServiceFailed {
+			failures = append(failures, e)
+		}
+	}
+	require.Equal(t, 2, len(failures))
+	assert.Equal(t, "shared.panickingStartService", failures[0].Service)
+	assert.ErrorContains(t, "service panicked during start: could not bind port", failures[0].Err)
+	assert.Equal(t, "shared.failingStopService", failures[1].Service)
+	assert.ErrorContains(t, "db locked", failures[1].Err)
+}
+
+func TestEvents_Overflow(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	entry := registry.snapshot()[0]
+	for i := 0; i < 2*registryEventsBuffer; i++ {
+		registry.emit(ServiceStarted, entry, nil)
+	}
+	assert.Equal(t, registryEventsBuffer, len(registry.Events()))
+	// The oldest events are kept.
+	assert.Equal(t, ServiceRegistered, (<-registry.Events()).Type)
+
+	registry.closeEvents()
+	registry.closeEvents()
+	registry.emit(ServiceStopped, entry, nil)
+}
+
+func TestRegistryEventType_String(t *testing.T) {
+	assert.Equal(t, "ready", ServiceReady.String())
+	assert.Equal(t, "unknown(42)", RegistryEventType(42).String())
+}
@@ -0,0 +1,76 @@
+package shared
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// FatalError is a fatal error reported by a service through the Fatalf
+// method of its service context.
+type FatalError struct {
+	// Service is the name of the service which reported the error.
+	Service string
+	Err     error
+}
+
+// Error returns the error prefixed with the name of the service.
+func (e *FatalError) Error() string {
+	return fmt.Sprintf("service %s: %v", e.Service, e.Err)
+}
+
+// Unwrap returns the error reported by the service.
+func (e *FatalError) Unwrap() error {
+	return e.Err
+}
+
+// Fatalf reports an unrecoverable error, such as a corrupted database or a
+// listener which died, to the registry the service is registered with, which
+// stops every service with ShutdownFatal as the reason. The error is formatted
+// like fmt.Errorf, and only the first fatal error reported to a registry
+// causes a shutdown: the next ones are logged. Fatalf does not stop the
+// calling goroutine, and the error is only logged if the context was never
+// registered.
+func (c *ServiceContext) Fatalf(format string, args ...interface{}) {
+	c.lock.RLock()
+	service, report := c.service, c.report
+	c.lock.RUnlock()
+	err := &FatalError{Service: service, Err: fmt.Errorf(format, args...)}
+	if report == nil {
+		log.WithError(err).Error("Fatal error reported by an unregistered service")
+		return
+	}
+	report(err)
+}
+
+// setFatalReporter sets the function Fatalf reports errors to, unless it was
+// already set, along with the service name the errors are attributed to.
+func (c *ServiceContext) setFatalReporter(service string, report func(error)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.report != nil {
+		return
+	}
+	c.service = service
+	c.report = report
+}
+
+// FatalErrors returns the channel receiving the first fatal error reported to
+// the registry, by a service through Fatalf or otherwise, for node entrypoints
+// which do not call Run. It receives at most one error, and must not be read
+// alongside Run.
+func (s *ServiceRegistry) FatalErrors() <-chan error {
+	return s.fatal
+}
+
+// fatalLog returns the registry logger with the given fatal error and, if it
+// was reported by a service, the name of the service as fields.
+func (s *ServiceRegistry) fatalLog(err error) *logrus.Entry {
+	entry := s.log.WithError(err)
+	var fatal *FatalError
+	if errors.As(err, &fatal) {
+		entry = entry.WithField("service", fatal.Service)
+	}
+	return entry
+}
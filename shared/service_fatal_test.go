@@ -0,0 +1,62 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+	logTest "github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestServiceContext_FatalfStopsRun(t *testing.T) {
+	hook := logTest.NewGlobal()
+	registry := NewServiceRegistry()
+	ctx := registry.NewServiceContext()
+	require.NoError(t, registry.RegisterNamedService("db", &mockService{}, ctx))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- registry.Run(context.Background())
+	}()
+	ctx.Fatalf("corrupted bucket %q: %w", "blocks", errors.New("bad checksum"))
+	ctx.Fatalf("second failure")
+
+	select {
+	case err := <-done:
+		var fatal *FatalError
+		require.Equal(t, true, errors.As(err, &fatal))
+		assert.Equal(t, "db", fatal.Service)
+		assert.ErrorContains(t, "service db: corrupted bucket \"blocks\": bad checksum", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after a fatal error")
+	}
+	require.LogsContain(t, hook, "Fatal error, stopping services")
+	require.LogsContain(t, hook, "service=db")
+	require.LogsContain(t, hook, "a previous one is already stopping the services")
+	require.Equal(t, ShutdownFatal, shutdownReason(ctx))
+}
+
+func TestServiceContext_FatalfCollapsesAfterRead(t *testing.T) {
+	registry := NewServiceRegistry()
+	ctx := registry.NewServiceContext()
+	require.NoError(t, registry.RegisterServiceWithConfig(&mockService{}, ctx, nil))
+
+	ctx.Fatalf("first")
+	err := <-registry.FatalErrors()
+	assert.ErrorContains(t, "first", err)
+	ctx.Fatalf("second")
+	select {
+	case err := <-registry.FatalErrors():
+		t.Fatalf("Received a second fatal error: %v", err)
+	default:
+	}
+}
+
+func TestServiceContext_FatalfUnregistered(t *testing.T) {
+	hook := logTest.NewGlobal()
+	NewServiceContext().Fatalf("listener died")
+	require.LogsContain(t, hook, "Fatal error reported by an unregistered service")
+}
@@ -0,0 +1,100 @@
+package shared
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// dotColor returns the fill color of a service in the dependency graph,
+// depending on its state and status.
+func dotColor(state ServiceState, status error) string {
+	switch {
+	case state == StateDisabled:
+		return "white"
+	case state == StateRegistered || state == StateStopped && status == nil:
+		return "lightgrey"
+	case status != nil:
+		return "tomato"
+	case state == StateStarting || state == StateStopping:
+		return "khaki"
+	case state == StatePaused:
+		return "lightblue"
+	default:
+		return "palegreen"
+	}
+}
+
+// DependencyGraphDOT returns the dependency graph of the registered services
+// in the Graphviz DOT language, to be rendered with a command such as
+// dot -Tsvg. Each service is a node labeled with its name and state, filled
+// according to its state and health, and each dependency is an edge from the
+// dependent service to its dependency. Disabled services are dashed, as are
+// the dependencies which are not registered. Services are listed in order of
+// registration, so that the output only changes with the services.
+func (s *ServiceRegistry) DependencyGraphDOT() string {
+	type stateCopy struct {
+		entry    *serviceEntry
+		startErr error
+		state    ServiceState
+		deps     []string
+	}
+	s.lock.RLock()
+	copies := make([]stateCopy, len(s.entries))
+	missing := make(map[string]bool)
+	for i, entry := range s.entries {
+		deps := make([]string, len(entry.cfg.Dependencies))
+		for j, dep := range entry.cfg.Dependencies {
+			if depEntry, ok := s.services[dep]; ok {
+				deps[j] = depEntry.String()
+			} else if d := s.disabledOf(dep); d != nil {
+				deps[j] = d.name
+			} else {
+				deps[j] = typeName(dep)
+				missing[deps[j]] = true
+			}
+		}
+		copies[i] = stateCopy{entry: entry, startErr: entry.startErr, state: entry.state, deps: deps}
+	}
+	s.lock.RUnlock()
+
+	var b strings.Builder
+	b.WriteString("digraph services {\n")
+	b.WriteString("\tnode [shape=box, style=filled];\n")
+	for _, c := range copies {
+		c := c
+		status := checkStatus(func() error { return entryStatus(c.entry, c.startErr, c.state) }, healthzStatusTimeout)
+		name := c.entry.String()
+		fmt.Fprintf(&b, "\t%q [label=%q, fillcolor=%q];\n", name, name+"\n"+c.state.String(), dotColor(c.state, status))
+	}
+	for _, d := range s.disabledSnapshot() {
+		fmt.Fprintf(&b, "\t%q [label=%q, style=\"filled,dashed\", fillcolor=%q];\n", d.name, d.name+"\n"+d.reason(), dotColor(StateDisabled, nil))
+	}
+	written := make(map[string]bool)
+	for _, c := range copies {
+		for _, dep := range c.deps {
+			if missing[dep] && !written[dep] {
+				written[dep] = true
+				fmt.Fprintf(&b, "\t%q [label=%q, style=dashed];\n", dep, dep+"\nunregistered")
+			}
+		}
+	}
+	for _, c := range copies {
+		for _, dep := range c.deps {
+			fmt.Fprintf(&b, "\t%q -> %q;\n", c.entry.String(), dep)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// DependencyGraphHandler returns an HTTP handler for debug endpoints, replying
+// with the output of DependencyGraphDOT.
+func (s *ServiceRegistry) DependencyGraphHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+		if _, err := w.Write([]byte(s.DependencyGraphDOT())); err != nil {
+			s.log.WithError(err).Error("Could not write dependency graph response")
+		}
+	})
+}
@@ -0,0 +1,57 @@
+package shared
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+func TestDependencyGraphDOT(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.RegisterServiceIfFlag("disable-grpc-gateway", false, &thirdMockService{}, nil))
+	require.NoError(t, registry.RegisterServiceWithConfig(&secondMockService{}, nil, &ServiceConfig{
+		Dependencies: []reflect.Type{reflect.TypeOf(&mockService{}), reflect.TypeOf(&thirdMockService{})},
+	}))
+	require.NoError(t, registry.RegisterNamedService("beacon", &failingStopService{}, nil))
+	require.NoError(t, registry.RegisterServiceWithConfig(&chainInfoService{}, nil, &ServiceConfig{
+		Dependencies: []reflect.Type{reflect.TypeOf(&mockService{}), reflect.TypeOf(&startCountingService{})},
+	}))
+
+	want, err := ioutil.ReadFile(filepath.Join("testdata", "services.dot"))
+	require.NoError(t, err)
+	assert.Equal(t, string(want), registry.DependencyGraphDOT())
+}
+
+func TestDependencyGraphDOT_Colors(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.RegisterService(&secondMockService{status: errors.New("syncing")}))
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, reflect.TypeOf(&mockService{}), StateRunning)
+	waitForState(t, registry, reflect.TypeOf(&secondMockService{}), StateRunning)
+
+	graph := registry.DependencyGraphDOT()
+	assert.Equal(t, true, strings.Contains(graph, "\"shared.mockService\" [label=\"shared.mockService\\nrunning\", fillcolor=\"palegreen\"];"), graph)
+	assert.Equal(t, true, strings.Contains(graph, "\"shared.secondMockService\" [label=\"shared.secondMockService\\nrunning\", fillcolor=\"tomato\"];"), graph)
+	require.NoError(t, registry.StopAll())
+}
+
+func TestDependencyGraphHandler(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&mockService{}))
+
+	rec := httptest.NewRecorder()
+	registry.DependencyGraphHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/services.dot", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/vnd.graphviz; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, registry.DependencyGraphDOT(), rec.Body.String())
+}
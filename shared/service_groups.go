@@ -0,0 +1,101 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// inGroup returns whether the service was tagged with the given group.
+func (e *serviceEntry) inGroup(group string) bool {
+	for _, g := range e.cfg.Groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+// groupEntries filters the given services, keeping those of the group.
+func groupEntries(entries []*serviceEntry, group string) []*serviceEntry {
+	var members []*serviceEntry
+	for _, entry := range entries {
+		if entry.inGroup(group) {
+			members = append(members, entry)
+		}
+	}
+	return members
+}
+
+// StartGroup starts every service of the given group which is not already
+// active, in the same order StartAll would. Services of the group which were
+// stopped are started again with a fresh service context if theirs was
+// cancelled. Dependencies outside of the group are not started.
+func (s *ServiceRegistry) StartGroup(group string) error {
+	if s.isClosed() {
+		return ErrRegistryClosed
+	}
+	s.constructLazy(func(entry *serviceEntry) bool { return entry.inGroup(group) })
+	order, err := s.startOrder()
+	if err != nil {
+		return err
+	}
+	members := groupEntries(order, group)
+	if len(members) == 0 {
+		return fmt.Errorf("unknown service group: %s", group)
+	}
+	s.lock.Lock()
+	if s.phase == phaseRegistering {
+		s.phase = phaseGroupsStarted
+	}
+	s.lock.Unlock()
+	s.log.Debugf("Starting %d services of group %s: %v", len(members), group, members)
+	for _, entry := range members {
+		s.lock.Lock()
+		state := entry.state
+		if state == StateStopped {
+			if entry.ctx.Err() != nil {
+				entry.ctx = entry.ctx.renew()
+			}
+			entry.startErr = nil
+		}
+		s.lock.Unlock()
+		if entry.isLazy() || (state != StateRegistered && state != StateStopped) {
+			continue
+		}
+		s.launch(entry)
+	}
+	return nil
+}
+
+// StopGroup stops every active service of the given group, in reverse start
+// order, or in reverse order of priority and registration if dependencies
+// cannot be ordered, logging the services which fail to stop. The
+// context of a service is not cancelled while it is shared with a service
+// outside of the group which is still active.
+func (s *ServiceRegistry) StopGroup(group string) error {
+	order, err := s.startOrder()
+	if err != nil {
+		order = byPriority(s.snapshot())
+	}
+	members := groupEntries(order, group)
+	if len(members) == 0 {
+		return fmt.Errorf("unknown service group: %s", group)
+	}
+	for i := len(members) - 1; i >= 0; i-- {
+		entry := members[i]
+		if state := s.stateOf(entry); state == StateRegistered || state == StateStopped {
+			continue
+		}
+		if err := s.stopService(context.Background(), entry); err != nil {
+			s.log.WithError(err).Errorf("Could not stop the following service: %v", entry)
+		}
+	}
+	return nil
+}
+
+// GroupStatuses is like Statuses, but only reports the services of the given
+// group.
+func (s *ServiceRegistry) GroupStatuses(group string) map[reflect.Type]error {
+	return s.statusesOf(groupEntries(s.snapshot(), group), s.status)
+}
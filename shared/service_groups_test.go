@@ -0,0 +1,86 @@
+package shared
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+func TestStartGroup_StartsOnlyGroupMembers(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&mockService{}, "core"))
+	require.NoError(t, registry.RegisterService(&secondMockService{}, "core", "api"))
+	require.NoError(t, registry.RegisterService(&thirdMockService{}, "sync"))
+
+	require.NoError(t, registry.StartGroup("api"))
+	waitForState(t, registry, reflect.TypeOf(&secondMockService{}), StateRunning)
+	for _, kind := range []reflect.Type{reflect.TypeOf(&mockService{}), reflect.TypeOf(&thirdMockService{})} {
+		state, err := registry.State(kind)
+		require.NoError(t, err)
+		assert.Equal(t, StateRegistered, state, "Unexpected state for %v", kind)
+	}
+
+	require.NoError(t, registry.StartGroup("core"))
+	waitForState(t, registry, reflect.TypeOf(&mockService{}), StateRunning)
+	require.NoError(t, registry.StopAll())
+}
+
+func TestStartGroup_UnknownGroup(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&mockService{}, "core"))
+	assert.ErrorContains(t, "unknown service group: api", registry.StartGroup("api"))
+	assert.ErrorContains(t, "unknown service group: api", registry.StopGroup("api"))
+}
+
+func TestStopGroup_RestartGroup(t *testing.T) {
+	registry := NewServiceRegistry()
+	s := &stopRecordingService{}
+	require.NoError(t, registry.RegisterService(s, "sync"))
+	require.NoError(t, registry.RegisterService(&mockService{}, "core"))
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, reflect.TypeOf(s), StateRunning)
+
+	require.NoError(t, registry.StopGroup("sync"))
+	assert.Equal(t, true, s.stopped)
+	state, err := registry.State(reflect.TypeOf(s))
+	require.NoError(t, err)
+	assert.Equal(t, StateStopped, state)
+	state, err = registry.State(reflect.TypeOf(&mockService{}))
+	require.NoError(t, err)
+	assert.Equal(t, StateRunning, state)
+
+	require.NoError(t, registry.StartGroup("sync"))
+	waitForState(t, registry, reflect.TypeOf(s), StateRunning)
+	assert.NoError(t, registry.Statuses()[reflect.TypeOf(s)])
+	require.NoError(t, registry.StopAll())
+}
+
+func TestStopGroup_KeepsSharedContext(t *testing.T) {
+	registry := NewServiceRegistry()
+	ctx := NewServiceContext()
+	require.NoError(t, registry.RegisterServiceWithConfig(&mockService{}, ctx, &ServiceConfig{Groups: []string{"sync"}}))
+	require.NoError(t, registry.RegisterServiceWithConfig(&secondMockService{}, ctx, &ServiceConfig{Groups: []string{"api"}}))
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, reflect.TypeOf(&mockService{}), StateRunning)
+	waitForState(t, registry, reflect.TypeOf(&secondMockService{}), StateRunning)
+
+	require.NoError(t, registry.StopGroup("sync"))
+	assert.NoError(t, ctx.Err(), "Expected context shared with a running service to be kept")
+
+	require.NoError(t, registry.StopGroup("api"))
+	assert.NotNil(t, ctx.Err(), "Expected context to be cancelled once no service uses it")
+}
+
+func TestGroupStatuses(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&mockService{}, "core"))
+	require.NoError(t, registry.RegisterService(&secondMockService{}, "api"))
+
+	statuses := registry.GroupStatuses("api")
+	assert.Equal(t, 1, len(statuses))
+	_, ok := statuses[reflect.TypeOf(&secondMockService{})]
+	assert.Equal(t, true, ok)
+	assert.Equal(t, 0, len(registry.GroupStatuses("sync")))
+}
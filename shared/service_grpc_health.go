@@ -0,0 +1,119 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// defaultHealthWatchInterval is how often statuses are re-evaluated for the
+// streams opened through the Watch RPC.
+const defaultHealthWatchInterval = time.Second
+
+// ReadinessServiceName is the service name under which HealthServer serves
+// the overall readiness of the registry.
+const ReadinessServiceName = "readiness"
+
+// HealthServer implements the standard gRPC health checking protocol on top
+// of the registry statuses. The empty service name refers to the overall
+// liveness of the registry, ReadinessServiceName to its overall readiness,
+// while other names refer to the liveness of individual services.
+type HealthServer struct {
+	grpc_health_v1.UnimplementedHealthServer
+	registry      *ServiceRegistry
+	watchInterval time.Duration
+}
+
+// NewHealthServer returns a gRPC health server for the given registry, whose
+// Watch streams re-evaluate statuses every watchInterval. A zero interval
+// falls back to a default of one second.
+func NewHealthServer(registry *ServiceRegistry, watchInterval time.Duration) *HealthServer {
+	if watchInterval <= 0 {
+		watchInterval = defaultHealthWatchInterval
+	}
+	return &HealthServer{
+		registry:      registry,
+		watchInterval: watchInterval,
+	}
+}
+
+// RegisterHealthServer registers the grpc.health.v1.Health service, backed by
+// the registry statuses, on an existing gRPC server.
+func (s *ServiceRegistry) RegisterHealthServer(server *grpc.Server) {
+	grpc_health_v1.RegisterHealthServer(server, NewHealthServer(s, defaultHealthWatchInterval))
+}
+
+// Check returns the serving status of the requested service, failing with
+// NOT_FOUND if no such service is registered.
+func (h *HealthServer) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	servingStatus := h.servingStatus(ctx, req.Service)
+	if servingStatus == grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN {
+		return nil, status.Errorf(codes.NotFound, "unknown service: %s", req.Service)
+	}
+	return &grpc_health_v1.HealthCheckResponse{Status: servingStatus}, nil
+}
+
+// Watch sends the serving status of the requested service immediately, and
+// then every time it changes, until the client cancels the stream.
+func (h *HealthServer) Watch(req *grpc_health_v1.HealthCheckRequest, stream grpc_health_v1.Health_WatchServer) error {
+	ticker := time.NewTicker(h.watchInterval)
+	defer ticker.Stop()
+	var last grpc_health_v1.HealthCheckResponse_ServingStatus
+	first := true
+	for {
+		servingStatus := h.servingStatus(stream.Context(), req.Service)
+		if first || servingStatus != last {
+			if err := stream.Send(&grpc_health_v1.HealthCheckResponse{Status: servingStatus}); err != nil {
+				return err
+			}
+			first = false
+			last = servingStatus
+		}
+		select {
+		case <-ticker.C:
+		case <-stream.Context().Done():
+			return status.Error(codes.Canceled, "stream has ended")
+		}
+	}
+}
+
+// servingStatus evaluates the status of the named service, or of every
+// service if the name is empty or refers to the readiness of the registry.
+// The statuses are checked concurrently, until the context is done or at
+// most for healthzStatusTimeout.
+func (h *HealthServer) servingStatus(ctx context.Context, name string) grpc_health_v1.HealthCheckResponse_ServingStatus {
+	check := h.registry.cachedStatus
+	if name == ReadinessServiceName {
+		check, name = h.registry.cachedReadiness, ""
+	}
+	var entries []*serviceEntry
+	for _, entry := range h.registry.snapshot() {
+		if name == "" || entry.String() == name {
+			entries = append(entries, entry)
+		}
+	}
+	found := name == "" || len(entries) > 0
+	ctx, cancel := context.WithTimeout(ctx, healthzStatusTimeout)
+	defer cancel()
+	healthy := true
+	for _, err := range checkStatuses(ctx, entries, check) {
+		// Optional services, and services stopped on request, do not affect
+		// the overall health of the node.
+		if err != nil && (name != "" || !(StatusSeverity(err) == SeverityDegraded || errors.Is(err, ErrServiceStopped))) {
+			healthy = false
+		}
+	}
+	switch {
+	case !found:
+		return grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN
+	case !healthy:
+		return grpc_health_v1.HealthCheckResponse_NOT_SERVING
+	default:
+		return grpc_health_v1.HealthCheckResponse_SERVING
+	}
+}
@@ -0,0 +1,94 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type mockWatchServer struct {
+	grpc.ServerStream
+	ctx       context.Context
+	responses chan *grpc_health_v1.HealthCheckResponse
+}
+
+func (m *mockWatchServer) Send(resp *grpc_health_v1.HealthCheckResponse) error {
+	m.responses <- resp
+	return nil
+}
+
+func (m *mockWatchServer) Context() context.Context {
+	return m.ctx
+}
+
+func TestHealthServer_Check(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.RegisterService(&secondMockService{status: errors.New("bad")}))
+	server := NewHealthServer(registry, 0)
+
+	resp, err := server.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, resp.Status)
+
+	resp, err = server.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: "shared.mockService"})
+	require.NoError(t, err)
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, resp.Status)
+
+	resp, err = server.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: "shared.secondMockService"})
+	require.NoError(t, err)
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, resp.Status)
+
+	_, err = server.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: "unknown"})
+	assert.ErrorContains(t, "unknown service: unknown", err)
+}
+
+func TestHealthServer_Watch(t *testing.T) {
+	registry := NewServiceRegistry()
+	s := &statusFuncService{status: func() error { return errors.New("syncing") }}
+	require.NoError(t, registry.RegisterService(s))
+	server := NewHealthServer(registry, 10*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &mockWatchServer{ctx: ctx, responses: make(chan *grpc_health_v1.HealthCheckResponse, 10)}
+	done := make(chan error)
+	go func() {
+		done <- server.Watch(&grpc_health_v1.HealthCheckRequest{}, stream)
+	}()
+
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, (<-stream.responses).Status)
+	// Unchanged statuses must not be sent again.
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 0, len(stream.responses))
+
+	s.setStatus(func() error { return nil })
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, (<-stream.responses).Status)
+
+	cancel()
+	assert.ErrorContains(t, "stream has ended", <-done)
+}
+
+func TestHealthServer_CheckHungServicesShareDeadline(t *testing.T) {
+	registry := NewServiceRegistry()
+	release := make(chan struct{})
+	defer close(release)
+	for _, name := range []string{"hung-a", "hung-b", "hung-c"} {
+		require.NoError(t, registry.RegisterNamedService(name, &hangingStatusService{release: release}, nil))
+	}
+	require.NoError(t, registry.StartAll())
+	server := NewHealthServer(registry, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	resp, err := server.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, resp.Status)
+	assert.Equal(t, true, time.Since(start) < healthzStatusTimeout, "Expected the hung services to share the request deadline")
+}
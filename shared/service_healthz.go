@@ -0,0 +1,130 @@
+package shared
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// healthzStatusTimeout caps the time the health endpoints wait for the
+// statuses of the services, which are checked concurrently.
+const healthzStatusTimeout = 2 * time.Second
+
+const (
+	healthzStarting  = "starting"
+	healthzOK        = "ok"
+	healthzUnhealthy = "unhealthy"
+	healthzStopping  = "stopping"
+	healthzPaused    = "paused"
+	healthzDegraded  = "degraded"
+)
+
+// healthzResponse is the JSON body served by the healthz handler. Services
+// are mapped to their status error, or to null when they are healthy, and
+// to their details if they report any.
+type healthzResponse struct {
+	Status   string                            `json:"status"`
+	Services map[string]*string                `json:"services"`
+	Details  map[string]map[string]interface{} `json:"details,omitempty"`
+	Uptime   map[string]float64                `json:"uptime_seconds,omitempty"`
+	Metadata map[string]*ServiceMetadata       `json:"metadata,omitempty"`
+}
+
+// HealthzHandler returns an HTTP handler replying 200 when every registered
+// service reports a healthy status and 503 otherwise, including before
+// StartAll was called and once StopAll began. The JSON body maps service
+// names to their status errors. The overall status is "paused" when the only
+// services not reporting a healthy status are paused, and "degraded" when
+// their status errors are of degraded severity, such as those of optional
+// services, in which case the handler still replies 200. Critical errors make
+// the node "unhealthy".
+// Services stopped by StopService are listed but do not affect the status.
+// The uptime of every running service and the metadata of the services
+// implementing Describer are reported as well.
+func (s *ServiceRegistry) HealthzHandler() http.Handler {
+	return s.healthzHandler(s.cachedStatus)
+}
+
+// ReadyzHandler is like HealthzHandler, but reports the readiness of the
+// services rather than their liveness, so that it can back the readiness
+// probe of a node which is alive but still syncing.
+func (s *ServiceRegistry) ReadyzHandler() http.Handler {
+	return s.healthzHandler(s.cachedReadiness)
+}
+
+// healthzHandler serves the result of the given check for every service.
+func (s *ServiceRegistry) healthzHandler(check func(entry *serviceEntry) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.lock.RLock()
+		started, stopping := s.phase.launched(), s.stopping
+		s.lock.RUnlock()
+
+		resp := &healthzResponse{
+			Status:   healthzOK,
+			Services: make(map[string]*string),
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), healthzStatusTimeout)
+		defer cancel()
+		entries := s.snapshot()
+		errs := checkStatuses(ctx, entries, check)
+		for i, entry := range entries {
+			if details := s.serviceDetails(entry); details != nil {
+				if resp.Details == nil {
+					resp.Details = make(map[string]map[string]interface{})
+				}
+				resp.Details[entry.String()] = details
+			}
+			if entry.metadata != nil {
+				if resp.Metadata == nil {
+					resp.Metadata = make(map[string]*ServiceMetadata)
+				}
+				resp.Metadata[entry.String()] = entry.metadata
+			}
+			if _, up := s.startTime(entry); up > 0 {
+				if resp.Uptime == nil {
+					resp.Uptime = make(map[string]float64)
+				}
+				resp.Uptime[entry.String()] = up.Seconds()
+			}
+			err := errs[i]
+			if err == nil {
+				resp.Services[entry.String()] = nil
+				continue
+			}
+			msg := err.Error()
+			resp.Services[entry.String()] = &msg
+			switch {
+			case errors.Is(err, ErrServiceStopped):
+				// Services stopped on request do not affect the node health.
+			case errors.Is(err, ErrServicePaused):
+				if resp.Status == healthzOK {
+					resp.Status = healthzPaused
+				}
+			case StatusSeverity(err) == SeverityDegraded:
+				if resp.Status == healthzOK || resp.Status == healthzPaused {
+					resp.Status = healthzDegraded
+				}
+			default:
+				resp.Status = healthzUnhealthy
+			}
+		}
+		switch {
+		case stopping:
+			resp.Status = healthzStopping
+		case !started:
+			resp.Status = healthzStarting
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if resp.Status == healthzOK || resp.Status == healthzDegraded {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			s.log.WithError(err).Error("Could not write healthz response")
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package shared
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+func serveHealthz(t *testing.T, registry *ServiceRegistry) (int, *healthzResponse) {
+	rr := httptest.NewRecorder()
+	registry.HealthzHandler().ServeHTTP(rr, httptest.NewRequest("GET", "/healthz", nil))
+	resp := &healthzResponse{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), resp))
+	return rr.Code, resp
+}
+
+func TestHealthzHandler_Lifecycle(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&mockService{}))
+
+	code, resp := serveHealthz(t, registry)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, healthzStarting, resp.Status)
+
+	require.NoError(t, registry.StartAll())
+	code, resp = serveHealthz(t, registry)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, healthzOK, resp.Status)
+	assert.DeepEqual(t, map[string]*string{"shared.mockService": nil}, resp.Services)
+
+	waitForState(t, registry, reflect.TypeOf(&mockService{}), StateRunning)
+	_, resp = serveHealthz(t, registry)
+	_, ok := resp.Uptime["shared.mockService"]
+	assert.Equal(t, true, ok, "Expected the uptime of the running service")
+
+	require.NoError(t, registry.StopAll())
+	code, resp = serveHealthz(t, registry)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, healthzStopping, resp.Status)
+	assert.Equal(t, 0, len(resp.Uptime))
+}
+
+func TestHealthzHandler_Unhealthy(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.RegisterService(&secondMockService{status: errors.New("no peers")}))
+	require.NoError(t, registry.StartAll())
+
+	code, resp := serveHealthz(t, registry)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, healthzUnhealthy, resp.Status)
+	require.NotNil(t, resp.Services["shared.secondMockService"])
+	assert.Equal(t, "no peers", *resp.Services["shared.secondMockService"])
+	assert.Equal(t, true, resp.Services["shared.mockService"] == nil)
+}
+
+func TestHealthzHandler_HungServicesShareDeadline(t *testing.T) {
+	registry := NewServiceRegistry()
+	release := make(chan struct{})
+	defer close(release)
+	names := []string{"hung-a", "hung-b", "hung-c"}
+	for _, name := range names {
+		require.NoError(t, registry.RegisterNamedService(name, &hangingStatusService{release: release}, nil))
+	}
+	require.NoError(t, registry.StartAll())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	rr := httptest.NewRecorder()
+	start := time.Now()
+	registry.HealthzHandler().ServeHTTP(rr, httptest.NewRequest("GET", "/healthz", nil).WithContext(ctx))
+	assert.Equal(t, true, time.Since(start) < healthzStatusTimeout, "Expected the hung services to share one deadline")
+
+	resp := &healthzResponse{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), resp))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	for _, name := range names {
+		require.NotNil(t, resp.Services[name])
+		assert.Equal(t, true, strings.Contains(*resp.Services[name], "status check did not return"), *resp.Services[name])
+	}
+}
@@ -0,0 +1,116 @@
+package shared
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultHeartbeatThreshold = time.Minute
+
+// ErrHeartbeatStale is wrapped by the status of a running service whose
+// heartbeat is older than the heartbeat threshold of the registry.
+var ErrHeartbeatStale = errors.New("service heartbeat is stale")
+
+// Heartbeater is optionally implemented by services whose main loop can get
+// stuck while their Status method keeps reporting them healthy. The status
+// poller reports a running service as unhealthy once its heartbeat is older
+// than the heartbeat threshold, whatever its Status method returns.
+type Heartbeater interface {
+	// Heartbeat returns the last time the main loop of the service made
+	// progress, or the zero time if it did not yet.
+	Heartbeat() time.Time
+}
+
+// HeartbeatRecorder implements Heartbeater for the services embedding it,
+// which call Beat from their main loop. Beat only stores the current time
+// atomically, so it can be called at every iteration of a hot loop.
+type HeartbeatRecorder struct {
+	last int64 // unix nanoseconds of the last beat, accessed atomically.
+}
+
+// Beat records that the main loop of the service made progress.
+func (h *HeartbeatRecorder) Beat() {
+	atomic.StoreInt64(&h.last, time.Now().UnixNano())
+}
+
+// Heartbeat returns the time of the last call to Beat, or the zero time if
+// Beat was never called.
+func (h *HeartbeatRecorder) Heartbeat() time.Time {
+	last := atomic.LoadInt64(&h.last)
+	if last == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, last)
+}
+
+// WithHeartbeatThreshold sets how old the heartbeat of a running service
+// implementing Heartbeater can get before the status poller reports it as
+// unhealthy. It defaults to a minute.
+func WithHeartbeatThreshold(threshold time.Duration) RegistryOption {
+	return func(s *ServiceRegistry) {
+		if threshold > 0 {
+			s.heartbeatThreshold = threshold
+		}
+	}
+}
+
+// heartbeatState holds the error the status poller found with the heartbeat
+// of a service, reported along with its status.
+type heartbeatState struct {
+	lock sync.Mutex
+	err  error
+}
+
+func (h *heartbeatState) get() error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.err
+}
+
+func (h *heartbeatState) set(err error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.err = err
+}
+
+// checkHeartbeat flags a running service implementing Heartbeater as stale
+// if it made no progress within the threshold, counting from when it
+// started, and clears the flag otherwise.
+func (s *ServiceRegistry) checkHeartbeat(entry *serviceEntry, now time.Time, threshold time.Duration) {
+	hb, ok := entry.service.(Heartbeater)
+	if !ok {
+		return
+	}
+	s.lock.RLock()
+	state, startedAt := entry.state, entry.startedAt
+	s.lock.RUnlock()
+	if state != StateRunning {
+		entry.heartbeat.set(nil)
+		return
+	}
+	last := heartbeatOf(hb)
+	if last.Before(startedAt) {
+		last = startedAt
+	}
+	if now.Sub(last) <= threshold {
+		entry.heartbeat.set(nil)
+		return
+	}
+	// The time of the last heartbeat, rather than its age, keeps the error
+	// unchanged while the service is stuck, so that it is logged only once.
+	entry.heartbeat.set(fmt.Errorf("%w: no progress since %v", ErrHeartbeatStale, last.Format(time.RFC3339)))
+}
+
+// heartbeatOf returns the heartbeat of a service, or the zero time if its
+// Heartbeat method panics.
+func heartbeatOf(hb Heartbeater) (last time.Time) {
+	defer func() {
+		if r := recover(); r != nil {
+			last = time.Time{}
+		}
+	}()
+	return hb.Heartbeat()
+}
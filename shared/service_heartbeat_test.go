@@ -0,0 +1,83 @@
+package shared
+
+import (
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+	logTest "github.com/sirupsen/logrus/hooks/test"
+)
+
+type heartbeatService struct {
+	HeartbeatRecorder
+}
+
+func (s *heartbeatService) Start() {}
+
+func (s *heartbeatService) Stop() error {
+	return nil
+}
+
+func (s *heartbeatService) Status() error {
+	return nil
+}
+
+func TestHeartbeatRecorder(t *testing.T) {
+	h := &HeartbeatRecorder{}
+	assert.Equal(t, true, h.Heartbeat().IsZero())
+	before := time.Now()
+	h.Beat()
+	assert.Equal(t, false, h.Heartbeat().Before(before))
+}
+
+func TestStatusPoller_StaleHeartbeat(t *testing.T) {
+	hook := logTest.NewGlobal()
+	registry := NewServiceRegistry(WithHeartbeatThreshold(time.Minute))
+	svc := &heartbeatService{}
+	kind := reflect.TypeOf(svc)
+	require.NoError(t, registry.RegisterService(svc))
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, kind, StateRunning)
+	p := &statusPoller{registry: registry, interval: time.Second, last: make(map[string]string)}
+
+	svc.Beat()
+	p.poll()
+	require.NoError(t, registry.Statuses()[kind])
+
+	// Pretend the main loop has been stuck for longer than the threshold.
+	registry.lock.Lock()
+	registry.services[kind].startedAt = time.Now().Add(-2 * time.Hour)
+	registry.lock.Unlock()
+	atomic.StoreInt64(&svc.last, time.Now().Add(-time.Hour).UnixNano())
+	p.poll()
+	err := registry.Statuses()[kind]
+	assert.Equal(t, true, errors.Is(err, ErrHeartbeatStale), "Expected a stale heartbeat, got %v", err)
+	p.poll()
+	assert.Equal(t, 1, countLogs(hook, "Service is unhealthy"), "Expected a stuck service to be logged once")
+
+	svc.Beat()
+	p.poll()
+	require.NoError(t, registry.Statuses()[kind])
+	assert.Equal(t, 1, countLogs(hook, "Service is healthy again"))
+}
+
+func TestStatusPoller_HeartbeatCountsFromStart(t *testing.T) {
+	registry := NewServiceRegistry(WithHeartbeatThreshold(time.Minute))
+	svc := &heartbeatService{}
+	kind := reflect.TypeOf(svc)
+	require.NoError(t, registry.RegisterService(svc))
+	p := &statusPoller{registry: registry, interval: time.Second, last: make(map[string]string)}
+
+	// A service which is not running, or just started, is not stale yet even
+	// though it never beat.
+	p.poll()
+	require.NoError(t, registry.Statuses()[kind])
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, kind, StateRunning)
+	p.poll()
+	require.NoError(t, registry.Statuses()[kind])
+}
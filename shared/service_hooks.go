@@ -0,0 +1,110 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"time"
+)
+
+// OnServiceStarted registers a callback invoked every time a service of the
+// given type has been started, that is once its Start method returned.
+// Callbacks of a type are invoked in the order they were registered.
+func (s *ServiceRegistry) OnServiceStarted(kind reflect.Type, fn func()) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.startedHooks[kind] = append(s.startedHooks[kind], fn)
+}
+
+// OnServiceStopped registers a callback invoked every time a service of the
+// given type has been stopped. Callbacks of a type are invoked in the order
+// they were registered.
+func (s *ServiceRegistry) OnServiceStopped(kind reflect.Type, fn func()) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.stoppedHooks[kind] = append(s.stoppedHooks[kind], fn)
+}
+
+// runHooks invokes the given callbacks of a service, outside of the registry
+// lock, recovering from any panic so that a faulty callback cannot prevent
+// the other services from being started or stopped.
+func (s *ServiceRegistry) runHooks(entry *serviceEntry, hooks map[reflect.Type][]func()) {
+	s.lock.RLock()
+	fns := make([]func(), len(hooks[entry.kind]))
+	copy(fns, hooks[entry.kind])
+	s.lock.RUnlock()
+	for _, fn := range fns {
+		s.runHook(entry, fn)
+	}
+}
+
+func (s *ServiceRegistry) runHook(entry *serviceEntry, fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.log.WithField("service", entry.String()).Errorf("Lifecycle callback panicked: %v\n%s", r, debug.Stack())
+		}
+	}()
+	fn()
+}
+
+// shutdownHook is a cleanup function registered with RegisterShutdownHook.
+type shutdownHook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// RegisterShutdownHook registers a cleanup function which is not a service,
+// such as removing a temporary directory or flushing metrics. StopAll calls
+// the hooks once every service has stopped, whether or not they stopped
+// successfully, in reverse order of registration. Each hook is given a
+// context bounded by the stop timeout of the registry, and its error or panic
+// is reported in the error returned by StopAll. Hooks are only called once,
+// by the first StopAll.
+func (s *ServiceRegistry) RegisterShutdownHook(name string, fn func(ctx context.Context) error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.shutdownHooks = append(s.shutdownHooks, shutdownHook{name: name, fn: fn})
+}
+
+// runShutdownHooks calls the registered shutdown hooks in reverse order of
+// registration, adding their errors to the given ones.
+func (s *ServiceRegistry) runShutdownHooks(ctx context.Context, errs *MultiError) {
+	s.lock.Lock()
+	hooks := s.shutdownHooks
+	s.shutdownHooks = nil
+	timeout := s.stopTimeout
+	s.lock.Unlock()
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hook := hooks[i]
+		if err := s.runShutdownHook(ctx, hook, timeout); err != nil {
+			s.log.WithError(err).Errorf("Shutdown hook %s failed", hook.name)
+			errs.add(fmt.Errorf("shutdown hook %s: %w", hook.name, err))
+			continue
+		}
+		s.log.Debugf("Ran shutdown hook %s", hook.name)
+	}
+}
+
+// runShutdownHook calls a shutdown hook, recovering a panic and giving up
+// once the timeout elapsed or the context is done.
+func (s *ServiceRegistry) runShutdownHook(parent context.Context, hook shutdownHook, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				s.log.Errorf("Shutdown hook %s panicked: %v\n%s", hook.name, r, debug.Stack())
+				done <- fmt.Errorf("hook panicked: %v", r)
+			}
+		}()
+		done <- hook.fn(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("hook did not return: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,111 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+func TestLifecycleHooks(t *testing.T) {
+	registry := NewServiceRegistry()
+
+	m := &mockService{}
+	kind := reflect.TypeOf(m)
+	require.NoError(t, registry.RegisterService(m))
+	require.NoError(t, registry.RegisterService(&secondMockService{}))
+
+	var lock sync.Mutex
+	var calls []string
+	record := func(call string) func() {
+		return func() {
+			lock.Lock()
+			defer lock.Unlock()
+			calls = append(calls, call)
+		}
+	}
+	started := make(chan struct{})
+	registry.OnServiceStarted(kind, record("started-1"))
+	registry.OnServiceStarted(kind, func() {
+		panic("bad callback")
+	})
+	registry.OnServiceStarted(kind, func() {
+		record("started-2")()
+		close(started)
+	})
+	registry.OnServiceStopped(kind, record("stopped"))
+
+	require.NoError(t, registry.StartAll())
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Started callbacks were not invoked")
+	}
+	require.NoError(t, registry.StopAll())
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.DeepEqual(t, []string{"started-1", "started-2", "stopped"}, calls)
+}
+
+func TestRegisterShutdownHook_ReverseOrderAfterServices(t *testing.T) {
+	registry := NewServiceRegistry(WithoutPreStop())
+	require.NoError(t, registry.RegisterService(&failingStopService{err: errors.New("could not close db")}))
+	var calls []string
+	registry.RegisterShutdownHook("remove-tmpdir", func(ctx context.Context) error {
+		calls = append(calls, "remove-tmpdir")
+		return nil
+	})
+	registry.RegisterShutdownHook("flush-metrics", func(ctx context.Context) error {
+		state, err := registry.State(reflect.TypeOf(&failingStopService{}))
+		require.NoError(t, err)
+		assert.Equal(t, StateStopped, state)
+		calls = append(calls, "flush-metrics")
+		return errors.New("push gateway unreachable")
+	})
+	require.NoError(t, registry.StartAll())
+
+	err := registry.StopAll()
+	assert.ErrorContains(t, "could not close db", err)
+	assert.ErrorContains(t, "shutdown hook flush-metrics: push gateway unreachable", err)
+	assert.DeepEqual(t, []string{"flush-metrics", "remove-tmpdir"}, calls)
+
+	// Hooks are only called by the first StopAll.
+	assert.ErrorContains(t, "could not close db", registry.StopAll())
+	assert.Equal(t, 2, len(calls))
+}
+
+func TestRegisterShutdownHook_Panics(t *testing.T) {
+	registry := NewServiceRegistry(WithoutPreStop())
+	called := false
+	registry.RegisterShutdownHook("release-lock", func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	registry.RegisterShutdownHook("faulty", func(ctx context.Context) error {
+		panic("boom")
+	})
+	require.NoError(t, registry.StartAll())
+
+	err := registry.StopAll()
+	assert.ErrorContains(t, "shutdown hook faulty: hook panicked: boom", err)
+	assert.Equal(t, true, called, "hook registered before a panicking hook was not called")
+}
+
+func TestRegisterShutdownHook_Timeout(t *testing.T) {
+	registry := NewServiceRegistry(WithoutPreStop(), WithStopTimeout(20*time.Millisecond))
+	registry.RegisterShutdownHook("stuck", func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(time.Second)
+		return nil
+	})
+	require.NoError(t, registry.StartAll())
+
+	err := registry.StopAll()
+	assert.ErrorContains(t, "shutdown hook stuck: hook did not return: context deadline exceeded", err)
+}
@@ -0,0 +1,81 @@
+package shared
+
+import (
+	"reflect"
+	"time"
+)
+
+// ServiceInfo describes a registered service, as reported by ListServices.
+type ServiceInfo struct {
+	// Name of the service, as used in statuses and logs.
+	Name string
+	// Type the service was registered with.
+	Type reflect.Type
+	// State of the service in its lifecycle.
+	State ServiceState
+	// Healthy is set when the service reports a nil status.
+	Healthy bool
+	// Status is the error reported by the service, as by StatusesByName, or
+	// nil if it is healthy.
+	Status error
+	// StartedAt is when the service last finished starting, or the zero time
+	// if it never did.
+	StartedAt time.Time
+	// Uptime is how long the service has been running since StartedAt, or
+	// zero if it is not running.
+	Uptime time.Duration
+	// Metadata is the description of services implementing Describer.
+	Metadata *ServiceMetadata
+	// Counters counts the restarts and failures of the service.
+	Counters ServiceCounters
+	// Disabled tells why a service registered by RegisterServiceIf is
+	// disabled, such as "disabled by flag X", and is empty otherwise.
+	Disabled string
+}
+
+// ListServices returns a description of every registered service, in order
+// of registration, followed by the disabled services, which are healthy and
+// in the disabled state. The registry lock is only taken once, to copy the state of
+// every service, and the status checks run outside of it, each bounded by the
+// same timeout as the health endpoints.
+func (s *ServiceRegistry) ListServices() []ServiceInfo {
+	type stateCopy struct {
+		entry     *serviceEntry
+		startErr  error
+		state     ServiceState
+		startedAt time.Time
+	}
+	s.lock.RLock()
+	copies := make([]stateCopy, len(s.entries))
+	for i, entry := range s.entries {
+		copies[i] = stateCopy{entry: entry, startErr: entry.startErr, state: entry.state, startedAt: entry.startedAt}
+	}
+	s.lock.RUnlock()
+
+	infos := make([]ServiceInfo, len(copies))
+	for i, c := range copies {
+		c := c
+		err := checkStatus(func() error { return entryStatus(c.entry, c.startErr, c.state) }, healthzStatusTimeout)
+		infos[i] = ServiceInfo{
+			Name:      c.entry.String(),
+			Type:      c.entry.kind,
+			State:     c.state,
+			Healthy:   err == nil,
+			Status:    err,
+			StartedAt: c.startedAt,
+			Uptime:    uptime(c.startedAt, c.state),
+			Metadata:  c.entry.metadata,
+			Counters:  c.entry.counters.get(),
+		}
+	}
+	for _, d := range s.disabledSnapshot() {
+		infos = append(infos, ServiceInfo{
+			Name:     d.name,
+			Type:     d.kind,
+			State:    StateDisabled,
+			Healthy:  true,
+			Disabled: d.reason(),
+		})
+	}
+	return infos
+}
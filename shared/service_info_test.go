@@ -0,0 +1,64 @@
+package shared
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+func TestListServices(t *testing.T) {
+	registry := NewServiceRegistry()
+	noPeers := errors.New("no peers")
+	require.NoError(t, registry.RegisterService(&secondMockService{status: noPeers}))
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.RegisterNamedService("beacon", &thirdMockService{}, nil))
+
+	assert.DeepEqual(t, []ServiceInfo{
+		{Name: "shared.secondMockService", Type: reflect.TypeOf(&secondMockService{}), State: StateRegistered, Healthy: false, Status: noPeers},
+		{Name: "shared.mockService", Type: reflect.TypeOf(&mockService{}), State: StateRegistered, Healthy: true},
+		{Name: "beacon", Type: reflect.TypeOf(&thirdMockService{}), State: StateRegistered, Healthy: true},
+	}, registry.ListServices())
+
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, reflect.TypeOf(&mockService{}), StateRunning)
+	require.NoError(t, registry.StopAll())
+	for _, info := range registry.ListServices() {
+		assert.Equal(t, StateStopped, info.State)
+		assert.Equal(t, false, info.Healthy, "Expected stopped service %s to be unhealthy", info.Name)
+	}
+}
+
+func TestListServices_Uptime(t *testing.T) {
+	registry := NewServiceRegistry()
+	kind := reflect.TypeOf(&mockService{})
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	info := registry.ListServices()[0]
+	assert.Equal(t, true, info.StartedAt.IsZero())
+	assert.Equal(t, time.Duration(0), info.Uptime)
+
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, kind, StateRunning)
+	time.Sleep(10 * time.Millisecond)
+	info = registry.ListServices()[0]
+	assert.Equal(t, false, info.StartedAt.IsZero())
+	assert.Equal(t, true, info.Uptime >= 10*time.Millisecond, "Unexpected uptime %v", info.Uptime)
+	status := registry.DetailedStatuses()["shared.mockService"]
+	assert.Equal(t, info.StartedAt, status.StartedAt)
+	assert.Equal(t, true, status.Uptime > 0)
+
+	// A restart resets the uptime.
+	require.NoError(t, registry.RestartService(kind))
+	waitForState(t, registry, kind, StateRunning)
+	restarted := registry.ListServices()[0]
+	assert.Equal(t, true, restarted.StartedAt.After(info.StartedAt))
+	assert.Equal(t, true, restarted.Uptime < info.Uptime, "Expected uptime to be reset by the restart")
+
+	require.NoError(t, registry.StopAll())
+	stopped := registry.ListServices()[0]
+	assert.Equal(t, restarted.StartedAt, stopped.StartedAt)
+	assert.Equal(t, time.Duration(0), stopped.Uptime)
+}
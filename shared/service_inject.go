@@ -0,0 +1,53 @@
+package shared
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// injectTag is the struct tag marking the fields set by Inject.
+const injectTag = "registry"
+
+// Inject sets every field of the struct pointed to by target which is tagged
+// with `registry:"inject"` to the registered service of the same type, as
+// FetchService would. Interface fields receive the single service
+// implementing them. Fields of embedded structs are injected as well, and
+// untagged fields are left untouched. An error naming each field which could
+// not be set is returned if services are missing.
+func (s *ServiceRegistry) Inject(target interface{}) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("inject target must be a non-nil pointer to a struct, received %T", target)
+	}
+	errs := &MultiError{}
+	s.injectFields(v.Elem(), v.Elem().Type().String(), errs)
+	return errs.errorOrNil()
+}
+
+// injectFields injects the tagged fields of a struct value, naming them after
+// the given path in errors.
+func (s *ServiceRegistry) injectFields(v reflect.Value, path string, errs *MultiError) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := path + "." + field.Name
+		tag, tagged := field.Tag.Lookup(injectTag)
+		if !tagged {
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				s.injectFields(v.Field(i), name, errs)
+			}
+			continue
+		}
+		if tag != "inject" {
+			errs.add(fmt.Errorf("field %s has an unknown registry tag: %q", name, tag))
+			continue
+		}
+		if !v.Field(i).CanSet() {
+			errs.add(fmt.Errorf("field %s cannot be injected as it is not exported", name))
+			continue
+		}
+		if err := s.FetchService(v.Field(i).Addr().Interface()); err != nil {
+			errs.add(fmt.Errorf("could not inject field %s: %w", name, err))
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package shared
+
+import (
+	"testing"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+type statusReporter interface {
+	Status() error
+}
+
+type pauser interface {
+	Pausable
+}
+
+// InjectedService is exported so it can be embedded as an exported field.
+type InjectedService struct {
+	mockService
+}
+
+type injectedDeps struct {
+	Third *thirdMockService `registry:"inject"`
+}
+
+type injectTarget struct {
+	injectedDeps
+	*InjectedService `registry:"inject"`
+	Mock             *mockService `registry:"inject"`
+	Pauser           pauser       `registry:"inject"`
+	Untagged         *mockService
+	Count            int
+}
+
+func TestInject(t *testing.T) {
+	registry := NewServiceRegistry()
+	mock, embedded, third := &mockService{}, &InjectedService{}, &thirdMockService{}
+	pausable := &pausableService{}
+	require.NoError(t, registry.RegisterService(mock))
+	require.NoError(t, registry.RegisterService(embedded))
+	require.NoError(t, registry.RegisterService(third))
+	require.NoError(t, registry.RegisterService(pausable))
+
+	untagged := &mockService{}
+	target := &injectTarget{Untagged: untagged, Count: 3}
+	require.NoError(t, registry.Inject(target))
+	assert.Equal(t, mock, target.Mock)
+	assert.Equal(t, embedded, target.InjectedService)
+	assert.Equal(t, third, target.Third)
+	assert.Equal(t, pauser(pausable), target.Pauser)
+	assert.Equal(t, untagged, target.Untagged)
+	assert.Equal(t, 3, target.Count)
+}
+
+func TestInject_MissingServices(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&mockService{}))
+
+	target := &injectTarget{}
+	err := registry.Inject(target)
+	assert.ErrorContains(t, "could not inject field shared.injectTarget.injectedDeps.Third: unknown service: shared.thirdMockService", err)
+	assert.ErrorContains(t, "could not inject field shared.injectTarget.InjectedService: unknown service: shared.InjectedService", err)
+	assert.ErrorContains(t, "could not inject field shared.injectTarget.Pauser: no service implements shared.pauser", err)
+	// The services which are registered are still injected.
+	assert.NotNil(t, target.Mock)
+}
+
+func TestInject_AmbiguousInterface(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.RegisterService(&secondMockService{}))
+
+	target := &struct {
+		Reporter statusReporter `registry:"inject"`
+	}{}
+	assert.ErrorContains(t, "multiple services implement shared.statusReporter", registry.Inject(target))
+}
+
+func TestInject_InvalidFields(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&mockService{}))
+
+	unexported := &struct {
+		mock *mockService `registry:"inject"`
+	}{}
+	assert.ErrorContains(t, "field struct { mock *shared.mockService \"registry:\\\"inject\\\"\" }.mock cannot be injected as it is not exported", registry.Inject(unexported))
+	assert.Equal(t, true, unexported.mock == nil)
+
+	embeddedUnexported := &struct {
+		*secondMockService `registry:"inject"`
+	}{}
+	assert.ErrorContains(t, "secondMockService cannot be injected as it is not exported", registry.Inject(embeddedUnexported))
+
+	unknownTag := &struct {
+		Mock *mockService `registry:"optional"`
+	}{}
+	assert.ErrorContains(t, "has an unknown registry tag: \"optional\"", registry.Inject(unknownTag))
+}
+
+func TestInject_InvalidTarget(t *testing.T) {
+	registry := NewServiceRegistry()
+	assert.ErrorContains(t, "inject target must be a non-nil pointer to a struct, received shared.injectTarget", registry.Inject(injectTarget{}))
+	assert.ErrorContains(t, "received *shared.injectTarget", registry.Inject((*injectTarget)(nil)))
+	assert.ErrorContains(t, "received *int", registry.Inject(new(int)))
+}
@@ -0,0 +1,130 @@
+package shared
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrServiceNotConstructed is reported by lazy services which were not
+// constructed yet, and wrapped by FetchService when the requested service may
+// be one of them.
+var ErrServiceNotConstructed = errors.New("service not constructed yet")
+
+// ServiceConstructor builds a service registered with RegisterLazy, given the
+// service context it is registered with.
+type ServiceConstructor func(ctx *ServiceContext) (Service, error)
+
+// lazyService stands in for a lazy service until it is constructed. It then
+// remains in the registry only if the construction failed.
+type lazyService struct {
+	constructor ServiceConstructor
+}
+
+func (l *lazyService) Start() {
+}
+
+func (l *lazyService) Stop() error {
+	return nil
+}
+
+func (l *lazyService) Status() error {
+	return ErrServiceNotConstructed
+}
+
+// isLazy returns whether the entry stands in for a lazy service which is not
+// constructed.
+func (e *serviceEntry) isLazy() bool {
+	_, ok := e.service.(*lazyService)
+	return ok
+}
+
+// RegisterLazy registers a service which is only constructed when it is
+// started, by StartAll or by StartGroup for one of the given groups. The
+// constructed service is then registered under its type, as if it had been
+// registered with RegisterService. A construction error is logged and
+// reported in Statuses like a start failure. The logger of the service
+// context is only set once the service is constructed.
+//
+// FetchService never triggers the construction of a lazy service: fetching a
+// service which may not be constructed yet fails with an error wrapping
+// ErrServiceNotConstructed. Services cannot declare a dependency on a lazy
+// service.
+func (s *ServiceRegistry) RegisterLazy(constructor ServiceConstructor, groups ...string) error {
+	if constructor == nil {
+		return errors.New("lazy service constructor cannot be nil")
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return fmt.Errorf("could not register lazy service: %w", ErrRegistryClosed)
+	}
+	if s.phase.startAllCalled() {
+		return fmt.Errorf("could not register lazy service: %w", ErrAlreadyStarted)
+	}
+	s.lazyCount++
+	entry := s.newServiceEntry(&lazyService{constructor: constructor}, nil, &ServiceConfig{Groups: groups})
+	entry.name = fmt.Sprintf("lazy service %d", s.lazyCount)
+	s.entries = append(s.entries, entry)
+	s.emit(ServiceRegistered, entry, nil)
+	return nil
+}
+
+// constructLazy constructs every lazy service accepted by the filter, and
+// replaces its stand-in with the constructed service.
+func (s *ServiceRegistry) constructLazy(accept func(entry *serviceEntry) bool) {
+	for _, entry := range s.snapshot() {
+		if !entry.isLazy() || !accept(entry) || s.stateOf(entry) != StateRegistered {
+			continue
+		}
+		if err := s.construct(entry); err != nil {
+			s.log.WithError(err).Errorf("Could not construct the following service: %v", entry)
+			s.failed(entry, err)
+			s.lock.Lock()
+			entry.startErr = err
+			entry.state = StateStopped
+			s.lock.Unlock()
+		}
+	}
+}
+
+func (s *ServiceRegistry) construct(lazy *serviceEntry) error {
+	service, err := lazy.service.(*lazyService).constructor(lazy.ctx)
+	if err != nil {
+		return fmt.Errorf("could not construct service: %w", err)
+	}
+	if service == nil {
+		return errors.New("could not construct service: constructor returned a nil service")
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	kind := reflect.TypeOf(service)
+	if _, exists := s.services[kind]; exists {
+		return fmt.Errorf("%w: %s", ErrServiceAlreadyRegistered, typeName(kind))
+	}
+	entry := s.newServiceEntry(service, lazy.ctx, lazy.cfg)
+	if err := s.checkNameAvailable(entry); err != nil {
+		return err
+	}
+	entry.ctx.setLogger(entry.String())
+	entry.ctx.setFatalReporter(entry.String(), s.reportFatal)
+	for i, e := range s.entries {
+		if e == lazy {
+			s.entries[i] = entry
+			break
+		}
+	}
+	s.services[kind] = entry
+	return nil
+}
+
+// hasPendingLazy returns whether some lazy services may still be
+// constructed. The caller must hold the lock.
+func (s *ServiceRegistry) hasPendingLazy() bool {
+	for _, entry := range s.entries {
+		if entry.isLazy() && entry.state == StateRegistered {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package shared
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+func TestRegisterLazy_ConstructedByStartAll(t *testing.T) {
+	registry := NewServiceRegistry()
+	var constructed int
+	var received *ServiceContext
+	require.NoError(t, registry.RegisterLazy(func(ctx *ServiceContext) (Service, error) {
+		constructed++
+		received = ctx
+		return &mockService{}, nil
+	}))
+	require.NoError(t, registry.RegisterService(&secondMockService{}))
+
+	var m *mockService
+	err := registry.FetchService(&m)
+	assert.Equal(t, true, errors.Is(err, ErrServiceNotConstructed), "Expected a not constructed error, received %v", err)
+	assert.Equal(t, 0, constructed)
+
+	require.NoError(t, registry.StartAll())
+	assert.Equal(t, 1, constructed)
+	waitForState(t, registry, reflect.TypeOf(&mockService{}), StateRunning)
+	require.NoError(t, registry.FetchService(&m))
+	assert.NotNil(t, m)
+	assert.DeepEqual(t, []reflect.Type{reflect.TypeOf(&mockService{}), reflect.TypeOf(&secondMockService{})}, entryKinds(registry.snapshot()))
+
+	require.NoError(t, registry.StopAll())
+	assert.NotNil(t, received.Err(), "Expected the context given to the constructor to be cancelled")
+}
+
+func TestRegisterLazy_ConstructionError(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterLazy(func(_ *ServiceContext) (Service, error) {
+		return nil, errors.New("could not mmap file")
+	}))
+	require.NoError(t, registry.StartAll())
+
+	statuses := registry.StatusesByName()
+	assert.ErrorContains(t, "could not construct service: could not mmap file", statuses["lazy service 1"])
+	var m *mockService
+	assert.ErrorContains(t, "unknown service: shared.mockService", registry.FetchService(&m))
+	assert.Equal(t, false, errors.Is(registry.FetchService(&m), ErrServiceNotConstructed))
+	require.NoError(t, registry.StopAll())
+}
+
+func TestRegisterLazy_ConstructedByStartGroup(t *testing.T) {
+	registry := NewServiceRegistry()
+	var constructed []string
+	require.NoError(t, registry.RegisterLazy(func(_ *ServiceContext) (Service, error) {
+		constructed = append(constructed, "api")
+		return &mockService{}, nil
+	}, "api"))
+	require.NoError(t, registry.RegisterLazy(func(_ *ServiceContext) (Service, error) {
+		constructed = append(constructed, "sync")
+		return &secondMockService{}, nil
+	}, "sync"))
+
+	require.NoError(t, registry.StartGroup("api"))
+	waitForState(t, registry, reflect.TypeOf(&mockService{}), StateRunning)
+	assert.DeepEqual(t, []string{"api"}, constructed)
+	require.NoError(t, registry.StopAll())
+}
+
+func TestRegisterLazy_DuplicateType(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.RegisterLazy(func(_ *ServiceContext) (Service, error) {
+		return &mockService{}, nil
+	}))
+	require.NoError(t, registry.StartAll())
+
+	assert.ErrorContains(t, "service already exists: shared.mockService", registry.StatusesByName()["lazy service 1"])
+	require.NoError(t, registry.StopAll())
+}
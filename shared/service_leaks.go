@@ -0,0 +1,240 @@
+package shared
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// leakWaitTimeout is how long VerifyNoLeaks waits for the goroutines of
+	// stopped services to exit before reporting them.
+	leakWaitTimeout = 2 * time.Second
+	// leakPollInterval is how often VerifyNoLeaks counts the goroutines
+	// while waiting for them to exit.
+	leakPollInterval = 10 * time.Millisecond
+)
+
+// ErrLeakCheckDisabled is returned by VerifyNoLeaks if the registry was not
+// created with WithLeakCheck, or if StartAll was not called.
+var ErrLeakCheckDisabled = errors.New("goroutine leak check is not enabled")
+
+// serviceLabelPattern extracts the service label from the labels of a
+// goroutine in a goroutine profile.
+var serviceLabelPattern = regexp.MustCompile(`"service":"((?:[^"\\]|\\.)*)"`)
+
+// WithLeakCheck makes StartAll record the goroutines running before any
+// service is started, so that VerifyNoLeaks can report the goroutines the
+// services left running once stopped.
+func WithLeakCheck() RegistryOption {
+	return func(s *ServiceRegistry) {
+		s.leakCheck = true
+	}
+}
+
+// GoroutineLeak describes goroutines sharing the same stack which were left
+// running by the services.
+type GoroutineLeak struct {
+	// Service is the name of the service the goroutines are attributed to
+	// by their pprof label, empty if they are not labeled.
+	Service string
+	// Count is the number of leaked goroutines with this stack.
+	Count int
+	// Stack is the stack shared by the goroutines, one frame per line.
+	Stack string
+}
+
+// LeakError is returned by VerifyNoLeaks, listing the leaked goroutines.
+type LeakError struct {
+	Leaks []GoroutineLeak
+}
+
+// Error implements the error interface, summarizing the leaks by service.
+func (e *LeakError) Error() string {
+	total := 0
+	counts := make(map[string]int)
+	for _, leak := range e.Leaks {
+		total += leak.Count
+		counts[leak.Service] += leak.Count
+	}
+	services := make([]string, 0, len(counts))
+	for service, count := range counts {
+		if service == "" {
+			service = "unattributed"
+		}
+		services = append(services, fmt.Sprintf("%s: %d", service, count))
+	}
+	sort.Strings(services)
+	return fmt.Sprintf("%d goroutines leaked (%s)", total, strings.Join(services, ", "))
+}
+
+// VerifyNoLeaks is meant to be called once StopAll returned. It waits up to
+// two seconds for the goroutines started since StartAll to exit, then returns
+// a *LeakError describing those still running, attributed to a service when
+// they carry its pprof label, as the goroutines spawned by Start do. Every
+// leak is logged with its stack. ErrLeakCheckDisabled is returned if the
+// registry was not created with WithLeakCheck.
+func (s *ServiceRegistry) VerifyNoLeaks() error {
+	s.lock.RLock()
+	baseline := s.leakBaseline
+	s.lock.RUnlock()
+	if baseline == nil {
+		return ErrLeakCheckDisabled
+	}
+	deadline := time.Now().Add(leakWaitTimeout)
+	var leaks []GoroutineLeak
+	for {
+		leaks = goroutineLeaks(baseline)
+		if len(leaks) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(leakPollInterval)
+	}
+	for _, leak := range leaks {
+		s.log.WithField("service", leak.Service).Warnf("%d goroutines leaked:\n%s", leak.Count, leak.Stack)
+	}
+	return &LeakError{Leaks: leaks}
+}
+
+// recordLeakBaseline records the running goroutines, if the leak check is
+// enabled.
+func (s *ServiceRegistry) recordLeakBaseline() {
+	if !s.leakCheck {
+		return
+	}
+	baseline := make(map[string]bool)
+	for _, g := range parseStacks(allStacks()) {
+		baseline[g.id] = true
+	}
+	s.lock.Lock()
+	s.leakBaseline = baseline
+	s.lock.Unlock()
+}
+
+// goroutineStack is a goroutine parsed from a dump of runtime.Stack.
+type goroutineStack struct {
+	id string
+	// stack holds a line per frame, with the function and its location.
+	stack string
+	// locations holds the file and line of every frame, which identify the
+	// stack in a goroutine profile as well.
+	locations string
+}
+
+// goroutineHeader matches the first line of a goroutine in a dump of
+// runtime.Stack, capturing the goroutine ID.
+var goroutineHeader = regexp.MustCompile(`^goroutine (\d+) \[`)
+
+// parseStacks parses a dump of every goroutine by runtime.Stack.
+func parseStacks(dump []byte) []goroutineStack {
+	var stacks []goroutineStack
+	for _, block := range strings.Split(string(dump), "\n\n") {
+		lines := strings.Split(strings.TrimSpace(block), "\n")
+		m := goroutineHeader.FindStringSubmatch(lines[0])
+		if m == nil {
+			continue
+		}
+		g := goroutineStack{id: m[1]}
+		var frames, locations []string
+		// Frames are a function call followed by its indented location.
+		for i := 1; i+1 < len(lines); i += 2 {
+			fn := lines[i]
+			created := strings.HasPrefix(fn, "created by ")
+			if p := strings.LastIndex(fn, "("); p > 0 && !created {
+				fn = fn[:p]
+			}
+			location := strings.TrimSpace(lines[i+1])
+			if p := strings.LastIndex(location, " +0x"); p > 0 {
+				location = location[:p]
+			}
+			frames = append(frames, fn+"\n\t"+location)
+			// Goroutine profiles do not tell where a goroutine was created.
+			if !created {
+				locations = append(locations, location)
+			}
+		}
+		g.stack = strings.Join(frames, "\n")
+		g.locations = strings.Join(locations, "\n")
+		stacks = append(stacks, g)
+	}
+	return stacks
+}
+
+// serviceLabels returns the service label of the labeled goroutines in a
+// goroutine profile, keyed by the locations of their stack frames.
+func serviceLabels() map[string]string {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		return nil
+	}
+	labels := make(map[string]string)
+	for _, block := range strings.Split(buf.String(), "\n\n") {
+		var service string
+		var locations []string
+		scanner := bufio.NewScanner(strings.NewReader(block))
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "# labels: "):
+				if m := serviceLabelPattern.FindStringSubmatch(line); m != nil {
+					service = m[1]
+				}
+			case strings.HasPrefix(line, "#\t"):
+				// A frame is the program counter, the function and the
+				// location, separated by tabs.
+				fields := strings.Split(strings.TrimPrefix(line, "#\t"), "\t")
+				locations = append(locations, fields[len(fields)-1])
+			}
+		}
+		if service != "" {
+			labels[strings.Join(locations, "\n")] = service
+		}
+	}
+	return labels
+}
+
+// goroutineLeaks returns the goroutines running now which are not in the
+// baseline, except the calling goroutine, grouped by service and stack.
+func goroutineLeaks(baseline map[string]bool) []GoroutineLeak {
+	self := parseStacks(currentStack())
+	stacks := parseStacks(allStacks())
+	labels := serviceLabels()
+	byKey := make(map[string]*GoroutineLeak)
+	var keys []string
+	for _, g := range stacks {
+		if baseline[g.id] || len(self) == 1 && g.id == self[0].id {
+			continue
+		}
+		service := labels[g.locations]
+		key := service + "\x00" + g.stack
+		leak, ok := byKey[key]
+		if !ok {
+			leak = &GoroutineLeak{Service: service, Stack: g.stack}
+			byKey[key] = leak
+			keys = append(keys, key)
+		}
+		leak.Count++
+	}
+	sort.Strings(keys)
+	leaks := make([]GoroutineLeak, len(keys))
+	for i, key := range keys {
+		leaks[i] = *byKey[key]
+	}
+	return leaks
+}
+
+// currentStack returns the stack of the calling goroutine.
+func currentStack() []byte {
+	buf := make([]byte, 64<<10)
+	return buf[:runtime.Stack(buf, false)]
+}
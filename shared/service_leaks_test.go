@@ -0,0 +1,66 @@
+package shared
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+// leakingService spawns a goroutine in Start which Stop does not terminate.
+type leakingService struct {
+	release chan struct{}
+}
+
+func (s *leakingService) Start() {
+	go func() {
+		<-s.release
+	}()
+}
+
+func (s *leakingService) Stop() error {
+	return nil
+}
+
+func (s *leakingService) Status() error {
+	return nil
+}
+
+func TestVerifyNoLeaks(t *testing.T) {
+	registry := NewServiceRegistry(WithLeakCheck())
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, reflect.TypeOf(&mockService{}), StateRunning)
+	require.NoError(t, registry.StopAll())
+	require.NoError(t, registry.VerifyNoLeaks())
+}
+
+func TestVerifyNoLeaks_ReportsLeakedGoroutines(t *testing.T) {
+	registry := NewServiceRegistry(WithLeakCheck())
+	svc := &leakingService{release: make(chan struct{})}
+	defer close(svc.release)
+	require.NoError(t, registry.RegisterService(svc))
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, reflect.TypeOf(svc), StateRunning)
+	require.NoError(t, registry.StopAll())
+
+	err := registry.VerifyNoLeaks()
+	var leakErr *LeakError
+	require.Equal(t, true, errors.As(err, &leakErr), err)
+	require.Equal(t, 1, len(leakErr.Leaks), err)
+	leak := leakErr.Leaks[0]
+	assert.Equal(t, "shared.leakingService", leak.Service)
+	assert.Equal(t, 1, leak.Count)
+	assert.ErrorContains(t, "1 goroutines leaked (shared.leakingService: 1)", err)
+	assert.Equal(t, true, len(leak.Stack) > 0)
+}
+
+func TestVerifyNoLeaks_Disabled(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.StartAll())
+	require.NoError(t, registry.StopAll())
+	assert.Equal(t, ErrLeakCheckDisabled, registry.VerifyNoLeaks())
+}
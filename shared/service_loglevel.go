@@ -0,0 +1,95 @@
+package shared
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrLogLevelUnsupported is returned when changing the log level of a service
+// whose service context logger was not set by the registry.
+var ErrLogLevelUnsupported = errors.New("service does not use a logger set by the registry")
+
+// SetServiceLogLevel changes, at runtime, the level of the logger the registry
+// set in the service context of the named service, leaving the level of the
+// other services untouched. Services sharing a service context share its
+// logger. ErrLogLevelUnsupported is returned if the service context logger
+// was set by the caller.
+func (s *ServiceRegistry) SetServiceLogLevel(name string, level logrus.Level) error {
+	entry, err := s.entryByName(name)
+	if err != nil {
+		return err
+	}
+	s.lock.RLock()
+	logger := entry.ctx.logger
+	s.lock.RUnlock()
+	if logger == nil {
+		return fmt.Errorf("could not set log level of service %s: %w", name, ErrLogLevelUnsupported)
+	}
+	logger.SetLevel(level)
+	s.log.Infof("Set log level of service %s to %v", name, level)
+	return nil
+}
+
+// ServiceLogLevels returns the log level of every service, keyed by name,
+// except for the services whose service context logger was set by the caller.
+func (s *ServiceRegistry) ServiceLogLevels() map[string]logrus.Level {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	levels := make(map[string]logrus.Level, len(s.entries))
+	for _, entry := range s.entries {
+		if entry.ctx.logger != nil {
+			levels[entry.String()] = entry.ctx.logger.GetLevel()
+		}
+	}
+	return levels
+}
+
+// LogLevelHandler returns an HTTP handler for debug endpoints, replying to GET
+// requests with the log level of every service as a JSON object, and to POST
+// requests with the "service" and "level" form values by setting the log
+// level of that service.
+func (s *ServiceRegistry) LogLevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			levels := make(map[string]string)
+			for name, level := range s.ServiceLogLevels() {
+				levels[name] = level.String()
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(levels); err != nil {
+				s.log.WithError(err).Error("Could not write log levels response")
+			}
+		case http.MethodPost:
+			level, err := logrus.ParseLevel(r.FormValue("level"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := s.SetServiceLogLevel(r.FormValue("service"), level); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// entryByName returns the registered service with the given name.
+func (s *ServiceRegistry) entryByName(name string) (*serviceEntry, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	for _, entry := range s.entries {
+		if entry.String() == name {
+			return entry, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrServiceNotFound, name)
+}
@@ -0,0 +1,69 @@
+package shared
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+	"github.com/sirupsen/logrus"
+	logTest "github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestSetServiceLogLevel(t *testing.T) {
+	hook := logTest.NewGlobal()
+	registry := NewServiceRegistry()
+	ctx := NewServiceContext()
+	require.NoError(t, registry.RegisterServiceWithConfig(&mockService{}, ctx, nil))
+	other := NewServiceContext()
+	require.NoError(t, registry.RegisterServiceWithConfig(&secondMockService{}, other, nil))
+	custom := NewServiceContext()
+	custom.Log = logrus.WithField("prefix", "custom")
+	require.NoError(t, registry.RegisterServiceWithConfig(&thirdMockService{}, custom, nil))
+
+	level := logrus.GetLevel()
+	assert.DeepEqual(t, map[string]logrus.Level{
+		"shared.mockService":       level,
+		"shared.secondMockService": level,
+	}, registry.ServiceLogLevels())
+
+	require.NoError(t, registry.SetServiceLogLevel("shared.mockService", logrus.TraceLevel))
+	ctx.Log.Trace("Traced")
+	other.Log.Trace("Not traced")
+	require.LogsContain(t, hook, "Traced")
+	require.LogsDoNotContain(t, hook, "Not traced")
+	assert.Equal(t, logrus.TraceLevel, registry.ServiceLogLevels()["shared.mockService"])
+	assert.Equal(t, level, logrus.GetLevel(), "Expected the standard logger level to be unchanged")
+
+	err := registry.SetServiceLogLevel("shared.thirdMockService", logrus.DebugLevel)
+	assert.Equal(t, true, errors.Is(err, ErrLogLevelUnsupported))
+	assert.ErrorContains(t, "unknown service: beacon", registry.SetServiceLogLevel("beacon", logrus.DebugLevel))
+}
+
+func TestLogLevelHandler(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	handler := registry.LogLevelHandler()
+
+	form := url.Values{"service": {"shared.mockService"}, "level": {"debug"}}
+	req := httptest.NewRequest(http.MethodPost, "/loglevel", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/loglevel", nil))
+	levels := make(map[string]string)
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &levels))
+	assert.DeepEqual(t, map[string]string{"shared.mockService": "debug"}, levels)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/loglevel?service=shared.mockService&level=loud", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,39 @@
+package shared
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ServiceMetadata describes what a service is, as reported by ListServices,
+// DebugJSON and the health endpoints.
+type ServiceMetadata struct {
+	Name        string            `json:"name,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Version     string            `json:"version,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+}
+
+// Describer is optionally implemented by services which describe themselves.
+// Describe is called once, when the service is registered, and must not call
+// the registry.
+type Describer interface {
+	Describe() ServiceMetadata
+}
+
+// describe collects the metadata of a service, if it implements Describer. A
+// panic is logged and reported as the description.
+func (s *ServiceRegistry) describe(service lifecycle) (metadata *ServiceMetadata) {
+	d, ok := service.(Describer)
+	if !ok {
+		return nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			s.log.Errorf("Describe of service %s panicked: %v", typeName(reflect.TypeOf(service)), r)
+			metadata = &ServiceMetadata{Description: fmt.Sprintf("Describe panicked: %v", r)}
+		}
+	}()
+	m := d.Describe()
+	return &m
+}
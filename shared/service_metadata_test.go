@@ -0,0 +1,67 @@
+package shared
+
+import (
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+type describedService struct {
+	mockService
+	describes int32
+}
+
+func (s *describedService) Describe() ServiceMetadata {
+	atomic.AddInt32(&s.describes, 1)
+	return ServiceMetadata{
+		Name:        "sync",
+		Description: "Syncs the chain from peers",
+		Version:     "v2",
+		Labels:      map[string]string{"mode": "initial"},
+	}
+}
+
+func TestServiceMetadata(t *testing.T) {
+	registry := NewServiceRegistry()
+	s := &describedService{}
+	require.NoError(t, registry.RegisterService(s))
+	require.NoError(t, registry.RegisterService(&secondMockService{}))
+	want := &ServiceMetadata{
+		Name:        "sync",
+		Description: "Syncs the chain from peers",
+		Version:     "v2",
+		Labels:      map[string]string{"mode": "initial"},
+	}
+
+	infos := registry.ListServices()
+	assert.DeepEqual(t, want, infos[0].Metadata)
+	assert.Equal(t, true, infos[1].Metadata == nil)
+
+	b, err := registry.DebugJSON()
+	require.NoError(t, err)
+	dump := &debugDump{}
+	require.NoError(t, json.Unmarshal(b, dump))
+	assert.DeepEqual(t, want, dump.Services[0].Metadata)
+	assert.Equal(t, true, dump.Services[1].Metadata == nil)
+
+	_, resp := serveHealthz(t, registry)
+	assert.DeepEqual(t, map[string]*ServiceMetadata{"shared.describedService": want}, resp.Metadata)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&s.describes), "Expected the metadata to be collected once")
+}
+
+type panickingDescribeService struct {
+	mockService
+}
+
+func (s *panickingDescribeService) Describe() ServiceMetadata {
+	panic("not initialized")
+}
+
+func TestServiceMetadata_DescribePanics(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&panickingDescribeService{}))
+	assert.Equal(t, "Describe panicked: not initialized", registry.ListServices()[0].Metadata.Description)
+}
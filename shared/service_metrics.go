@@ -0,0 +1,105 @@
+package shared
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	serviceHealthyDesc = prometheus.NewDesc(
+		"service_healthy",
+		"Whether a registered service reports a healthy status (1) or not (0).",
+		[]string{"service"}, nil,
+	)
+	serviceStatusErrorsDesc = prometheus.NewDesc(
+		"service_status_check_errors_total",
+		"Total number of status checks of a registered service which returned an error.",
+		[]string{"service"}, nil,
+	)
+	serviceRestartsDesc = prometheus.NewDesc(
+		"service_restarts_total",
+		"Total number of restarts of a registered service.",
+		[]string{"service"}, nil,
+	)
+	serviceFailuresDesc = prometheus.NewDesc(
+		"service_failures_total",
+		"Total number of failures of a registered service.",
+		[]string{"service"}, nil,
+	)
+	serviceLastFailureDesc = prometheus.NewDesc(
+		"service_last_failure_timestamp_seconds",
+		"Unix time of the last failure of a registered service, or 0 if it never failed.",
+		[]string{"service"}, nil,
+	)
+	servicePanicsDesc = prometheus.NewDesc(
+		"service_panics_total",
+		"Total number of panics of a registered service recovered by the registry.",
+		[]string{"service"}, nil,
+	)
+	serviceStatusTimeoutsDesc = prometheus.NewDesc(
+		"service_status_timeouts_total",
+		"Total number of status checks of a registered service which did not return in time.",
+		[]string{"service"}, nil,
+	)
+)
+
+// ServiceHealthCollector is a prometheus collector exporting the health of
+// every service of a registry. Statuses are evaluated on each collection.
+type ServiceHealthCollector struct {
+	registry    *ServiceRegistry
+	lock        sync.Mutex
+	errorCounts map[string]float64
+}
+
+// NewServiceHealthCollector returns a collector for the services of the
+// given registry.
+func NewServiceHealthCollector(registry *ServiceRegistry) *ServiceHealthCollector {
+	return &ServiceHealthCollector{
+		registry:    registry,
+		errorCounts: make(map[string]float64),
+	}
+}
+
+// RegisterHealthCollector registers a ServiceHealthCollector for the
+// registry with the default prometheus registerer.
+func (s *ServiceRegistry) RegisterHealthCollector() error {
+	return prometheus.Register(NewServiceHealthCollector(s))
+}
+
+// Describe implements prometheus.Collector.
+func (c *ServiceHealthCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- serviceHealthyDesc
+	ch <- serviceStatusErrorsDesc
+	ch <- serviceRestartsDesc
+	ch <- serviceFailuresDesc
+	ch <- serviceLastFailureDesc
+	ch <- servicePanicsDesc
+	ch <- serviceStatusTimeoutsDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *ServiceHealthCollector) Collect(ch chan<- prometheus.Metric) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, entry := range c.registry.snapshot() {
+		name := entry.String()
+		healthy := 1.0
+		if err := c.registry.cachedStatus(entry); err != nil {
+			healthy = 0
+			c.errorCounts[name]++
+		}
+		ch <- prometheus.MustNewConstMetric(serviceHealthyDesc, prometheus.GaugeValue, healthy, name)
+		ch <- prometheus.MustNewConstMetric(serviceStatusErrorsDesc, prometheus.CounterValue, c.errorCounts[name], name)
+		counters := entry.counters.get()
+		lastFailure := 0.0
+		if !counters.LastFailure.IsZero() {
+			lastFailure = float64(counters.LastFailure.UnixNano()) / 1e9
+		}
+		ch <- prometheus.MustNewConstMetric(serviceRestartsDesc, prometheus.CounterValue, float64(counters.Restarts), name)
+		ch <- prometheus.MustNewConstMetric(serviceFailuresDesc, prometheus.CounterValue, float64(counters.Failures), name)
+		ch <- prometheus.MustNewConstMetric(serviceLastFailureDesc, prometheus.GaugeValue, lastFailure, name)
+		ch <- prometheus.MustNewConstMetric(servicePanicsDesc, prometheus.CounterValue, float64(counters.Panics), name)
+		ch <- prometheus.MustNewConstMetric(serviceStatusTimeoutsDesc, prometheus.CounterValue, float64(counters.StatusTimeouts), name)
+	}
+}
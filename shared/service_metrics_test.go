@@ -0,0 +1,61 @@
+package shared
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+func TestServiceHealthCollector(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.RegisterService(&secondMockService{status: errors.New("bad")}))
+	require.NoError(t, registry.RegisterNamedService("gateway", &mockService{}, nil))
+
+	promRegistry := prometheus.NewRegistry()
+	require.NoError(t, promRegistry.Register(NewServiceHealthCollector(registry)))
+
+	// Gather twice so the error counter is incremented twice.
+	_, err := promRegistry.Gather()
+	require.NoError(t, err)
+	families, err := promRegistry.Gather()
+	require.NoError(t, err)
+
+	values := make(map[string]map[string]float64)
+	for _, family := range families {
+		values[family.GetName()] = make(map[string]float64)
+		for _, metric := range family.GetMetric() {
+			values[family.GetName()][labelValue(metric, "service")] = metricValue(metric)
+		}
+	}
+	assert.DeepEqual(t, map[string]float64{
+		"shared.mockService":       1,
+		"shared.secondMockService": 0,
+		"gateway":                  1,
+	}, values["service_healthy"])
+	assert.DeepEqual(t, map[string]float64{
+		"shared.mockService":       0,
+		"shared.secondMockService": 2,
+		"gateway":                  0,
+	}, values["service_status_check_errors_total"])
+}
+
+func labelValue(metric *dto.Metric, name string) string {
+	for _, label := range metric.GetLabel() {
+		if label.GetName() == name {
+			return label.GetValue()
+		}
+	}
+	return ""
+}
+
+func metricValue(metric *dto.Metric) float64 {
+	if metric.GetGauge() != nil {
+		return metric.GetGauge().GetValue()
+	}
+	return metric.GetCounter().GetValue()
+}
@@ -0,0 +1,35 @@
+package shared
+
+import (
+	"errors"
+)
+
+// ErrServiceDegraded is matched by the status errors of optional services,
+// which degrade the node rather than make it unhealthy. Services can wrap it
+// in their status errors to report that they are degraded.
+var ErrServiceDegraded = errors.New("service degraded")
+
+// RegisterOptionalService registers a service the node can run without: its
+// start failures are logged rather than fatal, and its status errors are
+// reported as degraded.
+func (s *ServiceRegistry) RegisterOptionalService(service Service, groups ...string) error {
+	return s.RegisterServiceWithConfig(service, nil, &ServiceConfig{Optional: true, Groups: groups})
+}
+
+// degradedError wraps the status error of an optional service, so that it
+// matches both ErrServiceDegraded and the original error.
+type degradedError struct {
+	err error
+}
+
+func (e *degradedError) Error() string {
+	return "degraded: " + e.err.Error()
+}
+
+func (e *degradedError) Unwrap() error {
+	return e.err
+}
+
+func (e *degradedError) Is(target error) bool {
+	return target == ErrServiceDegraded
+}
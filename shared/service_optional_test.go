@@ -0,0 +1,69 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestOptionalService_StartPanicIsDegraded(t *testing.T) {
+	registry := NewServiceRegistry()
+	registry.SetStartPanicsFatal(true)
+
+	p := &panickingStartService{started: make(chan struct{})}
+	require.NoError(t, registry.RegisterOptionalService(p))
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.StartAll())
+	<-p.started
+	waitForState(t, registry, reflect.TypeOf(p), StateStopped)
+
+	err := registry.Statuses()[reflect.TypeOf(p)]
+	assert.Equal(t, true, errors.Is(err, ErrServiceDegraded), "Expected a degraded status, received %v", err)
+	assert.ErrorContains(t, "service panicked during start: could not bind port", err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, registry.WaitForAllReady(ctx), "Expected optional services not to block readiness")
+}
+
+func TestOptionalService_StatusErrorIsDegraded(t *testing.T) {
+	registry := NewServiceRegistry()
+	statusErr := errors.New("exporter unreachable")
+	require.NoError(t, registry.RegisterServiceWithConfig(&mockService{status: statusErr}, nil, &ServiceConfig{Optional: true}))
+	require.NoError(t, registry.RegisterService(&secondMockService{}))
+	require.NoError(t, registry.StartAll())
+
+	err := registry.Statuses()[reflect.TypeOf(&mockService{})]
+	assert.Equal(t, true, errors.Is(err, ErrServiceDegraded))
+	assert.Equal(t, true, errors.Is(err, statusErr), "Expected the original error to be wrapped")
+
+	code, resp := serveHealthz(t, registry)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, healthzDegraded, resp.Status)
+
+	server := NewHealthServer(registry, 0)
+	overall, err := server.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, overall.Status)
+	single, err := server.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: "shared.mockService"})
+	require.NoError(t, err)
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, single.Status)
+}
+
+func TestOptionalService_UnhealthyTakesPrecedence(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterServiceWithConfig(&mockService{status: errors.New("bad")}, nil, &ServiceConfig{Optional: true}))
+	require.NoError(t, registry.RegisterService(&secondMockService{status: errors.New("no peers")}))
+	require.NoError(t, registry.StartAll())
+
+	code, resp := serveHealthz(t, registry)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, healthzUnhealthy, resp.Status)
+}
@@ -0,0 +1,42 @@
+package shared
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RegistryOption configures a registry created by NewServiceRegistry or
+// NewServiceRegistryWithContext.
+type RegistryOption func(s *ServiceRegistry)
+
+// WithLogger makes the registry log through the given logger instead of the
+// package logger, whose prefix is "registry". It does not change the loggers
+// the registry sets in service contexts.
+func WithLogger(logger *logrus.Entry) RegistryOption {
+	return func(s *ServiceRegistry) {
+		if logger != nil {
+			s.log = logger
+		}
+	}
+}
+
+// WithStopTimeout sets how long StopAll waits for a service to stop when it
+// was registered without a stop timeout of its own. It defaults to 10
+// seconds.
+func WithStopTimeout(timeout time.Duration) RegistryOption {
+	return func(s *ServiceRegistry) {
+		if timeout > 0 {
+			s.stopTimeout = timeout
+		}
+	}
+}
+
+// WithStatusPollInterval sets how often the registry checks the statuses of
+// services it waits for, such as in WaitForAllReady, as SetReadyPollInterval
+// does.
+func WithStatusPollInterval(interval time.Duration) RegistryOption {
+	return func(s *ServiceRegistry) {
+		s.SetReadyPollInterval(interval)
+	}
+}
@@ -0,0 +1,41 @@
+package shared
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+	logTest "github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestWithLogger(t *testing.T) {
+	global := logTest.NewGlobal()
+	logger, hook := logTest.NewNullLogger()
+	registry := NewServiceRegistry(WithLogger(logger.WithField("prefix", "beacon-registry")))
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.StartAll())
+	require.NoError(t, registry.StopAll())
+
+	require.LogsContain(t, hook, "Stopped service shared.mockService")
+	assert.Equal(t, "beacon-registry", hook.LastEntry().Data["prefix"])
+	require.LogsDoNotContain(t, global, "Stopped service shared.mockService")
+}
+
+func TestWithStopTimeout(t *testing.T) {
+	registry := NewServiceRegistry(WithStopTimeout(10 * time.Millisecond))
+	b := &blockingStopService{release: make(chan struct{})}
+	defer close(b.release)
+	require.NoError(t, registry.RegisterService(b))
+	assert.ErrorContains(t, "service did not stop within 10ms", registry.StopAll())
+}
+
+func TestRegistryOptions_Defaults(t *testing.T) {
+	registry := NewServiceRegistry(WithLogger(nil), WithStopTimeout(0))
+	assert.Equal(t, log, registry.log)
+	assert.Equal(t, defaultStopTimeout, registry.stopTimeout)
+	assert.Equal(t, defaultReadyPollInterval, registry.readyPoll)
+
+	registry = NewServiceRegistry(WithStatusPollInterval(time.Millisecond))
+	assert.Equal(t, time.Millisecond, registry.readyPoll)
+}
@@ -0,0 +1,40 @@
+package shared
+
+import (
+	"fmt"
+	"time"
+)
+
+// maxPanicStackSize bounds the size of the stack trace kept for the last
+// panic of a service.
+const maxPanicStackSize = 8 << 10
+
+// ServicePanic describes a panic of a service recovered by the registry, such
+// as in its Start or Stop method.
+type ServicePanic struct {
+	// Value is the value the service panicked with.
+	Value string `json:"value"`
+	// Stack is the stack trace of the panicking goroutine, truncated to 8KiB.
+	Stack string `json:"stack"`
+	// At is when the service panicked.
+	At time.Time `json:"at"`
+}
+
+// recordPanic counts a recovered panic of a service and keeps it as the last
+// panic of the service, discarding the previous one.
+func (s *ServiceRegistry) recordPanic(entry *serviceEntry, r interface{}, stack []byte) {
+	entry.counters.panicked(newServicePanic(r, stack))
+}
+
+// newServicePanic describes a panic recovered with the given value and stack
+// trace, truncating the latter.
+func newServicePanic(r interface{}, stack []byte) *ServicePanic {
+	if len(stack) > maxPanicStackSize {
+		stack = append(stack[:maxPanicStackSize:maxPanicStackSize], "\n... truncated"...)
+	}
+	return &ServicePanic{
+		Value: fmt.Sprint(r),
+		Stack: string(stack),
+		At:    time.Now(),
+	}
+}
@@ -0,0 +1,89 @@
+package shared
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+func TestRecordPanic_StartAndStop(t *testing.T) {
+	registry := NewServiceRegistry(WithLegacyStartWait(), WithoutPreStop())
+	p := &panickingStartService{started: make(chan struct{})}
+	require.NoError(t, registry.RegisterService(p))
+	require.NoError(t, registry.RegisterService(&panickingStopService{}))
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	// The failed start stops the other services, recovering the panic of Stop.
+	assert.ErrorContains(t, "could not bind port", registry.StartAll())
+	<-p.started
+
+	statuses := registry.DetailedStatuses()
+	started := statuses["shared.panickingStartService"]
+	assert.Equal(t, 1, started.Panics)
+	require.NotNil(t, started.LastPanic)
+	assert.Equal(t, "could not bind port", started.LastPanic.Value)
+	assert.Equal(t, true, strings.Contains(started.LastPanic.Stack, "panickingStartService"), started.LastPanic.Stack)
+	assert.Equal(t, 0, statuses["shared.mockService"].Panics)
+	assert.Equal(t, (*ServicePanic)(nil), statuses["shared.mockService"].LastPanic)
+
+	stopped := registry.DetailedStatuses()["shared.panickingStopService"]
+	assert.Equal(t, 1, stopped.Panics)
+	require.NotNil(t, stopped.LastPanic)
+	assert.Equal(t, "could not flush", stopped.LastPanic.Value)
+
+	dump, err := registry.DebugJSON()
+	require.NoError(t, err)
+	var decoded debugDump
+	require.NoError(t, json.Unmarshal(dump, &decoded))
+	panics := make(map[string]int)
+	for _, service := range decoded.Services {
+		panics[service.Name] = service.Panics
+		if service.Panics > 0 {
+			require.NotNil(t, service.LastPanic)
+		}
+	}
+	assert.DeepEqual(t, map[string]int{
+		"shared.panickingStartService": 1,
+		"shared.panickingStopService":  1,
+		"shared.mockService":           0,
+	}, panics)
+
+	promRegistry := prometheus.NewRegistry()
+	require.NoError(t, promRegistry.Register(NewServiceHealthCollector(registry)))
+	families, err := promRegistry.Gather()
+	require.NoError(t, err)
+	values := make(map[string]float64)
+	for _, family := range families {
+		if family.GetName() != "service_panics_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			values[labelValue(metric, "service")] = metricValue(metric)
+		}
+	}
+	assert.DeepEqual(t, map[string]float64{
+		"shared.panickingStartService": 1,
+		"shared.panickingStopService":  1,
+		"shared.mockService":           0,
+	}, values)
+}
+
+func TestRecordPanic_KeepsOnlyLastTruncatedStack(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	entry := registry.snapshot()[0]
+
+	registry.recordPanic(entry, "first", []byte("short stack"))
+	registry.recordPanic(entry, "second", bytes.Repeat([]byte("x"), 3*maxPanicStackSize))
+
+	counters := registry.Counters()["shared.mockService"]
+	assert.Equal(t, 2, counters.Panics)
+	require.NotNil(t, counters.LastPanic)
+	assert.Equal(t, "second", counters.LastPanic.Value)
+	assert.Equal(t, true, len(counters.LastPanic.Stack) < maxPanicStackSize+100)
+	assert.Equal(t, true, strings.HasSuffix(counters.LastPanic.Stack, "... truncated"))
+}
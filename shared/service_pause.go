@@ -0,0 +1,78 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrServicePaused is reported as the status of a service paused by PauseAll,
+// so that it can be told apart from an unhealthy service.
+var ErrServicePaused = errors.New("service is paused")
+
+// Pausable is implemented by services which can temporarily stop producing
+// work, for instance during a maintenance window, while keeping their
+// connections and other resources open.
+type Pausable interface {
+	// Pause stops the service from producing new work, blocking until it is
+	// idle or the context is done.
+	Pause(ctx context.Context) error
+	// Resume lets a paused service produce work again.
+	Resume(ctx context.Context) error
+}
+
+// PauseAll pauses every running service implementing Pausable, in order of
+// registration. Services which do not implement Pausable are skipped and
+// logged. PauseAll returns as soon as a service fails to pause, leaving the
+// services paused so far in the paused state: ResumeAll resumes them.
+func (s *ServiceRegistry) PauseAll(ctx context.Context) error {
+	for _, entry := range s.snapshot() {
+		if s.stateOf(entry) != StateRunning {
+			continue
+		}
+		p, ok := entry.service.(Pausable)
+		if !ok {
+			s.log.WithField("service", entry.String()).Info("Skipping service which cannot be paused")
+			continue
+		}
+		s.log.Debugf("Pausing service %v", entry)
+		if err := p.Pause(ctx); err != nil {
+			return fmt.Errorf("could not pause service %v: %w", entry, err)
+		}
+		s.lock.Lock()
+		// The service may have been stopped while Pause was still running.
+		if entry.state == StateRunning {
+			entry.state = StatePaused
+		}
+		s.lock.Unlock()
+	}
+	return nil
+}
+
+// ResumeAll resumes every paused service, in reverse order of registration.
+// A service failing to resume is logged and left paused, and the first such
+// error is returned once every other paused service was resumed.
+func (s *ServiceRegistry) ResumeAll(ctx context.Context) error {
+	var firstErr error
+	entries := s.snapshot()
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if s.stateOf(entry) != StatePaused {
+			continue
+		}
+		s.log.Debugf("Resuming service %v", entry)
+		if err := entry.service.(Pausable).Resume(ctx); err != nil {
+			s.log.WithError(err).Errorf("Could not resume the following service: %v", entry)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("could not resume service %v: %w", entry, err)
+			}
+			continue
+		}
+		s.lock.Lock()
+		if entry.state == StatePaused {
+			entry.state = StateRunning
+		}
+		s.lock.Unlock()
+	}
+	return firstErr
+}
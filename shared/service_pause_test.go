@@ -0,0 +1,131 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+// callRecorder records the order in which pausable services are called.
+type callRecorder struct {
+	lock  sync.Mutex
+	calls []string
+}
+
+func (r *callRecorder) record(call string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+type pausableService struct {
+	mockService
+	name      string
+	recorder  *callRecorder
+	pauseErr  error
+	resumeErr error
+}
+
+func (p *pausableService) Pause(_ context.Context) error {
+	p.recorder.record("pause " + p.name)
+	return p.pauseErr
+}
+
+func (p *pausableService) Resume(_ context.Context) error {
+	p.recorder.record("resume " + p.name)
+	return p.resumeErr
+}
+
+type secondPausableService struct {
+	pausableService
+}
+
+func TestPauseAll_PausesInOrderAndResumesInReverse(t *testing.T) {
+	recorder := &callRecorder{}
+	registry := NewServiceRegistry()
+	first := &pausableService{name: "first", recorder: recorder}
+	second := &secondPausableService{pausableService{name: "second", recorder: recorder}}
+	require.NoError(t, registry.RegisterService(first))
+	require.NoError(t, registry.RegisterService(&secondMockService{}))
+	require.NoError(t, registry.RegisterService(second))
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, reflect.TypeOf(first), StateRunning)
+	waitForState(t, registry, reflect.TypeOf(second), StateRunning)
+	waitForState(t, registry, reflect.TypeOf(&secondMockService{}), StateRunning)
+
+	require.NoError(t, registry.PauseAll(context.Background()))
+	state, err := registry.State(reflect.TypeOf(first))
+	require.NoError(t, err)
+	assert.Equal(t, StatePaused, state)
+	state, err = registry.State(reflect.TypeOf(&secondMockService{}))
+	require.NoError(t, err)
+	assert.Equal(t, StateRunning, state, "Expected non pausable service to keep running")
+
+	statuses := registry.Statuses()
+	assert.Equal(t, true, errors.Is(statuses[reflect.TypeOf(first)], ErrServicePaused))
+	assert.NoError(t, statuses[reflect.TypeOf(&secondMockService{})])
+
+	require.NoError(t, registry.ResumeAll(context.Background()))
+	assert.DeepEqual(t, []string{"pause first", "pause second", "resume second", "resume first"}, recorder.calls)
+	assert.NoError(t, registry.Statuses()[reflect.TypeOf(first)])
+	require.NoError(t, registry.StopAll())
+}
+
+func TestPauseAll_StopsOnError(t *testing.T) {
+	recorder := &callRecorder{}
+	registry := NewServiceRegistry()
+	first := &pausableService{name: "first", recorder: recorder, pauseErr: errors.New("busy")}
+	second := &secondPausableService{pausableService{name: "second", recorder: recorder}}
+	require.NoError(t, registry.RegisterService(first))
+	require.NoError(t, registry.RegisterService(second))
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, reflect.TypeOf(first), StateRunning)
+	waitForState(t, registry, reflect.TypeOf(second), StateRunning)
+
+	assert.ErrorContains(t, "could not pause service shared.pausableService: busy", registry.PauseAll(context.Background()))
+	assert.DeepEqual(t, []string{"pause first"}, recorder.calls)
+	state, err := registry.State(reflect.TypeOf(first))
+	require.NoError(t, err)
+	assert.Equal(t, StateRunning, state)
+	require.NoError(t, registry.StopAll())
+}
+
+func TestResumeAll_ContinuesOnError(t *testing.T) {
+	recorder := &callRecorder{}
+	registry := NewServiceRegistry()
+	first := &pausableService{name: "first", recorder: recorder}
+	second := &secondPausableService{pausableService{name: "second", recorder: recorder, resumeErr: errors.New("no peers")}}
+	require.NoError(t, registry.RegisterService(first))
+	require.NoError(t, registry.RegisterService(second))
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, reflect.TypeOf(first), StateRunning)
+	waitForState(t, registry, reflect.TypeOf(second), StateRunning)
+	require.NoError(t, registry.PauseAll(context.Background()))
+
+	assert.ErrorContains(t, "no peers", registry.ResumeAll(context.Background()))
+	state, err := registry.State(reflect.TypeOf(first))
+	require.NoError(t, err)
+	assert.Equal(t, StateRunning, state)
+	state, err = registry.State(reflect.TypeOf(second))
+	require.NoError(t, err)
+	assert.Equal(t, StatePaused, state)
+	require.NoError(t, registry.StopAll())
+}
+
+func TestHealthzHandler_Paused(t *testing.T) {
+	registry := NewServiceRegistry()
+	p := &pausableService{name: "first", recorder: &callRecorder{}}
+	require.NoError(t, registry.RegisterService(p))
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, reflect.TypeOf(p), StateRunning)
+	require.NoError(t, registry.PauseAll(context.Background()))
+
+	_, resp := serveHealthz(t, registry)
+	assert.Equal(t, healthzPaused, resp.Status)
+	require.NoError(t, registry.StopAll())
+}
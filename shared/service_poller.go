@@ -0,0 +1,109 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// statusPoller periodically evaluates the status of every registered service
+// and logs the services whose status changed since the previous poll.
+type statusPoller struct {
+	registry *ServiceRegistry
+	interval time.Duration
+	last     map[string]string // last status error of every unhealthy service.
+	cancel   context.CancelFunc
+	done     chan struct{}
+	// unhealthy counts the consecutive unhealthy polls of critical services.
+	unhealthy map[*serviceEntry]int
+}
+
+// StartStatusPoller launches a goroutine which evaluates the status of every
+// service at the given interval. A warning is logged whenever the status
+// error of a service changes, rather than at every poll, and a recovery
+// message once a failing service becomes healthy again. The poller is
+// terminated by StopAll.
+//
+// The poller also checks the heartbeat of the services implementing
+// Heartbeater: a running service which made no progress within the heartbeat
+// threshold is reported as unhealthy, by the poller as by Statuses and the
+// health endpoints, until its heartbeat is fresh again. A running critical
+// service found unhealthy by as many consecutive polls as the critical
+// threshold is reported as a fatal error.
+func (s *ServiceRegistry) StartStatusPoller(interval time.Duration) error {
+	if interval <= 0 {
+		return errors.New("status poller interval must be positive")
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return ErrRegistryClosed
+	}
+	if s.poller != nil {
+		return errors.New("status poller already running")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &statusPoller{
+		registry:  s,
+		interval:  interval,
+		last:      make(map[string]string),
+		unhealthy: make(map[*serviceEntry]int),
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	s.poller = p
+	go p.run(ctx)
+	return nil
+}
+
+func (p *statusPoller) run(ctx context.Context) {
+	defer close(p.done)
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.poll()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (p *statusPoller) poll() {
+	p.registry.lock.RLock()
+	threshold := p.registry.heartbeatThreshold
+	criticalThreshold := p.registry.criticalThreshold
+	p.registry.lock.RUnlock()
+	now := time.Now()
+	for _, entry := range p.registry.snapshot() {
+		entry := entry
+		name := entry.String()
+		p.registry.checkHeartbeat(entry, now, threshold)
+		err := checkStatus(func() error { return p.registry.status(entry) }, p.interval)
+		p.checkCritical(entry, err, criticalThreshold)
+		previous, failing := p.last[name]
+		if err == nil {
+			if failing {
+				p.registry.log.WithField("service", name).Info("Service is healthy again")
+				delete(p.last, name)
+			}
+			continue
+		}
+		if msg := err.Error(); !failing || msg != previous {
+			logger := p.registry.log.WithField("service", name).WithError(err)
+			if StatusSeverity(err) == SeverityCritical && !isIntentional(err) {
+				logger.Error("Service is unhealthy")
+			} else {
+				logger.Warn("Service is unhealthy")
+			}
+			p.last[name] = msg
+		}
+	}
+}
+
+// stop terminates the poller and waits for its goroutine to exit.
+func (p *statusPoller) stop() {
+	p.cancel()
+	<-p.done
+}
@@ -0,0 +1,61 @@
+package shared
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+	logTest "github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestStatusPoller_LogsStatusChanges(t *testing.T) {
+	hook := logTest.NewGlobal()
+	registry := NewServiceRegistry()
+	s := &statusFuncService{}
+	s.setStatus(func() error { return errors.New("no peers") })
+	require.NoError(t, registry.RegisterService(s))
+	p := &statusPoller{registry: registry, interval: time.Second, last: make(map[string]string)}
+
+	p.poll()
+	p.poll()
+	assert.Equal(t, 1, countLogs(hook, "Service is unhealthy"), "Expected an unchanged error to be logged once")
+
+	s.setStatus(func() error { return errors.New("syncing") })
+	p.poll()
+	assert.Equal(t, 2, countLogs(hook, "Service is unhealthy"))
+	assert.Equal(t, "syncing", hook.LastEntry().Data["error"].(error).Error())
+
+	s.setStatus(func() error { return nil })
+	p.poll()
+	p.poll()
+	assert.Equal(t, 1, countLogs(hook, "Service is healthy again"))
+}
+
+func TestStartStatusPoller_StoppedByStopAll(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.StartStatusPoller(time.Millisecond))
+	assert.ErrorContains(t, "status poller already running", registry.StartStatusPoller(time.Millisecond))
+	assert.ErrorContains(t, "must be positive", registry.StartStatusPoller(0))
+
+	registry.lock.RLock()
+	p := registry.poller
+	registry.lock.RUnlock()
+	require.NoError(t, registry.StopAll())
+	select {
+	case <-p.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Status poller was not stopped by StopAll")
+	}
+}
+
+func countLogs(hook *logTest.Hook, msg string) int {
+	var count int
+	for _, entry := range hook.AllEntries() {
+		if entry.Message == msg {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,81 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// defaultPreflightTimeout bounds how long the preflight checks of every
+// service may take together.
+const defaultPreflightTimeout = 30 * time.Second
+
+// Preflighter is implemented by services which check invariants, such as
+// free disk space, file permissions or the sanity of their configuration,
+// before any service is started.
+type Preflighter interface {
+	// Preflight returns an error if the service cannot be started, which
+	// prevents StartAll from starting any service.
+	Preflight(ctx context.Context) error
+}
+
+// WithPreflightTimeout sets how long the preflight checks of every service
+// may take together before StartAll gives up on them, failing. It defaults
+// to 30 seconds.
+func WithPreflightTimeout(timeout time.Duration) RegistryOption {
+	return func(s *ServiceRegistry) {
+		if timeout > 0 {
+			s.preflightTimeout = timeout
+		}
+	}
+}
+
+// preflightAll runs the preflight checks of the services implementing
+// Preflighter one at a time, in start order, and returns a *MultiError listing
+// every failed check. Once the preflight timeout elapsed or the context is
+// done, the check running is abandoned and the remaining ones are reported
+// as not run.
+func (s *ServiceRegistry) preflightAll(parent context.Context, order []*serviceEntry) error {
+	ctx, cancel := context.WithTimeout(parent, s.preflightTimeout)
+	defer cancel()
+	errs := &MultiError{}
+	for _, entry := range order {
+		checker, ok := entry.service.(Preflighter)
+		if !ok || entry.isLazy() {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			errs.add(fmt.Errorf("preflight check of service %v not run: %w", entry, err))
+			continue
+		}
+		start := time.Now()
+		if err := s.preflight(ctx, entry, checker); err != nil {
+			errs.add(fmt.Errorf("preflight check of service %v failed: %w", entry, err))
+			continue
+		}
+		s.log.WithField("duration", time.Since(start)).Debugf("Preflight check of service %v passed", entry)
+	}
+	return errs.errorOrNil()
+}
+
+// preflight runs a preflight check, recovering a panic and giving up once the
+// context is done.
+func (s *ServiceRegistry) preflight(ctx context.Context, entry *serviceEntry, checker Preflighter) error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				s.recordPanic(entry, r, debug.Stack())
+				done <- fmt.Errorf("service panicked during preflight check: %v", r)
+			}
+		}()
+		done <- checker.Preflight(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("check did not return: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,100 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+type preflightService struct {
+	startCountingService
+	check func(ctx context.Context) error
+}
+
+func (s *preflightService) Preflight(ctx context.Context) error {
+	return s.check(ctx)
+}
+
+type secondPreflightService struct {
+	preflightService
+}
+
+type thirdPreflightService struct {
+	preflightService
+}
+
+func TestStartAll_PreflightPasses(t *testing.T) {
+	registry := NewServiceRegistry()
+	var checked []string
+	first := &preflightService{check: func(context.Context) error {
+		checked = append(checked, "first")
+		return nil
+	}}
+	second := &secondPreflightService{preflightService{check: func(context.Context) error {
+		checked = append(checked, "second")
+		return nil
+	}}}
+	require.NoError(t, registry.RegisterService(first))
+	require.NoError(t, registry.RegisterService(second))
+	require.NoError(t, registry.StartAll())
+	assert.DeepEqual(t, []string{"first", "second"}, checked)
+	waitForState(t, registry, reflect.TypeOf(first), StateRunning)
+	require.NoError(t, registry.StopAll())
+}
+
+func TestStartAll_PreflightFailureStartsNothing(t *testing.T) {
+	registry := NewServiceRegistry()
+	first := &preflightService{check: func(context.Context) error {
+		return errors.New("not enough disk space")
+	}}
+	second := &secondPreflightService{preflightService{check: func(context.Context) error {
+		return nil
+	}}}
+	third := &thirdPreflightService{preflightService{check: func(context.Context) error {
+		panic("bad config")
+	}}}
+	require.NoError(t, registry.RegisterService(first))
+	require.NoError(t, registry.RegisterService(second))
+	require.NoError(t, registry.RegisterService(third))
+
+	err := registry.StartAll()
+	var multiErr *MultiError
+	require.Equal(t, true, errors.As(err, &multiErr))
+	require.Equal(t, 2, len(multiErr.Errors))
+	assert.ErrorContains(t, "preflight check of service shared.preflightService failed: not enough disk space", multiErr.Errors[0])
+	assert.ErrorContains(t, "preflight check of service shared.thirdPreflightService failed: service panicked during preflight check: bad config", multiErr.Errors[1])
+	for _, info := range registry.ListServices() {
+		assert.Equal(t, StateRegistered, info.State)
+	}
+	assert.Equal(t, 0, first.starts)
+
+	// Nothing was started, so StartAll can be called again once fixed.
+	first.check = func(context.Context) error { return nil }
+	third.check = func(context.Context) error { return nil }
+	require.NoError(t, registry.StartAll())
+	require.NoError(t, registry.StopAll())
+}
+
+func TestStartAll_PreflightTimeout(t *testing.T) {
+	registry := NewServiceRegistry(WithPreflightTimeout(50 * time.Millisecond))
+	release := make(chan struct{})
+	defer close(release)
+	require.NoError(t, registry.RegisterService(&preflightService{check: func(context.Context) error {
+		<-release
+		return nil
+	}}))
+	require.NoError(t, registry.RegisterService(&secondPreflightService{preflightService{check: func(context.Context) error {
+		return nil
+	}}}))
+
+	start := time.Now()
+	err := registry.StartAll()
+	assert.Equal(t, true, time.Since(start) < 5*time.Second)
+	assert.ErrorContains(t, "preflight check of service shared.preflightService failed: check did not return: context deadline exceeded", err)
+	assert.ErrorContains(t, "preflight check of service shared.secondPreflightService not run: context deadline exceeded", err)
+}
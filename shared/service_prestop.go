@@ -0,0 +1,101 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// PreStopper is implemented by services which should stop accepting new work
+// before they are stopped, such as API servers letting their in-flight
+// requests complete.
+type PreStopper interface {
+	// PreStop tells the service the node is shutting down. The service is
+	// stopped once every service was told and the drain period elapsed.
+	PreStop(ctx context.Context) error
+}
+
+// WithDrainPeriod sets how long StopAll waits after calling PreStop on the
+// services implementing PreStopper before it stops any service. It defaults
+// to zero, and is cut short once the context of StopAllWithContext is done.
+func WithDrainPeriod(period time.Duration) RegistryOption {
+	return func(s *ServiceRegistry) {
+		if period > 0 {
+			s.drainPeriod = period
+		}
+	}
+}
+
+// WithoutPreStop makes StopAll stop services right away, without calling
+// PreStop or waiting for the drain period, as tests tearing down a registry
+// usually want.
+func WithoutPreStop() RegistryOption {
+	return func(s *ServiceRegistry) {
+		s.skipPreStop = true
+	}
+}
+
+// preStopAll calls PreStop on every active service implementing PreStopper,
+// in reverse order of registration, then waits for the drain period unless
+// the context is done first. Each call is bounded by the stop timeout of the
+// service, and its failure is logged without preventing the service from
+// being stopped. Nothing is waited for if no service implements PreStopper.
+func (s *ServiceRegistry) preStopAll(ctx context.Context, drain time.Duration) {
+	entries := s.snapshot()
+	called := false
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		stopper, ok := entry.service.(PreStopper)
+		if !ok || !s.isActive(entry) {
+			continue
+		}
+		called = true
+		if err := s.preStop(ctx, entry, stopper); err != nil {
+			s.log.WithError(err).Warnf("Could not prepare service %v for stopping", entry)
+		}
+	}
+	if !called || drain <= 0 {
+		return
+	}
+	s.log.WithField("period", drain).Info("Draining services before stopping them")
+	timer := time.NewTimer(drain)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		s.log.WithError(ctx.Err()).Warn("Shutdown deadline reached while draining services")
+	}
+}
+
+// preStop calls PreStop on a service, recovering a panic and giving up once
+// the stop timeout of the service elapsed or the context is done.
+func (s *ServiceRegistry) preStop(parent context.Context, entry *serviceEntry, stopper PreStopper) error {
+	ctx, cancel := context.WithTimeout(parent, s.stopTimeoutOf(entry))
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				s.recordPanic(entry, r, debug.Stack())
+				done <- fmt.Errorf("service panicked during pre-stop: %v", r)
+			}
+		}()
+		done <- stopper.PreStop(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("pre-stop did not return: %w", ctx.Err())
+	}
+}
+
+// isActive returns whether a service is starting, running or paused.
+func (s *ServiceRegistry) isActive(entry *serviceEntry) bool {
+	switch s.stateOf(entry) {
+	case StateStarting, StateRunning, StatePaused:
+		return true
+	}
+	return false
+}
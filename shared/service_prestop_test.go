@@ -0,0 +1,129 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+	logTest "github.com/sirupsen/logrus/hooks/test"
+)
+
+type callLog struct {
+	lock  sync.Mutex
+	calls []string
+}
+
+func (l *callLog) add(call string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.calls = append(l.calls, call)
+}
+
+func (l *callLog) get() []string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return append([]string(nil), l.calls...)
+}
+
+type drainingService struct {
+	name   string
+	log    *callLog
+	preErr error
+}
+
+func (s *drainingService) Name() string { return s.name }
+
+func (s *drainingService) Start() {}
+
+func (s *drainingService) PreStop(_ context.Context) error {
+	s.log.add("prestop " + s.name)
+	return s.preErr
+}
+
+func (s *drainingService) Stop() error {
+	s.log.add("stop " + s.name)
+	return nil
+}
+
+func (s *drainingService) Status() error {
+	return nil
+}
+
+func waitForNamedState(t *testing.T, registry *ServiceRegistry, name string, want ServiceState) {
+	entry, err := registry.entryByName(name)
+	require.NoError(t, err)
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if registry.stateOf(entry) == want {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Service %s did not reach state %v", name, want)
+}
+
+func TestStopAll_PreStopBeforeStop(t *testing.T) {
+	registry := NewServiceRegistry(WithDrainPeriod(100 * time.Millisecond))
+	registry.SetStrictStopOrder(true)
+	calls := &callLog{}
+	require.NoError(t, registry.RegisterNamedService("api", &drainingService{name: "api", log: calls}, nil))
+	require.NoError(t, registry.RegisterNamedService("grpc", &drainingService{name: "grpc", log: calls}, nil))
+	require.NoError(t, registry.StartAll())
+	for _, name := range []string{"api", "grpc"} {
+		waitForNamedState(t, registry, name, StateRunning)
+	}
+
+	start := time.Now()
+	require.NoError(t, registry.StopAll())
+	assert.Equal(t, true, time.Since(start) >= 100*time.Millisecond, "Services were stopped before the drain period elapsed")
+	assert.DeepEqual(t, []string{"prestop grpc", "prestop api", "stop grpc", "stop api"}, calls.get())
+}
+
+func TestStopAll_PreStopFailureStillStops(t *testing.T) {
+	hook := logTest.NewGlobal()
+	registry := NewServiceRegistry()
+	calls := &callLog{}
+	require.NoError(t, registry.RegisterNamedService("api", &drainingService{name: "api", log: calls, preErr: errors.New("listener closed")}, nil))
+	require.NoError(t, registry.StartAll())
+	waitForNamedState(t, registry, "api", StateRunning)
+
+	require.NoError(t, registry.StopAll())
+	assert.DeepEqual(t, []string{"prestop api", "stop api"}, calls.get())
+	require.LogsContain(t, hook, "Could not prepare service api for stopping")
+}
+
+func TestStopAll_WithoutPreStop(t *testing.T) {
+	registry := NewServiceRegistry(WithDrainPeriod(time.Hour), WithoutPreStop())
+	calls := &callLog{}
+	require.NoError(t, registry.RegisterNamedService("api", &drainingService{name: "api", log: calls}, nil))
+	require.NoError(t, registry.StartAll())
+	waitForNamedState(t, registry, "api", StateRunning)
+
+	require.NoError(t, registry.StopAll())
+	assert.DeepEqual(t, []string{"stop api"}, calls.get())
+}
+
+func TestStopAll_DrainRespectsDeadline(t *testing.T) {
+	registry := NewServiceRegistry(WithDrainPeriod(time.Hour))
+	calls := &callLog{}
+	require.NoError(t, registry.RegisterNamedService("api", &drainingService{name: "api", log: calls}, nil))
+	require.NoError(t, registry.StartAll())
+	waitForNamedState(t, registry, "api", StateRunning)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- registry.StopAllWithContext(ctx)
+	}()
+	select {
+	case err := <-done:
+		assert.ErrorContains(t, "service abandoned", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Drain period did not respect the shutdown deadline")
+	}
+}
@@ -0,0 +1,43 @@
+package shared
+
+import (
+	"reflect"
+)
+
+// ReadyChecker is optionally implemented by services which can be alive but
+// not ready to serve yet, such as a service which is still syncing. Status
+// then reports whether the service is alive, and Ready whether it is ready.
+type ReadyChecker interface {
+	// Ready returns an error if the service is not ready to serve yet.
+	Ready() error
+}
+
+// readiness returns the status of a service if it is not alive, or the result
+// of its Ready method otherwise. Services which do not implement ReadyChecker
+// are ready as soon as they are alive.
+func (s *ServiceRegistry) readiness(entry *serviceEntry) error {
+	return s.readinessWith(entry, s.status)
+}
+
+// readinessWith is like readiness, but checks whether the service is alive
+// with the given function.
+func (s *ServiceRegistry) readinessWith(entry *serviceEntry, status func(entry *serviceEntry) error) error {
+	if err := status(entry); err != nil {
+		return err
+	}
+	r, ok := entry.service.(ReadyChecker)
+	if !ok {
+		return nil
+	}
+	err := r.Ready()
+	if err != nil && entry.cfg.Optional {
+		return &degradedError{err: err}
+	}
+	return err
+}
+
+// ReadyStatuses is like Statuses, but reports whether services are ready
+// rather than alive. A service which is not alive is not ready either.
+func (s *ServiceRegistry) ReadyStatuses() map[reflect.Type]error {
+	return s.statusesOf(s.snapshot(), s.readiness)
+}
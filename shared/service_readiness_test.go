@@ -0,0 +1,102 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// syncingService is alive, but only ready once it is synced.
+type syncingService struct {
+	mockService
+	lock   sync.Mutex
+	synced bool
+}
+
+func (s *syncingService) Ready() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.synced {
+		return errors.New("still syncing")
+	}
+	return nil
+}
+
+func (s *syncingService) setSynced() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.synced = true
+}
+
+func TestReadyStatuses(t *testing.T) {
+	registry := NewServiceRegistry()
+	syncing := &syncingService{}
+	require.NoError(t, registry.RegisterService(syncing))
+	require.NoError(t, registry.RegisterService(&secondMockService{}))
+	require.NoError(t, registry.RegisterService(&thirdMockService{status: errors.New("bad")}))
+
+	live := registry.Statuses()
+	assert.NoError(t, live[reflect.TypeOf(syncing)], "Expected a syncing service to be alive")
+	ready := registry.ReadyStatuses()
+	assert.ErrorContains(t, "still syncing", ready[reflect.TypeOf(syncing)])
+	assert.NoError(t, ready[reflect.TypeOf(&secondMockService{})], "Expected services without Ready to be ready when alive")
+	assert.ErrorContains(t, "bad", ready[reflect.TypeOf(&thirdMockService{})], "Expected a service which is not alive not to be ready")
+
+	syncing.setSynced()
+	assert.NoError(t, registry.ReadyStatuses()[reflect.TypeOf(syncing)])
+}
+
+func TestReadyzHandler(t *testing.T) {
+	registry := NewServiceRegistry()
+	syncing := &syncingService{}
+	require.NoError(t, registry.RegisterService(syncing))
+	require.NoError(t, registry.StartAll())
+
+	code, _ := serveHealthz(t, registry)
+	assert.Equal(t, http.StatusOK, code, "Expected a syncing node to be alive")
+	rr := httptest.NewRecorder()
+	registry.ReadyzHandler().ServeHTTP(rr, httptest.NewRequest("GET", "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+
+	syncing.setSynced()
+	rr = httptest.NewRecorder()
+	registry.ReadyzHandler().ServeHTTP(rr, httptest.NewRequest("GET", "/readyz", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestHealthServer_Readiness(t *testing.T) {
+	registry := NewServiceRegistry()
+	syncing := &syncingService{}
+	require.NoError(t, registry.RegisterService(syncing))
+	server := NewHealthServer(registry, 0)
+
+	resp, err := server.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, resp.Status)
+	resp, err = server.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: ReadinessServiceName})
+	require.NoError(t, err)
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, resp.Status)
+}
+
+func TestWaitForAllReady_WaitsForReadiness(t *testing.T) {
+	registry := NewServiceRegistry()
+	registry.SetReadyPollInterval(time.Millisecond)
+	syncing := &syncingService{}
+	require.NoError(t, registry.RegisterService(syncing))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorContains(t, "still syncing", registry.WaitForAllReady(ctx))
+
+	syncing.setSynced()
+	require.NoError(t, registry.WaitForAllReady(context.Background()))
+}
@@ -3,14 +3,34 @@
 package shared
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"reflect"
+	"runtime/debug"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/prysmaticlabs/prysm/shared/event"
+	"github.com/prysmaticlabs/prysm/shared/traceutil"
 	"github.com/sirupsen/logrus"
+	"go.opencensus.io/trace"
 )
 
 var log = logrus.WithField("prefix", "registry")
 
+// defaultReadyPollInterval is how often WaitForAllReady checks the status of
+// every registered service.
+const defaultReadyPollInterval = 500 * time.Millisecond
+
+// defaultStopTimeout is how long StopAll waits for a single service to stop
+// before moving on to the next one, unless configured with WithStopTimeout.
+const defaultStopTimeout = 10 * time.Second
+
 // Service is a struct that can be registered into a ServiceRegistry for
 // easy dependency management.
 type Service interface {
@@ -23,75 +43,1437 @@ type Service interface {
 	Status() error
 }
 
+// ServiceConfig defines optional registration parameters of a service.
+type ServiceConfig struct {
+	// Dependencies lists the types of the services which must be started
+	// before this one.
+	Dependencies []reflect.Type
+	// StopTimeout overrides how long StopAll waits for the service to stop.
+	StopTimeout time.Duration
+	// Priority orders the start of services: services with a lower priority
+	// are started first and stopped last. Services registered without a
+	// priority have priority 0, and ties are broken by registration order.
+	Priority int
+	// Groups tags the service with the groups it belongs to, which can be
+	// started and stopped on their own with StartGroup and StopGroup.
+	Groups []string
+	// Optional marks a service the node can run without. Its failures are
+	// logged and reported as degraded, and never make the process exit.
+	Optional bool
+	// StartRetry retries failed starts of the service when set.
+	StartRetry *StartRetryPolicy
+	// StartupDeadline, when set, is how long after StartAll the service is
+	// expected to report a healthy status. A warning is logged otherwise.
+	StartupDeadline time.Duration
+	// CrashLoop overrides when the watchdog stops restarting the service.
+	CrashLoop *CrashLoopPolicy
+	// Name is the display name of the service in logs, metrics, statuses and
+	// health endpoints, replacing the name derived from its type.
+	Name string
+	// Critical marks a service the node cannot run without. Its failed
+	// start, or a status which stays unhealthy for the critical threshold,
+	// is reported as a fatal error. A service cannot be both optional and
+	// critical.
+	Critical bool
+	// StatusDebounce, if set, keeps transient failures of the Status method
+	// of the service from making it unhealthy.
+	StatusDebounce *StatusDebouncePolicy
+}
+
+// serviceEntry holds a registered service along with its registration data.
+type serviceEntry struct {
+	name    string // only set for services registered by name.
+	kind    reflect.Type
+	service lifecycle
+	ctx     *ServiceContext
+	cfg     *ServiceConfig
+	// startErr records why the service could not be started, such as a
+	// panic, or that it was stopped by StopService, and is reported as its
+	// status.
+	startErr error
+	state    ServiceState
+	// startAttempts counts the consecutive failed starts of the service.
+	startAttempts int
+	// startedAt is when the service last finished starting.
+	startedAt time.Time
+	// fetches counts the fetches of the service, and is accessed atomically.
+	fetches int32
+	// metadata is collected from services implementing Describer when they
+	// are registered.
+	metadata *ServiceMetadata
+	// statusCache holds the last result of the Status method of the service.
+	statusCache statusCache
+	// heartbeat holds the error found with the heartbeat of the service by
+	// the status poller, if it implements Heartbeater.
+	heartbeat heartbeatState
+	// counters counts the restarts and failures of the service.
+	counters serviceCounters
+	// debounce holds the consecutive results of the Status method, for
+	// the StatusDebounce policy of the service.
+	debounce statusDebouncer
+	// statusCall holds the pending call to the Status method, which is
+	// waited for until statusTimeout elapsed.
+	statusCall    statusCall
+	statusTimeout time.Duration
+}
+
+// Named is optionally implemented by services which provide their own name,
+// used in statuses, logs, metrics, events and health endpoints instead of
+// their type.
+type Named interface {
+	Name() string
+}
+
+// String returns the name of the service: the name it was registered under,
+// its configured display name, the name it provides by implementing Named, or
+// the name of the type it was registered as otherwise.
+func (e *serviceEntry) String() string {
+	if e.name != "" {
+		return e.name
+	}
+	if e.cfg != nil && e.cfg.Name != "" {
+		return e.cfg.Name
+	}
+	if n, ok := e.service.(Named); ok && n.Name() != "" {
+		return n.Name()
+	}
+	return typeName(e.kind)
+}
+
+// serviceName returns the name the registry reports a service under before
+// it is registered: the name it provides by implementing Named, or the name
+// of its type otherwise.
+func serviceName(service interface{}) string {
+	if n, ok := service.(Named); ok && n.Name() != "" {
+		return n.Name()
+	}
+	return typeName(reflect.TypeOf(service))
+}
+
+// typeName returns the name of a service type without its pointer prefix,
+// qualified by the name of its package, as in kv.Store.
+func typeName(kind reflect.Type) string {
+	if kind == nil {
+		return "<nil>"
+	}
+	return strings.TrimLeft(kind.String(), "*")
+}
+
 // ServiceRegistry provides a useful pattern for managing services.
 // It allows for ease of dependency management and ensures services
 // dependent on others use the same references in memory.
+//
+// A ServiceRegistry is safe for concurrent use: services may be registered,
+// fetched and queried for their statuses from any goroutine. Once StartAll
+// was called, registering a service fails with ErrAlreadyStarted rather than
+// leaving a service which would never be started; use ReplaceService to swap
+// a running service.
 type ServiceRegistry struct {
-	services     map[reflect.Type]Service // map of types to services.
-	serviceTypes []reflect.Type           // keep an ordered slice of registered service types.
+	lock      sync.RWMutex
+	services  map[reflect.Type]*serviceEntry // map of types to services.
+	named     map[string]*serviceEntry       // map of names to services registered by name.
+	entries   []*serviceEntry                // keep an ordered slice of all registered services.
+	readyPoll time.Duration                  // interval between status checks in WaitForAllReady.
+	watchdog  *watchdog                      // optional watchdog restarting unhealthy services.
+	poller    *statusPoller                  // optional poller logging status changes.
+	// startPanicsFatal makes a panic during a service start crash the process
+	// instead of only marking the service as failed.
+	startPanicsFatal bool
+	// strictStopOrder makes StopAll stop one service at a time, or stop
+	// independent services concurrently. Unless set by SetStrictStopOrder,
+	// services are only stopped concurrently once some declared their
+	// dependencies or priorities.
+	strictStopOrder *bool
+	lazyCount       int           // number of services registered with RegisterLazy.
+	shutdown        chan struct{} // closed once StopAll began.
+	phase           registryPhase // how far the services were started.
+	stopping        bool          // set once StopAll began stopping the services.
+	startedHooks    map[reflect.Type][]func()
+	stoppedHooks    map[reflect.Type][]func()
+	bus             event.Bus // bus shared by services for notifications.
+	// root is the context service contexts created by the registry derive
+	// from, and cancelRoot cancels it.
+	root       *rootContext
+	cancelRoot context.CancelFunc
+	fatal      chan error // fatal errors stopping Run, of which only the first is kept.
+	// dependencyTimeout bounds how long a service waits for its
+	// dependencies to be ready, and dependencyFatal makes exceeding it fail
+	// the start of the service.
+	dependencyTimeout time.Duration
+	dependencyFatal   bool
+	events            registryEvents
+	stackDump         sync.Once     // logs the goroutine stacks on the first stop timeout.
+	log               *logrus.Entry // logger of the registry itself.
+	stopTimeout       time.Duration // stop timeout of the services configured without one.
+	statusTTL         time.Duration // how long results of Status calls are cached, if positive.
+	statusTimeout     time.Duration // how long the Status method of a service is waited for.
+	drainPeriod       time.Duration // how long StopAll waits between the PreStop and Stop passes.
+	skipPreStop       bool          // makes StopAll skip the PreStop pass.
+	closed            bool          // set once Close was called.
+	closeOnce         sync.Once
+	shutdownNotice    sync.Once // notifies the services implementing ShutdownListener.
+	closeErr          error     // result of the StopAll run by Close.
+	// heartbeatThreshold is how old the heartbeat of a running service can
+	// get before the status poller reports it as unhealthy.
+	heartbeatThreshold time.Duration
+	// stopGrace is how long into a Stop call the service context is
+	// cancelled, if positive.
+	stopGrace time.Duration
+	// fatalReported is set once a fatal error was delivered to Run.
+	fatalReported int32
+	// disabled holds the services registered while disabled.
+	disabled []*disabledService
+	// aliases maps the interface types services were registered as by
+	// RegisterServiceAs to the types they were registered with.
+	aliases map[reflect.Type]reflect.Type
+	// criticalThreshold is the number of consecutive unhealthy polls after
+	// which a critical service is reported as a fatal error.
+	criticalThreshold int
+	// preflightTimeout bounds the preflight checks run by StartAll.
+	preflightTimeout time.Duration
+	// startupReport collects the startup times of the services launched by
+	// StartAll, and is logged after startupReportDeadline at the latest.
+	startupReport         *startupReport
+	startupReportDeadline time.Duration
+	// now returns the current time, and is replaced by tests.
+	now func() time.Time
+	// startSlots holds a value for every service starting, when their
+	// number is limited by WithMaxConcurrentStarts.
+	startSlots chan struct{}
+	// leakCheck makes StartAll record the running goroutines in
+	// leakBaseline, for VerifyNoLeaks.
+	leakCheck    bool
+	leakBaseline map[string]bool
+	// subsystems are the child registries registered with
+	// RegisterSubsystem, guarded by subsystemLock.
+	subsystems []*ServiceRegistry
+	// subsystemErr is the error which prevented Start from starting the
+	// services of a subsystem.
+	subsystemErr error
+	// shutdownHooks are the cleanup functions StopAll calls once every
+	// service has stopped.
+	shutdownHooks []shutdownHook
+	// shuffleSeed is the seed the start order of services is shuffled
+	// with, zero if it is not shuffled.
+	shuffleSeed int64
+	// starting follows the services launched by StartAll until it returns,
+	// waiting for them at most for startWait. Services implementing Service
+	// are only waited for if legacyStartWait is set.
+	starting        *startWaiter
+	startWait       time.Duration
+	legacyStartWait bool
 }
 
 // NewServiceRegistry starts a registry instance for convenience
-func NewServiceRegistry() *ServiceRegistry {
-	return &ServiceRegistry{
-		services: make(map[reflect.Type]Service),
+func NewServiceRegistry(opts ...RegistryOption) *ServiceRegistry {
+	return NewServiceRegistryWithContext(context.Background(), opts...)
+}
+
+// NewServiceRegistryWithContext starts a registry whose root context derives
+// from the given context, so that its values, such as a logger, are visible to
+// every service context created by the registry, and its cancellation
+// cancels them.
+func NewServiceRegistryWithContext(ctx context.Context, opts ...RegistryOption) *ServiceRegistry {
+	root, cancel := context.WithCancel(ctx)
+	s := &ServiceRegistry{
+		services:     make(map[reflect.Type]*serviceEntry),
+		named:        make(map[string]*serviceEntry),
+		aliases:      make(map[reflect.Type]reflect.Type),
+		readyPoll:    defaultReadyPollInterval,
+		startedHooks: make(map[reflect.Type][]func()),
+		stoppedHooks: make(map[reflect.Type][]func()),
+		shutdown:     make(chan struct{}),
+		root:         &rootContext{Context: root},
+		cancelRoot:   cancel,
+		fatal:        make(chan error, 1),
+
+		dependencyTimeout: defaultDependencyTimeout,
+		events:            registryEvents{ch: make(chan RegistryEvent, registryEventsBuffer)},
+		log:               log,
+		stopTimeout:       defaultStopTimeout,
+		statusTimeout:     defaultStatusTimeout,
+
+		heartbeatThreshold: defaultHeartbeatThreshold,
+		criticalThreshold:  defaultCriticalThreshold,
+		preflightTimeout:   defaultPreflightTimeout,
+
+		startupReportDeadline: defaultStartupReportDeadline,
+		startWait:             defaultStartWaitTimeout,
+		now:                   time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// SetReadyPollInterval overrides how often WaitForAllReady polls the status
+// of the registered services.
+func (s *ServiceRegistry) SetReadyPollInterval(interval time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.readyPoll = interval
+}
+
+// SetStartPanicsFatal configures whether a panic while starting a service
+// crashes the process. By default the panic is recovered, logged, and the
+// service is marked as failed in Statuses. Panics of optional services never
+// crash the process.
+func (s *ServiceRegistry) SetStartPanicsFatal(fatal bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.startPanicsFatal = fatal
+}
+
+// EventBus returns the bus services can use to notify each other without
+// depending on one another directly.
+func (s *ServiceRegistry) EventBus() *event.Bus {
+	return &s.bus
+}
+
+// snapshot returns a copy of the ordered slice of registered services, so it
+// can be iterated over without holding the lock.
+func (s *ServiceRegistry) snapshot() []*serviceEntry {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	entries := make([]*serviceEntry, len(s.entries))
+	copy(entries, s.entries)
+	return entries
+}
+
+// ErrAlreadyStarted is returned by StartAll once it was called, and by the
+// registrations attempted after it.
+var ErrAlreadyStarted = errors.New("registry already started")
+
+var (
+	// ErrServiceAlreadyRegistered is wrapped by the errors of registrations
+	// of a type or name which is already registered.
+	ErrServiceAlreadyRegistered = errors.New("service already exists")
+	// ErrServiceNotFound is matched by the errors of operations targeting a
+	// service which is not registered, including *UnknownServiceError.
+	ErrServiceNotFound = errors.New("unknown service")
+	// ErrNotPointer is wrapped by the errors of fetches given a value rather
+	// than a pointer to set.
+	ErrNotPointer = errors.New("input must be of pointer type")
+)
+
+// StartAll initialized each service in order of priority and registration, making
+// sure any declared dependencies of a service are started before the service itself.
+// Lazy services are constructed first, then the registrations are checked by
+// Validate: an error listing every problem is returned, and no service is
+// started, if for instance a dependency was never registered or dependencies
+// form a cycle, in which case the error prints the full cycle. The preflight
+// checks of the services implementing Preflighter then run, and no service
+// is started either if any of them fails: the error lists every failed check.
+//
+// The time each service takes to be ready is exported as a metric and, when
+// traced, recorded in a span under a "node-start" span. It is also logged
+// once every service is ready or failed, or once the startup report deadline
+// elapsed, along with the slowest services, and returned by StartupReport.
+//
+// Start is called with the pprof label "service" set to the name of the
+// service, so that the goroutines it spawns, and the goroutines those spawn
+// in turn, can be told apart in goroutine and CPU profiles. Work a service
+// hands to goroutines it did not spawn, such as those of another service, is
+// only labeled if the service labels it itself with pprof.Do.
+//
+// A service with dependencies is only started once they are ready, see
+// SetDependencyTimeout. The number of services starting at once can be limited
+// with WithMaxConcurrentStarts.
+//
+// StartAll returns once the Start method of every ServiceV2 returned,
+// including the retried starts allowed by the StartRetry policy of a service,
+// without waiting for the services to be ready, or once the start wait timeout
+// set by WithStartWaitTimeout elapsed. The starts of services implementing
+// Service are only waited for if configured with WithLegacyStartWait. If a
+// service which is not optional fails to start in the meantime, the services
+// which were started are stopped again,
+// in reverse start order, the services which were not started yet are not,
+// and the error is returned. StopAll has nothing left to do afterwards.
+//
+// StartAll can only succeed once: later calls return ErrAlreadyStarted, even
+// after a failed start was rolled back. The services of groups started by
+// StartGroup before are not started again.
+func (s *ServiceRegistry) StartAll() error {
+	return s.StartAllWithContext(context.Background())
+}
+
+// StartAllWithContext is like StartAll, but links the root context of the
+// registry to the given context: its values and deadline become visible to
+// every service context, and cancelling it cancels every service context, as
+// CancelRoot does, unless StopAll was called first. If the context is done
+// before every service started, the services which were started are stopped
+// as if one of them failed to start.
+func (s *ServiceRegistry) StartAllWithContext(ctx context.Context) error {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return ErrRegistryClosed
+	}
+	if s.phase.startAllCalled() {
+		s.lock.Unlock()
+		return ErrAlreadyStarted
+	}
+	previous := s.phase
+	s.phase = phaseStarting
+	s.lock.Unlock()
+	s.constructLazy(func(*serviceEntry) bool { return true })
+	order, err := s.validatedStartOrder()
+	if err == nil {
+		err = s.preflightAll(ctx, order)
+	}
+	if err != nil {
+		// Nothing was started, so the registrations can be fixed.
+		s.lock.Lock()
+		s.phase = previous
+		s.lock.Unlock()
+		return err
+	}
+	s.recordLeakBaseline()
+	s.linkStartContext(ctx)
+	s.lock.Lock()
+	s.phase = phaseStarted
+	s.lock.Unlock()
+	if s.shuffleSeed != 0 {
+		s.log.WithField("seed", s.shuffleSeed).Infof("Shuffled the start order of services: %v", order)
+	}
+	s.log.Debugf("Starting %d services: %v", len(order), order)
+	startup := newStartupTrace()
+	defer startup.end()
+	launched := make([]*serviceEntry, 0, len(order))
+	for _, entry := range order {
+		// Services already started by StartGroup are left running.
+		if state := s.stateOf(entry); !entry.isLazy() && state != StateStarting && state != StateRunning && state != StatePaused {
+			launched = append(launched, entry)
+		}
+	}
+	report := s.newStartupReport(launched)
+	waiter := newStartWaiter(s.awaitedStarts(launched))
+	s.lock.Lock()
+	s.starting = waiter
+	s.lock.Unlock()
+	for _, entry := range launched {
+		s.log.Debugf("Starting service %v", entry)
+		traced := startup.serviceStarting(entry)
+		reported := report.serviceStarting(entry, s.now())
+		if s.startSlots == nil {
+			s.launchAfterDependencies(entry)
+		}
+		go s.observeStartup(entry, time.Now(), func(err error) {
+			traced(err)
+			reported(err)
+		})
+		if entry.cfg.StartupDeadline > 0 {
+			go s.checkStartupDeadline(entry, entry.cfg.StartupDeadline)
+		}
+	}
+	if s.startSlots != nil {
+		go s.launchWithinLimit(launched)
+	}
+	return s.waitStarted(ctx, waiter, launched)
+}
+
+// launch transitions a service to the starting state and calls its Start
+// method on a new goroutine.
+func (s *ServiceRegistry) launch(entry *serviceEntry) {
+	s.setState(entry, StateStarting)
+	go s.startService(entry)
+}
+
+// startService calls the Start method of a service, recovering from any
+// panic so the faulty service can be identified and its status reflects the
+// failure. An error returned by the Start method of a ServiceV2 is handled
+// as a panic, without being fatal.
+func (s *ServiceRegistry) startService(entry *serviceEntry) {
+	defer func() {
+		if r := recover(); r != nil {
+			stack := debug.Stack()
+			s.log.WithField("service", entry.String()).Errorf("Service panicked during start: %v\n%s", r, stack)
+			s.recordPanic(entry, r, stack)
+			retrying := s.startFailed(entry, fmt.Errorf("service panicked during start: %v", r))
+			s.lock.RLock()
+			fatal := s.startPanicsFatal && !entry.cfg.Optional && !retrying
+			s.lock.RUnlock()
+			if fatal {
+				panic(r)
+			}
+		}
+	}()
+	s.lock.RLock()
+	ctx := entry.ctx
+	s.lock.RUnlock()
+	entry.statusCache.invalidate()
+	entry.debounce.reset()
+	entry.heartbeat.set(nil)
+	// Goroutines spawned by Start inherit the label, and so do the
+	// goroutines they spawn in turn.
+	var err error
+	pprof.Do(ctx, pprof.Labels("service", entry.String()), func(context.Context) {
+		err = entry.start(ctx)
+	})
+	if err != nil {
+		s.log.WithField("service", entry.String()).WithError(err).Error("Could not start service")
+		s.startFailed(entry, err)
+		return
+	}
+	s.lock.Lock()
+	// The service may have been stopped while Start was still running.
+	running := entry.state == StateStarting
+	if running {
+		entry.state = StateRunning
+		entry.startErr = nil
+		entry.startAttempts = 0
+		entry.startedAt = time.Now()
+	}
+	waiter := s.starting
+	s.lock.Unlock()
+	if !running {
+		waiter.settle(entry, errStoppedWhileStarting)
+		return
+	}
+	s.emit(ServiceStarted, entry, nil)
+	s.runHooks(entry, s.startedHooks)
+	waiter.settle(entry, nil)
+}
+
+// validatedStartOrder validates the registrations, then returns the start
+// order of the services.
+func (s *ServiceRegistry) validatedStartOrder() ([]*serviceEntry, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return s.startOrder()
+}
+
+// startOrder computes a topological ordering of the registered services based
+// on their declared dependencies. Services without dependencies keep their
+// relative order of registration.
+func (s *ServiceRegistry) startOrder() ([]*serviceEntry, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	order := make([]*serviceEntry, 0, len(s.entries))
+	visited := make(map[*serviceEntry]bool, len(s.entries))
+	// path holds the chain of services being visited, to report cycles.
+	var path []*serviceEntry
+	var visit func(entry *serviceEntry) error
+	visit = func(entry *serviceEntry) error {
+		if visited[entry] {
+			return nil
+		}
+		for i, e := range path {
+			if e == entry {
+				return fmt.Errorf("circular dependency detected: %s", cyclePath(append(path[i:], entry)))
+			}
+		}
+		path = append(path, entry)
+		for _, dep := range entry.cfg.Dependencies {
+			depEntry, exists := s.services[dep]
+			if !exists {
+				return fmt.Errorf("service %v depends on unregistered service: %s", entry, typeName(dep))
+			}
+			if err := visit(depEntry); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		visited[entry] = true
+		order = append(order, entry)
+		return nil
+	}
+	for _, entry := range s.shuffled(byPriority(s.entries)) {
+		if err := visit(entry); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// cyclePath formats a chain of services depending on each other.
+func cyclePath(entries []*serviceEntry) string {
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.String()
+	}
+	return strings.Join(names, " -> ")
+}
+
+// byPriority returns a copy of the given services sorted by priority, keeping
+// services of equal priority in their original order.
+func byPriority(entries []*serviceEntry) []*serviceEntry {
+	sorted := make([]*serviceEntry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].cfg.Priority < sorted[j].cfg.Priority
+	})
+	return sorted
+}
+
+// StopAll ends every service, recovering and reporting a panic in the Stop
+// method of any of them. Services are stopped one at a time, in reverse start
+// order so that consumers are stopped before the services they depend on,
+// falling back to reverse order of priority and registration if dependencies
+// cannot be ordered. Once any service declared its dependencies or priority,
+// which tells which services are independent, services are stopped
+// concurrently instead, except that a service is only stopped once the
+// services depending on it, and those with a higher priority started after
+// it, have stopped. SetStrictStopOrder picks either behavior explicitly. Each
+// service is given its stop timeout to terminate, after which its context is
+// cancelled and StopAll moves on. The errors of every service which failed
+// to stop in time are returned as a *MultiError.
+//
+// The reason of the shutdown, ShutdownNormal unless one is given, is passed
+// on to every service through its service context, as read by
+// ShutdownReasonFromContext.
+func (s *ServiceRegistry) StopAll(reason ...ShutdownReason) error {
+	r := ShutdownNormal
+	if len(reason) > 0 {
+		r = reason[0]
+	}
+	return s.StopAllWithContext(WithShutdownReason(context.Background(), r))
+}
+
+// StopAllWithContext is like StopAll, but bounds the whole shutdown by the
+// given context. Once the context is done, StopAllWithContext no longer waits
+// for Stop to return: the services which are not stopped yet have their
+// service context cancelled immediately, and are reported as abandoned with
+// the context error in the returned *MultiError.
+//
+// Each Stop call is recorded in a span, under a "node-stop" span nested in any
+// span of the given context. The reason of the shutdown is read from the
+// context with ShutdownReasonFromContext, defaulting to ShutdownNormal.
+//
+// Before any service is stopped, the services implementing ShutdownListener
+// are all told the shutdown began, each given up to a second to return. Then
+// the services implementing PreStopper are told to stop accepting new work,
+// in reverse order of registration, and given the drain period set by
+// WithDrainPeriod to complete their in-flight work, unless the registry was
+// created WithoutPreStop.
+func (s *ServiceRegistry) StopAllWithContext(ctx context.Context) error {
+	s.lock.Lock()
+	if s.phase == phaseRolledBack {
+		// StartAll already stopped the services it started.
+		s.lock.Unlock()
+		return nil
+	}
+	s.beginStopping()
+	s.lock.Unlock()
+	return s.stopServices(ctx, nil)
+}
+
+// beginStopping marks the registry as stopping, so that no service is started
+// anymore. The caller must hold the lock.
+func (s *ServiceRegistry) beginStopping() {
+	if !s.stopping {
+		close(s.shutdown)
+	}
+	s.stopping = true
+}
+
+// stopServices stops every service as StopAll does or, when StartAll rolls
+// back, only the given services, in reverse order and without telling the
+// services a shutdown began beforehand.
+func (s *ServiceRegistry) stopServices(ctx context.Context, rollback []*serviceEntry) error {
+	s.lock.Lock()
+	strict := s.stopsStrictly()
+	drain, skipPreStop := s.drainPeriod, s.skipPreStop
+	w, p := s.watchdog, s.poller
+	s.watchdog, s.poller = nil, nil
+	s.lock.Unlock()
+	if w != nil {
+		w.stop()
+	}
+	if p != nil {
+		p.stop()
+	}
+	reason := shutdownReason(ctx)
+	ctx = WithShutdownReason(ctx, reason)
+	s.log.WithField("reason", reason).Info("Stopping services")
+	ctx, span := trace.StartSpan(ctx, "node-stop")
+	defer span.End()
+	span.AddAttributes(trace.StringAttribute("reason", string(reason)))
+	errs := &MultiError{}
+	if rollback != nil {
+		for i := len(rollback) - 1; i >= 0; i-- {
+			errs.add(s.stopAndLog(ctx, rollback[i]))
+		}
+		return s.finishStop(ctx, span, errs)
+	}
+	s.notifyShutdown(ctx)
+	if !skipPreStop {
+		s.preStopAll(ctx, drain)
+	}
+	order, err := s.startOrder()
+	if strict || err != nil {
+		// Stopping in reverse start order stops consumers before the
+		// services they depend on, whatever their order of registration.
+		entries := order
+		if err != nil {
+			entries = byPriority(s.snapshot())
+		}
+		for i := len(entries) - 1; i >= 0; i-- {
+			errs.add(s.stopAndLog(ctx, entries[i]))
+		}
+	} else {
+		s.stopConcurrently(ctx, order, errs)
+	}
+	return s.finishStop(ctx, span, errs)
+}
+
+// finishStop runs the shutdown hooks once the services stopped, and closes
+// the events channel.
+func (s *ServiceRegistry) finishStop(ctx context.Context, span *trace.Span, errs *MultiError) error {
+	s.runShutdownHooks(ctx, errs)
+	err := errs.errorOrNil()
+	traceutil.AnnotateError(span, err)
+	s.closeEvents()
+	return err
+}
+
+// stopConcurrently stops the given services, sorted in start order, each on
+// its own goroutine waiting for the services which must be stopped before it.
+// Every such service comes later in the start order, so the waits cannot
+// form a cycle. Once the context is done, services stop waiting and are
+// abandoned right away.
+func (s *ServiceRegistry) stopConcurrently(ctx context.Context, order []*serviceEntry, errs *MultiError) {
+	done := make(map[*serviceEntry]chan struct{}, len(order))
+	for _, entry := range order {
+		done[entry] = make(chan struct{})
+	}
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	for i, entry := range order {
+		var waitFor []chan struct{}
+		for _, later := range order[i+1:] {
+			if later.cfg.Priority > entry.cfg.Priority || later.dependsOn(entry) {
+				waitFor = append(waitFor, done[later])
+			}
+		}
+		wg.Add(1)
+		go func(entry *serviceEntry, waitFor []chan struct{}) {
+			defer wg.Done()
+			defer close(done[entry])
+			for _, ch := range waitFor {
+				select {
+				case <-ch:
+				case <-ctx.Done():
+				}
+			}
+			err := s.stopAndLog(ctx, entry)
+			lock.Lock()
+			errs.add(err)
+			lock.Unlock()
+		}(entry, waitFor)
+	}
+	wg.Wait()
+}
+
+// stopAndLog stops a service, logging how long it took or why it failed, and
+// records the duration of the Stop call in a metric.
+func (s *ServiceRegistry) stopAndLog(ctx context.Context, entry *serviceEntry) error {
+	start := time.Now()
+	err := s.traceStop(ctx, entry)
+	duration := time.Since(start)
+	serviceStopDuration.WithLabelValues(entry.String()).Observe(duration.Seconds())
+	if err != nil {
+		s.log.WithError(err).WithField("duration", duration).Errorf("Could not stop the following service: %v", entry)
+		return fmt.Errorf("%v: %w", entry, err)
+	}
+	s.log.WithField("duration", duration).Infof("Stopped service %v", entry)
+	return nil
+}
+
+// dependsOn returns whether a service declared the other one as a dependency.
+func (e *serviceEntry) dependsOn(other *serviceEntry) bool {
+	if other.name != "" {
+		return false
+	}
+	for _, dep := range e.cfg.Dependencies {
+		if dep == other.kind {
+			return true
+		}
+	}
+	return false
+}
+
+// SetStrictStopOrder configures whether StopAll stops one service at a time,
+// in reverse start order, rather than stopping independent services
+// concurrently, whether or not the services declared their dependencies.
+func (s *ServiceRegistry) SetStrictStopOrder(strict bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.strictStopOrder = &strict
+}
+
+// stopsStrictly returns whether StopAll stops one service at a time. Unless
+// configured otherwise, services which did not declare any dependency or
+// priority may still rely on being stopped in reverse start order, so they are
+// only stopped concurrently once some service declared them. The caller must
+// hold the lock.
+func (s *ServiceRegistry) stopsStrictly() bool {
+	if s.strictStopOrder != nil {
+		return *s.strictStopOrder
+	}
+	for _, entry := range s.entries {
+		if len(entry.cfg.Dependencies) > 0 || entry.cfg.Priority != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// RestartService stops the service of the given type, cancels its context and
+// starts it again on a new goroutine with a fresh service context. The same
+// service instance is restarted, so pointers previously obtained through
+// FetchService remain valid. The service is not started again if it could not
+// be stopped.
+func (s *ServiceRegistry) RestartService(kind reflect.Type) error {
+	s.lock.RLock()
+	entry, ok := s.services[kind]
+	closed := s.closed
+	s.lock.RUnlock()
+	if closed {
+		return fmt.Errorf("could not restart service %s: %w", typeName(kind), ErrRegistryClosed)
+	}
+	if !ok {
+		return &UnknownServiceError{Kind: kind}
+	}
+	return s.restartService(entry)
+}
+
+func (s *ServiceRegistry) restartService(entry *serviceEntry) error {
+	if entry.isLazy() {
+		return fmt.Errorf("could not restart service %v: %w", entry, ErrServiceNotConstructed)
+	}
+	s.log.Debugf("Restarting service %v", entry)
+	if err := s.stopService(WithShutdownReason(context.Background(), ShutdownRestart), entry); err != nil {
+		return fmt.Errorf("could not stop service %v: %w", entry, err)
+	}
+	s.lock.Lock()
+	entry.ctx = entry.ctx.renew()
+	entry.startErr = nil
+	entry.startAttempts = 0
+	s.lock.Unlock()
+	entry.counters.restarted()
+	s.launch(entry)
+	return nil
+}
+
+// stopService stops a registered service, waiting at most for its stop
+// timeout or until the given context is done, and cancels the service
+// context unless it is shared with another service which is still active. A
+// panic in Stop is recovered and returned as an error, so that StopAll goes
+// on stopping the other services. The shutdown reason of the given context is
+// recorded in the service context before Stop is called.
+func (s *ServiceRegistry) stopService(parent context.Context, entry *serviceEntry) (err error) {
+	s.lock.Lock()
+	serviceCtx := entry.ctx
+	entry.state = StateStopping
+	s.lock.Unlock()
+	serviceCtx.setShutdownReason(shutdownReason(parent))
+	defer func() {
+		if !s.contextInUse(entry) {
+			serviceCtx.Cancel()
+		}
+		s.setState(entry, StateStopped)
+		if err != nil {
+			s.failed(entry, err)
+		}
+		s.emit(ServiceStopped, entry, nil)
+		s.runHooks(entry, s.stoppedHooks)
+	}()
+	timeout := s.stopTimeoutOf(entry)
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	stopped := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				stack := debug.Stack()
+				s.log.WithField("service", entry.String()).Errorf("Service panicked during stop: %v\n%s", r, stack)
+				s.recordPanic(entry, r, stack)
+				stopped <- fmt.Errorf("service panicked during stop: %v", r)
+			}
+		}()
+		stopped <- entry.service.Stop()
+	}()
+	var escalate <-chan time.Time
+	if grace := s.stopGracePeriod(); grace > 0 && grace < timeout {
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+		escalate = timer.C
+	}
+	start := time.Now()
+	escalated := false
+	for {
+		select {
+		case err := <-stopped:
+			if escalated {
+				s.log.WithField("duration", time.Since(start)).Infof("Service %v returned from Stop once its context was cancelled", entry)
+			}
+			if waitErr := s.waitGoroutines(ctx, entry, serviceCtx, timeout); err == nil {
+				err = waitErr
+			}
+			return err
+		case <-escalate:
+			escalate = nil
+			escalated = s.escalateStop(entry, serviceCtx, time.Since(start), timeout)
+		case <-ctx.Done():
+			if err := parent.Err(); err != nil {
+				return fmt.Errorf("service abandoned: %w", err)
+			}
+			s.dumpStacks(entry, timeout)
+			return fmt.Errorf("service did not stop within %v", timeout)
+		}
 	}
 }
 
-// StartAll initialized each service in order of registration.
-func (s *ServiceRegistry) StartAll() {
-	log.Debugf("Starting %d services: %v", len(s.serviceTypes), s.serviceTypes)
-	for _, kind := range s.serviceTypes {
-		log.Debugf("Starting service type %v", kind)
-		go s.services[kind].Start()
+// stopTimeoutOf returns the stop timeout of a service, falling back to the
+// stop timeout of the registry.
+func (s *ServiceRegistry) stopTimeoutOf(entry *serviceEntry) time.Duration {
+	if entry.cfg.StopTimeout > 0 {
+		return entry.cfg.StopTimeout
 	}
+	return s.stopTimeout
 }
 
-// StopAll ends every service in reverse order of registration, logging a
-// panic if any of them fail to stop.
-func (s *ServiceRegistry) StopAll() {
-	for i := len(s.serviceTypes) - 1; i >= 0; i-- {
-		kind := s.serviceTypes[i]
-		service := s.services[kind]
-		if err := service.Stop(); err != nil {
-			log.WithError(err).Errorf("Could not stop the following service: %v", kind)
+// contextInUse returns whether the service context of a service is shared
+// with another service which is still active.
+func (s *ServiceRegistry) contextInUse(entry *serviceEntry) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	for _, e := range s.entries {
+		if e == entry || e.ctx != entry.ctx {
+			continue
+		}
+		switch e.state {
+		case StateStarting, StateRunning, StatePaused:
+			return true
 		}
 	}
+	return false
+}
+
+// status returns the error which prevented the service from starting, if any,
+// or the result of its Status method otherwise. A service which is stopping
+// or stopped reports its state as an error, and a paused service reports
+// ErrServicePaused without its Status method being called. Errors of optional
+// services are reported as degraded.
+func (s *ServiceRegistry) status(entry *serviceEntry) error {
+	s.lock.RLock()
+	startErr, state := entry.startErr, entry.state
+	s.lock.RUnlock()
+	return entryStatus(entry, startErr, state)
+}
+
+// entryStatus computes the status of a service from its start error and
+// state, which the caller read under the lock.
+func entryStatus(entry *serviceEntry, startErr error, state ServiceState) error {
+	return entryStatusWith(entry, startErr, state, entry.checkStatus)
+}
+
+// entryStatusWith is like entryStatus, but calls the given function rather
+// than the Status method of the service.
+func entryStatusWith(entry *serviceEntry, startErr error, state ServiceState, status func() error) error {
+	if stale := entry.heartbeat.get(); stale != nil {
+		check := status
+		status = func() error {
+			if err := check(); err != nil {
+				return err
+			}
+			return stale
+		}
+	}
+	err := serviceStatus(startErr, state, status)
+	if err != nil && entry.cfg.Optional && !isIntentional(err) {
+		return &degradedError{err: err}
+	}
+	return err
+}
+
+func serviceStatus(startErr error, state ServiceState, status func() error) error {
+	if startErr != nil {
+		return startErr
+	}
+	if state == StatePaused {
+		return ErrServicePaused
+	}
+	if err := status(); err != nil {
+		return err
+	}
+	if state == StateStopping || state == StateStopped {
+		return fmt.Errorf("service is %v", state)
+	}
+	return nil
 }
 
 // Statuses returns a map of Service type -> error. The map will be populated
-// with the results of each service.Status() method call.
-func (s *ServiceRegistry) Statuses() map[reflect.Type]error {
-	m := make(map[reflect.Type]error, len(s.serviceTypes))
-	for _, kind := range s.serviceTypes {
-		m[kind] = s.services[kind].Status()
+// with the results of each service.Status() method call, or with an error
+// reporting the state of services which are stopping or stopped, and with
+// ErrServicePaused for paused services. Services registered
+// by name are reported under their type, which is considered unhealthy if any
+// of its instances is.
+//
+// The results of Status calls are cached when SetStatusCacheTTL was called,
+// unless the ForceRefresh option is given. The services of subsystems are
+// reported under their own type, their errors prefixed with the name of the
+// subsystem. A Status call which does not return within the status timeout
+// of the registry is reported as ErrStatusTimeout, and a panic in it as an
+// error.
+func (s *ServiceRegistry) Statuses(opts ...StatusOption) map[reflect.Type]error {
+	entries, subsystems := subsystemsOf(s.snapshot())
+	m := s.statusesOf(entries, s.statusCheck(opts))
+	addSubsystemStatuses(m, subsystems, opts)
+	return m
+}
+
+// statusesOf reports the result of the given check for every service, folding
+// named services under their type.
+func (s *ServiceRegistry) statusesOf(entries []*serviceEntry, check func(entry *serviceEntry) error) map[reflect.Type]error {
+	m := make(map[reflect.Type]error, len(entries))
+	for _, entry := range entries {
+		err := check(entry)
+		if err != nil && entry.name != "" {
+			err = fmt.Errorf("%s: %w", entry.name, err)
+		}
+		if m[entry.kind] == nil {
+			m[entry.kind] = err
+		}
 	}
 	return m
 }
 
+// StatusesByName is like Statuses, but reports every service under its name,
+// as logged and served by the health endpoints, rather than under its type.
+// The services of a subsystem are named after it, as in "p2p/p2p.Service",
+// and a subsystem whose services could not be started is reported under its
+// own name.
+func (s *ServiceRegistry) StatusesByName(opts ...StatusOption) map[string]error {
+	entries, subsystems := subsystemsOf(s.snapshot())
+	check := s.statusCheck(opts)
+	m := make(map[string]error, len(entries))
+	for _, entry := range entries {
+		m[entry.String()] = check(entry)
+	}
+	for _, entry := range subsystems {
+		child := entry.service.(*ServiceRegistry)
+		if err := child.startError(); err != nil {
+			m[entry.String()] = err
+		}
+		for name, err := range child.StatusesByName(opts...) {
+			m[entry.String()+"/"+name] = err
+		}
+	}
+	return m
+}
+
+// WaitForAllReady blocks until every registered service is ready, as
+// reported by ReadyStatuses, or until the context is done. In the latter case, the returned
+// error lists the services which were still unhealthy. Optional services do
+// not need to be ready.
+func (s *ServiceRegistry) WaitForAllReady(ctx context.Context) error {
+	s.lock.RLock()
+	interval := s.readyPoll
+	s.lock.RUnlock()
+	if interval <= 0 {
+		interval = defaultReadyPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		var unhealthy []string
+		for _, entry := range s.snapshot() {
+			entry := entry
+			err := checkStatus(func() error { return s.readiness(entry) }, interval)
+			if err != nil && StatusSeverity(err) != SeverityDegraded && !errors.Is(err, ErrServiceStopped) {
+				unhealthy = append(unhealthy, fmt.Sprintf("%v: %v", entry, err))
+			}
+		}
+		if len(unhealthy) == 0 {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return fmt.Errorf("services not ready (%s): %w", strings.Join(unhealthy, ", "), ctx.Err())
+		}
+	}
+}
+
+// checkStatus calls the status function of a service, converting a panic into
+// an error and giving up on calls which do not return within the timeout.
+func checkStatus(status func() error, timeout time.Duration) error {
+	result := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				result <- fmt.Errorf("status check panicked: %v", r)
+			}
+		}()
+		result <- status()
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("status check did not return within %v", timeout)
+	}
+}
+
+// checkStatuses runs the given check for every service concurrently, so that
+// the time hung services take does not add up, and returns their results in
+// order. The checks which did not return once the context is done report it,
+// and the checks which panicked report the panic.
+func checkStatuses(ctx context.Context, entries []*serviceEntry, check func(entry *serviceEntry) error) []error {
+	results := make([]chan error, len(entries))
+	for i, entry := range entries {
+		result := make(chan error, 1)
+		results[i] = result
+		go func(entry *serviceEntry) {
+			defer func() {
+				if r := recover(); r != nil {
+					result <- fmt.Errorf("status check panicked: %v", r)
+				}
+			}()
+			result <- check(entry)
+		}(entry)
+	}
+	errs := make([]error, len(entries))
+	for i, result := range results {
+		select {
+		case errs[i] = <-result:
+			continue
+		default:
+		}
+		select {
+		case errs[i] = <-result:
+		case <-ctx.Done():
+			errs[i] = fmt.Errorf("status check did not return: %w", ctx.Err())
+		}
+	}
+	return errs
+}
+
 // RegisterService appends a service constructor function to the service
-// registry.
-func (s *ServiceRegistry) RegisterService(service Service) error {
+// registry, optionally tagging it with the groups it belongs to.
+func (s *ServiceRegistry) RegisterService(service Service, groups ...string) error {
+	return s.RegisterServiceWithConfig(service, nil, &ServiceConfig{Groups: groups})
+}
+
+// RegisterServiceWithDeps registers a service along with the types of the
+// services it depends on. StartAll guarantees the dependencies are started
+// before the service, regardless of the order in which they were registered.
+func (s *ServiceRegistry) RegisterServiceWithDeps(service Service, deps ...reflect.Type) error {
+	return s.RegisterServiceWithConfig(service, nil, &ServiceConfig{Dependencies: deps})
+}
+
+// RegisterServiceWithPriority appends a service constructor function to the
+// service registry with the given start priority. Services with a lower
+// priority are started first and stopped last.
+func (s *ServiceRegistry) RegisterServiceWithPriority(service Service, priority int) error {
+	return s.RegisterServiceWithConfig(service, nil, &ServiceConfig{Priority: priority})
+}
+
+// RegisterServiceWithName appends a service constructor function to the
+// service registry with the given display name, which must not be used by
+// another service. The service is still fetched by type.
+func (s *ServiceRegistry) RegisterServiceWithName(service Service, name string) error {
+	if name == "" {
+		return fmt.Errorf("service name cannot be empty: %s", typeName(reflect.TypeOf(service)))
+	}
+	return s.RegisterServiceWithConfig(service, nil, &ServiceConfig{Name: name})
+}
+
+// RegisterServiceWithConfig registers a service together with the context it
+// was constructed with and its registration config. If ctx is nil, a new
+// service context derived from the root context is created for the service.
+func (s *ServiceRegistry) RegisterServiceWithConfig(service Service, ctx *ServiceContext, cfg *ServiceConfig) error {
+	if service == nil {
+		return errNilService
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.register(service, ctx, cfg)
+}
+
+// register registers a service as RegisterServiceWithConfig does. The caller
+// must hold the registry lock.
+func (s *ServiceRegistry) register(service lifecycle, ctx *ServiceContext, cfg *ServiceConfig) error {
+	if s.closed {
+		return fmt.Errorf("could not register service %s: %w", serviceName(service), ErrRegistryClosed)
+	}
+	if s.phase.startAllCalled() {
+		return fmt.Errorf("could not register service %s: %w", serviceName(service), ErrAlreadyStarted)
+	}
+	if _, ok := service.(*ServiceRegistry); ok {
+		return errors.New("registries must be registered with RegisterSubsystem")
+	}
 	kind := reflect.TypeOf(service)
 	if _, exists := s.services[kind]; exists {
-		return fmt.Errorf("service already exists: %v", kind)
+		return fmt.Errorf("%w: %s", ErrServiceAlreadyRegistered, typeName(kind))
+	}
+	entry := s.newServiceEntry(service, ctx, cfg)
+	if err := s.checkNameAvailable(entry); err != nil {
+		return err
 	}
-	s.services[kind] = service
-	s.serviceTypes = append(s.serviceTypes, kind)
+	entry.ctx.setLogger(entry.String())
+	entry.ctx.setFatalReporter(entry.String(), s.reportFatal)
+	s.services[kind] = entry
+	s.entries = append(s.entries, entry)
+	s.emit(ServiceRegistered, entry, nil)
 	return nil
 }
 
+// RegisterNamedService registers a service under a name rather than under its
+// type, which allows several instances of the same type to be registered. If
+// ctx is nil, a new service context derived from the root context is created
+// for the service. A registry registered this way is a subsystem, as with
+// RegisterSubsystem.
+func (s *ServiceRegistry) RegisterNamedService(name string, service Service, ctx *ServiceContext) (err error) {
+	if name == "" {
+		return fmt.Errorf("service name cannot be empty: %s", typeName(reflect.TypeOf(service)))
+	}
+	if service == nil {
+		return errNilService
+	}
+	if child, ok := service.(*ServiceRegistry); ok {
+		if err := s.addSubsystem(name, child); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				s.removeSubsystem(child)
+			}
+		}()
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return fmt.Errorf("could not register service %s: %w", name, ErrRegistryClosed)
+	}
+	if s.phase.startAllCalled() {
+		return fmt.Errorf("could not register service %s: %w", name, ErrAlreadyStarted)
+	}
+	if _, exists := s.named[name]; exists {
+		return fmt.Errorf("%w: %s", ErrServiceAlreadyRegistered, name)
+	}
+	entry := s.newServiceEntry(service, ctx, &ServiceConfig{})
+	entry.name = name
+	if err := s.checkNameAvailable(entry); err != nil {
+		return err
+	}
+	entry.ctx.setLogger(name)
+	entry.ctx.setFatalReporter(name, s.reportFatal)
+	s.named[name] = entry
+	s.entries = append(s.entries, entry)
+	s.emit(ServiceRegistered, entry, nil)
+	return nil
+}
+
+// checkNameAvailable returns an error if another registered service has the
+// same name as the given one. The caller must hold the lock.
+func (s *ServiceRegistry) checkNameAvailable(entry *serviceEntry) error {
+	name := entry.String()
+	for _, e := range s.entries {
+		if e.String() == name {
+			return fmt.Errorf("service name %s is already used by %s", name, typeName(e.kind))
+		}
+	}
+	return nil
+}
+
+func (s *ServiceRegistry) newServiceEntry(service lifecycle, ctx *ServiceContext, cfg *ServiceConfig) *serviceEntry {
+	if ctx == nil {
+		ctx = s.NewServiceContext()
+	}
+	if cfg == nil {
+		cfg = &ServiceConfig{}
+	}
+	return &serviceEntry{
+		kind:     reflect.TypeOf(service),
+		service:  service,
+		ctx:      ctx,
+		cfg:      cfg,
+		metadata: s.describe(service),
+
+		statusTimeout: s.statusTimeout,
+	}
+}
+
+// UnknownServiceError is returned when an operation targets a service type
+// which is not registered. It matches ErrServiceNotFound.
+type UnknownServiceError struct {
+	Kind reflect.Type
+	// Err optionally tells why the service may be missing, such as
+	// ErrServiceNotConstructed.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *UnknownServiceError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("unknown service: %s: %v", typeName(e.Kind), e.Err)
+	}
+	return fmt.Sprintf("unknown service: %s", typeName(e.Kind))
+}
+
+// Unwrap returns the reason why the service may be missing, if any.
+func (e *UnknownServiceError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches ErrServiceNotFound.
+func (e *UnknownServiceError) Is(target error) bool {
+	return target == ErrServiceNotFound
+}
+
+// UnregisterService removes the service of the given type from the registry
+// and cancels its service context. A service which was started must be
+// stopped first, otherwise an error is returned and the service is left
+// registered. An *UnknownServiceError is returned if no service of the given
+// type is registered.
+func (s *ServiceRegistry) UnregisterService(kind reflect.Type) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	entry, ok := s.services[kind]
+	if !ok {
+		return &UnknownServiceError{Kind: kind}
+	}
+	if entry.state != StateRegistered && entry.state != StateStopped {
+		return fmt.Errorf("cannot unregister service %v while it is %v", entry, entry.state)
+	}
+	s.removeEntry(entry)
+	return nil
+}
+
+// StopAndUnregisterService stops the service of the given type if it is
+// running, then removes it from the registry. The service is only removed
+// once it stopped successfully.
+func (s *ServiceRegistry) StopAndUnregisterService(kind reflect.Type) error {
+	s.lock.RLock()
+	entry, ok := s.services[kind]
+	s.lock.RUnlock()
+	if !ok {
+		return &UnknownServiceError{Kind: kind}
+	}
+	if state := s.stateOf(entry); state != StateRegistered && state != StateStopped {
+		if err := s.stopService(context.Background(), entry); err != nil {
+			return fmt.Errorf("could not stop service %v: %w", entry, err)
+		}
+	}
+	return s.UnregisterService(kind)
+}
+
+// removeEntry cancels the context of a service and drops it from every
+// lookup structure. The caller must hold the write lock.
+func (s *ServiceRegistry) removeEntry(entry *serviceEntry) {
+	entry.ctx.Cancel()
+	delete(s.services, entry.kind)
+	if entry.name != "" {
+		delete(s.named, entry.name)
+	}
+	for i, e := range s.entries {
+		if e == entry {
+			s.entries = append(s.entries[:i], s.entries[i+1:]...)
+			break
+		}
+	}
+}
+
 // FetchService takes in a struct pointer and sets the value of that pointer
 // to a service currently stored in the service registry. This ensures the input argument is
 // set to the right pointer that refers to the originally registered service.
+//
+// If the input is a pointer to an interface, it is set to the service
+// registered as that interface by RegisterServiceAs, or else to the registered
+// service implementing that interface. An error is returned if more than one
+// registered service implements it. Services missing from the registry are then
+// looked for in its subsystems.
 func (s *ServiceRegistry) FetchService(service interface{}) error {
+	err := s.fetchService(service)
+	if errors.Is(err, ErrServiceNotFound) && s.fetchFromSubsystems(service) {
+		return nil
+	}
+	return err
+}
+
+func (s *ServiceRegistry) fetchService(service interface{}) error {
 	if reflect.TypeOf(service).Kind() != reflect.Ptr {
-		return fmt.Errorf("input must be of pointer type, received value type instead: %T", service)
+		return fmt.Errorf("%w, received value type instead: %T", ErrNotPointer, service)
+	}
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if s.closed {
+		return ErrRegistryClosed
 	}
 	element := reflect.ValueOf(service).Elem()
-	if running, ok := s.services[element.Type()]; ok {
-		element.Set(reflect.ValueOf(running))
+	if entry, ok := s.services[element.Type()]; ok {
+		if !reflect.TypeOf(entry.service).AssignableTo(element.Type()) {
+			return fmt.Errorf("service %v is overridden by %s", entry, typeName(reflect.TypeOf(entry.service)))
+		}
+		if entry.state == StateStopped {
+			s.log.Warnf("Fetching stopped service %v", entry)
+		}
+		atomic.AddInt32(&entry.fetches, 1)
+		element.Set(reflect.ValueOf(entry.service))
 		return nil
 	}
-	return fmt.Errorf("unknown service: %T", service)
+	if kind, ok := s.aliases[element.Type()]; ok {
+		return s.fetchByAlias(element, kind)
+	}
+	if element.Kind() == reflect.Interface {
+		return s.fetchByInterface(element)
+	}
+	if s.hasPendingLazy() {
+		return &UnknownServiceError{Kind: element.Type(), Err: ErrServiceNotConstructed}
+	}
+	return &UnknownServiceError{Kind: element.Type()}
+}
+
+// fetchByInterface sets the element to the only registered service that
+// implements the interface type of the element.
+func (s *ServiceRegistry) fetchByInterface(element reflect.Value) error {
+	var found *serviceEntry
+	for _, entry := range s.entries {
+		if entry.isLazy() || !reflect.TypeOf(entry.service).Implements(element.Type()) {
+			continue
+		}
+		if _, ok := entry.service.(*ServiceRegistry); ok {
+			continue
+		}
+		if found != nil {
+			return fmt.Errorf("multiple services implement %v: %v and %v", element.Type(), found, entry)
+		}
+		found = entry
+	}
+	if found == nil {
+		return fmt.Errorf("no service implements %v: %w", element.Type(), ErrServiceNotFound)
+	}
+	atomic.AddInt32(&found.fetches, 1)
+	element.Set(reflect.ValueOf(found.service))
+	return nil
+}
+
+// FetchNamedService sets the value of the given pointer to the service
+// registered under the given name.
+func (s *ServiceRegistry) FetchNamedService(name string, service interface{}) error {
+	if reflect.TypeOf(service).Kind() != reflect.Ptr {
+		return fmt.Errorf("%w, received value type instead: %T", ErrNotPointer, service)
+	}
+	s.lock.RLock()
+	entry, ok := s.named[name]
+	closed := s.closed
+	s.lock.RUnlock()
+	if closed {
+		return ErrRegistryClosed
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrServiceNotFound, name)
+	}
+	element := reflect.ValueOf(service).Elem()
+	if element.Type() != entry.kind {
+		return fmt.Errorf("service %s is of type %s, received %T", name, typeName(entry.kind), service)
+	}
+	element.Set(reflect.ValueOf(entry.service))
+	return nil
 }
@@ -1,12 +1,20 @@
 package shared
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
 	"reflect"
+	"runtime/pprof"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
 	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+	logTest "github.com/sirupsen/logrus/hooks/test"
 )
 
 type mockService struct {
@@ -39,29 +47,25 @@ func (s *secondMockService) Status() error {
 }
 
 func TestRegisterService_Twice(t *testing.T) {
-	registry := &ServiceRegistry{
-		services: make(map[reflect.Type]Service),
-	}
+	registry := NewServiceRegistry()
 
 	m := &mockService{}
 	require.NoError(t, registry.RegisterService(m), "Failed to register first service")
 
 	// Checks if first service was indeed registered.
-	require.Equal(t, 1, len(registry.serviceTypes))
+	require.Equal(t, 1, len(registry.entries))
 	assert.ErrorContains(t, "service already exists", registry.RegisterService(m))
 }
 
 func TestRegisterService_Different(t *testing.T) {
-	registry := &ServiceRegistry{
-		services: make(map[reflect.Type]Service),
-	}
+	registry := NewServiceRegistry()
 
 	m := &mockService{}
 	s := &secondMockService{}
 	require.NoError(t, registry.RegisterService(m), "Failed to register first service")
 	require.NoError(t, registry.RegisterService(s), "Failed to register second service")
 
-	require.Equal(t, 2, len(registry.serviceTypes))
+	require.Equal(t, 2, len(registry.entries))
 
 	_, exists := registry.services[reflect.TypeOf(m)]
 	assert.Equal(t, true, exists, "service of type %v not registered", reflect.TypeOf(m))
@@ -71,9 +75,7 @@ func TestRegisterService_Different(t *testing.T) {
 }
 
 func TestFetchService_OK(t *testing.T) {
-	registry := &ServiceRegistry{
-		services: make(map[reflect.Type]Service),
-	}
+	registry := NewServiceRegistry()
 
 	m := &mockService{}
 	require.NoError(t, registry.RegisterService(m), "Failed to register first service")
@@ -89,9 +91,7 @@ func TestFetchService_OK(t *testing.T) {
 }
 
 func TestServiceStatus_OK(t *testing.T) {
-	registry := &ServiceRegistry{
-		services: make(map[reflect.Type]Service),
-	}
+	registry := NewServiceRegistry()
 
 	m := &mockService{}
 	require.NoError(t, registry.RegisterService(m), "Failed to register first service")
@@ -107,3 +107,1078 @@ func TestServiceStatus_OK(t *testing.T) {
 	assert.ErrorContains(t, "something bad has happened", statuses[reflect.TypeOf(m)])
 	assert.ErrorContains(t, "woah, horsee", statuses[reflect.TypeOf(s)])
 }
+
+type thirdMockService struct {
+	status error
+}
+
+func (s *thirdMockService) Start() {
+}
+
+func (s *thirdMockService) Stop() error {
+	return nil
+}
+
+func (s *thirdMockService) Status() error {
+	return s.status
+}
+
+func entryKinds(entries []*serviceEntry) []reflect.Type {
+	kinds := make([]reflect.Type, len(entries))
+	for i, entry := range entries {
+		kinds[i] = entry.kind
+	}
+	return kinds
+}
+
+func TestStartOrder_Dependencies(t *testing.T) {
+	registry := NewServiceRegistry()
+
+	m := &mockService{}
+	s := &secondMockService{}
+	th := &thirdMockService{}
+	require.NoError(t, registry.RegisterServiceWithDeps(th, reflect.TypeOf(s)))
+	require.NoError(t, registry.RegisterServiceWithDeps(s, reflect.TypeOf(m)))
+	require.NoError(t, registry.RegisterService(m))
+
+	order, err := registry.startOrder()
+	require.NoError(t, err)
+	assert.DeepEqual(t, []reflect.Type{reflect.TypeOf(m), reflect.TypeOf(s), reflect.TypeOf(th)}, entryKinds(order))
+}
+
+func TestStartOrder_NoDependenciesKeepsRegistrationOrder(t *testing.T) {
+	registry := NewServiceRegistry()
+
+	m := &mockService{}
+	s := &secondMockService{}
+	require.NoError(t, registry.RegisterService(s))
+	require.NoError(t, registry.RegisterService(m))
+
+	order, err := registry.startOrder()
+	require.NoError(t, err)
+	assert.DeepEqual(t, []reflect.Type{reflect.TypeOf(s), reflect.TypeOf(m)}, entryKinds(order))
+}
+
+func TestStartOrder_Priority(t *testing.T) {
+	registry := NewServiceRegistry()
+
+	m := &mockService{}
+	s := &secondMockService{}
+	th := &thirdMockService{}
+	require.NoError(t, registry.RegisterServiceWithPriority(th, 10))
+	require.NoError(t, registry.RegisterService(s))
+	require.NoError(t, registry.RegisterServiceWithPriority(m, -10))
+
+	order, err := registry.startOrder()
+	require.NoError(t, err)
+	assert.DeepEqual(t, []reflect.Type{reflect.TypeOf(m), reflect.TypeOf(s), reflect.TypeOf(th)}, entryKinds(order))
+}
+
+func TestStartOrder_PriorityTiesKeepRegistrationOrder(t *testing.T) {
+	registry := NewServiceRegistry()
+
+	m := &mockService{}
+	s := &secondMockService{}
+	th := &thirdMockService{}
+	require.NoError(t, registry.RegisterServiceWithPriority(s, 1))
+	require.NoError(t, registry.RegisterServiceWithPriority(m, 1))
+	require.NoError(t, registry.RegisterService(th))
+
+	order, err := registry.startOrder()
+	require.NoError(t, err)
+	assert.DeepEqual(t, []reflect.Type{reflect.TypeOf(th), reflect.TypeOf(s), reflect.TypeOf(m)}, entryKinds(order))
+}
+
+func TestStartOrder_DependenciesOverridePriority(t *testing.T) {
+	registry := NewServiceRegistry()
+
+	m := &mockService{}
+	s := &secondMockService{}
+	require.NoError(t, registry.RegisterServiceWithConfig(s, nil, &ServiceConfig{
+		Priority:     -1,
+		Dependencies: []reflect.Type{reflect.TypeOf(m)},
+	}))
+	require.NoError(t, registry.RegisterServiceWithPriority(m, 1))
+
+	order, err := registry.startOrder()
+	require.NoError(t, err)
+	assert.DeepEqual(t, []reflect.Type{reflect.TypeOf(m), reflect.TypeOf(s)}, entryKinds(order))
+}
+
+func TestStopAll_ReversePriority(t *testing.T) {
+	registry := NewServiceRegistry()
+
+	m := &mockService{}
+	s := &secondMockService{}
+	var stopped []reflect.Type
+	for _, svc := range []Service{m, s} {
+		kind := reflect.TypeOf(svc)
+		registry.OnServiceStopped(kind, func() { stopped = append(stopped, kind) })
+	}
+	require.NoError(t, registry.RegisterServiceWithPriority(m, 1))
+	require.NoError(t, registry.RegisterService(s))
+	require.NoError(t, registry.StartAll())
+
+	require.NoError(t, registry.StopAll())
+	assert.DeepEqual(t, []reflect.Type{reflect.TypeOf(m), reflect.TypeOf(s)}, stopped)
+}
+
+func TestStartAll_DependencyCycle(t *testing.T) {
+	registry := NewServiceRegistry()
+
+	m := &mockService{}
+	s := &secondMockService{}
+	th := &thirdMockService{}
+	require.NoError(t, registry.RegisterServiceWithDeps(m, reflect.TypeOf(s)))
+	require.NoError(t, registry.RegisterServiceWithDeps(s, reflect.TypeOf(th)))
+	require.NoError(t, registry.RegisterServiceWithDeps(th, reflect.TypeOf(m)))
+
+	err := registry.StartAll()
+	assert.ErrorContains(t, "circular dependency detected: shared.mockService -> shared.secondMockService -> shared.thirdMockService -> shared.mockService", err)
+	state, err := registry.State(reflect.TypeOf(m))
+	require.NoError(t, err)
+	assert.Equal(t, StateRegistered, state, "Expected no service to be started")
+}
+
+func TestStartAll_SelfDependency(t *testing.T) {
+	registry := NewServiceRegistry()
+
+	m := &mockService{}
+	require.NoError(t, registry.RegisterService(&secondMockService{}))
+	require.NoError(t, registry.RegisterServiceWithDeps(m, reflect.TypeOf(m)))
+
+	err := registry.StartAll()
+	assert.ErrorContains(t, "circular dependency detected: shared.mockService -> shared.mockService", err)
+}
+
+func TestStartAll_MissingDependency(t *testing.T) {
+	registry := NewServiceRegistry()
+
+	s := &secondMockService{}
+	require.NoError(t, registry.RegisterServiceWithDeps(s, reflect.TypeOf(&mockService{})))
+
+	err := registry.StartAll()
+	assert.ErrorContains(t, "depends on unregistered service: shared.mockService", err)
+}
+
+type startCountingService struct {
+	lock   sync.Mutex
+	starts int
+}
+
+func (s *startCountingService) Start() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.starts++
+}
+
+func (s *startCountingService) Stop() error {
+	return nil
+}
+
+func (s *startCountingService) Status() error {
+	return nil
+}
+
+func TestStartAll_Twice(t *testing.T) {
+	registry := NewServiceRegistry()
+
+	svc := &startCountingService{}
+	require.NoError(t, registry.RegisterService(svc))
+	require.NoError(t, registry.StartAll())
+	assert.Equal(t, true, errors.Is(registry.StartAll(), ErrAlreadyStarted))
+	waitForState(t, registry, reflect.TypeOf(svc), StateRunning)
+	require.NoError(t, registry.StopAll())
+	assert.Equal(t, true, errors.Is(registry.StartAll(), ErrAlreadyStarted))
+
+	svc.lock.Lock()
+	defer svc.lock.Unlock()
+	assert.Equal(t, 1, svc.starts)
+}
+
+func TestStartAll_RetryAfterInvalidRegistrations(t *testing.T) {
+	registry := NewServiceRegistry()
+
+	require.NoError(t, registry.RegisterServiceWithDeps(&secondMockService{}, reflect.TypeOf(&mockService{})))
+	assert.ErrorContains(t, "depends on unregistered service", registry.StartAll())
+	// Nothing was started, so the missing service can still be registered.
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.StartAll())
+	require.NoError(t, registry.StopAll())
+}
+
+func TestStartAll_AfterStartGroup(t *testing.T) {
+	registry := NewServiceRegistry()
+
+	svc := &startCountingService{}
+	require.NoError(t, registry.RegisterService(svc, "core"))
+	require.NoError(t, registry.StartGroup("core"))
+	waitForState(t, registry, reflect.TypeOf(svc), StateRunning)
+	require.NoError(t, registry.RegisterServiceWithDeps(&secondMockService{}, reflect.TypeOf(&mockService{})))
+	assert.ErrorContains(t, "depends on unregistered service", registry.StartAll())
+	// The failed StartAll leaves the registry as StartGroup left it.
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, reflect.TypeOf(&secondMockService{}), StateRunning)
+	require.NoError(t, registry.StopAll())
+
+	svc.lock.Lock()
+	defer svc.lock.Unlock()
+	assert.Equal(t, 1, svc.starts, "StartAll started the group again")
+}
+
+func TestRegisterService_AfterStartAll(t *testing.T) {
+	registry := NewServiceRegistry()
+
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.StartAll())
+	err := registry.RegisterService(&secondMockService{})
+	assert.Equal(t, true, errors.Is(err, ErrAlreadyStarted))
+	assert.ErrorContains(t, "could not register service shared.secondMockService: registry already started", err)
+	assert.Equal(t, true, errors.Is(registry.RegisterNamedService("gateway", &secondMockService{}, nil), ErrAlreadyStarted))
+	assert.Equal(t, true, errors.Is(registry.RegisterLazy(func(*ServiceContext) (Service, error) {
+		return &secondMockService{}, nil
+	}), ErrAlreadyStarted))
+	require.Equal(t, 1, len(registry.snapshot()))
+	require.NoError(t, registry.StopAll())
+}
+
+type statusFuncService struct {
+	lock   sync.Mutex
+	status func() error
+}
+
+func (s *statusFuncService) Start() {
+}
+
+func (s *statusFuncService) Stop() error {
+	return nil
+}
+
+func (s *statusFuncService) Status() error {
+	s.lock.Lock()
+	status := s.status
+	s.lock.Unlock()
+	return status()
+}
+
+func (s *statusFuncService) setStatus(status func() error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.status = status
+}
+
+type hangingStatusService struct {
+	release chan struct{}
+}
+
+func (s *hangingStatusService) Start() {
+}
+
+func (s *hangingStatusService) Stop() error {
+	return nil
+}
+
+func (s *hangingStatusService) Status() error {
+	<-s.release
+	return nil
+}
+
+func TestWaitForAllReady_OK(t *testing.T) {
+	registry := NewServiceRegistry()
+	registry.SetReadyPollInterval(10 * time.Millisecond)
+
+	s := &statusFuncService{status: func() error { return errors.New("not ready") }}
+	require.NoError(t, registry.RegisterService(s))
+	require.NoError(t, registry.RegisterService(&mockService{}))
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		s.setStatus(func() error { return nil })
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, registry.WaitForAllReady(ctx))
+}
+
+func TestWaitForAllReady_Timeout(t *testing.T) {
+	registry := NewServiceRegistry()
+	registry.SetReadyPollInterval(10 * time.Millisecond)
+
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.RegisterService(&secondMockService{status: errors.New("still syncing")}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := registry.WaitForAllReady(ctx)
+	assert.ErrorContains(t, "shared.secondMockService: still syncing", err)
+	assert.Equal(t, true, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestWaitForAllReady_PanickingAndHangingStatus(t *testing.T) {
+	registry := NewServiceRegistry()
+	registry.SetReadyPollInterval(10 * time.Millisecond)
+
+	h := &hangingStatusService{release: make(chan struct{})}
+	defer close(h.release)
+	require.NoError(t, registry.RegisterService(&statusFuncService{status: func() error {
+		panic("bad status")
+	}}))
+	require.NoError(t, registry.RegisterService(h))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := registry.WaitForAllReady(ctx)
+	assert.ErrorContains(t, "status check panicked: bad status", err)
+	assert.ErrorContains(t, "status check did not return", err)
+}
+
+type blockingStopService struct {
+	release chan struct{}
+}
+
+func (s *blockingStopService) Start() {
+}
+
+func (s *blockingStopService) Stop() error {
+	<-s.release
+	return nil
+}
+
+func (s *blockingStopService) Status() error {
+	return nil
+}
+
+// barrierStopService only returns from Stop once every service sharing its
+// barrier is being stopped.
+type barrierStopService struct {
+	barrier *sync.WaitGroup
+}
+
+func (s *barrierStopService) Start() {
+}
+
+func (s *barrierStopService) Stop() error {
+	s.barrier.Done()
+	s.barrier.Wait()
+	return nil
+}
+
+func (s *barrierStopService) Status() error {
+	return nil
+}
+
+type secondBarrierStopService struct {
+	barrierStopService
+}
+
+func TestStopAll_StopsIndependentServicesConcurrently(t *testing.T) {
+	registry := NewServiceRegistry()
+	barrier := &sync.WaitGroup{}
+	barrier.Add(2)
+	// Declaring priorities tells the registry which services are independent.
+	cfg := &ServiceConfig{StopTimeout: 5 * time.Second, Priority: 1}
+	require.NoError(t, registry.RegisterServiceWithConfig(&barrierStopService{barrier: barrier}, nil, cfg))
+	require.NoError(t, registry.RegisterServiceWithConfig(&secondBarrierStopService{barrierStopService{barrier: barrier}}, nil, cfg))
+	require.NoError(t, registry.StartAll())
+
+	start := time.Now()
+	require.NoError(t, registry.StopAll())
+	assert.Equal(t, true, time.Since(start) < cfg.StopTimeout, "Expected services to be stopped concurrently")
+	for _, err := range registry.Statuses() {
+		assert.ErrorContains(t, "service is stopped", err)
+	}
+}
+
+func TestStopAll_StopsInReverseOrderByDefault(t *testing.T) {
+	registry := NewServiceRegistry()
+	var lock sync.Mutex
+	stopped := false
+	registry.OnServiceStopped(reflect.TypeOf(&mockService{}), func() {
+		lock.Lock()
+		defer lock.Unlock()
+		stopped = true
+	})
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	blocking := &blockingStopService{release: make(chan struct{})}
+	require.NoError(t, registry.RegisterServiceWithConfig(blocking, nil, &ServiceConfig{StopTimeout: 5 * time.Second}))
+	require.NoError(t, registry.StartAll())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- registry.StopAll()
+	}()
+	time.Sleep(20 * time.Millisecond)
+	lock.Lock()
+	assert.Equal(t, false, stopped, "Service registered first was stopped before the service registered after it")
+	lock.Unlock()
+	close(blocking.release)
+	require.NoError(t, <-done)
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, true, stopped)
+}
+
+func TestStopAll_StopsDependentsFirst(t *testing.T) {
+	registry := NewServiceRegistry()
+
+	var lock sync.Mutex
+	var stopped []reflect.Type
+	kinds := []reflect.Type{reflect.TypeOf(&mockService{}), reflect.TypeOf(&secondMockService{}), reflect.TypeOf(&thirdMockService{})}
+	for _, kind := range kinds {
+		kind := kind
+		registry.OnServiceStopped(kind, func() {
+			lock.Lock()
+			defer lock.Unlock()
+			stopped = append(stopped, kind)
+		})
+	}
+	require.NoError(t, registry.RegisterServiceWithDeps(&thirdMockService{}, kinds[1]))
+	require.NoError(t, registry.RegisterServiceWithDeps(&secondMockService{}, kinds[0]))
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.StartAll())
+
+	require.NoError(t, registry.StopAll())
+	assert.DeepEqual(t, []reflect.Type{kinds[2], kinds[1], kinds[0]}, stopped)
+}
+
+func TestStopAll_StrictStopOrder(t *testing.T) {
+	registry := NewServiceRegistry()
+	registry.SetStrictStopOrder(true)
+
+	var stopped []reflect.Type
+	kinds := []reflect.Type{reflect.TypeOf(&mockService{}), reflect.TypeOf(&secondMockService{}), reflect.TypeOf(&thirdMockService{})}
+	for _, kind := range kinds {
+		kind := kind
+		registry.OnServiceStopped(kind, func() { stopped = append(stopped, kind) })
+	}
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.RegisterService(&secondMockService{}))
+	require.NoError(t, registry.RegisterService(&thirdMockService{}))
+	require.NoError(t, registry.StartAll())
+
+	require.NoError(t, registry.StopAll())
+	assert.DeepEqual(t, []reflect.Type{kinds[2], kinds[1], kinds[0]}, stopped)
+}
+
+func TestStopAll_StrictStopOrder_FollowsDependencies(t *testing.T) {
+	registry := NewServiceRegistry()
+	registry.SetStrictStopOrder(true)
+
+	var stopped []reflect.Type
+	db, consumer, other := reflect.TypeOf(&mockService{}), reflect.TypeOf(&secondMockService{}), reflect.TypeOf(&thirdMockService{})
+	for _, kind := range []reflect.Type{db, consumer, other} {
+		kind := kind
+		registry.OnServiceStopped(kind, func() { stopped = append(stopped, kind) })
+	}
+	// In reverse order of registration, the database would be stopped before
+	// its consumer.
+	require.NoError(t, registry.RegisterServiceWithDeps(&secondMockService{}, db))
+	require.NoError(t, registry.RegisterService(&thirdMockService{}))
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.StartAll())
+
+	require.NoError(t, registry.StopAll())
+	assert.DeepEqual(t, []reflect.Type{other, consumer, db}, stopped)
+}
+
+type stopRecordingService struct {
+	stopped bool
+}
+
+func (s *stopRecordingService) Start() {
+}
+
+func (s *stopRecordingService) Stop() error {
+	s.stopped = true
+	return nil
+}
+
+func (s *stopRecordingService) Status() error {
+	return nil
+}
+
+func TestStopAll_StopTimeout(t *testing.T) {
+	registry := NewServiceRegistry()
+
+	r := &stopRecordingService{}
+	require.NoError(t, registry.RegisterService(r))
+	b := &blockingStopService{release: make(chan struct{})}
+	defer close(b.release)
+	ctx := NewServiceContext()
+	require.NoError(t, registry.RegisterServiceWithConfig(b, ctx, &ServiceConfig{StopTimeout: 50 * time.Millisecond}))
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- registry.StopAll()
+	}()
+	select {
+	case err := <-stopped:
+		assert.ErrorContains(t, "shared.blockingStopService: service did not stop within 50ms", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("StopAll did not return after the stop timeout")
+	}
+	assert.Equal(t, true, r.stopped, "Expected service registered first to be stopped")
+	assert.ErrorContains(t, context.Canceled.Error(), ctx.Err())
+}
+
+type otherBlockingStopService struct {
+	blockingStopService
+}
+
+func TestStopAll_StopTimeoutDumpsStacksOnce(t *testing.T) {
+	hook := logTest.NewGlobal()
+	registry := NewServiceRegistry()
+	registry.SetStrictStopOrder(true)
+	first := &blockingStopService{release: make(chan struct{})}
+	defer close(first.release)
+	require.NoError(t, registry.RegisterServiceWithConfig(first, nil, &ServiceConfig{StopTimeout: 10 * time.Millisecond}))
+	second := &otherBlockingStopService{blockingStopService{release: make(chan struct{})}}
+	defer close(second.release)
+	require.NoError(t, registry.RegisterServiceWithConfig(second, nil, &ServiceConfig{StopTimeout: 10 * time.Millisecond}))
+
+	assert.ErrorContains(t, "service did not stop within 10ms", registry.StopAll())
+	var dumps []string
+	for _, entry := range hook.AllEntries() {
+		if strings.Contains(entry.Message, "goroutine stacks") {
+			dumps = append(dumps, entry.Message)
+		}
+	}
+	require.Equal(t, 1, len(dumps), "Expected a single goroutine dump")
+	assert.Equal(t, true, strings.HasPrefix(dumps[0], "Service shared.otherBlockingStopService did not stop within 10ms, goroutine stacks:"))
+	assert.Equal(t, true, strings.Contains(dumps[0], "(*blockingStopService).Stop"))
+}
+
+type failingStopService struct {
+	err error
+}
+
+func (s *failingStopService) Start() {
+}
+
+func (s *failingStopService) Stop() error {
+	return s.err
+}
+
+func (s *failingStopService) Status() error {
+	return nil
+}
+
+func TestStopAll_ReturnsStopErrors(t *testing.T) {
+	registry := NewServiceRegistry()
+
+	flushErr := errors.New("could not flush database")
+	require.NoError(t, registry.RegisterService(&failingStopService{err: flushErr}))
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.StartAll())
+
+	err := registry.StopAll()
+	assert.ErrorContains(t, "shared.failingStopService: could not flush database", err)
+	assert.Equal(t, true, errors.Is(err, flushErr), "Expected the stop error to be propagated")
+	var multiErr *MultiError
+	require.Equal(t, true, errors.As(err, &multiErr))
+	assert.Equal(t, 1, len(multiErr.Errors))
+}
+
+func TestStopAllWithContext_AbandonsServicesAfterDeadline(t *testing.T) {
+	registry := NewServiceRegistry()
+
+	b := &blockingStopService{release: make(chan struct{})}
+	defer close(b.release)
+	bCtx := NewServiceContext()
+	require.NoError(t, registry.RegisterServiceWithConfig(b, bCtx, &ServiceConfig{StopTimeout: time.Minute}))
+	mCtx := NewServiceContext()
+	require.NoError(t, registry.RegisterServiceWithConfig(&mockService{}, mCtx, &ServiceConfig{}))
+	require.NoError(t, registry.StartAll())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- registry.StopAllWithContext(ctx)
+	}()
+	select {
+	case err := <-stopped:
+		assert.Equal(t, true, errors.Is(err, context.DeadlineExceeded), "Expected a deadline exceeded error, received %v", err)
+		assert.ErrorContains(t, "shared.blockingStopService: service abandoned", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("StopAllWithContext did not return after the context deadline")
+	}
+	assert.NotNil(t, bCtx.Err(), "Expected abandoned service context to be cancelled")
+	assert.NotNil(t, mCtx.Err())
+}
+
+func TestStopAll_CancelsServiceContext(t *testing.T) {
+	registry := NewServiceRegistry()
+
+	ctx := NewServiceContext()
+	require.NoError(t, registry.RegisterServiceWithConfig(&mockService{}, ctx, nil))
+	require.NoError(t, ctx.Err())
+
+	require.NoError(t, registry.StopAll())
+	assert.ErrorContains(t, context.Canceled.Error(), ctx.Err())
+}
+
+func TestRegisterNamedService_Twice(t *testing.T) {
+	registry := NewServiceRegistry()
+
+	require.NoError(t, registry.RegisterNamedService("gateway-1", &mockService{}, nil))
+	require.NoError(t, registry.RegisterNamedService("gateway-2", &mockService{}, nil))
+	assert.ErrorContains(t, "service already exists: gateway-1", registry.RegisterNamedService("gateway-1", &mockService{}, nil))
+	assert.ErrorContains(t, "service name cannot be empty", registry.RegisterNamedService("", &mockService{}, nil))
+	require.Equal(t, 2, len(registry.entries))
+}
+
+func TestRegisterServiceWithName(t *testing.T) {
+	registry := NewServiceRegistry()
+
+	svc := &mockService{status: errors.New("bad")}
+	require.NoError(t, registry.RegisterServiceWithName(svc, "initial-sync"))
+	require.NoError(t, registry.RegisterService(&secondMockService{}))
+	require.NoError(t, registry.RegisterNamedService("gateway", &thirdMockService{}, nil))
+	assert.ErrorContains(t, "service name initial-sync is already used by shared.mockService", registry.RegisterServiceWithName(&stopRecordingService{}, "initial-sync"))
+	assert.ErrorContains(t, "service name shared.secondMockService is already used", registry.RegisterServiceWithName(&stopRecordingService{}, "shared.secondMockService"))
+	assert.ErrorContains(t, "service name gateway is already used", registry.RegisterServiceWithName(&stopRecordingService{}, "gateway"))
+	assert.ErrorContains(t, "service name cannot be empty", registry.RegisterServiceWithName(&stopRecordingService{}, ""))
+	require.Equal(t, 3, len(registry.entries))
+
+	var fetched *mockService
+	require.NoError(t, registry.FetchService(&fetched))
+	assert.Equal(t, svc, fetched)
+	assert.ErrorContains(t, "bad", registry.StatusesByName()["initial-sync"])
+	assert.Equal(t, "initial-sync", registry.snapshot()[0].ctx.Log.Data["prefix"])
+	b, err := registry.DebugJSON()
+	require.NoError(t, err)
+	assert.Equal(t, true, strings.Contains(string(b), `"name":"initial-sync"`), "Unexpected debug dump %s", b)
+
+	stops := histogramCount(t, serviceStopDuration, "initial-sync")
+	require.NoError(t, registry.StopAll())
+	assert.Equal(t, stops+1, histogramCount(t, serviceStopDuration, "initial-sync"))
+}
+
+func TestFetchNamedService_OK(t *testing.T) {
+	registry := NewServiceRegistry()
+
+	first := &mockService{}
+	second := &mockService{}
+	require.NoError(t, registry.RegisterNamedService("first", first, nil))
+	require.NoError(t, registry.RegisterNamedService("second", second, nil))
+	require.NoError(t, registry.RegisterService(&mockService{}))
+
+	var m *mockService
+	require.NoError(t, registry.FetchNamedService("second", &m))
+	assert.Equal(t, second, m)
+	require.NoError(t, registry.FetchNamedService("first", &m))
+	assert.Equal(t, first, m)
+
+	var s *secondMockService
+	assert.ErrorContains(t, "service first is of type shared.mockService", registry.FetchNamedService("first", &s))
+	assert.ErrorContains(t, "unknown service: third", registry.FetchNamedService("third", &m))
+	assert.ErrorContains(t, "input must be of pointer type", registry.FetchNamedService("first", *m))
+}
+
+func TestNamedServices_StopAllAndStatuses(t *testing.T) {
+	registry := NewServiceRegistry()
+
+	first := &stopRecordingService{}
+	second := &stopRecordingService{}
+	require.NoError(t, registry.RegisterNamedService("first", first, nil))
+	require.NoError(t, registry.RegisterNamedService("second", second, nil))
+	require.NoError(t, registry.RegisterNamedService("unhealthy", &mockService{status: errors.New("bad")}, nil))
+	require.NoError(t, registry.RegisterService(&mockService{}))
+
+	statuses := registry.Statuses()
+	assert.NoError(t, statuses[reflect.TypeOf(first)])
+	assert.ErrorContains(t, "unhealthy: bad", statuses[reflect.TypeOf(&mockService{})])
+
+	require.NoError(t, registry.StopAll())
+	assert.Equal(t, true, first.stopped)
+	assert.Equal(t, true, second.stopped)
+}
+
+type restartableService struct {
+	started chan struct{}
+	stopped int
+}
+
+func (s *restartableService) Start() {
+	s.started <- struct{}{}
+}
+
+func (s *restartableService) Stop() error {
+	s.stopped++
+	return nil
+}
+
+func (s *restartableService) Status() error {
+	return nil
+}
+
+func TestRestartService_OK(t *testing.T) {
+	registry := NewServiceRegistry()
+
+	r := &restartableService{started: make(chan struct{}, 1)}
+	ctx := NewServiceContext()
+	require.NoError(t, registry.RegisterServiceWithConfig(r, ctx, nil))
+	require.NoError(t, registry.StartAll())
+	<-r.started
+
+	require.NoError(t, registry.RestartService(reflect.TypeOf(r)))
+	select {
+	case <-r.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Service was not started again")
+	}
+	assert.Equal(t, 1, r.stopped)
+	assert.ErrorContains(t, context.Canceled.Error(), ctx.Err())
+	assert.NoError(t, registry.services[reflect.TypeOf(r)].ctx.Err())
+
+	var fetched *restartableService
+	require.NoError(t, registry.FetchService(&fetched))
+	assert.Equal(t, r, fetched)
+}
+
+func TestRestartService_Unknown(t *testing.T) {
+	registry := NewServiceRegistry()
+	assert.ErrorContains(t, "unknown service: shared.mockService", registry.RestartService(reflect.TypeOf(&mockService{})))
+}
+
+func TestRestartService_StopFails(t *testing.T) {
+	registry := NewServiceRegistry()
+
+	b := &blockingStopService{release: make(chan struct{})}
+	defer close(b.release)
+	require.NoError(t, registry.RegisterServiceWithConfig(b, nil, &ServiceConfig{StopTimeout: 10 * time.Millisecond}))
+	assert.ErrorContains(t, "could not stop service shared.blockingStopService", registry.RestartService(reflect.TypeOf(b)))
+}
+
+func TestServiceRegistry_ConcurrentAccess(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&mockService{}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(3)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, registry.RegisterNamedService(fmt.Sprintf("service-%d", i), &secondMockService{}, nil))
+		}(i)
+		go func() {
+			defer wg.Done()
+			var m *mockService
+			assert.NoError(t, registry.FetchService(&m))
+		}()
+		go func() {
+			defer wg.Done()
+			registry.Statuses()
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, 51, len(registry.entries))
+}
+
+type panickingStartService struct {
+	started chan struct{}
+}
+
+func (s *panickingStartService) Start() {
+	close(s.started)
+	panic("could not bind port")
+}
+
+func (s *panickingStartService) Stop() error {
+	return nil
+}
+
+func (s *panickingStartService) Status() error {
+	return nil
+}
+
+func TestStartAll_RecoversStartPanic(t *testing.T) {
+	registry := NewServiceRegistry(WithLegacyStartWait())
+	registry.SetReadyPollInterval(10 * time.Millisecond)
+
+	p := &panickingStartService{started: make(chan struct{})}
+	require.NoError(t, registry.RegisterService(p))
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	assert.ErrorContains(t, "service panicked during start: could not bind port", registry.StartAll())
+	<-p.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.ErrorContains(t, "service panicked during start: could not bind port", registry.WaitForAllReady(ctx))
+
+	statuses := registry.Statuses()
+	assert.ErrorContains(t, "service panicked during start: could not bind port", statuses[reflect.TypeOf(p)])
+	assert.ErrorContains(t, "service is stopped", statuses[reflect.TypeOf(&mockService{})])
+}
+
+type panickingStopService struct{}
+
+func (s *panickingStopService) Start() {
+}
+
+func (s *panickingStopService) Stop() error {
+	panic("could not flush")
+}
+
+func (s *panickingStopService) Status() error {
+	return nil
+}
+
+func TestStopAll_RecoversStopPanic(t *testing.T) {
+	for _, strict := range []bool{false, true} {
+		t.Run(fmt.Sprintf("strict=%v", strict), func(t *testing.T) {
+			hook := logTest.NewGlobal()
+			registry := NewServiceRegistry()
+			registry.SetStrictStopOrder(strict)
+			db := &stopRecordingService{}
+			require.NoError(t, registry.RegisterService(db))
+			ctx := NewServiceContext()
+			require.NoError(t, registry.RegisterServiceWithConfig(&panickingStopService{}, ctx, nil))
+			require.NoError(t, registry.RegisterService(&mockService{}))
+			require.NoError(t, registry.StartAll())
+			waitForState(t, registry, reflect.TypeOf(db), StateRunning)
+
+			err := registry.StopAll()
+			assert.ErrorContains(t, "shared.panickingStopService: service panicked during stop: could not flush", err)
+			assert.Equal(t, true, db.stopped)
+			assert.ErrorContains(t, "context canceled", ctx.Err())
+			require.LogsContain(t, hook, "Service panicked during stop: could not flush")
+			require.LogsContain(t, hook, "Stopped service shared.mockService")
+		})
+	}
+}
+
+type labeledService struct {
+	release chan struct{}
+}
+
+func (s *labeledService) Start() {
+	go func() {
+		<-s.release
+	}()
+}
+
+func (s *labeledService) Stop() error {
+	close(s.release)
+	return nil
+}
+
+func (s *labeledService) Status() error {
+	return nil
+}
+
+func TestStartAll_LabelsServiceGoroutines(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&labeledService{release: make(chan struct{})}))
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, reflect.TypeOf(&labeledService{}), StateRunning)
+
+	var profile bytes.Buffer
+	require.NoError(t, pprof.Lookup("goroutine").WriteTo(&profile, 1))
+	var labeled bool
+	for _, record := range strings.Split(profile.String(), "\n\n") {
+		if strings.Contains(record, `labels: {"service":"shared.labeledService"}`) && strings.Contains(record, "(*labeledService).Start.func1") {
+			labeled = true
+		}
+	}
+	assert.Equal(t, true, labeled, "Expected the goroutine spawned by Start to be labeled")
+	require.NoError(t, registry.StopAll())
+}
+
+type headFetcher interface {
+	HeadSlot() uint64
+}
+
+type chainInfoService struct {
+	mockService
+}
+
+func (s *chainInfoService) HeadSlot() uint64 {
+	return 42
+}
+
+func TestFetchService_ByInterface(t *testing.T) {
+	registry := NewServiceRegistry()
+
+	c := &chainInfoService{}
+	require.NoError(t, registry.RegisterService(c))
+	require.NoError(t, registry.RegisterService(&mockService{}))
+
+	var fetcher headFetcher
+	require.NoError(t, registry.FetchService(&fetcher))
+	assert.Equal(t, c, fetcher.(*chainInfoService))
+	assert.Equal(t, uint64(42), fetcher.HeadSlot())
+
+	var status interface{ Status() error }
+	assert.ErrorContains(t, "multiple services implement interface { Status() error }", registry.FetchService(&status))
+
+	var unknown fmt.Stringer
+	assert.ErrorContains(t, "no service implements fmt.Stringer", registry.FetchService(&unknown))
+}
+
+func TestServiceRegistry_EventBus(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.Equal(t, registry.EventBus(), registry.EventBus(), "Expected the same bus on every call")
+
+	ch := make(chan string, 1)
+	defer registry.EventBus().Subscribe(ch)()
+	assert.Equal(t, 1, registry.EventBus().Publish("ping"))
+	assert.Equal(t, "ping", <-ch)
+}
+
+func TestUnregisterService_UnknownService(t *testing.T) {
+	registry := NewServiceRegistry()
+	err := registry.UnregisterService(reflect.TypeOf(&mockService{}))
+	var unknown *UnknownServiceError
+	require.Equal(t, true, errors.As(err, &unknown), "Expected an UnknownServiceError, received %v", err)
+	assert.Equal(t, reflect.TypeOf(&mockService{}), unknown.Kind)
+}
+
+func TestUnregisterService_RemovesService(t *testing.T) {
+	registry := NewServiceRegistry()
+	ctx := NewServiceContext()
+	m := &mockService{}
+	require.NoError(t, registry.RegisterServiceWithConfig(m, ctx, &ServiceConfig{}))
+	require.NoError(t, registry.RegisterService(&secondMockService{}))
+
+	require.NoError(t, registry.UnregisterService(reflect.TypeOf(m)))
+	assert.NotNil(t, ctx.Err(), "Expected service context to be cancelled")
+	var fetched *mockService
+	assert.ErrorContains(t, "unknown service", registry.FetchService(&fetched))
+	assert.DeepEqual(t, []reflect.Type{reflect.TypeOf(&secondMockService{})}, entryKinds(registry.snapshot()))
+	_, ok := registry.Statuses()[reflect.TypeOf(m)]
+	assert.Equal(t, false, ok, "Expected unregistered service to be absent from statuses")
+
+	require.NoError(t, registry.RegisterService(&mockService{}), "Expected service to be registrable again")
+}
+
+func TestUnregisterService_RefusesRunningService(t *testing.T) {
+	registry := NewServiceRegistry()
+	kind := reflect.TypeOf(&mockService{})
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, kind, StateRunning)
+
+	assert.ErrorContains(t, "while it is running", registry.UnregisterService(kind))
+	assert.DeepEqual(t, []reflect.Type{kind}, entryKinds(registry.snapshot()))
+
+	require.NoError(t, registry.StopAll())
+	require.NoError(t, registry.UnregisterService(kind))
+	assert.Equal(t, 0, len(entryKinds(registry.snapshot())))
+}
+
+func TestStopAndUnregisterService(t *testing.T) {
+	registry := NewServiceRegistry()
+	s := &stopRecordingService{}
+	kind := reflect.TypeOf(s)
+	require.NoError(t, registry.RegisterService(s))
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, kind, StateRunning)
+
+	require.NoError(t, registry.StopAndUnregisterService(kind))
+	assert.Equal(t, true, s.stopped, "Expected service to be stopped")
+	assert.Equal(t, 0, len(entryKinds(registry.snapshot())))
+	_, err := registry.State(kind)
+	var unknown *UnknownServiceError
+	assert.Equal(t, true, errors.As(err, &unknown))
+}
+
+type namedService struct {
+	mockService
+	name string
+}
+
+func (s *namedService) Name() string {
+	return s.name
+}
+
+type secondNamedService struct {
+	namedService
+}
+
+func TestStatusesByName(t *testing.T) {
+	registry := NewServiceRegistry()
+	statusErr := errors.New("no peers")
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.RegisterService(&namedService{mockService: mockService{status: statusErr}, name: "p2p"}))
+	require.NoError(t, registry.RegisterNamedService("beacon", &secondMockService{}, nil))
+
+	statuses := registry.StatusesByName()
+	assert.Equal(t, 3, len(statuses))
+	assert.NoError(t, statuses["shared.mockService"])
+	assert.Equal(t, statusErr, statuses["p2p"])
+	assert.NoError(t, statuses["beacon"])
+}
+
+func TestRegisterService_NameCollision(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&namedService{name: "p2p"}))
+
+	err := registry.RegisterService(&secondNamedService{namedService{name: "p2p"}})
+	assert.ErrorContains(t, "service name p2p is already used by shared.namedService", err)
+	err = registry.RegisterNamedService("p2p", &mockService{}, nil)
+	assert.ErrorContains(t, "service name p2p is already used by shared.namedService", err)
+	err = registry.RegisterNamedService("shared.mockService", &mockService{}, nil)
+	require.NoError(t, err)
+	err = registry.RegisterService(&mockService{})
+	assert.ErrorContains(t, "service name shared.mockService is already used by shared.mockService", err)
+}
+
+func TestServiceName(t *testing.T) {
+	var nilService *mockService
+	m := &mockService{}
+	tests := []struct {
+		name    string
+		service interface{}
+		want    string
+	}{
+		{name: "pointer", service: &mockService{}, want: "shared.mockService"},
+		{name: "value", service: mockService{}, want: "shared.mockService"},
+		{name: "double pointer", service: &m, want: "shared.mockService"},
+		{name: "nil pointer", service: nilService, want: "shared.mockService"},
+		{name: "nil", service: nil, want: "<nil>"},
+		{name: "named", service: &namedService{name: "p2p"}, want: "p2p"},
+		{name: "empty name", service: &namedService{}, want: "shared.namedService"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, serviceName(tt.service))
+		})
+	}
+}
+
+func TestRegisterService_SetsContextLogger(t *testing.T) {
+	registry := NewServiceRegistry()
+
+	ctx := NewServiceContext()
+	require.NoError(t, registry.RegisterServiceWithConfig(&mockService{}, ctx, nil))
+	require.NotNil(t, ctx.Log)
+	assert.Equal(t, "shared.mockService", ctx.Log.Data["prefix"])
+
+	named := NewServiceContext()
+	require.NoError(t, registry.RegisterNamedService("beacon", &secondMockService{}, named))
+	assert.Equal(t, "beacon", named.Log.Data["prefix"])
+
+	custom := NewServiceContext()
+	custom.Log = log.WithField("custom", true)
+	require.NoError(t, registry.RegisterServiceWithConfig(&thirdMockService{}, custom, nil))
+	assert.Equal(t, true, custom.Log.Data["custom"], "Expected a logger set by the caller to be kept")
+	assert.Equal(t, "registry", custom.Log.Data["prefix"])
+}
+
+func TestRestartService_KeepsContextLogger(t *testing.T) {
+	registry := NewServiceRegistry()
+	ctx := NewServiceContext()
+	require.NoError(t, registry.RegisterServiceWithConfig(&mockService{}, ctx, nil))
+	require.NoError(t, registry.StartAll())
+
+	require.NoError(t, registry.RestartService(reflect.TypeOf(&mockService{})))
+	registry.lock.RLock()
+	renewed := registry.services[reflect.TypeOf(&mockService{})].ctx
+	registry.lock.RUnlock()
+	assert.Equal(t, ctx.Log, renewed.Log)
+	require.NoError(t, registry.StopAll())
+}
@@ -0,0 +1,91 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ConfigReloader is implemented by services which can apply updated runtime
+// settings, such as a log level or peer limits, without being restarted.
+type ConfigReloader interface {
+	// ReloadConfig applies the given configuration, whose type is agreed
+	// upon by the node and its services.
+	ReloadConfig(ctx context.Context, cfg interface{}) error
+}
+
+// ReloadAll passes the given configuration to every service implementing
+// ConfigReloader, in order of registration, and returns the errors of the
+// services which could not reload it, keyed by service name. Services not
+// implementing ConfigReloader are skipped. A panic in ReloadConfig is
+// recovered and reported as the error of the service, and once the context is
+// done, ReloadAll no longer waits for a service to reload: it and the
+// services not called yet report the context error.
+func (s *ServiceRegistry) ReloadAll(ctx context.Context, cfg interface{}) map[string]error {
+	errs := make(map[string]error)
+	for _, entry := range s.snapshot() {
+		reloader, ok := entry.service.(ConfigReloader)
+		if !ok {
+			continue
+		}
+		if err := s.reload(ctx, reloader, cfg); err != nil {
+			s.log.WithError(err).Errorf("Could not reload the configuration of service %v", entry)
+			errs[entry.String()] = err
+			continue
+		}
+		s.log.Debugf("Reloaded the configuration of service %v", entry)
+	}
+	return errs
+}
+
+func (s *ServiceRegistry) reload(ctx context.Context, reloader ConfigReloader, cfg interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("service panicked during reload: %v", r)
+			}
+		}()
+		done <- reloader.ReloadConfig(ctx, cfg)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// HandleReloadSignals calls ReloadAll every time the process receives SIGHUP,
+// with the configuration returned by load, until the context is done. A
+// configuration which cannot be loaded is logged, and no service is reloaded.
+func (s *ServiceRegistry) HandleReloadSignals(ctx context.Context, load func() (interface{}, error)) {
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGHUP)
+	defer signal.Stop(sigc)
+	s.handleReloadSignals(ctx, sigc, load)
+}
+
+func (s *ServiceRegistry) handleReloadSignals(ctx context.Context, sigc <-chan os.Signal, load func() (interface{}, error)) {
+	for {
+		select {
+		case sig := <-sigc:
+			s.log.WithField("signal", sig).Info("Reloading configuration")
+			cfg, err := load()
+			if err != nil {
+				s.log.WithError(err).Error("Could not load configuration to reload")
+				continue
+			}
+			if errs := s.ReloadAll(ctx, cfg); len(errs) > 0 {
+				s.log.WithField("failed", len(errs)).Warn("Some services could not reload their configuration")
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
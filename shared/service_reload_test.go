@@ -0,0 +1,112 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+	logTest "github.com/sirupsen/logrus/hooks/test"
+)
+
+type reloadingService struct {
+	err     error
+	panics  bool
+	block   chan struct{}
+	configs chan interface{}
+}
+
+func newReloadingService(err error) *reloadingService {
+	return &reloadingService{err: err, configs: make(chan interface{}, 4)}
+}
+
+func (s *reloadingService) Start() {}
+
+func (s *reloadingService) Stop() error {
+	return nil
+}
+
+func (s *reloadingService) Status() error {
+	return nil
+}
+
+func (s *reloadingService) ReloadConfig(_ context.Context, cfg interface{}) error {
+	if s.panics {
+		panic("bad config")
+	}
+	if s.block != nil {
+		<-s.block
+	}
+	s.configs <- cfg
+	return s.err
+}
+
+func TestReloadAll(t *testing.T) {
+	registry := NewServiceRegistry()
+	ok, failing, panicking := newReloadingService(nil), newReloadingService(errors.New("invalid peer limit")), newReloadingService(nil)
+	panicking.panics = true
+	require.NoError(t, registry.RegisterNamedService("p2p", ok, nil))
+	require.NoError(t, registry.RegisterNamedService("sync", failing, nil))
+	require.NoError(t, registry.RegisterNamedService("rpc", panicking, nil))
+	require.NoError(t, registry.RegisterService(&mockService{}))
+
+	errs := registry.ReloadAll(context.Background(), "config")
+	require.Equal(t, 2, len(errs))
+	assert.ErrorContains(t, "invalid peer limit", errs["sync"])
+	assert.ErrorContains(t, "service panicked during reload: bad config", errs["rpc"])
+	assert.Equal(t, "config", <-ok.configs)
+	assert.Equal(t, "config", <-failing.configs)
+}
+
+func TestReloadAll_ContextDone(t *testing.T) {
+	registry := NewServiceRegistry()
+	hung, next := newReloadingService(nil), newReloadingService(nil)
+	hung.block = make(chan struct{})
+	defer close(hung.block)
+	require.NoError(t, registry.RegisterNamedService("hung", hung, nil))
+	require.NoError(t, registry.RegisterNamedService("next", next, nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	errs := registry.ReloadAll(ctx, "config")
+	assert.Equal(t, context.DeadlineExceeded, errs["hung"])
+	assert.Equal(t, context.DeadlineExceeded, errs["next"])
+	assert.Equal(t, 0, len(next.configs))
+}
+
+func TestHandleReloadSignals(t *testing.T) {
+	hook := logTest.NewGlobal()
+	registry := NewServiceRegistry()
+	svc := newReloadingService(nil)
+	require.NoError(t, registry.RegisterService(svc))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sigc := make(chan os.Signal)
+	done := make(chan struct{})
+	loads := 0
+	go func() {
+		defer close(done)
+		registry.handleReloadSignals(ctx, sigc, func() (interface{}, error) {
+			loads++
+			if loads == 1 {
+				return nil, errors.New("unreadable config file")
+			}
+			return loads, nil
+		})
+	}()
+	sigc <- syscall.SIGHUP
+	sigc <- syscall.SIGHUP
+	select {
+	case cfg := <-svc.configs:
+		assert.Equal(t, 2, cfg)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Service did not reload its configuration")
+	}
+	cancel()
+	<-done
+	require.LogsContain(t, hook, "Could not load configuration to reload")
+}
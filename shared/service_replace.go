@@ -0,0 +1,167 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+)
+
+// ReplaceService replaces the service registered with the type of the given
+// one. The current service is stopped and its service context cancelled, then
+// the new service is installed with a fresh service context and the same
+// configuration, and started unless StartAll was not called yet or StopAll
+// was.
+//
+// Pointers obtained through FetchService keep referring to the replaced
+// service, so a warning is logged if it was ever fetched. Callers which need
+// to follow replacements should use a ServiceHandle instead.
+func (s *ServiceRegistry) ReplaceService(service Service) error {
+	if service == nil {
+		return errNilService
+	}
+	kind := reflect.TypeOf(service)
+	s.lock.RLock()
+	old, ok := s.services[kind]
+	closed := s.closed
+	s.lock.RUnlock()
+	if closed {
+		return fmt.Errorf("could not replace service %s: %w", typeName(kind), ErrRegistryClosed)
+	}
+	if !ok {
+		return &UnknownServiceError{Kind: kind}
+	}
+	if old.isLazy() {
+		return fmt.Errorf("could not replace service %v: %w", old, ErrServiceNotConstructed)
+	}
+	if state := s.stateOf(old); state != StateRegistered && state != StateStopped {
+		if err := s.stopService(context.Background(), old); err != nil {
+			return fmt.Errorf("could not stop service %v: %w", old, err)
+		}
+	}
+	if !s.contextInUse(old) {
+		old.ctx.Cancel()
+	}
+
+	s.lock.Lock()
+	if s.services[kind] != old {
+		s.lock.Unlock()
+		return fmt.Errorf("service %v was replaced or unregistered concurrently", old)
+	}
+	entry := s.newServiceEntry(service, old.ctx.renew(), old.cfg)
+	for _, e := range s.entries {
+		if e != old && e.String() == entry.String() {
+			s.lock.Unlock()
+			return fmt.Errorf("service name %s is already used by %s", entry, typeName(e.kind))
+		}
+	}
+	for i, e := range s.entries {
+		if e == old {
+			s.entries[i] = entry
+			break
+		}
+	}
+	s.services[kind] = entry
+	launch := s.phase.launched() && !s.stopping
+	s.lock.Unlock()
+
+	if fetches := atomic.LoadInt32(&old.fetches); fetches > 0 {
+		s.log.WithField("fetches", fetches).Warnf("Replaced service %v was fetched before, fetched references still use the old instance", entry)
+	}
+	s.log.Debugf("Replaced service %v", entry)
+	if launch {
+		s.launch(entry)
+	}
+	return nil
+}
+
+// ServiceHandle refers to the service registered with a given type, and
+// follows its replacements by ReplaceService.
+type ServiceHandle struct {
+	registry *ServiceRegistry
+	kind     reflect.Type
+}
+
+// Handle returns a handle to the service registered with the given type.
+func (s *ServiceRegistry) Handle(kind reflect.Type) (*ServiceHandle, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if _, ok := s.services[kind]; !ok {
+		return nil, &UnknownServiceError{Kind: kind}
+	}
+	return &ServiceHandle{registry: s, kind: kind}, nil
+}
+
+// Service returns the service currently registered with the type of the
+// handle, a Service or a ServiceV2, or nil if it was unregistered.
+func (h *ServiceHandle) Service() interface{} {
+	h.registry.lock.RLock()
+	defer h.registry.lock.RUnlock()
+	entry, ok := h.registry.services[h.kind]
+	if !ok {
+		return nil
+	}
+	return entry.service
+}
+
+// OverrideService substitutes another service, which does not need to be of
+// the same type, for the service registered with the given type, or for the
+// only registered service implementing the given interface type. The
+// substitute takes over the registration, including the name, configuration
+// and service context, and the type dependencies and statuses refer to, so
+// that tests can swap a service of a populated registry for a fake. It must
+// be called before StartAll.
+//
+// Once overridden, a service can no longer be fetched by its concrete type,
+// only by the interfaces the substitute implements.
+func (s *ServiceRegistry) OverrideService(kind reflect.Type, service Service) error {
+	if isNilService(service) {
+		return errNilService
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return fmt.Errorf("could not override service %s: %w", typeName(kind), ErrRegistryClosed)
+	}
+	if s.phase.startAllCalled() {
+		return fmt.Errorf("could not override service %s: %w", typeName(kind), ErrAlreadyStarted)
+	}
+	old, ok := s.services[kind]
+	if !ok && kind.Kind() == reflect.Interface {
+		for _, e := range s.entries {
+			if e.isLazy() || !reflect.TypeOf(e.service).Implements(kind) {
+				continue
+			}
+			if old != nil {
+				return fmt.Errorf("multiple services implement %s: %v and %v", typeName(kind), old, e)
+			}
+			old = e
+		}
+	}
+	if old == nil {
+		return &UnknownServiceError{Kind: kind}
+	}
+	if old.isLazy() {
+		return fmt.Errorf("could not override service %v: %w", old, ErrServiceNotConstructed)
+	}
+	if kind.Kind() == reflect.Interface && !reflect.TypeOf(service).Implements(kind) {
+		return fmt.Errorf("service %s does not implement %s", serviceName(service), typeName(kind))
+	}
+
+	entry := s.newServiceEntry(service, old.ctx, old.cfg)
+	entry.kind = old.kind
+	entry.name = old.name
+	for i, e := range s.entries {
+		if e == old {
+			s.entries[i] = entry
+			break
+		}
+	}
+	if old.name != "" {
+		s.named[old.name] = entry
+	} else {
+		s.services[old.kind] = entry
+	}
+	s.log.Debugf("Overrode service %v with %s", entry, serviceName(service))
+	return nil
+}
@@ -0,0 +1,129 @@
+package shared
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+	logTest "github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestReplaceService(t *testing.T) {
+	hook := logTest.NewGlobal()
+	registry := NewServiceRegistry()
+	old := &stopRecordingService{}
+	ctx := NewServiceContext()
+	require.NoError(t, registry.RegisterServiceWithConfig(old, ctx, nil))
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.StartAll())
+	kind := reflect.TypeOf(old)
+	waitForState(t, registry, kind, StateRunning)
+	var fetched *stopRecordingService
+	require.NoError(t, registry.FetchService(&fetched))
+	handle, err := registry.Handle(kind)
+	require.NoError(t, err)
+
+	replacement := &stopRecordingService{}
+	require.NoError(t, registry.ReplaceService(replacement))
+	assert.Equal(t, true, old.stopped)
+	assert.ErrorContains(t, "context canceled", ctx.Err())
+	waitForState(t, registry, kind, StateRunning)
+	require.LogsContain(t, hook, "Replaced service shared.stopRecordingService was fetched before")
+
+	require.NoError(t, registry.FetchService(&fetched))
+	assert.Equal(t, replacement, fetched)
+	assert.Equal(t, Service(replacement), handle.Service())
+	assert.DeepEqual(t, []reflect.Type{kind, reflect.TypeOf(&mockService{})}, entryKinds(registry.snapshot()))
+	require.NoError(t, registry.snapshot()[0].ctx.Err())
+
+	require.NoError(t, registry.StopAll())
+	assert.Equal(t, true, replacement.stopped)
+}
+
+func TestReplaceService_BeforeStartAll(t *testing.T) {
+	hook := logTest.NewGlobal()
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterServiceWithDeps(&secondMockService{}, reflect.TypeOf(&mockService{})))
+	require.NoError(t, registry.RegisterService(&mockService{}))
+
+	stub := &secondMockService{status: errors.New("stub")}
+	require.NoError(t, registry.ReplaceService(stub))
+	kind := reflect.TypeOf(stub)
+	state, err := registry.State(kind)
+	require.NoError(t, err)
+	assert.Equal(t, StateRegistered, state)
+	require.LogsDoNotContain(t, hook, "was fetched before")
+
+	// The configuration of the replaced service is kept.
+	require.NoError(t, registry.StartAll())
+	order, err := registry.startOrder()
+	require.NoError(t, err)
+	assert.DeepEqual(t, []reflect.Type{reflect.TypeOf(&mockService{}), kind}, entryKinds(order))
+	waitForState(t, registry, kind, StateRunning)
+	assert.ErrorContains(t, "stub", registry.Statuses()[kind])
+	require.NoError(t, registry.StopAll())
+}
+
+func TestReplaceService_Unknown(t *testing.T) {
+	registry := NewServiceRegistry()
+	err := registry.ReplaceService(&mockService{})
+	var unknown *UnknownServiceError
+	assert.Equal(t, true, errors.As(err, &unknown))
+	assert.ErrorContains(t, "cannot register a nil service", registry.ReplaceService(nil))
+	_, err = registry.Handle(reflect.TypeOf(&mockService{}))
+	assert.ErrorContains(t, "unknown service: shared.mockService", err)
+}
+
+func TestServiceHandle_Unregistered(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	handle, err := registry.Handle(reflect.TypeOf(&mockService{}))
+	require.NoError(t, err)
+	require.NoError(t, registry.UnregisterService(reflect.TypeOf(&mockService{})))
+	assert.Equal(t, nil, handle.Service())
+}
+
+func TestOverrideService(t *testing.T) {
+	registry := NewServiceRegistry()
+	kind := reflect.TypeOf(&mockService{})
+	require.NoError(t, registry.RegisterServiceWithDeps(&secondMockService{}, kind))
+	require.NoError(t, registry.RegisterService(&mockService{}))
+
+	fake := &thirdMockService{status: errors.New("fake")}
+	require.NoError(t, registry.OverrideService(kind, fake))
+	var fetched *mockService
+	assert.ErrorContains(t, "service shared.mockService is overridden by shared.thirdMockService", registry.FetchService(&fetched))
+
+	// The fake keeps the type dependencies and statuses refer to, and the
+	// dependent service is started although the fake is never ready.
+	registry.SetDependencyTimeout(10*time.Millisecond, false)
+	require.NoError(t, registry.StartAll())
+	order, err := registry.startOrder()
+	require.NoError(t, err)
+	assert.DeepEqual(t, []reflect.Type{kind, reflect.TypeOf(&secondMockService{})}, entryKinds(order))
+	waitForState(t, registry, kind, StateRunning)
+	assert.ErrorContains(t, "fake", registry.Statuses()[kind])
+	assert.ErrorContains(t, ErrAlreadyStarted.Error(), registry.OverrideService(kind, &thirdMockService{}))
+	require.NoError(t, registry.StopAll())
+}
+
+func TestOverrideService_ByInterface(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	kind := reflect.TypeOf((*Service)(nil)).Elem()
+	fake := &thirdMockService{}
+	require.NoError(t, registry.OverrideService(kind, fake))
+
+	var fetched Service
+	require.NoError(t, registry.FetchService(&fetched))
+	assert.Equal(t, Service(fake), fetched)
+
+	require.NoError(t, registry.RegisterService(&secondMockService{}))
+	assert.ErrorContains(t, "multiple services implement shared.Service", registry.OverrideService(kind, &thirdMockService{}))
+	err := registry.OverrideService(reflect.TypeOf(&thirdMockService{}), &mockService{})
+	var unknown *UnknownServiceError
+	assert.Equal(t, true, errors.As(err, &unknown))
+}
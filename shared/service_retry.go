@@ -0,0 +1,84 @@
+package shared
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// defaultRetryMultiplier is the backoff multiplier of a StartRetryPolicy
+// which does not set one.
+const defaultRetryMultiplier = 2
+
+// StartRetryPolicy configures how the registry retries the start of a service
+// which failed to start, such as a service whose Start method panicked
+// because a remote endpoint was not up yet.
+type StartRetryPolicy struct {
+	// MaxAttempts is the total number of starts attempted, including the
+	// first one.
+	MaxAttempts int
+	// InitialDelay is the delay before the first retry.
+	InitialDelay time.Duration
+	// Multiplier grows the delay between two consecutive retries. It
+	// defaults to 2.
+	Multiplier float64
+}
+
+// delay returns how long to wait before the next start, after the given
+// number of failed attempts.
+func (p *StartRetryPolicy) delay(attempts int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = defaultRetryMultiplier
+	}
+	return time.Duration(float64(p.InitialDelay) * math.Pow(multiplier, float64(attempts-1)))
+}
+
+// startFailed records why a service failed to start, and schedules another
+// start if the retry policy of the service allows it and StopAll did not run.
+// It returns whether a retry was scheduled.
+func (s *ServiceRegistry) startFailed(entry *serviceEntry, err error) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	entry.startAttempts++
+	attempts, policy := entry.startAttempts, entry.cfg.StartRetry
+	entry.state = StateStopped
+	s.failed(entry, err)
+	if policy == nil || policy.MaxAttempts <= 1 {
+		entry.startErr = err
+		s.criticalStartFailed(entry, err)
+		s.starting.settle(entry, err)
+		return false
+	}
+	if attempts >= policy.MaxAttempts || s.stopping {
+		entry.startErr = fmt.Errorf("start failed after %d attempts: %w", attempts, err)
+		s.criticalStartFailed(entry, entry.startErr)
+		s.starting.settle(entry, entry.startErr)
+		return false
+	}
+	entry.startErr = fmt.Errorf("start attempt %d of %d failed: %w", attempts, policy.MaxAttempts, err)
+	delay := policy.delay(attempts)
+	s.log.WithError(err).Warnf("Retrying start of service %v in %v", entry, delay)
+	go s.retryStart(entry, attempts, delay)
+	return true
+}
+
+// retryStart starts a service again after the given delay, unless StopAll
+// runs in the meantime or the service was started by other means.
+func (s *ServiceRegistry) retryStart(entry *serviceEntry, attempts int, delay time.Duration) {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-s.shutdown:
+		return
+	}
+	s.lock.Lock()
+	if s.stopping || entry.state != StateStopped || entry.startAttempts != attempts {
+		s.lock.Unlock()
+		return
+	}
+	entry.state = StateStarting
+	s.lock.Unlock()
+	go s.startService(entry)
+}
@@ -0,0 +1,96 @@
+package shared
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+// flakyStartService panics during its first starts, as many as its failures.
+type flakyStartService struct {
+	lock     sync.Mutex
+	failures int
+	starts   int
+}
+
+func (s *flakyStartService) Start() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.starts++
+	if s.starts <= s.failures {
+		panic("execution client not up")
+	}
+}
+
+func (s *flakyStartService) Stop() error {
+	return nil
+}
+
+func (s *flakyStartService) Status() error {
+	return nil
+}
+
+func (s *flakyStartService) startCount() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.starts
+}
+
+func TestStartRetry_SucceedsAfterFailures(t *testing.T) {
+	registry := NewServiceRegistry()
+	f := &flakyStartService{failures: 2}
+	require.NoError(t, registry.RegisterServiceWithConfig(f, nil, &ServiceConfig{
+		StartRetry: &StartRetryPolicy{MaxAttempts: 3, InitialDelay: time.Millisecond},
+	}))
+	require.NoError(t, registry.StartAll())
+
+	waitForState(t, registry, reflect.TypeOf(f), StateRunning)
+	assert.Equal(t, 3, f.startCount())
+	assert.NoError(t, registry.Statuses()[reflect.TypeOf(f)])
+	require.NoError(t, registry.StopAll())
+}
+
+func TestStartRetry_GivesUpAfterMaxAttempts(t *testing.T) {
+	registry := NewServiceRegistry(WithLegacyStartWait())
+	f := &flakyStartService{failures: 10}
+	require.NoError(t, registry.RegisterServiceWithConfig(f, nil, &ServiceConfig{
+		StartRetry: &StartRetryPolicy{MaxAttempts: 2, InitialDelay: time.Millisecond},
+	}))
+	// StartAll waits for the retries.
+	assert.ErrorContains(t, "start failed after 2 attempts", registry.StartAll())
+	assert.ErrorContains(t, "start failed after 2 attempts: service panicked during start: execution client not up", registry.Statuses()[reflect.TypeOf(f)])
+	assert.Equal(t, 2, f.startCount())
+	require.NoError(t, registry.StopAll())
+}
+
+func TestStartRetry_ReportsAttemptsAndStopsOnStopAll(t *testing.T) {
+	registry := NewServiceRegistry(WithLegacyStartWait())
+	f := &flakyStartService{failures: 10}
+	require.NoError(t, registry.RegisterServiceWithConfig(f, nil, &ServiceConfig{
+		StartRetry: &StartRetryPolicy{MaxAttempts: 3, InitialDelay: 50 * time.Millisecond},
+	}))
+	done := startAsync(registry)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for registry.Statuses()[reflect.TypeOf(f)] == nil && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.ErrorContains(t, "start attempt 1 of 3 failed", registry.Statuses()[reflect.TypeOf(f)])
+
+	require.NoError(t, registry.StopAll())
+	assert.ErrorContains(t, "registry was stopped before every service started", <-done)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 1, f.startCount(), "Expected no retry once StopAll ran")
+}
+
+func TestStartRetryPolicy_Delay(t *testing.T) {
+	p := &StartRetryPolicy{InitialDelay: time.Second}
+	assert.Equal(t, time.Second, p.delay(1))
+	assert.Equal(t, 4*time.Second, p.delay(3))
+	p.Multiplier = 1.5
+	assert.Equal(t, 2250*time.Millisecond, p.delay(3))
+}
@@ -0,0 +1,216 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultStartWaitTimeout is how long StartAll waits for the services to be
+// started, unless configured with WithStartWaitTimeout.
+const defaultStartWaitTimeout = 5 * time.Second
+
+// WithStartWaitTimeout configures how long StartAll waits for the services it
+// launched to be running or to have failed to start. StartAll returns once the
+// timeout elapsed with the services which are still starting left to start,
+// and their failures are no longer rolled back. A non-positive timeout makes
+// StartAll wait for every service.
+func WithStartWaitTimeout(timeout time.Duration) RegistryOption {
+	return func(s *ServiceRegistry) {
+		s.startWait = timeout
+	}
+}
+
+// WithLegacyStartWait makes StartAll also wait for the services implementing
+// Service to be running, rather than only for those implementing ServiceV2.
+// The Start method of a Service cannot report an error, and often only
+// returns once the service is done, such as a service syncing the chain, so
+// StartAll does not wait for them unless configured with this option.
+func WithLegacyStartWait() RegistryOption {
+	return func(s *ServiceRegistry) {
+		s.legacyStartWait = true
+	}
+}
+
+// errStoppedWhileStarting is the start result of a service which was stopped
+// before its Start method returned.
+var errStoppedWhileStarting = errors.New("service was stopped while starting")
+
+// startResult is the outcome of the start of a service launched by StartAll.
+type startResult struct {
+	entry *serviceEntry
+	err   error
+}
+
+// startWaiter follows the services StartAll waits for until each of them is
+// running or failed to start, which they report with settle.
+type startWaiter struct {
+	lock    sync.Mutex
+	awaited int
+	pending map[*serviceEntry]bool
+	running map[*serviceEntry]bool
+	results chan startResult
+}
+
+func newStartWaiter(entries []*serviceEntry) *startWaiter {
+	w := &startWaiter{
+		awaited: len(entries),
+		pending: make(map[*serviceEntry]bool, len(entries)),
+		running: make(map[*serviceEntry]bool, len(entries)),
+		results: make(chan startResult, len(entries)),
+	}
+	for _, entry := range entries {
+		w.pending[entry] = true
+	}
+	return w
+}
+
+// settle records that a service is running, or why it failed to start. Only
+// the first result of every service launched by StartAll is recorded, so that
+// the starts of a service by other means, such as a restart, are ignored.
+func (w *startWaiter) settle(entry *serviceEntry, err error) {
+	if w == nil {
+		return
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if !w.pending[entry] {
+		return
+	}
+	delete(w.pending, entry)
+	if err == nil {
+		w.running[entry] = true
+	}
+	w.results <- startResult{entry: entry, err: err}
+}
+
+// isRunning returns whether a service settled as running.
+func (w *startWaiter) isRunning(entry *serviceEntry) bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.running[entry]
+}
+
+// isPending returns whether a service did not settle yet.
+func (w *startWaiter) isPending(entry *serviceEntry) bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.pending[entry]
+}
+
+// awaitedStarts returns the launched services StartAll waits for: those
+// implementing ServiceV2, and those implementing Service if configured with
+// WithLegacyStartWait.
+func (s *ServiceRegistry) awaitedStarts(launched []*serviceEntry) []*serviceEntry {
+	awaited := make([]*serviceEntry, 0, len(launched))
+	for _, entry := range launched {
+		if _, ok := entry.service.(ServiceV2); ok || s.legacyStartWait {
+			awaited = append(awaited, entry)
+		}
+	}
+	return awaited
+}
+
+// waitStarted waits for every service StartAll waits for to be running or to
+// have failed to start, up to the start wait timeout. Once a service which
+// is not optional failed to start, or the context is done first, the services
+// which were started are stopped again and the error is returned.
+func (s *ServiceRegistry) waitStarted(ctx context.Context, w *startWaiter, launched []*serviceEntry) error {
+	defer func() {
+		s.lock.Lock()
+		s.starting = nil
+		s.lock.Unlock()
+	}()
+	var timeout <-chan time.Time
+	if s.startWait > 0 {
+		timer := time.NewTimer(s.startWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	for remaining := w.awaited; remaining > 0; remaining-- {
+		select {
+		case r := <-w.results:
+			if r.err == nil || r.entry.cfg.Optional {
+				continue
+			}
+			err := fmt.Errorf("could not start service %v: %w", r.entry, r.err)
+			s.rollbackStart(ctx, w, launched, err)
+			return err
+		case <-ctx.Done():
+			err := fmt.Errorf("could not start every service: %w", ctx.Err())
+			s.rollbackStart(ctx, w, launched, err)
+			return err
+		case <-s.shutdown:
+			return errors.New("registry was stopped before every service started")
+		case <-timeout:
+			for _, entry := range launched {
+				if w.isPending(entry) {
+					s.log.WithField("timeout", s.startWait).Infof("Service %v is still starting, not waiting for it", entry)
+				}
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// rollbackStart stops the services StartAll started, in reverse start order,
+// once the start of another service failed. The registry shuts down first so
+// that the services which were not started yet, such as those waiting for
+// their dependencies, are not started anymore, then the Start calls which are
+// still running are waited for, up to the start wait timeout or until the
+// context is done, after which those services are stopped while starting.
+// The services StartAll did not wait for are stopped as well, unless they
+// failed to start. StopAll has nothing left to do afterwards.
+func (s *ServiceRegistry) rollbackStart(ctx context.Context, w *startWaiter, launched []*serviceEntry, cause error) {
+	s.lock.Lock()
+	if s.stopping {
+		// StopAll is already stopping every service.
+		s.lock.Unlock()
+		return
+	}
+	s.phase = phaseRolledBack
+	s.beginStopping()
+	s.lock.Unlock()
+	s.log.WithError(cause).Error("Could not start services, stopping the services which were started")
+
+	var timeout <-chan time.Time
+	if s.startWait > 0 {
+		timer := time.NewTimer(s.startWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+wait:
+	for s.hasStartInFlight(w, launched) {
+		select {
+		case <-w.results:
+		case <-ctx.Done():
+			break wait
+		case <-timeout:
+			break wait
+		}
+	}
+	started := make([]*serviceEntry, 0, len(launched))
+	for _, entry := range launched {
+		if state := s.stateOf(entry); w.isRunning(entry) || state == StateStarting || state == StateRunning {
+			started = append(started, entry)
+		}
+	}
+	stopCtx := WithShutdownReason(context.Background(), ShutdownFatal)
+	if err := s.stopServices(stopCtx, started); err != nil {
+		s.log.WithError(err).Error("Could not stop every service which was started")
+	}
+}
+
+// hasStartInFlight returns whether the Start method of one of the launched
+// services is still running.
+func (s *ServiceRegistry) hasStartInFlight(w *startWaiter, launched []*serviceEntry) bool {
+	for _, entry := range launched {
+		if w.isPending(entry) && s.stateOf(entry) == StateStarting {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,170 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+// stopOrder records the order in which rollbackServices are stopped.
+type stopOrder struct {
+	lock  sync.Mutex
+	stops []int
+}
+
+func (o *stopOrder) get() []int {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return append([]int{}, o.stops...)
+}
+
+// rollbackService is the service of the given index, which panics in Start
+// when failing is set.
+type rollbackService struct {
+	index   int
+	failing bool
+	order   *stopOrder
+}
+
+func (s *rollbackService) Start() {
+	if s.failing {
+		panic("could not open database")
+	}
+}
+
+func (s *rollbackService) Stop() error {
+	s.order.lock.Lock()
+	defer s.order.lock.Unlock()
+	s.order.stops = append(s.order.stops, s.index)
+	return nil
+}
+
+func (s *rollbackService) Status() error {
+	return nil
+}
+
+func registerRollbackServices(t *testing.T, registry *ServiceRegistry, failing int) *stopOrder {
+	order := &stopOrder{}
+	for i := 0; i < 3; i++ {
+		svc := &rollbackService{index: i, failing: i == failing, order: order}
+		require.NoError(t, registry.RegisterNamedService(fmt.Sprintf("service-%d", i), svc, nil))
+	}
+	return order
+}
+
+func TestStartAll_RollsBackPartialStart(t *testing.T) {
+	registry := NewServiceRegistry(WithLegacyStartWait())
+	order := registerRollbackServices(t, registry, 2)
+
+	err := registry.StartAll()
+	assert.ErrorContains(t, "could not start service service-2: service panicked during start: could not open database", err)
+	assert.DeepEqual(t, []int{1, 0}, order.get(), "Services were not stopped in reverse start order")
+	statuses := registry.StatusesByName()
+	assert.ErrorContains(t, "service is stopped", statuses["service-0"])
+	assert.ErrorContains(t, "service is stopped", statuses["service-1"])
+	assert.ErrorContains(t, "could not open database", statuses["service-2"])
+
+	assert.ErrorContains(t, ErrAlreadyStarted.Error(), registry.StartAll())
+	// The services were already stopped.
+	require.NoError(t, registry.StopAll())
+	require.NoError(t, registry.Close())
+	assert.DeepEqual(t, []int{1, 0}, order.get())
+}
+
+func TestStartAll_RollbackSkipsServicesNotStarted(t *testing.T) {
+	registry := NewServiceRegistry(WithLegacyStartWait())
+	registry.SetReadyPollInterval(time.Millisecond)
+	stopped := &stopRecordingService{}
+	dependent := &startCountingService{}
+	require.NoError(t, registry.RegisterService(stopped))
+	require.NoError(t, registry.RegisterServiceWithDeps(dependent, reflect.TypeOf(&panickingStartService{})))
+	require.NoError(t, registry.RegisterService(&panickingStartService{started: make(chan struct{})}))
+
+	assert.ErrorContains(t, "could not bind port", registry.StartAll())
+	assert.Equal(t, true, stopped.stopped)
+	time.Sleep(20 * time.Millisecond)
+	dependent.lock.Lock()
+	defer dependent.lock.Unlock()
+	assert.Equal(t, 0, dependent.starts, "The dependent of the failed service was started")
+}
+
+func TestStartAll_OptionalStartFailureIsNotRolledBack(t *testing.T) {
+	registry := NewServiceRegistry()
+	stopped := &stopRecordingService{}
+	require.NoError(t, registry.RegisterService(stopped))
+	require.NoError(t, registry.RegisterServiceWithConfig(&panickingStartService{started: make(chan struct{})}, nil, &ServiceConfig{Optional: true}))
+
+	require.NoError(t, registry.StartAll())
+	assert.Equal(t, false, stopped.stopped)
+	require.NoError(t, registry.StopAll())
+	assert.Equal(t, true, stopped.stopped)
+}
+
+func TestStartAllWithContext_RollsBackOnContextDone(t *testing.T) {
+	registry := NewServiceRegistry(WithLegacyStartWait())
+	stopped := &stopRecordingService{}
+	blocking := &blockingStartService{release: make(chan struct{})}
+	defer close(blocking.release)
+	require.NoError(t, registry.RegisterService(stopped))
+	require.NoError(t, registry.RegisterService(blocking))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorContains(t, "could not start every service: context deadline exceeded", registry.StartAllWithContext(ctx))
+	assert.Equal(t, true, stopped.stopped)
+	state, err := registry.State(reflect.TypeOf(blocking))
+	require.NoError(t, err)
+	assert.Equal(t, StateStopped, state)
+}
+
+// blockingStartServiceV2 only returns from Start once released.
+type blockingStartServiceV2 struct {
+	release chan struct{}
+}
+
+func (s *blockingStartServiceV2) Start(context.Context) error {
+	<-s.release
+	return nil
+}
+
+func (s *blockingStartServiceV2) Stop() error {
+	return nil
+}
+
+func (s *blockingStartServiceV2) Status() error {
+	return nil
+}
+
+func TestStartAll_DoesNotWaitForLegacyServices(t *testing.T) {
+	registry := NewServiceRegistry()
+	blocking := &blockingStartService{release: make(chan struct{})}
+	require.NoError(t, registry.RegisterService(blocking))
+
+	start := time.Now()
+	require.NoError(t, registry.StartAll())
+	assert.Equal(t, true, time.Since(start) < defaultStartWaitTimeout, "StartAll waited for a legacy service")
+	state, err := registry.State(reflect.TypeOf(blocking))
+	require.NoError(t, err)
+	assert.Equal(t, StateStarting, state)
+	close(blocking.release)
+	require.NoError(t, registry.StopAll())
+}
+
+func TestStartAll_StartWaitTimeout(t *testing.T) {
+	registry := NewServiceRegistry(WithStartWaitTimeout(10 * time.Millisecond))
+	blocking := &blockingStartServiceV2{release: make(chan struct{})}
+	require.NoError(t, registry.RegisterServiceV2(blocking, nil, nil))
+
+	require.NoError(t, registry.StartAll())
+	state, err := registry.State(reflect.TypeOf(blocking))
+	require.NoError(t, err)
+	assert.Equal(t, StateStarting, state)
+	close(blocking.release)
+	require.NoError(t, registry.StopAll())
+}
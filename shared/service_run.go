@@ -0,0 +1,59 @@
+package shared
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Run starts every service, then blocks until the context is done or a fatal
+// error is reported through a channel given to RegisterFatalErrors, and
+// finally stops every service, with ShutdownFatal as the reason in the latter
+// case. The fatal error, if any, and the errors of
+// StopAll are returned together as a *MultiError. Like StartAll, Run can only
+// be called once: it returns ErrAlreadyStarted if StartAll was called before.
+func (s *ServiceRegistry) Run(ctx context.Context) error {
+	if err := s.StartAll(); err != nil {
+		return err
+	}
+	errs := &MultiError{}
+	reason := ShutdownNormal
+	select {
+	case <-ctx.Done():
+		s.log.Info("Context done, stopping services")
+	case err := <-s.fatal:
+		s.fatalLog(err).Error("Fatal error, stopping services")
+		errs.add(err)
+		reason = ShutdownFatal
+	}
+	errs.add(s.StopAll(reason))
+	return errs.errorOrNil()
+}
+
+// RegisterFatalErrors makes Run stop every service once an error is received
+// from the given channel. Only the first fatal error is kept: the next ones
+// are logged. The channel is no longer read once StopAll began.
+func (s *ServiceRegistry) RegisterFatalErrors(errc <-chan error) {
+	go func() {
+		for {
+			select {
+			case err, ok := <-errc:
+				if !ok {
+					return
+				}
+				s.reportFatal(err)
+			case <-s.shutdown:
+				return
+			}
+		}
+	}()
+}
+
+// reportFatal delivers a fatal error to Run, unless one was already, even if
+// Run has received it since.
+func (s *ServiceRegistry) reportFatal(err error) {
+	if atomic.CompareAndSwapInt32(&s.fatalReported, 0, 1) {
+		s.fatal <- err
+		return
+	}
+	s.fatalLog(err).Error("Ignoring fatal error, a previous one is already stopping the services")
+}
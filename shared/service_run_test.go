@@ -0,0 +1,66 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+	logTest "github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestRun_ContextCancelled(t *testing.T) {
+	registry := NewServiceRegistry()
+	svc := &stopRecordingService{}
+	require.NoError(t, registry.RegisterService(svc))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.NoError(t, registry.Run(ctx))
+	assert.Equal(t, true, svc.stopped)
+	assert.Equal(t, true, errors.Is(registry.Run(context.Background()), ErrAlreadyStarted))
+}
+
+func TestRun_FatalError(t *testing.T) {
+	hook := logTest.NewGlobal()
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&failingStopService{err: errors.New("db locked")}))
+	errc := make(chan error, 2)
+	registry.RegisterFatalErrors(errc)
+	errc <- errors.New("listener died")
+
+	done := make(chan error, 1)
+	go func() {
+		done <- registry.Run(context.Background())
+	}()
+	select {
+	case err := <-done:
+		var multiErr *MultiError
+		require.Equal(t, true, errors.As(err, &multiErr))
+		require.Equal(t, 2, len(multiErr.Errors))
+		assert.ErrorContains(t, "listener died", multiErr.Errors[0])
+		assert.ErrorContains(t, "db locked", multiErr.Errors[1])
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after a fatal error")
+	}
+	require.LogsContain(t, hook, "Fatal error, stopping services")
+}
+
+func TestRun_KeepsFirstFatalError(t *testing.T) {
+	hook := logTest.NewGlobal()
+	registry := NewServiceRegistry()
+	registry.reportFatal(errors.New("first"))
+	registry.reportFatal(errors.New("second"))
+	require.LogsContain(t, hook, "a previous one is already stopping the services")
+
+	assert.ErrorContains(t, "first", registry.Run(context.Background()))
+}
+
+func TestRun_StartAllFails(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterServiceWithDeps(&mockService{}, reflect.TypeOf(&secondMockService{})))
+	assert.ErrorContains(t, "depends on unregistered service", registry.Run(context.Background()))
+}
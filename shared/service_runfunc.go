@@ -0,0 +1,95 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// RunFuncService adapts a function running a background loop, such as a
+// cache pruner, into a service. Since every such service has the same type,
+// they are registered with RegisterNamedService.
+type RunFuncService struct {
+	name string
+	run  func(ctx context.Context) error
+
+	lock   sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{} // closed once the current run returned.
+	err    error         // error returned by the last run.
+}
+
+// NewRunFuncService returns a service whose Start calls run on a new
+// goroutine, and whose Stop cancels the context given to run and waits for it
+// to return. Its status is the error run last returned, if any: run may
+// return on its own, in which case the service is done rather than failed if
+// it returned nil.
+func NewRunFuncService(name string, run func(ctx context.Context) error) *RunFuncService {
+	return &RunFuncService{name: name, run: run}
+}
+
+// Name implements Named.
+func (s *RunFuncService) Name() string {
+	return s.name
+}
+
+// Start launches the function, unless it is still running.
+func (s *RunFuncService) Start() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.done != nil {
+		select {
+		case <-s.done:
+		default:
+			return
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	s.cancel, s.done, s.err = cancel, done, nil
+	go func() {
+		defer close(done)
+		err := s.call(ctx)
+		if errors.Is(err, context.Canceled) && ctx.Err() != nil {
+			// Returning because Stop cancelled the context is not a failure.
+			err = nil
+		}
+		s.lock.Lock()
+		s.err = err
+		s.lock.Unlock()
+	}()
+}
+
+// call runs the function, converting a panic into an error.
+func (s *RunFuncService) call(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s panicked: %v", s.name, r)
+		}
+	}()
+	return s.run(ctx)
+}
+
+// Stop cancels the context of the function and waits for it to return. The
+// error it returned, if any, is returned.
+func (s *RunFuncService) Stop() error {
+	s.lock.Lock()
+	cancel, done := s.cancel, s.done
+	s.lock.Unlock()
+	if done == nil {
+		return nil
+	}
+	cancel()
+	<-done
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.err
+}
+
+// Status returns the error the function last returned, if any.
+func (s *RunFuncService) Status() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.err
+}
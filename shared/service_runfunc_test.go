@@ -0,0 +1,80 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+func TestRunFuncService_Cancellation(t *testing.T) {
+	running := make(chan struct{})
+	s := NewRunFuncService("pruner", func(ctx context.Context) error {
+		close(running)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterNamedService(s.Name(), s, nil))
+	require.NoError(t, registry.StartAll())
+	<-running
+	assert.NoError(t, s.Status())
+
+	require.NoError(t, registry.StopAll())
+	assert.NoError(t, s.Status(), "Expected a cancelled run not to be reported as a failure")
+}
+
+func TestRunFuncService_EarlyExit(t *testing.T) {
+	returned := make(chan error)
+	s := NewRunFuncService("flusher", func(ctx context.Context) error {
+		return <-returned
+	})
+	s.Start()
+	returned <- errors.New("could not flush metrics")
+	waitForStatus(t, s, "could not flush metrics")
+	assert.ErrorContains(t, "could not flush metrics", s.Stop())
+
+	// Starting again resets the status.
+	s.Start()
+	assert.NoError(t, s.Status())
+	returned <- nil
+	require.NoError(t, s.Stop())
+	assert.NoError(t, s.Status())
+}
+
+func TestRunFuncService_Panic(t *testing.T) {
+	s := NewRunFuncService("pruner", func(ctx context.Context) error {
+		panic("nil cache")
+	})
+	s.Start()
+	waitForStatus(t, s, "pruner panicked: nil cache")
+}
+
+func TestRunFuncService_StartWhileRunning(t *testing.T) {
+	var runs int
+	s := NewRunFuncService("pruner", func(ctx context.Context) error {
+		runs++
+		<-ctx.Done()
+		return nil
+	})
+	s.Start()
+	s.Start()
+	require.NoError(t, s.Stop())
+	assert.Equal(t, 1, runs)
+	require.NoError(t, NewRunFuncService("never started", nil).Stop())
+}
+
+func waitForStatus(t *testing.T, s Service, msg string) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if err := s.Status(); err != nil {
+			assert.ErrorContains(t, msg, err)
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Status did not report %q", msg)
+}
@@ -0,0 +1,60 @@
+package shared
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrServiceCritical can be wrapped by a status error to make it critical,
+// even if it also wraps ErrServiceDegraded. Status errors are critical by
+// default, unless they come from an optional service.
+var ErrServiceCritical = errors.New("service critical")
+
+// Severity grades a status error.
+type Severity int
+
+const (
+	// SeverityOK is the severity of a nil status.
+	SeverityOK Severity = iota
+	// SeverityDegraded is the severity of a status error which leaves the
+	// node working, such as a low peer count. It is reported by status
+	// errors wrapping ErrServiceDegraded and by optional services.
+	SeverityDegraded
+	// SeverityCritical is the severity of any other status error, such as a
+	// database which cannot be written to.
+	SeverityCritical
+)
+
+// String returns the name of the severity.
+func (s Severity) String() string {
+	switch s {
+	case SeverityOK:
+		return "ok"
+	case SeverityDegraded:
+		return "degraded"
+	case SeverityCritical:
+		return "critical"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(s))
+	}
+}
+
+// StatusSeverity returns the severity of a status error, as reported by the
+// registry: the errors of optional services are degraded, then errors
+// wrapping ErrServiceCritical are critical and errors wrapping
+// ErrServiceDegraded are degraded. Any other error is critical.
+func StatusSeverity(err error) Severity {
+	var degraded *degradedError
+	switch {
+	case err == nil:
+		return SeverityOK
+	case errors.As(err, &degraded):
+		return SeverityDegraded
+	case errors.Is(err, ErrServiceCritical):
+		return SeverityCritical
+	case errors.Is(err, ErrServiceDegraded):
+		return SeverityDegraded
+	default:
+		return SeverityCritical
+	}
+}
@@ -0,0 +1,63 @@
+package shared
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+	"github.com/sirupsen/logrus"
+	logTest "github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestStatusSeverity(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want Severity
+	}{
+		{name: "nil", err: nil, want: SeverityOK},
+		{name: "plain error", err: errors.New("db closed"), want: SeverityCritical},
+		{name: "wrapped degraded", err: fmt.Errorf("low peer count: %w", ErrServiceDegraded), want: SeverityDegraded},
+		{name: "wrapped critical", err: fmt.Errorf("db closed: %w", ErrServiceCritical), want: SeverityCritical},
+		{name: "optional service", err: &degradedError{err: fmt.Errorf("db closed: %w", ErrServiceCritical)}, want: SeverityDegraded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, StatusSeverity(tt.err))
+		})
+	}
+	assert.Equal(t, "degraded", SeverityDegraded.String())
+}
+
+func TestHealthzHandler_DegradedSeverity(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.RegisterService(&secondMockService{status: fmt.Errorf("low peer count: %w", ErrServiceDegraded)}))
+	require.NoError(t, registry.StartAll())
+
+	code, resp := serveHealthz(t, registry)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, healthzDegraded, resp.Status)
+	require.NotNil(t, resp.Services["shared.secondMockService"])
+	assert.Equal(t, "low peer count: service degraded", *resp.Services["shared.secondMockService"])
+}
+
+func TestStatusPoller_LogsBySeverity(t *testing.T) {
+	hook := logTest.NewGlobal()
+	registry := NewServiceRegistry()
+	s := &statusFuncService{}
+	s.setStatus(func() error { return fmt.Errorf("low peer count: %w", ErrServiceDegraded) })
+	require.NoError(t, registry.RegisterService(s))
+	p := &statusPoller{registry: registry, interval: time.Second, last: make(map[string]string)}
+
+	p.poll()
+	assert.Equal(t, logrus.WarnLevel, hook.LastEntry().Level)
+
+	s.setStatus(func() error { return errors.New("db closed") })
+	p.poll()
+	assert.Equal(t, logrus.ErrorLevel, hook.LastEntry().Level)
+}
@@ -0,0 +1,54 @@
+package shared
+
+import (
+	"sort"
+
+	"github.com/prysmaticlabs/prysm/shared/rand"
+)
+
+// WithShuffledStartOrder makes the registry shuffle the start order of
+// services of the same priority with the given seed, to reveal services which
+// only work because of the order they were registered in. Declared
+// dependencies are still started first. A seed of zero picks a random seed,
+// as WithRandomShuffledStartOrder does. The seed is logged by StartAll, so
+// that a failing order can be reproduced. It is meant for tests and debugging
+// only.
+func WithShuffledStartOrder(seed int64) RegistryOption {
+	return func(s *ServiceRegistry) {
+		for seed == 0 {
+			seed = rand.NewGenerator().Int63()
+		}
+		s.shuffleSeed = seed
+	}
+}
+
+// WithRandomShuffledStartOrder makes the registry shuffle the start order of
+// services as WithShuffledStartOrder does, with a random seed.
+func WithRandomShuffledStartOrder() RegistryOption {
+	return WithShuffledStartOrder(0)
+}
+
+// StartOrderSeed returns the seed the start order of services is shuffled
+// with, or zero if it is not shuffled.
+func (s *ServiceRegistry) StartOrderSeed() int64 {
+	return s.shuffleSeed
+}
+
+// shuffled returns the services, sorted by priority, after shuffling those of
+// the same priority with the seed of the registry. The order is the same for
+// the same services and seed, so that StopAll stops services in the reverse
+// order StartAll started them in.
+func (s *ServiceRegistry) shuffled(entries []*serviceEntry) []*serviceEntry {
+	if s.shuffleSeed == 0 {
+		return entries
+	}
+	gen := rand.NewDeterministicGenerator()
+	gen.Seed(s.shuffleSeed)
+	gen.Shuffle(len(entries), func(i, j int) {
+		entries[i], entries[j] = entries[j], entries[i]
+	})
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].cfg.Priority < entries[j].cfg.Priority
+	})
+	return entries
+}
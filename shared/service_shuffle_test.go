@@ -0,0 +1,64 @@
+package shared
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+// shuffleRegistry registers several independent named services, a service
+// depending on another one, and a service of higher priority.
+func shuffleRegistry(t *testing.T, opts ...RegistryOption) *ServiceRegistry {
+	registry := NewServiceRegistry(opts...)
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		require.NoError(t, registry.RegisterNamedService(name, &mockService{}, nil))
+	}
+	require.NoError(t, registry.RegisterServiceWithDeps(&secondMockService{}, reflect.TypeOf(&thirdMockService{})))
+	require.NoError(t, registry.RegisterService(&thirdMockService{}))
+	require.NoError(t, registry.RegisterServiceWithPriority(&chainInfoService{}, -1))
+	return registry
+}
+
+func startOrderNames(t *testing.T, registry *ServiceRegistry) []string {
+	order, err := registry.startOrder()
+	require.NoError(t, err)
+	names := make([]string, len(order))
+	for i, entry := range order {
+		names[i] = entry.String()
+	}
+	return names
+}
+
+func TestWithShuffledStartOrder(t *testing.T) {
+	registered := startOrderNames(t, shuffleRegistry(t))
+	assert.Equal(t, int64(0), shuffleRegistry(t).StartOrderSeed())
+
+	orders := make(map[string]bool)
+	for seed := int64(1); seed <= 10; seed++ {
+		registry := shuffleRegistry(t, WithShuffledStartOrder(seed))
+		assert.Equal(t, seed, registry.StartOrderSeed())
+		order := startOrderNames(t, registry)
+		assert.DeepEqual(t, order, startOrderNames(t, registry), "order is not reproducible")
+		assert.DeepEqual(t, order, startOrderNames(t, shuffleRegistry(t, WithShuffledStartOrder(seed))), "order is not reproducible")
+		assert.Equal(t, len(registered), len(order))
+
+		position := make(map[string]int, len(order))
+		for i, name := range order {
+			position[name] = i
+		}
+		assert.Equal(t, 0, position["shared.chainInfoService"], "priority was not honored")
+		assert.Equal(t, true, position["shared.thirdMockService"] < position["shared.secondMockService"], "dependency was not honored")
+		orders[strings.Join(order, ",")] = true
+	}
+	assert.Equal(t, true, len(orders) > 1, "start order was never shuffled")
+}
+
+func TestWithRandomShuffledStartOrder(t *testing.T) {
+	registry := shuffleRegistry(t, WithRandomShuffledStartOrder())
+	assert.NotEqual(t, int64(0), registry.StartOrderSeed())
+	require.NoError(t, registry.StartAll())
+	require.NoError(t, registry.StopAll())
+}
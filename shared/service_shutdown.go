@@ -0,0 +1,125 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// shutdownNoticeTimeout bounds how long StopAll waits for a service to return
+// from ShutdownImminent.
+const shutdownNoticeTimeout = time.Second
+
+// ShutdownReason tells services why they are being stopped, so that they can
+// behave differently on a fatal error than on a normal exit, for instance by
+// not saying goodbye to their peers.
+type ShutdownReason string
+
+const (
+	// ShutdownNormal is the reason of a shutdown requested by the node
+	// itself, the default of StopAll.
+	ShutdownNormal ShutdownReason = "normal"
+	// ShutdownSignal is the reason of a shutdown caused by SIGINT or SIGTERM.
+	ShutdownSignal ShutdownReason = "signal"
+	// ShutdownFatal is the reason of a shutdown caused by a fatal error
+	// reported to Run.
+	ShutdownFatal ShutdownReason = "fatal"
+	// ShutdownReload is the reason of a service stopped to reload its
+	// configuration.
+	ShutdownReload ShutdownReason = "reload"
+	// ShutdownRestart is the reason of a service stopped by RestartService.
+	ShutdownRestart ShutdownReason = "restart"
+)
+
+type shutdownReasonKey struct{}
+
+// WithShutdownReason returns a copy of the context carrying the given reason,
+// which StopAllWithContext passes on to the services it stops.
+func WithShutdownReason(ctx context.Context, reason ShutdownReason) context.Context {
+	return context.WithValue(ctx, shutdownReasonKey{}, reason)
+}
+
+// ShutdownReasonFromContext returns the reason a service is being stopped
+// for. Once the registry began stopping a service, the reason is carried by
+// its service context and every context derived from it. The second return
+// value is false if the service is not being stopped.
+func ShutdownReasonFromContext(ctx context.Context) (ShutdownReason, bool) {
+	reason, ok := ctx.Value(shutdownReasonKey{}).(ShutdownReason)
+	return reason, ok
+}
+
+// shutdownReason returns the reason carried by the context, defaulting to
+// ShutdownNormal.
+func shutdownReason(ctx context.Context) ShutdownReason {
+	if reason, ok := ShutdownReasonFromContext(ctx); ok {
+		return reason
+	}
+	return ShutdownNormal
+}
+
+// ShutdownListener is implemented by services which should stop accepting new
+// work as soon as the node begins shutting down, such as API servers replying
+// unavailable to new requests or queues no longer enqueueing, rather than
+// once their turn to be stopped comes.
+type ShutdownListener interface {
+	// ShutdownImminent tells the service StopAll began. It is called
+	// before any service is stopped, and must return quickly.
+	ShutdownImminent()
+}
+
+// ShutdownImminent tells the services of the registry it is about to be
+// stopped, so that a subsystem registered with RegisterSubsystem notifies
+// its services along with those of its parent.
+func (s *ServiceRegistry) ShutdownImminent() {
+	s.notifyShutdown(context.Background())
+}
+
+// notifyShutdown calls ShutdownImminent on every active service implementing
+// ShutdownListener, concurrently, and returns once each of them returned or
+// was given up on after shutdownNoticeTimeout, or once the context is done.
+// The services are only notified once, even if StopAll is called again.
+func (s *ServiceRegistry) notifyShutdown(ctx context.Context) {
+	s.shutdownNotice.Do(func() {
+		var wg sync.WaitGroup
+		for _, entry := range s.snapshot() {
+			listener, ok := entry.service.(ShutdownListener)
+			if !ok || !s.isActive(entry) {
+				continue
+			}
+			wg.Add(1)
+			go func(entry *serviceEntry) {
+				defer wg.Done()
+				if err := s.notifyShutdownOf(ctx, entry, listener); err != nil {
+					s.log.WithError(err).Warnf("Could not notify service %v of the shutdown", entry)
+				}
+			}(entry)
+		}
+		wg.Wait()
+	})
+}
+
+// notifyShutdownOf calls ShutdownImminent on a service, recovering a panic
+// and giving up once shutdownNoticeTimeout elapsed or the context is done.
+func (s *ServiceRegistry) notifyShutdownOf(parent context.Context, entry *serviceEntry, listener ShutdownListener) error {
+	ctx, cancel := context.WithTimeout(parent, shutdownNoticeTimeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				s.recordPanic(entry, r, debug.Stack())
+				done <- fmt.Errorf("service panicked during shutdown notice: %v", r)
+			}
+		}()
+		listener.ShutdownImminent()
+		done <- nil
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("shutdown notice did not return: %w", ctx.Err())
+	}
+}
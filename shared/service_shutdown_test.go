@@ -0,0 +1,245 @@
+package shared
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+	logTest "github.com/sirupsen/logrus/hooks/test"
+)
+
+type reasonRecordingService struct {
+	ctx     *ServiceContext
+	reasons chan ShutdownReason
+}
+
+func newReasonRecordingService(registry *ServiceRegistry) *reasonRecordingService {
+	return &reasonRecordingService{ctx: registry.NewServiceContext(), reasons: make(chan ShutdownReason, 4)}
+}
+
+func (s *reasonRecordingService) Start() {}
+
+func (s *reasonRecordingService) Stop() error {
+	// Read the reason from a context derived from the service context, as
+	// the goroutines of a service would.
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+	reason, ok := ShutdownReasonFromContext(ctx)
+	if !ok {
+		reason = "none"
+	}
+	s.reasons <- reason
+	return nil
+}
+
+func (s *reasonRecordingService) Status() error {
+	return nil
+}
+
+func (s *reasonRecordingService) reason(t *testing.T) ShutdownReason {
+	select {
+	case reason := <-s.reasons:
+		return reason
+	case <-time.After(5 * time.Second):
+		t.Fatal("Service was not stopped")
+		return ""
+	}
+}
+
+func TestShutdownReasonFromContext(t *testing.T) {
+	_, ok := ShutdownReasonFromContext(context.Background())
+	assert.Equal(t, false, ok)
+
+	reason, ok := ShutdownReasonFromContext(WithShutdownReason(context.Background(), ShutdownReload))
+	assert.Equal(t, true, ok)
+	assert.Equal(t, ShutdownReload, reason)
+}
+
+func TestStopAll_ShutdownReason(t *testing.T) {
+	tests := []struct {
+		name string
+		stop func(registry *ServiceRegistry) error
+		want ShutdownReason
+	}{
+		{
+			name: "default",
+			stop: func(registry *ServiceRegistry) error { return registry.StopAll() },
+			want: ShutdownNormal,
+		},
+		{
+			name: "fatal",
+			stop: func(registry *ServiceRegistry) error { return registry.StopAll(ShutdownFatal) },
+			want: ShutdownFatal,
+		},
+		{
+			name: "context",
+			stop: func(registry *ServiceRegistry) error {
+				return registry.StopAllWithContext(WithShutdownReason(context.Background(), ShutdownReload))
+			},
+			want: ShutdownReload,
+		},
+		{
+			name: "context without reason",
+			stop: func(registry *ServiceRegistry) error { return registry.StopAllWithContext(context.Background()) },
+			want: ShutdownNormal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewServiceRegistry()
+			first, second := newReasonRecordingService(registry), newReasonRecordingService(registry)
+			require.NoError(t, registry.RegisterNamedService("first", first, first.ctx))
+			require.NoError(t, registry.RegisterNamedService("second", second, second.ctx))
+			require.NoError(t, registry.StartAll())
+
+			_, ok := ShutdownReasonFromContext(first.ctx)
+			assert.Equal(t, false, ok, "Running service should have no shutdown reason")
+			require.NoError(t, tt.stop(registry))
+			assert.Equal(t, tt.want, first.reason(t))
+			assert.Equal(t, tt.want, second.reason(t))
+		})
+	}
+}
+
+func TestRun_FatalShutdownReason(t *testing.T) {
+	registry := NewServiceRegistry()
+	svc := newReasonRecordingService(registry)
+	require.NoError(t, registry.RegisterNamedService("recorder", svc, svc.ctx))
+	errc := make(chan error, 1)
+	registry.RegisterFatalErrors(errc)
+	errc <- os.ErrClosed
+
+	assert.ErrorContains(t, os.ErrClosed.Error(), registry.Run(context.Background()))
+	assert.Equal(t, ShutdownFatal, svc.reason(t))
+}
+
+func TestHandleSignals_ShutdownReason(t *testing.T) {
+	registry := NewServiceRegistry()
+	svc := newReasonRecordingService(registry)
+	require.NoError(t, registry.RegisterNamedService("recorder", svc, svc.ctx))
+	require.NoError(t, registry.StartAll())
+	sigc := make(chan os.Signal, 1)
+	sigc <- syscall.SIGTERM
+
+	require.NoError(t, registry.handleSignals(context.Background(), sigc))
+	assert.Equal(t, ShutdownSignal, svc.reason(t))
+}
+
+func TestRestartService_ShutdownReason(t *testing.T) {
+	registry := NewServiceRegistry()
+	svc := &reasonRecordingService{reasons: make(chan ShutdownReason, 4)}
+	require.NoError(t, registry.RegisterService(svc))
+	kind := reflect.TypeOf(svc)
+	registry.lock.RLock()
+	svc.ctx = registry.services[kind].ctx
+	registry.lock.RUnlock()
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, kind, StateRunning)
+
+	require.NoError(t, registry.RestartService(kind))
+	assert.Equal(t, ShutdownRestart, svc.reason(t))
+
+	// The restarted service has a fresh service context, without a reason.
+	registry.lock.RLock()
+	ctx := registry.services[kind].ctx
+	registry.lock.RUnlock()
+	_, ok := ShutdownReasonFromContext(ctx)
+	assert.Equal(t, false, ok)
+}
+
+type listeningService struct {
+	drainingService
+	block chan struct{}
+}
+
+func (s *listeningService) ShutdownImminent() {
+	s.log.add("imminent " + s.name)
+	if s.block != nil {
+		<-s.block
+	}
+}
+
+func TestStopAll_NotifiesShutdownBeforeStopping(t *testing.T) {
+	registry := NewServiceRegistry(WithoutPreStop())
+	calls := &callLog{}
+	first := &listeningService{drainingService: drainingService{name: "first", log: calls}}
+	second := &listeningService{drainingService: drainingService{name: "second", log: calls}}
+	require.NoError(t, registry.RegisterNamedService("first", first, nil))
+	require.NoError(t, registry.RegisterNamedService("plain", &drainingService{name: "plain", log: calls}, nil))
+	require.NoError(t, registry.RegisterNamedService("second", second, nil))
+	require.NoError(t, registry.StartAll())
+	waitForNamedState(t, registry, "second", StateRunning)
+
+	require.NoError(t, registry.StopAll())
+	got := calls.get()
+	require.Equal(t, 5, len(got), got)
+	assert.DeepEqual(t, []string{"imminent first", "imminent second"}, sortedStrings(got[:2]))
+	for _, call := range got[2:] {
+		assert.Equal(t, true, strings.HasPrefix(call, "stop "), got)
+	}
+
+	// The services are only notified once.
+	require.NoError(t, registry.StopAll())
+	for _, call := range calls.get()[5:] {
+		assert.Equal(t, false, strings.HasPrefix(call, "imminent "), calls.get())
+	}
+}
+
+func TestStopAll_NotifiesShutdownBeforePreStop(t *testing.T) {
+	registry := NewServiceRegistry()
+	calls := &callLog{}
+	require.NoError(t, registry.RegisterService(&listeningService{drainingService: drainingService{name: "api", log: calls}}))
+	require.NoError(t, registry.StartAll())
+	waitForNamedState(t, registry, "api", StateRunning)
+
+	require.NoError(t, registry.StopAll())
+	assert.DeepEqual(t, []string{"imminent api", "prestop api", "stop api"}, calls.get())
+}
+
+func TestStopAll_ShutdownNoticeIsBounded(t *testing.T) {
+	hook := logTest.NewGlobal()
+	registry := NewServiceRegistry(WithoutPreStop())
+	calls := &callLog{}
+	hung := &listeningService{drainingService: drainingService{name: "hung", log: calls}, block: make(chan struct{})}
+	defer close(hung.block)
+	require.NoError(t, registry.RegisterService(hung))
+	require.NoError(t, registry.StartAll())
+	waitForNamedState(t, registry, "hung", StateRunning)
+
+	start := time.Now()
+	require.NoError(t, registry.StopAll())
+	assert.Equal(t, true, time.Since(start) < shutdownNoticeTimeout+time.Second, "shutdown notice was not bounded")
+	assert.DeepEqual(t, []string{"imminent hung", "stop hung"}, calls.get())
+	require.LogsContain(t, hook, "Could not notify service hung of the shutdown")
+}
+
+func TestStopAll_ShutdownNoticeReachesSubsystems(t *testing.T) {
+	registry := NewServiceRegistry(WithoutPreStop())
+	child := NewServiceRegistry(WithoutPreStop())
+	calls := &callLog{}
+	require.NoError(t, child.RegisterService(&listeningService{drainingService: drainingService{name: "child", log: calls}}))
+	require.NoError(t, registry.RegisterSubsystem("p2p", child))
+	require.NoError(t, registry.RegisterService(&drainingService{name: "parent", log: calls}))
+	require.NoError(t, registry.StartAll())
+	waitForNamedState(t, registry, "parent", StateRunning)
+	waitForNamedState(t, child, "child", StateRunning)
+
+	require.NoError(t, registry.StopAll())
+	got := calls.get()
+	require.Equal(t, 3, len(got), got)
+	assert.Equal(t, "imminent child", got[0])
+	assert.DeepEqual(t, []string{"stop child", "stop parent"}, sortedStrings(got[1:]))
+}
+
+func sortedStrings(s []string) []string {
+	sorted := append([]string(nil), s...)
+	sort.Strings(sorted)
+	return sorted
+}
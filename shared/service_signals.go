@@ -0,0 +1,42 @@
+package shared
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// HandleShutdownSignals blocks until the process receives SIGINT or SIGTERM,
+// then stops every service with ShutdownSignal as the reason and returns the
+// result of StopAll. Signals received while the services are stopping are
+// logged and otherwise ignored, so StopAll runs only once. If the context is
+// done before any signal is received, HandleShutdownSignals returns nil
+// without stopping the services.
+func (s *ServiceRegistry) HandleShutdownSignals(ctx context.Context) error {
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigc)
+	return s.handleSignals(ctx, sigc)
+}
+
+func (s *ServiceRegistry) handleSignals(ctx context.Context, sigc <-chan os.Signal) error {
+	select {
+	case sig := <-sigc:
+		s.log.WithField("signal", sig).Info("Got interrupt, shutting down...")
+	case <-ctx.Done():
+		return nil
+	}
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- s.StopAll(ShutdownSignal)
+	}()
+	for {
+		select {
+		case err := <-stopped:
+			return err
+		case sig := <-sigc:
+			s.log.WithField("signal", sig).Info("Already shutting down")
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package shared
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+// countingStopService counts the calls to its Stop method, which blocks
+// until released.
+type countingStopService struct {
+	stops   int32
+	release chan struct{}
+}
+
+func (s *countingStopService) Start() {
+}
+
+func (s *countingStopService) Stop() error {
+	atomic.AddInt32(&s.stops, 1)
+	<-s.release
+	return nil
+}
+
+func (s *countingStopService) Status() error {
+	return nil
+}
+
+func TestHandleSignals_StopsOnceOnFirstSignal(t *testing.T) {
+	registry := NewServiceRegistry()
+	c := &countingStopService{release: make(chan struct{})}
+	require.NoError(t, registry.RegisterService(c))
+	require.NoError(t, registry.StartAll())
+
+	sigc := make(chan os.Signal)
+	done := make(chan error, 1)
+	go func() {
+		done <- registry.handleSignals(context.Background(), sigc)
+	}()
+	sigc <- syscall.SIGINT
+	sigc <- syscall.SIGTERM
+	close(c.release)
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Signal handler did not return once services stopped")
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&c.stops))
+}
+
+func TestHandleSignals_ContextCancelled(t *testing.T) {
+	registry := NewServiceRegistry()
+	c := &countingStopService{release: make(chan struct{})}
+	close(c.release)
+	require.NoError(t, registry.RegisterService(c))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, registry.HandleShutdownSignals(ctx))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&c.stops), "Expected services not to be stopped")
+}
@@ -0,0 +1,33 @@
+package shared
+
+import (
+	"runtime"
+	"time"
+)
+
+// maxStackDumpSize bounds the size of the goroutine dump logged when a service
+// does not stop in time.
+const maxStackDumpSize = 64 << 20
+
+// dumpStacks logs the stacks of all goroutines, labeled with the service
+// whose Stop did not return within its timeout, so that a hanging shutdown
+// can be diagnosed. Dumps are large, so only the first one of the registry is
+// logged.
+func (s *ServiceRegistry) dumpStacks(entry *serviceEntry, timeout time.Duration) {
+	s.stackDump.Do(func() {
+		s.log.WithField("service", entry.String()).Errorf("Service %v did not stop within %v, goroutine stacks:\n%s", entry, timeout, allStacks())
+	})
+}
+
+// allStacks returns the stacks of all goroutines, growing the buffer until
+// they fit or it reaches maxStackDumpSize.
+func allStacks() []byte {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) || len(buf) >= maxStackDumpSize {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
@@ -0,0 +1,69 @@
+package shared
+
+// WithMaxConcurrentStarts limits how many services StartAll lets be starting
+// at once, counting a service from the call to its Start method until it is
+// ready or failed, so that small machines are not overwhelmed by every
+// service opening files, dialing peers and allocating at the same time. The
+// services without dependencies are admitted in start order, and a service
+// with dependencies waits for a slot once its dependencies are ready. By
+// default, the number of services starting at once is not limited.
+func WithMaxConcurrentStarts(n int) RegistryOption {
+	return func(s *ServiceRegistry) {
+		if n > 0 {
+			s.startSlots = make(chan struct{}, n)
+		} else {
+			s.startSlots = nil
+		}
+	}
+}
+
+// launchWithinLimit launches the given services in order, each once a start
+// slot is free, or once its dependencies are ready and a start slot is free,
+// until the registry shuts down.
+func (s *ServiceRegistry) launchWithinLimit(entries []*serviceEntry) {
+	for _, entry := range entries {
+		if len(entry.cfg.Dependencies) > 0 {
+			go s.waitForDependencies(entry)
+			continue
+		}
+		if !s.acquireStartSlot() {
+			return
+		}
+		go s.releaseStartSlot(entry)
+		s.lock.Lock()
+		if s.stopping {
+			s.lock.Unlock()
+			return
+		}
+		entry.state = StateStarting
+		s.lock.Unlock()
+		go s.startService(entry)
+	}
+}
+
+// acquireStartSlot blocks until fewer than the maximum number of services
+// are starting, and returns false if the registry shut down first. It
+// returns true right away if the number of starting services is not limited.
+func (s *ServiceRegistry) acquireStartSlot() bool {
+	if s.startSlots == nil {
+		return true
+	}
+	select {
+	case s.startSlots <- struct{}{}:
+		return true
+	case <-s.shutdown:
+		return false
+	}
+}
+
+// releaseStartSlot frees the start slot of a service launched after
+// acquireStartSlot once it is ready, failed, or the registry shut down.
+func (s *ServiceRegistry) releaseStartSlot(entry *serviceEntry) {
+	if s.startSlots == nil {
+		return
+	}
+	if err := s.waitUntilReady(entry); err != nil {
+		s.log.WithError(err).Debugf("Service %v did not become ready, admitting the next service", entry)
+	}
+	<-s.startSlots
+}
@@ -0,0 +1,78 @@
+package shared
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+func TestWithMaxConcurrentStarts_AdmitsOnceReady(t *testing.T) {
+	registry := NewServiceRegistry(WithMaxConcurrentStarts(1), WithStatusPollInterval(5*time.Millisecond), WithoutPreStop())
+	syncing := &syncingService{}
+	require.NoError(t, registry.RegisterService(syncing))
+	require.NoError(t, registry.RegisterService(&startCountingService{}))
+	done := startAsync(registry)
+
+	waitForState(t, registry, reflect.TypeOf(syncing), StateRunning)
+	time.Sleep(50 * time.Millisecond)
+	state, err := registry.State(reflect.TypeOf(&startCountingService{}))
+	require.NoError(t, err)
+	assert.Equal(t, StateRegistered, state, "service started before the previous one was ready")
+
+	syncing.setSynced()
+	require.NoError(t, <-done)
+	waitForState(t, registry, reflect.TypeOf(&startCountingService{}), StateRunning)
+	require.NoError(t, registry.StopAll())
+}
+
+func TestWithMaxConcurrentStarts_AdmitsAfterFailure(t *testing.T) {
+	registry := NewServiceRegistry(WithMaxConcurrentStarts(1), WithStatusPollInterval(5*time.Millisecond), WithoutPreStop())
+	p := &panickingStartService{started: make(chan struct{})}
+	require.NoError(t, registry.RegisterServiceWithConfig(p, nil, &ServiceConfig{Optional: true}))
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.StartAll())
+
+	waitForState(t, registry, reflect.TypeOf(&mockService{}), StateRunning)
+	require.NoError(t, registry.StopAll())
+}
+
+func TestWithMaxConcurrentStarts_KeepsDependencyOrder(t *testing.T) {
+	registry := NewServiceRegistry(WithMaxConcurrentStarts(2), WithStatusPollInterval(5*time.Millisecond), WithoutPreStop())
+	syncing := &syncingService{}
+	require.NoError(t, registry.RegisterServiceWithDeps(&startCountingService{}, reflect.TypeOf(syncing)))
+	require.NoError(t, registry.RegisterService(syncing))
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	done := startAsync(registry)
+
+	// A free slot does not let a service start before its dependencies are
+	// ready.
+	waitForState(t, registry, reflect.TypeOf(&mockService{}), StateRunning)
+	time.Sleep(50 * time.Millisecond)
+	state, err := registry.State(reflect.TypeOf(&startCountingService{}))
+	require.NoError(t, err)
+	assert.Equal(t, StateRegistered, state)
+
+	syncing.setSynced()
+	require.NoError(t, <-done)
+	waitForState(t, registry, reflect.TypeOf(&startCountingService{}), StateRunning)
+	require.NoError(t, registry.StopAll())
+}
+
+func TestWithMaxConcurrentStarts_StopWhileQueued(t *testing.T) {
+	registry := NewServiceRegistry(WithLegacyStartWait(), WithMaxConcurrentStarts(1), WithStatusPollInterval(5*time.Millisecond), WithoutPreStop())
+	queued := &startCountingService{}
+	require.NoError(t, registry.RegisterService(&syncingService{}))
+	require.NoError(t, registry.RegisterService(queued))
+	done := startAsync(registry)
+
+	waitForState(t, registry, reflect.TypeOf(&syncingService{}), StateRunning)
+	require.NoError(t, registry.StopAll())
+	assert.ErrorContains(t, "registry was stopped before every service started", <-done)
+	time.Sleep(20 * time.Millisecond)
+	queued.lock.Lock()
+	defer queued.lock.Unlock()
+	assert.Equal(t, 0, queued.starts, "queued service started after StopAll")
+}
@@ -0,0 +1,35 @@
+package shared
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var startupDeadlineExceededCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "service_startup_deadline_exceeded_total",
+		Help: "Count of services still unhealthy once their startup deadline elapsed.",
+	},
+	[]string{"service"},
+)
+
+// checkStartupDeadline waits for the startup deadline of a service to elapse,
+// then logs a warning and increments a metric if the service is still
+// unhealthy. Nothing is checked if StopAll runs in the meantime.
+func (s *ServiceRegistry) checkStartupDeadline(entry *serviceEntry, deadline time.Duration) {
+	timer := time.NewTimer(deadline)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-s.shutdown:
+		return
+	}
+	err := checkStatus(func() error { return s.status(entry) }, healthzStatusTimeout)
+	if err == nil {
+		return
+	}
+	s.log.WithField("service", entry.String()).Warnf("Service %v still unhealthy %v after start: %v", entry, deadline, err)
+	startupDeadlineExceededCounter.WithLabelValues(entry.String()).Inc()
+}
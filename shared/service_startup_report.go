@@ -0,0 +1,220 @@
+package shared
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultStartupReportDeadline is how long after StartAll the startup
+	// report is logged if some services are still not ready.
+	defaultStartupReportDeadline = 2 * time.Minute
+	// startupReportSlowest is the number of slowest services named by the
+	// startup summary.
+	startupReportSlowest = 3
+)
+
+// ServiceStartup is how long a service launched by StartAll took to be
+// ready, as reported by StartupReport.
+type ServiceStartup struct {
+	Name string
+	// TimeToReady is the time from the launch of the service until it was
+	// ready, or until its startup failed. It is zero while pending.
+	TimeToReady time.Duration
+	// Ready is set once the service is ready.
+	Ready bool
+	// Err is why the service never became ready, if its startup failed.
+	Err error
+}
+
+// StartupReport describes the startup of the services launched by StartAll.
+type StartupReport struct {
+	// StartedAt is when StartAll launched the services.
+	StartedAt time.Time
+	// Total is the time from StartedAt until every service was ready or
+	// failed, or until the report deadline. It is zero until then.
+	Total time.Duration
+	// Done is set once every service was ready or failed, or once the
+	// report deadline elapsed, at which point the report was logged.
+	Done bool
+	// Services lists the services in start order.
+	Services []ServiceStartup
+}
+
+// Slowest returns the at most n services which took the longest to be
+// ready, slowest first. Services which are pending or failed are left out.
+func (r *StartupReport) Slowest(n int) []ServiceStartup {
+	var ready []ServiceStartup
+	for _, svc := range r.Services {
+		if svc.Ready {
+			ready = append(ready, svc)
+		}
+	}
+	sort.SliceStable(ready, func(i, j int) bool {
+		return ready[i].TimeToReady > ready[j].TimeToReady
+	})
+	if len(ready) > n {
+		ready = ready[:n]
+	}
+	return ready
+}
+
+// WithStartupReportDeadline sets how long after StartAll the startup report
+// is logged, with the services which are still not ready, if they did not
+// all become ready or fail before. It defaults to 2 minutes.
+func WithStartupReportDeadline(deadline time.Duration) RegistryOption {
+	return func(s *ServiceRegistry) {
+		if deadline > 0 {
+			s.startupReportDeadline = deadline
+		}
+	}
+}
+
+// StartupReport returns how long the services launched by StartAll took to
+// be ready so far, or nil if StartAll did not launch them yet.
+func (s *ServiceRegistry) StartupReport() *StartupReport {
+	s.lock.RLock()
+	r := s.startupReport
+	s.lock.RUnlock()
+	if r == nil {
+		return nil
+	}
+	return r.snapshot()
+}
+
+// startupReport collects the startup times of the services launched by
+// StartAll, and logs them once every service was ready or failed, or once
+// the report deadline elapsed.
+type startupReport struct {
+	registry *ServiceRegistry
+	lock     sync.Mutex
+	report   StartupReport
+	index    map[*serviceEntry]int
+	pending  int
+	done     chan struct{}
+}
+
+// newStartupReport starts the report of the given services, launched now.
+func (s *ServiceRegistry) newStartupReport(entries []*serviceEntry) *startupReport {
+	r := &startupReport{
+		registry: s,
+		report:   StartupReport{StartedAt: s.now(), Services: make([]ServiceStartup, len(entries))},
+		index:    make(map[*serviceEntry]int, len(entries)),
+		pending:  len(entries),
+		done:     make(chan struct{}),
+	}
+	for i, entry := range entries {
+		r.report.Services[i].Name = entry.String()
+		r.index[entry] = i
+	}
+	s.lock.Lock()
+	s.startupReport = r
+	deadline := s.startupReportDeadline
+	s.lock.Unlock()
+	if r.pending == 0 {
+		r.finish(false)
+	} else {
+		go r.watchDeadline(deadline)
+	}
+	return r
+}
+
+// serviceStarting returns the function recording that a service launched at
+// the given time is ready, or that its startup failed with the given error.
+func (r *startupReport) serviceStarting(entry *serviceEntry, start time.Time) func(err error) {
+	return func(err error) {
+		elapsed := r.registry.now().Sub(start)
+		r.lock.Lock()
+		svc := &r.report.Services[r.index[entry]]
+		if r.report.Done || svc.Ready || svc.Err != nil {
+			r.lock.Unlock()
+			return
+		}
+		svc.TimeToReady, svc.Ready, svc.Err = elapsed, err == nil, err
+		r.pending--
+		complete := r.pending == 0
+		r.lock.Unlock()
+		if complete {
+			r.finish(false)
+		}
+	}
+}
+
+// watchDeadline finishes the report once the deadline elapsed, unless it was
+// finished before or the registry shuts down.
+func (r *startupReport) watchDeadline(deadline time.Duration) {
+	timer := time.NewTimer(deadline)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		r.finish(true)
+	case <-r.done:
+	case <-r.registry.shutdown:
+	}
+}
+
+// finish marks the report as done and logs it, once.
+func (r *startupReport) finish(deadlineElapsed bool) {
+	r.lock.Lock()
+	if r.report.Done {
+		r.lock.Unlock()
+		return
+	}
+	r.report.Done = true
+	r.report.Total = r.registry.now().Sub(r.report.StartedAt)
+	close(r.done)
+	report := r.copyLocked()
+	r.lock.Unlock()
+	r.registry.logStartupReport(report, deadlineElapsed)
+}
+
+func (r *startupReport) snapshot() *StartupReport {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.copyLocked()
+}
+
+func (r *startupReport) copyLocked() *StartupReport {
+	report := r.report
+	report.Services = make([]ServiceStartup, len(r.report.Services))
+	copy(report.Services, r.report.Services)
+	return &report
+}
+
+// logStartupReport logs a line per service with its time to ready, then a
+// summary with the total startup time and the slowest services.
+func (s *ServiceRegistry) logStartupReport(report *StartupReport, deadlineElapsed bool) {
+	var pending []string
+	for _, svc := range report.Services {
+		logger := s.log.WithField("service", svc.Name)
+		switch {
+		case svc.Ready:
+			logger.WithField("timeToReady", svc.TimeToReady).Info("Service ready")
+		case svc.Err != nil:
+			logger.WithError(svc.Err).WithField("timeToFailure", svc.TimeToReady).Warn("Service failed to start")
+		default:
+			pending = append(pending, svc.Name)
+			logger.Warn("Service still not ready")
+		}
+	}
+	slowest := report.Slowest(startupReportSlowest)
+	names := make([]string, len(slowest))
+	for i, svc := range slowest {
+		names[i] = fmt.Sprintf("%s (%v)", svc.Name, svc.TimeToReady)
+	}
+	logger := s.log.WithFields(logrus.Fields{
+		"services": len(report.Services),
+		"total":    report.Total,
+		"slowest":  strings.Join(names, ", "),
+	})
+	if deadlineElapsed {
+		logger.WithField("notReady", strings.Join(pending, ", ")).Warn("Startup report deadline elapsed before every service was ready")
+		return
+	}
+	logger.Info("Services started")
+}
@@ -0,0 +1,107 @@
+package shared
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+	logTest "github.com/sirupsen/logrus/hooks/test"
+)
+
+// fakeClock is a clock which only moves forward when told to.
+type fakeClock struct {
+	lock sync.Mutex
+	now  time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// waitForReport waits until the startup report satisfies the condition.
+func waitForReport(t *testing.T, registry *ServiceRegistry, condition func(r *StartupReport) bool) *StartupReport {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		r := registry.StartupReport()
+		if r != nil && condition(r) {
+			return r
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Startup report did not reach the expected state: %+v", r)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestStartupReport(t *testing.T) {
+	hook := logTest.NewGlobal()
+	clock := &fakeClock{now: time.Unix(1600000000, 0)}
+	registry := NewServiceRegistry()
+	registry.now = clock.Now
+	registry.SetReadyPollInterval(time.Millisecond)
+	notReady := func() error { return errors.New("syncing") }
+	services := []*statusFuncService{{status: notReady}, {status: notReady}, {status: notReady}, {status: notReady}}
+	names := []string{"db", "p2p", "sync", "rpc"}
+	for i, svc := range services {
+		require.NoError(t, registry.RegisterNamedService(names[i], svc, nil))
+	}
+	assert.Equal(t, (*StartupReport)(nil), registry.StartupReport())
+	require.NoError(t, registry.StartAll())
+
+	// Services become ready one after the other, at 1s, 3s, 6s and 10s.
+	for i, svc := range []int{1, 0, 3, 2} {
+		clock.advance(time.Duration(i+1) * time.Second)
+		services[svc].setStatus(func() error { return nil })
+		waitForReport(t, registry, func(r *StartupReport) bool { return r.Services[svc].Ready })
+	}
+	report := waitForReport(t, registry, func(r *StartupReport) bool { return r.Done })
+	assert.Equal(t, 10*time.Second, report.Total)
+	assert.DeepEqual(t, []ServiceStartup{
+		{Name: "db", TimeToReady: 3 * time.Second, Ready: true},
+		{Name: "p2p", TimeToReady: time.Second, Ready: true},
+		{Name: "sync", TimeToReady: 10 * time.Second, Ready: true},
+		{Name: "rpc", TimeToReady: 6 * time.Second, Ready: true},
+	}, report.Services)
+	assert.DeepEqual(t, []ServiceStartup{
+		{Name: "sync", TimeToReady: 10 * time.Second, Ready: true},
+		{Name: "rpc", TimeToReady: 6 * time.Second, Ready: true},
+		{Name: "db", TimeToReady: 3 * time.Second, Ready: true},
+	}, report.Slowest(3))
+	require.LogsContain(t, hook, "msg=\"Service ready\" prefix=registry service=sync timeToReady=10s")
+	require.LogsContain(t, hook, "msg=\"Services started\" prefix=registry services=4 slowest=\"sync (10s), rpc (6s), db (3s)\" total=10s")
+	require.NoError(t, registry.StopAll())
+}
+
+func TestStartupReport_Deadline(t *testing.T) {
+	hook := logTest.NewGlobal()
+	registry := NewServiceRegistry(WithStartupReportDeadline(50 * time.Millisecond))
+	registry.SetReadyPollInterval(time.Millisecond)
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.RegisterService(&statusFuncService{status: func() error { return errors.New("syncing") }}))
+	p := &panickingStartService{started: make(chan struct{})}
+	require.NoError(t, registry.RegisterServiceWithConfig(p, nil, &ServiceConfig{Optional: true}))
+	require.NoError(t, registry.StartAll())
+
+	report := waitForReport(t, registry, func(r *StartupReport) bool { return r.Done })
+	assert.Equal(t, true, report.Services[0].Ready)
+	assert.Equal(t, false, report.Services[1].Ready)
+	assert.NoError(t, report.Services[1].Err)
+	assert.ErrorContains(t, "could not bind port", report.Services[2].Err)
+	assert.Equal(t, 1, len(report.Slowest(3)))
+	require.LogsContain(t, hook, "notReady=shared.statusFuncService")
+	require.LogsContain(t, hook, "Startup report deadline elapsed before every service was ready")
+	waitForState(t, registry, reflect.TypeOf(p), StateStopped)
+	require.NoError(t, registry.StopAll())
+}
@@ -0,0 +1,45 @@
+package shared
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+	logTest "github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestStartupDeadline_WarnsWhenStillUnhealthy(t *testing.T) {
+	hook := logTest.NewGlobal()
+	registry := NewServiceRegistry()
+	cfg := &ServiceConfig{StartupDeadline: 10 * time.Millisecond}
+	require.NoError(t, registry.RegisterServiceWithConfig(&mockService{status: errors.New("waiting for lock")}, nil, cfg))
+	require.NoError(t, registry.RegisterServiceWithConfig(&secondMockService{}, nil, cfg))
+	require.NoError(t, registry.RegisterService(&thirdMockService{status: errors.New("exempt")}))
+	before := testutil.ToFloat64(startupDeadlineExceededCounter.WithLabelValues("shared.mockService"))
+	require.NoError(t, registry.StartAll())
+
+	deadline := time.Now().Add(5 * time.Second)
+	for testutil.ToFloat64(startupDeadlineExceededCounter.WithLabelValues("shared.mockService")) == before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, before+1, testutil.ToFloat64(startupDeadlineExceededCounter.WithLabelValues("shared.mockService")))
+	require.LogsContain(t, hook, "Service shared.mockService still unhealthy 10ms after start: waiting for lock")
+	require.LogsDoNotContain(t, hook, "Service shared.secondMockService still unhealthy")
+	require.LogsDoNotContain(t, hook, "exempt")
+	require.NoError(t, registry.StopAll())
+}
+
+func TestStartupDeadline_SkippedOnStopAll(t *testing.T) {
+	hook := logTest.NewGlobal()
+	registry := NewServiceRegistry()
+	cfg := &ServiceConfig{StartupDeadline: 20 * time.Millisecond}
+	require.NoError(t, registry.RegisterServiceWithConfig(&mockService{status: errors.New("waiting for lock")}, nil, cfg))
+	require.NoError(t, registry.StartAll())
+	require.NoError(t, registry.StopAll())
+
+	time.Sleep(40 * time.Millisecond)
+	require.LogsDoNotContain(t, hook, "still unhealthy")
+}
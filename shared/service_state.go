@@ -0,0 +1,160 @@
+package shared
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// ServiceState describes where a registered service is in its lifecycle.
+type ServiceState int
+
+const (
+	// StateRegistered is the state of a service which was never started.
+	StateRegistered ServiceState = iota
+	// StateStarting is the state of a service whose Start method has been
+	// called but has not returned yet.
+	StateStarting
+	// StateRunning is the state of a service whose Start method returned.
+	StateRunning
+	// StateStopping is the state of a service whose Stop method has been
+	// called but has not returned yet.
+	StateStopping
+	// StateStopped is the state of a service which was stopped, or whose
+	// start failed.
+	StateStopped
+	// StatePaused is the state of a running service which was paused by
+	// PauseAll.
+	StatePaused
+	// StateDisabled is the state of a service registered while disabled by
+	// RegisterServiceIf, which is never started.
+	StateDisabled
+)
+
+// String returns the name of the state.
+func (s ServiceState) String() string {
+	switch s {
+	case StateRegistered:
+		return "registered"
+	case StateStarting:
+		return "starting"
+	case StateRunning:
+		return "running"
+	case StateStopping:
+		return "stopping"
+	case StateStopped:
+		return "stopped"
+	case StatePaused:
+		return "paused"
+	case StateDisabled:
+		return "disabled"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(s))
+	}
+}
+
+// MarshalText encodes the state as its name, such as in JSON documents.
+func (s ServiceState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a state encoded by MarshalText.
+func (s *ServiceState) UnmarshalText(text []byte) error {
+	for state := StateRegistered; state <= StateDisabled; state++ {
+		if state.String() == string(text) {
+			*s = state
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown service state: %q", text)
+}
+
+// registryPhase is how far a registry got in starting its services. It only
+// moves forward, except when StartAll fails before starting any service.
+type registryPhase int
+
+const (
+	// phaseRegistering is the phase of a registry of which no service was
+	// started yet.
+	phaseRegistering registryPhase = iota
+	// phaseGroupsStarted is the phase of a registry of which services were
+	// started by StartGroup, which still accepts registrations.
+	phaseGroupsStarted
+	// phaseStarting is the phase of a registry of which StartAll checks the
+	// registrations and runs the preflight checks.
+	phaseStarting
+	// phaseStarted is the phase of a registry of which StartAll launched the
+	// services.
+	phaseStarted
+	// phaseRolledBack is the phase of a registry of which StartAll stopped
+	// the services it started, because one of them failed to start.
+	phaseRolledBack
+)
+
+// startAllCalled returns whether StartAll was called, in which case services
+// can no longer be registered and StartAll cannot be called again.
+func (p registryPhase) startAllCalled() bool {
+	return p >= phaseStarting
+}
+
+// launched returns whether services were launched, by StartAll or by
+// StartGroup.
+func (p registryPhase) launched() bool {
+	return p == phaseGroupsStarted || p == phaseStarted || p == phaseRolledBack
+}
+
+// State returns the lifecycle state of the service of the given type.
+func (s *ServiceRegistry) State(kind reflect.Type) (ServiceState, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	entry, ok := s.services[kind]
+	if !ok {
+		return StateRegistered, &UnknownServiceError{Kind: kind}
+	}
+	return entry.state, nil
+}
+
+// IsRunning returns whether the service of the given type is running, from
+// the return of its Start method until its Stop method is called. Paused
+// services are running. False is returned for unknown services.
+func (s *ServiceRegistry) IsRunning(kind reflect.Type) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	entry, ok := s.services[kind]
+	if !ok {
+		return false
+	}
+	return entry.state == StateRunning || entry.state == StatePaused
+}
+
+// uptime returns how long a service has been running since it last finished
+// starting, which a restart resets. It is zero unless the service is running
+// or paused.
+func uptime(startedAt time.Time, state ServiceState) time.Duration {
+	if state != StateRunning && state != StatePaused {
+		return 0
+	}
+	return time.Since(startedAt)
+}
+
+// startTime returns when a service last finished starting, or the zero time
+// if it never did, along with its uptime.
+func (s *ServiceRegistry) startTime(entry *serviceEntry) (time.Time, time.Duration) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return entry.startedAt, uptime(entry.startedAt, entry.state)
+}
+
+// setState transitions a service to the given state.
+func (s *ServiceRegistry) setState(entry *serviceEntry, state ServiceState) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	entry.state = state
+}
+
+// stateOf returns the current lifecycle state of a service.
+func (s *ServiceRegistry) stateOf(entry *serviceEntry) ServiceState {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return entry.state
+}
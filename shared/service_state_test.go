@@ -0,0 +1,181 @@
+package shared
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+type blockingStartService struct {
+	release chan struct{}
+}
+
+func (s *blockingStartService) Start() {
+	<-s.release
+}
+
+func (s *blockingStartService) Stop() error {
+	return nil
+}
+
+func (s *blockingStartService) Status() error {
+	return nil
+}
+
+func waitForState(t *testing.T, registry *ServiceRegistry, kind reflect.Type, want ServiceState) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		state, err := registry.State(kind)
+		require.NoError(t, err)
+		if state == want {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Service %v did not reach state %v", kind, want)
+}
+
+// startAsync calls StartAll on a new goroutine, for services which are not
+// all started before the test makes them.
+func startAsync(registry *ServiceRegistry) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		done <- registry.StartAll()
+	}()
+	return done
+}
+
+func TestState_Lifecycle(t *testing.T) {
+	registry := NewServiceRegistry(WithLegacyStartWait())
+
+	b := &blockingStartService{release: make(chan struct{})}
+	kind := reflect.TypeOf(b)
+	require.NoError(t, registry.RegisterService(b))
+	state, err := registry.State(kind)
+	require.NoError(t, err)
+	assert.Equal(t, StateRegistered, state)
+
+	done := startAsync(registry)
+	waitForState(t, registry, kind, StateStarting)
+
+	close(b.release)
+	require.NoError(t, <-done)
+	state, err = registry.State(kind)
+	require.NoError(t, err)
+	assert.Equal(t, StateRunning, state)
+	assert.NoError(t, registry.Statuses()[kind])
+
+	require.NoError(t, registry.StopAll())
+	state, err = registry.State(kind)
+	require.NoError(t, err)
+	assert.Equal(t, StateStopped, state)
+	assert.ErrorContains(t, "service is stopped", registry.Statuses()[kind])
+}
+
+func TestState_StartPanic(t *testing.T) {
+	registry := NewServiceRegistry(WithLegacyStartWait())
+
+	p := &panickingStartService{started: make(chan struct{})}
+	require.NoError(t, registry.RegisterService(p))
+	assert.ErrorContains(t, "could not bind port", registry.StartAll())
+	state, err := registry.State(reflect.TypeOf(p))
+	require.NoError(t, err)
+	assert.Equal(t, StateStopped, state)
+}
+
+func TestState_Unknown(t *testing.T) {
+	registry := NewServiceRegistry()
+	_, err := registry.State(reflect.TypeOf(&mockService{}))
+	assert.ErrorContains(t, "unknown service", err)
+}
+
+// runningDuringStopService records whether the registry reported it as
+// running while it was being stopped.
+type runningDuringStopService struct {
+	registry      *ServiceRegistry
+	runningAtStop bool
+}
+
+func (s *runningDuringStopService) Start() {
+}
+
+func (s *runningDuringStopService) Stop() error {
+	s.runningAtStop = s.registry.IsRunning(reflect.TypeOf(s))
+	return nil
+}
+
+func (s *runningDuringStopService) Status() error {
+	return nil
+}
+
+func TestIsRunning(t *testing.T) {
+	registry := NewServiceRegistry(WithLegacyStartWait())
+	blocking := &blockingStartService{release: make(chan struct{})}
+	svc := &runningDuringStopService{registry: registry}
+	require.NoError(t, registry.RegisterService(blocking))
+	require.NoError(t, registry.RegisterService(svc))
+	blockingKind, kind := reflect.TypeOf(blocking), reflect.TypeOf(svc)
+	assert.Equal(t, false, registry.IsRunning(kind))
+	assert.Equal(t, false, registry.IsRunning(reflect.TypeOf(&mockService{})))
+
+	done := startAsync(registry)
+	waitForState(t, registry, kind, StateRunning)
+	assert.Equal(t, true, registry.IsRunning(kind))
+	// A service whose Start did not return yet is not running.
+	assert.Equal(t, false, registry.IsRunning(blockingKind))
+	close(blocking.release)
+	require.NoError(t, <-done)
+	assert.Equal(t, true, registry.IsRunning(blockingKind))
+
+	require.NoError(t, registry.StopAll())
+	assert.Equal(t, false, svc.runningAtStop, "Expected service not to be running once Stop is called")
+	assert.Equal(t, false, registry.IsRunning(kind))
+	assert.Equal(t, false, registry.IsRunning(blockingKind))
+}
+
+func TestIsRunning_ConcurrentStartAndStop(t *testing.T) {
+	registry := NewServiceRegistry()
+	kinds := []reflect.Type{reflect.TypeOf(&mockService{}), reflect.TypeOf(&secondMockService{}), reflect.TypeOf(&thirdMockService{})}
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.RegisterService(&secondMockService{}))
+	require.NoError(t, registry.RegisterService(&thirdMockService{}))
+
+	done := make(chan struct{})
+	readers := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		go func() {
+			defer func() { readers <- struct{}{} }()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				for _, kind := range kinds {
+					registry.IsRunning(kind)
+				}
+			}
+		}()
+	}
+	started := make(chan error, 1)
+	go func() {
+		started <- registry.StartAll()
+	}()
+	require.NoError(t, <-started)
+	require.NoError(t, registry.StopAll())
+	close(done)
+	for i := 0; i < 4; i++ {
+		<-readers
+	}
+	for _, kind := range kinds {
+		assert.Equal(t, false, registry.IsRunning(kind), "Expected %v not to be running after StopAll", kind)
+	}
+}
+
+func TestServiceState_String(t *testing.T) {
+	assert.Equal(t, "running", StateRunning.String())
+	assert.Equal(t, "unknown(42)", ServiceState(42).String())
+}
@@ -0,0 +1,147 @@
+package shared
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// StatusOption configures how statuses are checked by Statuses and
+// StatusesByName.
+type StatusOption func(opts *statusOptions)
+
+type statusOptions struct {
+	force bool
+	raw   bool
+}
+
+// ForceRefresh makes Statuses and StatusesByName call the Status method of
+// every service rather than use cached results.
+func ForceRefresh() StatusOption {
+	return func(opts *statusOptions) {
+		opts.force = true
+	}
+}
+
+// SetStatusCacheTTL makes the health endpoints, the health metrics, Statuses
+// and StatusesByName reuse the result of the Status method of a service for
+// the given time, so that frequent probes do not multiply expensive status
+// checks. Once a result is older than the TTL, it is still served while the
+// Status method is called again in the background. Results are discarded
+// when a service is started or restarted. A TTL which is not positive, the
+// default, disables the cache.
+//
+// The status poller, the watchdog and WaitForAllReady always call the Status
+// method.
+func (s *ServiceRegistry) SetStatusCacheTTL(ttl time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.statusTTL = ttl
+}
+
+// WithStatusCacheTTL configures the registry as SetStatusCacheTTL does.
+func WithStatusCacheTTL(ttl time.Duration) RegistryOption {
+	return func(s *ServiceRegistry) {
+		s.statusTTL = ttl
+	}
+}
+
+// statusCheck returns the status check to use given the status options.
+func (s *ServiceRegistry) statusCheck(opts []StatusOption) func(entry *serviceEntry) error {
+	o := &statusOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.raw {
+		return s.rawStatus
+	}
+	if o.force {
+		return s.status
+	}
+	return s.cachedStatus
+}
+
+// cachedStatus is like status, but serves the result of the Status method of
+// the service from its cache.
+func (s *ServiceRegistry) cachedStatus(entry *serviceEntry) error {
+	s.lock.RLock()
+	startErr, state, ttl := entry.startErr, entry.state, s.statusTTL
+	s.lock.RUnlock()
+	if ttl <= 0 {
+		return entryStatus(entry, startErr, state)
+	}
+	return entryStatusWith(entry, startErr, state, func() error {
+		return entry.statusCache.get(entry.checkStatus, ttl)
+	})
+}
+
+// cachedReadiness is like readiness, but checks whether the service is alive
+// with cachedStatus.
+func (s *ServiceRegistry) cachedReadiness(entry *serviceEntry) error {
+	return s.readinessWith(entry, s.cachedStatus)
+}
+
+// statusCache holds the last result of the Status method of a service.
+type statusCache struct {
+	lock       sync.Mutex
+	err        error
+	at         time.Time // when err was returned, zero if nothing is cached.
+	refreshing bool
+	generation int // incremented by invalidate, to drop results of earlier runs.
+}
+
+// get returns the cached result if it is younger than the TTL. An older
+// result is returned as well while refreshing it in the background, unless
+// the refresh is taking longer than the health endpoints wait for a status,
+// in which case the service is reported as hanging. The status is checked
+// synchronously when nothing is cached.
+func (c *statusCache) get(status func() error, ttl time.Duration) error {
+	c.lock.Lock()
+	if !c.at.IsZero() {
+		defer c.lock.Unlock()
+		age := time.Since(c.at)
+		if age < ttl {
+			return c.err
+		}
+		if !c.refreshing {
+			c.refreshing = true
+			go c.refresh(status, c.generation)
+		} else if age > ttl+healthzStatusTimeout {
+			return fmt.Errorf("status check did not return within %v", age-ttl)
+		}
+		return c.err
+	}
+	generation := c.generation
+	c.lock.Unlock()
+	err := status()
+	c.store(err, generation)
+	return err
+}
+
+func (c *statusCache) refresh(status func() error, generation int) {
+	err := status()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.generation == generation {
+		c.err, c.at, c.refreshing = err, time.Now(), false
+	}
+}
+
+// store caches the result of a status check, unless the cache was
+// invalidated since the check began.
+func (c *statusCache) store(err error, generation int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.generation != generation {
+		return
+	}
+	c.err, c.at = err, time.Now()
+}
+
+// invalidate discards the cached result.
+func (c *statusCache) invalidate() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.generation++
+	c.err, c.at, c.refreshing = nil, time.Time{}, false
+}
@@ -0,0 +1,101 @@
+package shared
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+// countingStatusService counts its Status calls, and reports the error
+// stored in it.
+type countingStatusService struct {
+	mockService
+	calls int32
+	err   atomic.Value
+}
+
+func (s *countingStatusService) Status() error {
+	atomic.AddInt32(&s.calls, 1)
+	if status, ok := s.err.Load().(storedStatus); ok {
+		return status.err
+	}
+	return nil
+}
+
+type storedStatus struct {
+	err error
+}
+
+func (s *countingStatusService) setStatus(err error) {
+	s.err.Store(storedStatus{err: err})
+}
+
+func TestStatusCache_ServesProbes(t *testing.T) {
+	registry := NewServiceRegistry(WithStatusCacheTTL(time.Hour))
+	s := &countingStatusService{}
+	s.setStatus(errors.New("no peers"))
+	require.NoError(t, registry.RegisterService(s))
+	kind := reflect.TypeOf(s)
+
+	calls := atomic.LoadInt32(&s.calls)
+	for i := 0; i < 5; i++ {
+		code, _ := serveHealthz(t, registry)
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.ErrorContains(t, "no peers", registry.Statuses()[kind])
+	}
+	assert.Equal(t, calls+1, atomic.LoadInt32(&s.calls))
+
+	s.setStatus(nil)
+	assert.ErrorContains(t, "no peers", registry.Statuses()[kind], "Expected the cached status")
+	assert.NoError(t, registry.Statuses(ForceRefresh())[kind])
+	assert.NoError(t, registry.StatusesByName(ForceRefresh())["shared.countingStatusService"])
+	assert.Equal(t, calls+3, atomic.LoadInt32(&s.calls))
+}
+
+func TestStatusCache_RefreshesInBackground(t *testing.T) {
+	registry := NewServiceRegistry()
+	registry.SetStatusCacheTTL(10 * time.Millisecond)
+	s := &countingStatusService{}
+	s.setStatus(errors.New("no peers"))
+	require.NoError(t, registry.RegisterService(s))
+	kind := reflect.TypeOf(s)
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, kind, StateRunning)
+	assert.ErrorContains(t, "no peers", registry.Statuses()[kind])
+
+	s.setStatus(nil)
+	time.Sleep(20 * time.Millisecond)
+	// The stale status is served while it is refreshed.
+	assert.ErrorContains(t, "no peers", registry.Statuses()[kind])
+	deadline := time.Now().Add(5 * time.Second)
+	for registry.Statuses()[kind] != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Cached status was not refreshed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	require.NoError(t, registry.StopAll())
+}
+
+func TestStatusCache_InvalidatedByRestart(t *testing.T) {
+	registry := NewServiceRegistry(WithStatusCacheTTL(time.Hour))
+	s := &countingStatusService{}
+	s.setStatus(errors.New("no peers"))
+	require.NoError(t, registry.RegisterService(s))
+	kind := reflect.TypeOf(s)
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, kind, StateRunning)
+	assert.ErrorContains(t, "no peers", registry.Statuses()[kind])
+
+	s.setStatus(nil)
+	require.NoError(t, registry.RestartService(kind))
+	waitForState(t, registry, kind, StateRunning)
+	assert.NoError(t, registry.Statuses()[kind])
+	require.NoError(t, registry.StopAll())
+}
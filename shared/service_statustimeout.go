@@ -0,0 +1,90 @@
+package shared
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// defaultStatusTimeout is how long the registry waits for the Status method
+// of a service unless WithStatusTimeout was given.
+const defaultStatusTimeout = 2 * time.Second
+
+// ErrStatusTimeout is reported as the status of a service whose Status method
+// did not return within the status timeout of the registry.
+var ErrStatusTimeout = errors.New("status check timed out")
+
+// WithStatusTimeout sets how long the registry waits for the Status method of
+// a service before reporting ErrStatusTimeout as its status, so that a single
+// hung service cannot block Statuses, the health endpoints or the status
+// poller. It defaults to 2 seconds. The health endpoints do not wait for more
+// than 2 seconds regardless.
+func WithStatusTimeout(timeout time.Duration) RegistryOption {
+	return func(s *ServiceRegistry) {
+		if timeout > 0 {
+			s.statusTimeout = timeout
+		}
+	}
+}
+
+// statusCall holds the Status call of a service which has not returned yet,
+// so that a hung service is not called again until it returns, and at most
+// one goroutine is leaked per service.
+type statusCall struct {
+	lock    sync.Mutex
+	pending *pendingStatus
+}
+
+type pendingStatus struct {
+	done chan struct{} // closed once the call returned and err is set.
+	err  error
+}
+
+// callStatus calls the Status method of the service on its own goroutine,
+// and waits for it until the status timeout of the service elapsed. A panic
+// is recovered and reported as the status of the service. Concurrent checks
+// wait for the same call, as does every check until a hung call returns.
+func (e *serviceEntry) callStatus() error {
+	c := &e.statusCall
+	c.lock.Lock()
+	p := c.pending
+	if p == nil {
+		p = &pendingStatus{done: make(chan struct{})}
+		c.pending = p
+		go e.runStatus(p)
+	}
+	c.lock.Unlock()
+
+	timeout := e.statusTimeout
+	if timeout <= 0 {
+		timeout = defaultStatusTimeout
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-p.done:
+		return p.err
+	case <-timer.C:
+		e.counters.statusTimedOut()
+		return fmt.Errorf("%w after %v", ErrStatusTimeout, timeout)
+	}
+}
+
+func (e *serviceEntry) runStatus(p *pendingStatus) {
+	defer func() {
+		if r := recover(); r != nil {
+			e.counters.panicked(newServicePanic(r, debug.Stack()))
+			p.err = fmt.Errorf("status check panicked: %v", r)
+		}
+		c := &e.statusCall
+		c.lock.Lock()
+		if c.pending == p {
+			c.pending = nil
+		}
+		c.lock.Unlock()
+		close(p.done)
+	}()
+	p.err = e.service.Status()
+}
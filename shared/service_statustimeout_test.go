@@ -0,0 +1,94 @@
+package shared
+
+import (
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+type countingHangingStatusService struct {
+	hangingStatusService
+	calls int32
+}
+
+func (s *countingHangingStatusService) Status() error {
+	atomic.AddInt32(&s.calls, 1)
+	return s.hangingStatusService.Status()
+}
+
+func TestStatuses_HangingStatusTimesOut(t *testing.T) {
+	registry := NewServiceRegistry(WithStatusTimeout(20 * time.Millisecond))
+	h := &countingHangingStatusService{hangingStatusService: hangingStatusService{release: make(chan struct{})}}
+	require.NoError(t, registry.RegisterService(h))
+	require.NoError(t, registry.RegisterService(&mockService{}))
+
+	start := time.Now()
+	statuses := registry.Statuses()
+	assert.Equal(t, true, time.Since(start) < time.Second, "statuses waited for the hung service")
+	err := statuses[reflect.TypeOf(h)]
+	assert.Equal(t, true, errors.Is(err, ErrStatusTimeout), err)
+	assert.ErrorContains(t, "status check timed out after 20ms", err)
+	assert.NoError(t, statuses[reflect.TypeOf(&mockService{})])
+
+	// The hung call is waited for again rather than calling the service once
+	// more, and each check which timed out is counted.
+	assert.Equal(t, true, errors.Is(registry.StatusesByName()["shared.countingHangingStatusService"], ErrStatusTimeout))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&h.calls))
+	assert.Equal(t, 2, registry.Counters()["shared.countingHangingStatusService"].StatusTimeouts)
+
+	promRegistry := prometheus.NewRegistry()
+	require.NoError(t, promRegistry.Register(NewServiceHealthCollector(registry)))
+	families, err := promRegistry.Gather()
+	require.NoError(t, err)
+	values := make(map[string]float64)
+	for _, family := range families {
+		if family.GetName() != "service_status_timeouts_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			values[labelValue(metric, "service")] = metricValue(metric)
+		}
+	}
+	assert.DeepEqual(t, map[string]float64{
+		"shared.countingHangingStatusService": 3,
+		"shared.mockService":                  0,
+	}, values)
+
+	// Once the service returns, it is called again.
+	close(h.release)
+	deadline := time.Now().Add(5 * time.Second)
+	for registry.Statuses()[reflect.TypeOf(h)] != nil && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.NoError(t, registry.Statuses()[reflect.TypeOf(h)])
+	assert.Equal(t, true, atomic.LoadInt32(&h.calls) >= 2)
+}
+
+func TestStatuses_PanickingStatus(t *testing.T) {
+	registry := NewServiceRegistry()
+	s := &statusFuncService{status: func() error {
+		panic("bad status")
+	}}
+	require.NoError(t, registry.RegisterService(s))
+
+	err := registry.Statuses()[reflect.TypeOf(s)]
+	assert.ErrorContains(t, "status check panicked: bad status", err)
+	assert.ErrorContains(t, "status check panicked: bad status", registry.Statuses(Raw())[reflect.TypeOf(s)])
+	counters := registry.Counters()["shared.statusFuncService"]
+	assert.Equal(t, 2, counters.Panics)
+	require.NotNil(t, counters.LastPanic)
+	assert.Equal(t, "bad status", counters.LastPanic.Value)
+}
+
+func TestWithStatusTimeout_IgnoresNonPositive(t *testing.T) {
+	registry := NewServiceRegistry(WithStatusTimeout(0))
+	assert.Equal(t, defaultStatusTimeout, registry.statusTimeout)
+	registry = NewServiceRegistry(WithStatusTimeout(-time.Second))
+	assert.Equal(t, defaultStatusTimeout, registry.statusTimeout)
+}
@@ -0,0 +1,106 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+var (
+	// ErrServiceStopped is reported as the status of a service stopped by
+	// StopService, which does not make the node unhealthy.
+	ErrServiceStopped = errors.New("service was stopped on request")
+	// ErrServiceAlreadyStopped is returned by StopService for a service
+	// which is not active.
+	ErrServiceAlreadyStopped = errors.New("service is already stopped")
+)
+
+// StopService stops the service of the given type and leaves it stopped,
+// waiting at most for its stop timeout and cancelling its service context.
+// The service then reports ErrServiceStopped as its status, until it is
+// started again. An *UnknownServiceError is returned for an unknown service,
+// and an error wrapping ErrServiceAlreadyStopped for a service which was
+// never started or is already stopped.
+func (s *ServiceRegistry) StopService(kind reflect.Type) error {
+	s.lock.RLock()
+	entry, ok := s.services[kind]
+	s.lock.RUnlock()
+	if !ok {
+		return &UnknownServiceError{Kind: kind}
+	}
+	switch s.stateOf(entry) {
+	case StateRegistered, StateStopping, StateStopped:
+		return fmt.Errorf("could not stop service %v: %w", entry, ErrServiceAlreadyStopped)
+	}
+	err := s.stopAndLog(context.Background(), entry)
+	s.lock.Lock()
+	entry.startErr = ErrServiceStopped
+	s.lock.Unlock()
+	return err
+}
+
+// isIntentional reports whether a status error is expected from a service
+// which is paused or was stopped on request, rather than a failure.
+func isIntentional(err error) bool {
+	return errors.Is(err, ErrServicePaused) || errors.Is(err, ErrServiceStopped)
+}
+
+// WithStopGracePeriod makes the registry cancel the service context of a
+// service which did not return from Stop within the given period, while Stop
+// is still running, so that a Stop call waiting for goroutines which only exit
+// once the context is cancelled can complete. The call is still abandoned once
+// the stop timeout of the service elapsed, so a grace period which is not
+// shorter than the stop timeout has no effect. By default, the service
+// context is only cancelled once Stop returned or was abandoned.
+func WithStopGracePeriod(grace time.Duration) RegistryOption {
+	return func(s *ServiceRegistry) {
+		s.stopGrace = grace
+	}
+}
+
+func (s *ServiceRegistry) stopGracePeriod() time.Duration {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.stopGrace
+}
+
+// escalateStop cancels the context of a service whose Stop call exceeded the
+// grace period, unless the context is shared with another service which is
+// still active, and returns whether it was cancelled.
+func (s *ServiceRegistry) escalateStop(entry *serviceEntry, ctx *ServiceContext, elapsed, timeout time.Duration) bool {
+	logger := s.log.WithField("elapsed", elapsed)
+	if s.contextInUse(entry) {
+		logger.Warnf("Service %v is still stopping, but its context is shared with an active service", entry)
+		return false
+	}
+	logger.Warnf("Service %v is still stopping, cancelling its context and waiting until its stop timeout of %v", entry, timeout)
+	ctx.Cancel()
+	return true
+}
+
+// waitGoroutines cancels the context of a stopped service, unless it is
+// shared with another service which is still active, and waits for the
+// goroutines spawned with its Go method to return until the given context is
+// done. Goroutines returning the cancellation of their context are not
+// reported as failed.
+func (s *ServiceRegistry) waitGoroutines(ctx context.Context, entry *serviceEntry, serviceCtx *ServiceContext, timeout time.Duration) error {
+	if !serviceCtx.hasGoroutines() || s.contextInUse(entry) {
+		return nil
+	}
+	serviceCtx.Cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- serviceCtx.Wait()
+	}()
+	select {
+	case err := <-done:
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return fmt.Errorf("goroutine of the service failed: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("goroutines of the service did not return within %v", timeout)
+	}
+}
@@ -0,0 +1,146 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+	logTest "github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestStopService(t *testing.T) {
+	registry := NewServiceRegistry()
+	svc := &stopRecordingService{}
+	ctx := NewServiceContext()
+	require.NoError(t, registry.RegisterServiceWithConfig(svc, ctx, nil))
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.StartAll())
+	kind := reflect.TypeOf(svc)
+	waitForState(t, registry, kind, StateRunning)
+
+	require.NoError(t, registry.StopService(kind))
+	assert.Equal(t, true, svc.stopped)
+	assert.ErrorContains(t, "context canceled", ctx.Err())
+	state, err := registry.State(kind)
+	require.NoError(t, err)
+	assert.Equal(t, StateStopped, state)
+	assert.Equal(t, true, errors.Is(registry.Statuses()[kind], ErrServiceStopped))
+
+	// The node is still healthy.
+	code, resp := serveHealthz(t, registry)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, healthzOK, resp.Status)
+	assert.Equal(t, "service was stopped on request", *resp.Services["shared.stopRecordingService"])
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, registry.WaitForAllReady(ctxTimeout))
+
+	// The service can be started again.
+	require.NoError(t, registry.RestartService(kind))
+	waitForState(t, registry, kind, StateRunning)
+	require.NoError(t, registry.Statuses()[kind])
+	require.NoError(t, registry.StopAll())
+}
+
+func TestStopService_Errors(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	kind := reflect.TypeOf(&mockService{})
+
+	var unknown *UnknownServiceError
+	assert.Equal(t, true, errors.As(registry.StopService(reflect.TypeOf(&secondMockService{})), &unknown))
+	assert.Equal(t, true, errors.Is(registry.StopService(kind), ErrServiceAlreadyStopped), "Expected a service never started to be already stopped")
+
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, kind, StateRunning)
+	require.NoError(t, registry.StopService(kind))
+	err := registry.StopService(kind)
+	assert.Equal(t, true, errors.Is(err, ErrServiceAlreadyStopped))
+	assert.ErrorContains(t, "could not stop service shared.mockService: service is already stopped", err)
+	require.NoError(t, registry.StopAll())
+}
+
+func TestStopService_NotRestartedByWatchdog(t *testing.T) {
+	registry := NewServiceRegistry()
+	svc := &stopRecordingService{}
+	require.NoError(t, registry.RegisterService(svc))
+	require.NoError(t, registry.StartAll())
+	kind := reflect.TypeOf(svc)
+	waitForState(t, registry, kind, StateRunning)
+	require.NoError(t, registry.StopService(kind))
+
+	restarted := make(chan string, 1)
+	require.NoError(t, registry.StartWatchdog(&WatchdogConfig{
+		Interval:         10 * time.Millisecond,
+		FailureThreshold: 1,
+		OnRestart:        func(service string, _ error) { restarted <- service },
+	}))
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, registry.StopAll())
+	select {
+	case name := <-restarted:
+		t.Errorf("Service %s stopped on request was restarted", name)
+	default:
+	}
+}
+
+// cancelWaitingService blocks in Stop until its service context is
+// cancelled, like a service waiting for goroutines which only exit then.
+type cancelWaitingService struct {
+	ctx *ServiceContext
+}
+
+func (s *cancelWaitingService) Start() {}
+
+func (s *cancelWaitingService) Stop() error {
+	<-s.ctx.Done()
+	return nil
+}
+
+func (s *cancelWaitingService) Status() error {
+	return nil
+}
+
+func TestStopAll_GracePeriodCancelsServiceContext(t *testing.T) {
+	hook := logTest.NewGlobal()
+	registry := NewServiceRegistry(WithStopGracePeriod(10*time.Millisecond), WithStopTimeout(5*time.Second))
+	svc := &cancelWaitingService{ctx: registry.NewServiceContext()}
+	require.NoError(t, registry.RegisterServiceWithConfig(svc, svc.ctx, nil))
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, reflect.TypeOf(svc), StateRunning)
+
+	require.NoError(t, registry.StopAll())
+	require.LogsContain(t, hook, "cancelling its context and waiting until its stop timeout of 5s")
+	require.LogsContain(t, hook, "returned from Stop once its context was cancelled")
+}
+
+func TestStopAll_WithoutGracePeriod(t *testing.T) {
+	registry := NewServiceRegistry(WithStopTimeout(50 * time.Millisecond))
+	svc := &cancelWaitingService{ctx: registry.NewServiceContext()}
+	require.NoError(t, registry.RegisterServiceWithConfig(svc, svc.ctx, nil))
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, reflect.TypeOf(svc), StateRunning)
+
+	assert.ErrorContains(t, "service did not stop within 50ms", registry.StopAll())
+}
+
+func TestStopAll_GracePeriodKeepsSharedContext(t *testing.T) {
+	hook := logTest.NewGlobal()
+	registry := NewServiceRegistry(WithStopGracePeriod(10*time.Millisecond), WithStopTimeout(100*time.Millisecond))
+	registry.SetStrictStopOrder(true)
+	ctx := registry.NewServiceContext()
+	svc := &cancelWaitingService{ctx: ctx}
+	require.NoError(t, registry.RegisterServiceWithConfig(&mockService{}, ctx, nil))
+	require.NoError(t, registry.RegisterServiceWithConfig(svc, ctx, nil))
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, reflect.TypeOf(svc), StateRunning)
+	waitForState(t, registry, reflect.TypeOf(&mockService{}), StateRunning)
+
+	assert.ErrorContains(t, "service did not stop within 100ms", registry.StopAll())
+	require.LogsContain(t, hook, "its context is shared with an active service")
+}
@@ -0,0 +1,145 @@
+package shared
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ErrRegistryCycle is returned when registering a registry as a subsystem
+// would make it, directly or not, a subsystem of itself.
+var ErrRegistryCycle = errors.New("registry would be a subsystem of itself")
+
+// subsystemLock guards the subsystems of every registry, so that cycles can
+// be detected without holding the locks of several registries at once.
+var subsystemLock sync.Mutex
+
+// RegisterSubsystem registers a child registry as a service of this one,
+// under the name of the subsystem it groups, such as p2p or sync. Starting
+// this registry starts the services of the child in their own order once the
+// child is started, and stopping it stops them in reverse order. Statuses
+// and StatusesByName report the services of the child prefixed with the name
+// of the subsystem, and FetchService finds them.
+func (s *ServiceRegistry) RegisterSubsystem(name string, child *ServiceRegistry) error {
+	return s.RegisterNamedService(name, child, nil)
+}
+
+// addSubsystem records the child as a subsystem of the registry, unless the
+// registry already is a subsystem of the child.
+func (s *ServiceRegistry) addSubsystem(name string, child *ServiceRegistry) error {
+	subsystemLock.Lock()
+	defer subsystemLock.Unlock()
+	if child == s || child.hasSubsystem(s) {
+		return fmt.Errorf("could not register subsystem %s: %w", name, ErrRegistryCycle)
+	}
+	s.subsystems = append(s.subsystems, child)
+	return nil
+}
+
+// removeSubsystem forgets the child, whose registration failed.
+func (s *ServiceRegistry) removeSubsystem(child *ServiceRegistry) {
+	subsystemLock.Lock()
+	defer subsystemLock.Unlock()
+	for i, sub := range s.subsystems {
+		if sub == child {
+			s.subsystems = append(s.subsystems[:i], s.subsystems[i+1:]...)
+			return
+		}
+	}
+}
+
+// hasSubsystem returns whether the other registry is a subsystem of this one,
+// directly or not. The caller must hold the subsystem lock.
+func (s *ServiceRegistry) hasSubsystem(other *ServiceRegistry) bool {
+	for _, sub := range s.subsystems {
+		if sub == other || sub.hasSubsystem(other) {
+			return true
+		}
+	}
+	return false
+}
+
+// subsystemsOf returns the child registries among the given services.
+func subsystemsOf(entries []*serviceEntry) (services []*serviceEntry, subsystems []*serviceEntry) {
+	for _, entry := range entries {
+		if _, ok := entry.service.(*ServiceRegistry); ok {
+			subsystems = append(subsystems, entry)
+			continue
+		}
+		services = append(services, entry)
+	}
+	return services, subsystems
+}
+
+// Start starts the services of a registry registered as a subsystem. An
+// error preventing them from being started is reported by Status.
+func (s *ServiceRegistry) Start() {
+	if err := s.StartAll(); err != nil {
+		s.log.WithError(err).Error("Could not start subsystem")
+		s.lock.Lock()
+		s.subsystemErr = err
+		s.lock.Unlock()
+	}
+}
+
+// Stop stops the services of a registry registered as a subsystem.
+func (s *ServiceRegistry) Stop() error {
+	return s.StopAll()
+}
+
+// Status reports the services of a registry registered as a subsystem which
+// are unhealthy, in order of registration.
+func (s *ServiceRegistry) Status() error {
+	if err := s.startError(); err != nil {
+		return err
+	}
+	errs := &MultiError{}
+	check := s.statusCheck(nil)
+	for _, entry := range s.snapshot() {
+		if err := check(entry); err != nil {
+			errs.add(fmt.Errorf("%v: %w", entry, err))
+		}
+	}
+	return errs.errorOrNil()
+}
+
+// startError returns the error which prevented Start from starting the
+// services of a subsystem.
+func (s *ServiceRegistry) startError() error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.subsystemErr
+}
+
+// addSubsystemStatuses adds the statuses of the services of the given
+// subsystems to the map, under their type. A subsystem whose services could
+// not be started is reported as a whole, under the type of the registry.
+func addSubsystemStatuses(m map[reflect.Type]error, subsystems []*serviceEntry, opts []StatusOption) {
+	for _, entry := range subsystems {
+		child := entry.service.(*ServiceRegistry)
+		if err := child.startError(); err != nil && m[entry.kind] == nil {
+			m[entry.kind] = fmt.Errorf("%v: %w", entry, err)
+		}
+		for kind, err := range child.Statuses(opts...) {
+			if err != nil {
+				err = fmt.Errorf("%v: %w", entry, err)
+			}
+			if m[kind] == nil {
+				m[kind] = err
+			}
+		}
+	}
+}
+
+// fetchFromSubsystems sets the value of the given pointer to a service found
+// in the subsystems of the registry.
+func (s *ServiceRegistry) fetchFromSubsystems(service interface{}) bool {
+	_, subsystems := subsystemsOf(s.snapshot())
+	for _, entry := range subsystems {
+		if entry.service.(*ServiceRegistry).FetchService(service) == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package shared
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+func TestRegisterSubsystem_StartsAndStopsChildServices(t *testing.T) {
+	parent := NewServiceRegistry()
+	child := NewServiceRegistry()
+	require.NoError(t, child.RegisterService(&secondMockService{status: errors.New("no peers")}))
+	require.NoError(t, parent.RegisterService(&mockService{}))
+	require.NoError(t, parent.RegisterSubsystem("p2p", child))
+
+	require.NoError(t, parent.StartAll())
+	waitForNamedState(t, parent, "p2p", StateRunning)
+	waitForState(t, child, reflect.TypeOf(&secondMockService{}), StateRunning)
+
+	statuses := parent.Statuses()
+	assert.Equal(t, 2, len(statuses))
+	assert.NoError(t, statuses[reflect.TypeOf(&mockService{})])
+	assert.ErrorContains(t, "p2p: no peers", statuses[reflect.TypeOf(&secondMockService{})])
+
+	byName := parent.StatusesByName()
+	assert.Equal(t, 2, len(byName))
+	assert.NoError(t, byName["shared.mockService"])
+	assert.ErrorContains(t, "no peers", byName["p2p/shared.secondMockService"])
+	assert.ErrorContains(t, "shared.secondMockService: no peers", child.Status())
+
+	var fetched *secondMockService
+	require.NoError(t, parent.FetchService(&fetched))
+	assert.ErrorContains(t, "no peers", fetched.Status())
+	var missing *thirdMockService
+	assert.Equal(t, true, errors.Is(parent.FetchService(&missing), ErrServiceNotFound))
+
+	require.NoError(t, parent.StopAll())
+	state, err := child.State(reflect.TypeOf(&secondMockService{}))
+	require.NoError(t, err)
+	assert.Equal(t, StateStopped, state)
+}
+
+func TestRegisterSubsystem_ReportsStartFailure(t *testing.T) {
+	parent := NewServiceRegistry()
+	child := NewServiceRegistry()
+	require.NoError(t, child.RegisterServiceWithDeps(&mockService{}, reflect.TypeOf(&thirdMockService{})))
+	require.NoError(t, parent.RegisterSubsystem("sync", child))
+
+	require.NoError(t, parent.StartAll())
+	waitForNamedState(t, parent, "sync", StateRunning)
+	assert.ErrorContains(t, "thirdMockService", child.Status())
+	assert.ErrorContains(t, "thirdMockService", parent.StatusesByName()["sync"])
+	assert.ErrorContains(t, "sync: ", parent.Statuses()[reflect.TypeOf(child)])
+}
+
+func TestRegisterSubsystem_RejectsCycles(t *testing.T) {
+	parent := NewServiceRegistry()
+	child := NewServiceRegistry()
+	grandchild := NewServiceRegistry()
+	require.NoError(t, parent.RegisterSubsystem("rpc", child))
+	require.NoError(t, child.RegisterSubsystem("gateway", grandchild))
+
+	assert.Equal(t, true, errors.Is(parent.RegisterSubsystem("self", parent), ErrRegistryCycle))
+	assert.Equal(t, true, errors.Is(grandchild.RegisterSubsystem("parent", parent), ErrRegistryCycle))
+	assert.Equal(t, true, errors.Is(child.RegisterNamedService("parent", parent, nil), ErrRegistryCycle))
+	assert.ErrorContains(t, "RegisterSubsystem", parent.RegisterService(NewServiceRegistry()))
+
+	// A registration failing for another reason does not leave the child
+	// behind as a subsystem.
+	other := NewServiceRegistry()
+	assert.Equal(t, true, errors.Is(parent.RegisterSubsystem("rpc", other), ErrServiceAlreadyRegistered))
+	require.NoError(t, other.RegisterSubsystem("parent", parent))
+}
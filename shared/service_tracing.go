@@ -0,0 +1,65 @@
+package shared
+
+import (
+	"context"
+	"sync"
+
+	"github.com/prysmaticlabs/prysm/shared/traceutil"
+	"go.opencensus.io/trace"
+)
+
+// startupTrace traces the startup of the services launched by StartAll, with
+// a span per service nested under a "node-start" span. A nil startupTrace,
+// used when the trace is not sampled, does nothing.
+type startupTrace struct {
+	ctx  context.Context
+	span *trace.Span
+	wg   sync.WaitGroup
+}
+
+// newStartupTrace starts the "node-start" span, returning nil if it is not
+// recorded so that no per service span is ever created.
+func newStartupTrace() *startupTrace {
+	ctx, span := trace.StartSpan(context.Background(), "node-start")
+	if !span.IsRecordingEvents() {
+		span.End()
+		return nil
+	}
+	return &startupTrace{ctx: ctx, span: span}
+}
+
+// serviceStarting starts the span of a service about to be launched,
+// returning the function ending it once the service is ready or its startup
+// failed with the given error.
+func (t *startupTrace) serviceStarting(entry *serviceEntry) func(err error) {
+	if t == nil {
+		return func(error) {}
+	}
+	_, span := trace.StartSpan(t.ctx, entry.String())
+	t.wg.Add(1)
+	return func(err error) {
+		traceutil.AnnotateError(span, err)
+		span.End()
+		t.wg.Done()
+	}
+}
+
+// end ends the "node-start" span once every service span has ended.
+func (t *startupTrace) end() {
+	if t == nil {
+		return
+	}
+	go func() {
+		t.wg.Wait()
+		t.span.End()
+	}()
+}
+
+// traceStop stops a service under a span named after it.
+func (s *ServiceRegistry) traceStop(ctx context.Context, entry *serviceEntry) error {
+	ctx, span := trace.StartSpan(ctx, entry.String())
+	defer span.End()
+	err := s.stopService(ctx, entry)
+	traceutil.AnnotateError(span, err)
+	return err
+}
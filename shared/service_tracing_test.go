@@ -0,0 +1,107 @@
+package shared
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+	"go.opencensus.io/trace"
+)
+
+// spanRecorder is a trace exporter keeping the exported spans.
+type spanRecorder struct {
+	lock  sync.Mutex
+	spans []*trace.SpanData
+}
+
+func (r *spanRecorder) ExportSpan(s *trace.SpanData) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.spans = append(r.spans, s)
+}
+
+// span returns the exported span of the given name and parent, waiting for it
+// if needed. Any parent matches the zero span ID.
+func (r *spanRecorder) span(t *testing.T, name string, parent trace.SpanID) *trace.SpanData {
+	for i := 0; i < 100; i++ {
+		r.lock.Lock()
+		for _, s := range r.spans {
+			if s.Name == name && (parent == trace.SpanID{} || s.ParentSpanID == parent) {
+				r.lock.Unlock()
+				return s
+			}
+		}
+		r.lock.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Span %s was not exported", name)
+	return nil
+}
+
+func recordSpans(t *testing.T) *spanRecorder {
+	recorder := &spanRecorder{}
+	trace.RegisterExporter(recorder)
+	trace.ApplyConfig(trace.Config{DefaultSampler: trace.AlwaysSample()})
+	t.Cleanup(func() {
+		trace.UnregisterExporter(recorder)
+		trace.ApplyConfig(trace.Config{DefaultSampler: trace.ProbabilitySampler(1e-4)})
+	})
+	return recorder
+}
+
+func TestStartAll_TracesStartup(t *testing.T) {
+	recorder := recordSpans(t)
+	registry := NewServiceRegistry()
+	registry.SetReadyPollInterval(10 * time.Millisecond)
+	svc := &statusFuncService{}
+	svc.setStatus(func() error { return errors.New("syncing") })
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.RegisterService(svc))
+	require.NoError(t, registry.StartAll())
+
+	healthy := recorder.span(t, "shared.mockService", trace.SpanID{})
+	// The span of a service lasts until it is ready.
+	time.Sleep(50 * time.Millisecond)
+	recorder.lock.Lock()
+	for _, s := range recorder.spans {
+		assert.NotEqual(t, "node-start", s.Name)
+		assert.NotEqual(t, "shared.statusFuncService", s.Name)
+	}
+	recorder.lock.Unlock()
+	svc.setStatus(func() error { return nil })
+	parent := recorder.span(t, "node-start", trace.SpanID{})
+	assert.Equal(t, parent.SpanID, healthy.ParentSpanID)
+	span := recorder.span(t, "shared.statusFuncService", parent.SpanID)
+	assert.Equal(t, int32(trace.StatusCodeOK), span.Status.Code)
+	require.NoError(t, registry.StopAll())
+}
+
+func TestStartAll_TracesUnhealthyStartup(t *testing.T) {
+	recorder := recordSpans(t)
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&mockService{status: errors.New("no peers")}))
+	require.NoError(t, registry.StartAll())
+	require.NoError(t, registry.StopAll())
+
+	parent := recorder.span(t, "node-start", trace.SpanID{})
+	span := recorder.span(t, "shared.mockService", parent.SpanID)
+	assert.Equal(t, true, strings.Contains(span.Status.Message, "no peers"), "Unexpected status %v", span.Status)
+}
+
+func TestStopAll_TracesStop(t *testing.T) {
+	recorder := recordSpans(t)
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.RegisterService(&failingStopService{err: errors.New("db locked")}))
+	require.NoError(t, registry.StartAll())
+	assert.ErrorContains(t, "db locked", registry.StopAll())
+
+	parent := recorder.span(t, "node-stop", trace.SpanID{})
+	assert.Equal(t, "shared.failingStopService: db locked", parent.Status.Message)
+	recorder.span(t, "shared.mockService", parent.SpanID)
+	assert.Equal(t, "db locked", recorder.span(t, "shared.failingStopService", parent.SpanID).Status.Message)
+}
@@ -0,0 +1,81 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+)
+
+// ServiceV2 is like Service, but its Start method is given the
+// *ServiceContext of the service and returns an error when the service could not be started, which
+// the registry handles as it handles a panic in the Start method of a
+// Service: the start is retried if the StartRetry policy of the service
+// allows it, the status of the service reports the error, degraded for an
+// optional service, a critical service stops the node, and unless the service
+// is optional, StartAll stops the services it already started and returns the
+// error. Start is expected
+// to return once the service is running, leaving any long-running work to
+// goroutines, such as those spawned with the Go method of the service
+// context.
+type ServiceV2 interface {
+	// Start spawns any goroutines required by the service, and returns an
+	// error if the service could not be started.
+	Start(ctx context.Context) error
+	// Stop terminates all goroutines belonging to the service,
+	// blocking until they are all terminated.
+	Stop() error
+	// Returns error if the service is not considered healthy.
+	Status() error
+}
+
+// lifecycle is implemented by both Service and ServiceV2.
+type lifecycle interface {
+	Stop() error
+	Status() error
+}
+
+// legacyService adapts a Service to ServiceV2.
+type legacyService struct {
+	Service
+}
+
+// AdaptService returns a ServiceV2 starting the given service, for code
+// migrating to ServiceV2 which still has to handle services which were not
+// migrated yet. The registry registers an adapted service as the service
+// itself, under its own type.
+func AdaptService(service Service) ServiceV2 {
+	return &legacyService{Service: service}
+}
+
+// Start starts the adapted service, which cannot fail.
+func (s *legacyService) Start(context.Context) error {
+	s.Service.Start()
+	return nil
+}
+
+// RegisterServiceV2 registers a service implementing ServiceV2 as
+// RegisterServiceWithConfig registers a Service. Both kinds of services can
+// be registered in the same registry and depend on each other.
+func (s *ServiceRegistry) RegisterServiceV2(service ServiceV2, ctx *ServiceContext, cfg *ServiceConfig) error {
+	if service == nil {
+		return errNilService
+	}
+	if legacy, ok := service.(*legacyService); ok {
+		return s.RegisterServiceWithConfig(legacy.Service, ctx, cfg)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.register(service, ctx, cfg)
+}
+
+// start calls the Start method of the service with the given context.
+func (e *serviceEntry) start(ctx context.Context) error {
+	switch service := e.service.(type) {
+	case ServiceV2:
+		if err := service.Start(ctx); err != nil {
+			return fmt.Errorf("service could not start: %w", err)
+		}
+	case Service:
+		service.Start()
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+var errNoGenesisState = errors.New("could not load genesis state")
+
+// v2Service fails to start as many times as configured.
+type v2Service struct {
+	lock     sync.Mutex
+	failures int
+	starts   int
+	stops    int
+	ctx      context.Context
+}
+
+func (s *v2Service) Start(ctx context.Context) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.starts++
+	s.ctx = ctx
+	if s.starts <= s.failures {
+		return errNoGenesisState
+	}
+	return nil
+}
+
+func (s *v2Service) Stop() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.stops++
+	return nil
+}
+
+func (s *v2Service) Status() error {
+	return nil
+}
+
+func TestRegisterServiceV2(t *testing.T) {
+	registry := NewServiceRegistry()
+	svc := &v2Service{}
+	ctx := registry.NewServiceContext()
+	require.NoError(t, registry.RegisterServiceV2(svc, ctx, nil))
+	// A legacy service can depend on a ServiceV2.
+	require.NoError(t, registry.RegisterServiceWithDeps(&mockService{}, reflect.TypeOf(svc)))
+	require.NoError(t, registry.RegisterServiceV2(AdaptService(&secondMockService{}), nil, nil))
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, reflect.TypeOf(svc), StateRunning)
+	waitForState(t, registry, reflect.TypeOf(&mockService{}), StateRunning)
+	waitForState(t, registry, reflect.TypeOf(&secondMockService{}), StateRunning)
+
+	var fetched *v2Service
+	require.NoError(t, registry.FetchService(&fetched))
+	assert.Equal(t, svc, fetched)
+	svc.lock.Lock()
+	assert.Equal(t, context.Context(ctx), svc.ctx)
+	svc.lock.Unlock()
+	for kind, err := range registry.Statuses() {
+		assert.NoError(t, err, kind)
+	}
+	require.NoError(t, registry.StopAll())
+}
+
+func TestRegisterServiceV2_StartError(t *testing.T) {
+	registry := NewServiceRegistry()
+	svc := &v2Service{failures: 1}
+	require.NoError(t, registry.RegisterServiceV2(svc, nil, nil))
+	assert.ErrorContains(t, "could not load genesis state", registry.StartAll())
+	waitForState(t, registry, reflect.TypeOf(svc), StateStopped)
+
+	err := registry.Statuses()[reflect.TypeOf(svc)]
+	assert.ErrorContains(t, "service could not start: could not load genesis state", err)
+	assert.Equal(t, SeverityCritical, StatusSeverity(err))
+	assert.Equal(t, 1, registry.Counters()["shared.v2Service"].Failures)
+}
+
+func TestRegisterServiceV2_StartErrorRollsBackStart(t *testing.T) {
+	registry := NewServiceRegistry()
+	order := &stopOrder{}
+	for i := 0; i < 2; i++ {
+		svc := &rollbackService{index: i, order: order}
+		require.NoError(t, registry.RegisterNamedService(fmt.Sprintf("service-%d", i), svc, nil))
+	}
+	svc := &v2Service{failures: 1}
+	require.NoError(t, registry.RegisterServiceV2(svc, nil, nil))
+
+	err := registry.StartAll()
+	assert.Equal(t, true, errors.Is(err, errNoGenesisState), "Unexpected error %v", err)
+	assert.DeepEqual(t, []int{1, 0}, order.get(), "Services were not stopped in reverse start order")
+	svc.lock.Lock()
+	assert.Equal(t, 0, svc.stops, "The service which failed to start was stopped")
+	svc.lock.Unlock()
+	require.NoError(t, registry.StopAll())
+}
+
+func TestRegisterServiceV2_OptionalStartError(t *testing.T) {
+	registry := NewServiceRegistry()
+	svc := &v2Service{failures: 1}
+	require.NoError(t, registry.RegisterServiceV2(svc, nil, &ServiceConfig{Optional: true}))
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, reflect.TypeOf(svc), StateStopped)
+
+	err := registry.Statuses()[reflect.TypeOf(svc)]
+	assert.ErrorContains(t, "could not load genesis state", err)
+	assert.Equal(t, SeverityDegraded, StatusSeverity(err))
+}
+
+func TestRegisterServiceV2_RetriesStart(t *testing.T) {
+	registry := NewServiceRegistry()
+	svc := &v2Service{failures: 2}
+	cfg := &ServiceConfig{StartRetry: &StartRetryPolicy{MaxAttempts: 3, InitialDelay: time.Millisecond}}
+	require.NoError(t, registry.RegisterServiceV2(svc, nil, cfg))
+	require.NoError(t, registry.StartAll())
+	waitForState(t, registry, reflect.TypeOf(svc), StateRunning)
+
+	assert.NoError(t, registry.Statuses()[reflect.TypeOf(svc)])
+	svc.lock.Lock()
+	assert.Equal(t, 3, svc.starts)
+	svc.lock.Unlock()
+}
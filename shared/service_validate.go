@@ -0,0 +1,104 @@
+package shared
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Validate checks the registered services for misconfigurations: nil
+// services, missing service contexts, optional critical services, duplicate
+// names, dependencies on unregistered or disabled services and circular
+// dependencies. Every problem found is reported in the returned error, which
+// is a *MultiError, so that they can all be fixed at once.
+func (s *ServiceRegistry) Validate() error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	errs := &MultiError{}
+	names := make(map[string]int, len(s.entries))
+	for i, entry := range s.entries {
+		if isNilService(entry.service) {
+			errs.add(fmt.Errorf("service #%d is nil: %s", i, typeName(entry.kind)))
+			continue
+		}
+		name := entry.String()
+		if entry.ctx == nil {
+			errs.add(fmt.Errorf("service %v has no service context", name))
+		}
+		if entry.cfg.Optional && entry.cfg.Critical {
+			errs.add(fmt.Errorf("service %v cannot be both optional and critical", name))
+		}
+		if names[name]++; names[name] == 2 {
+			errs.add(fmt.Errorf("service name %s is used by several services", name))
+		}
+		for _, dep := range entry.cfg.Dependencies {
+			if _, exists := s.services[dep]; exists {
+				continue
+			}
+			if d := s.disabledOf(dep); d != nil {
+				errs.add(fmt.Errorf("service %v depends on service %v, which is %s", name, d.name, d.reason()))
+			} else {
+				errs.add(fmt.Errorf("service %v depends on unregistered service: %s", name, typeName(dep)))
+			}
+		}
+	}
+	for _, cycle := range s.dependencyCycles() {
+		errs.add(fmt.Errorf("circular dependency detected: %s", cyclePath(cycle)))
+	}
+	return errs.errorOrNil()
+}
+
+// dependencyCycles returns every dependency cycle found while walking the
+// registered services, each starting and ending with the same service.
+// Dependencies on unregistered services are ignored. The caller must hold the
+// registry lock.
+func (s *ServiceRegistry) dependencyCycles() [][]*serviceEntry {
+	var cycles [][]*serviceEntry
+	visited := make(map[*serviceEntry]bool, len(s.entries))
+	var path []*serviceEntry
+	var visit func(entry *serviceEntry)
+	visit = func(entry *serviceEntry) {
+		for i, e := range path {
+			if e == entry {
+				cycle := make([]*serviceEntry, 0, len(path)-i+1)
+				cycles = append(cycles, append(append(cycle, path[i:]...), entry))
+				return
+			}
+		}
+		if visited[entry] {
+			return
+		}
+		path = append(path, entry)
+		for _, dep := range entry.cfg.Dependencies {
+			if depEntry, exists := s.services[dep]; exists {
+				visit(depEntry)
+			}
+		}
+		path = path[:len(path)-1]
+		visited[entry] = true
+	}
+	for _, entry := range s.entries {
+		if !isNilService(entry.service) {
+			visit(entry)
+		}
+	}
+	return cycles
+}
+
+// errNilService is returned when registering a nil service.
+var errNilService = errors.New("cannot register a nil service")
+
+// isNilService reports whether a service is nil, including nil pointers of a
+// service type.
+func isNilService(service lifecycle) bool {
+	if service == nil {
+		return true
+	}
+	v := reflect.ValueOf(service)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface:
+		return v.IsNil()
+	default:
+		return false
+	}
+}
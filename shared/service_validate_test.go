@@ -0,0 +1,68 @@
+package shared
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+func TestValidate_OK(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	require.NoError(t, registry.RegisterServiceWithDeps(&secondMockService{}, reflect.TypeOf(&mockService{})))
+	require.NoError(t, registry.Validate())
+}
+
+func TestValidate_ReportsEveryProblem(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterServiceWithDeps(&mockService{}, reflect.TypeOf(&secondMockService{})))
+	require.NoError(t, registry.RegisterServiceWithDeps(&secondMockService{}, reflect.TypeOf(&mockService{})))
+	require.NoError(t, registry.RegisterServiceWithDeps(&thirdMockService{}, reflect.TypeOf(&stopRecordingService{})))
+	require.NoError(t, registry.RegisterService((*blockingStopService)(nil)))
+
+	err := registry.Validate()
+	require.NotNil(t, err)
+	var multiErr *MultiError
+	require.Equal(t, true, errors.As(err, &multiErr))
+	require.Equal(t, 3, len(multiErr.Errors), "Unexpected errors: %v", err)
+	assert.ErrorContains(t, "depends on unregistered service: shared.stopRecordingService", multiErr.Errors[0])
+	assert.ErrorContains(t, "service #3 is nil: shared.blockingStopService", multiErr.Errors[1])
+	assert.ErrorContains(t, "circular dependency detected: shared.mockService -> shared.secondMockService -> shared.mockService", multiErr.Errors[2])
+}
+
+func TestValidate_DuplicateNames(t *testing.T) {
+	registry := NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&mockService{}))
+	// Bypass the checks done at registration, as a name can change after it.
+	registry.lock.Lock()
+	entry := registry.newServiceEntry(&secondMockService{}, nil, nil)
+	entry.name = "shared.mockService"
+	registry.entries = append(registry.entries, entry)
+	registry.lock.Unlock()
+
+	assert.ErrorContains(t, "service name shared.mockService is used by several services", registry.Validate())
+}
+
+func TestStartAll_RefusesInvalidRegistry(t *testing.T) {
+	registry := NewServiceRegistry()
+	svc := &panickingStartService{}
+	require.NoError(t, registry.RegisterService(svc))
+	require.NoError(t, registry.RegisterServiceWithDeps(&mockService{}, reflect.TypeOf(&secondMockService{})))
+	require.NoError(t, registry.RegisterServiceWithDeps(&thirdMockService{}, reflect.TypeOf(&stopRecordingService{})))
+
+	err := registry.StartAll()
+	assert.ErrorContains(t, "shared.mockService depends on unregistered service: shared.secondMockService", err)
+	assert.ErrorContains(t, "shared.thirdMockService depends on unregistered service: shared.stopRecordingService", err)
+	state, err := registry.State(reflect.TypeOf(svc))
+	require.NoError(t, err)
+	assert.Equal(t, StateRegistered, state)
+}
+
+func TestRegisterService_Nil(t *testing.T) {
+	registry := NewServiceRegistry()
+	assert.ErrorContains(t, "cannot register a nil service", registry.RegisterService(nil))
+	assert.ErrorContains(t, "cannot register a nil service", registry.RegisterNamedService("db", nil, nil))
+}
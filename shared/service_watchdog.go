@@ -0,0 +1,143 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// defaultWatchdogInterval is how often the watchdog checks service statuses.
+const defaultWatchdogInterval = 10 * time.Second
+
+// defaultWatchdogThreshold is the number of consecutive failed status checks
+// after which the watchdog restarts a service.
+const defaultWatchdogThreshold = 3
+
+// WatchdogConfig defines the behavior of the registry watchdog.
+type WatchdogConfig struct {
+	// Interval between two status checks of every service.
+	Interval time.Duration
+	// FailureThreshold is the number of consecutive failed status checks
+	// after which a service is restarted.
+	FailureThreshold int
+	// OnRestart, if set, is called after the watchdog restarted a service,
+	// with the name of the service and the status error which triggered it.
+	OnRestart func(service string, err error)
+}
+
+// watchdog periodically checks the status of every registered service and
+// restarts the services which remain unhealthy for too long.
+type watchdog struct {
+	cfg      *WatchdogConfig
+	registry *ServiceRegistry
+	failures map[*serviceEntry]int
+	// restarts holds the recent restart times of every service, and
+	// healthySince when a restarted service became healthy again.
+	restarts     map[*serviceEntry][]time.Time
+	healthySince map[*serviceEntry]time.Time
+	// crashLooping holds the services which are not restarted anymore.
+	crashLooping map[*serviceEntry]bool
+	cancel       context.CancelFunc
+	done         chan struct{}
+}
+
+// StartWatchdog launches a goroutine which restarts registered services once
+// their status has been failing for the configured number of consecutive
+// checks. The same service instances are restarted, so pointers obtained
+// through FetchService remain valid. Services restarted too often are left
+// alone, as configured by their CrashLoopPolicy. The watchdog is terminated
+// by StopAll.
+func (s *ServiceRegistry) StartWatchdog(cfg *WatchdogConfig) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return ErrRegistryClosed
+	}
+	if s.watchdog != nil {
+		return errors.New("watchdog already running")
+	}
+	if cfg == nil {
+		cfg = &WatchdogConfig{}
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultWatchdogInterval
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = defaultWatchdogThreshold
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &watchdog{
+		cfg:          cfg,
+		registry:     s,
+		failures:     make(map[*serviceEntry]int),
+		restarts:     make(map[*serviceEntry][]time.Time),
+		healthySince: make(map[*serviceEntry]time.Time),
+		crashLooping: make(map[*serviceEntry]bool),
+		cancel:       cancel,
+		done:         make(chan struct{}),
+	}
+	s.watchdog = w
+	go w.run(ctx)
+	return nil
+}
+
+func (w *watchdog) run(ctx context.Context) {
+	defer close(w.done)
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.check()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (w *watchdog) check() {
+	for _, entry := range w.registry.snapshot() {
+		entry := entry
+		err := checkStatus(func() error { return w.registry.status(entry) }, w.cfg.Interval)
+		now := time.Now()
+		if w.crashLooping[entry] {
+			if errors.Is(err, ErrServiceCrashLooping) {
+				continue
+			}
+			// The service was restarted by hand.
+			delete(w.crashLooping, entry)
+			delete(w.restarts, entry)
+		}
+		// Paused services are expected not to do any work, and are left alone
+		// as are services stopped on request.
+		if err == nil || isIntentional(err) {
+			w.failures[entry] = 0
+			w.healthy(entry, now)
+			continue
+		}
+		delete(w.healthySince, entry)
+		w.failures[entry]++
+		if w.failures[entry] < w.cfg.FailureThreshold {
+			continue
+		}
+		w.failures[entry] = 0
+		entry.counters.failed(err)
+		if !w.allowRestart(entry, now) {
+			continue
+		}
+		w.registry.log.WithError(err).Warnf("Restarting unhealthy service %v", entry)
+		if restartErr := w.registry.restartService(entry); restartErr != nil {
+			w.registry.log.WithError(restartErr).Errorf("Could not restart the following service: %v", entry)
+			continue
+		}
+		if w.cfg.OnRestart != nil {
+			w.cfg.OnRestart(entry.String(), err)
+		}
+	}
+}
+
+// stop terminates the watchdog and waits for its goroutine to exit.
+func (w *watchdog) stop() {
+	w.cancel()
+	<-w.done
+}
@@ -0,0 +1,49 @@
+package shared
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+func TestWatchdog_RestartsUnhealthyService(t *testing.T) {
+	registry := NewServiceRegistry()
+
+	r := &restartableService{started: make(chan struct{}, 1)}
+	s := &statusFuncService{status: func() error { return errors.New("stuck") }}
+	require.NoError(t, registry.RegisterService(r))
+	require.NoError(t, registry.RegisterService(s))
+	require.NoError(t, registry.StartAll())
+	<-r.started
+
+	restarted := make(chan string, 10)
+	require.NoError(t, registry.StartWatchdog(&WatchdogConfig{
+		Interval:         10 * time.Millisecond,
+		FailureThreshold: 2,
+		OnRestart: func(service string, err error) {
+			assert.ErrorContains(t, "stuck", err)
+			restarted <- service
+		},
+	}))
+	assert.ErrorContains(t, "watchdog already running", registry.StartWatchdog(nil))
+
+	select {
+	case name := <-restarted:
+		assert.Equal(t, "shared.statusFuncService", name)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Unhealthy service was not restarted")
+	}
+	require.NoError(t, registry.StopAll())
+	assert.Equal(t, true, registry.watchdog == nil, "Expected watchdog to be stopped")
+
+	// The healthy service must never have been restarted by the watchdog.
+	assert.Equal(t, 1, r.stopped)
+	select {
+	case <-r.started:
+		t.Error("Healthy service was restarted")
+	default:
+	}
+}
@@ -0,0 +1,105 @@
+package testutil
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// orderingTimeout is how long the ordering assertions wait for the
+// services they compare to be started or stopped.
+const orderingTimeout = 5 * time.Second
+
+// LifecycleRecorder records the order in which the registry calls Start and
+// Stop on the fakes it created, so that tests can assert the ordering implied
+// by registrations and dependencies. Calls are recorded as they happen, and
+// the assertions wait for the calls they compare rather than for some time,
+// so that they do not depend on goroutine scheduling.
+type LifecycleRecorder struct {
+	lock   sync.Mutex
+	calls  []string // "Start name" or "Stop name", in call order.
+	notify chan struct{}
+}
+
+// NewLifecycleRecorder returns an empty recorder.
+func NewLifecycleRecorder() *LifecycleRecorder {
+	return &LifecycleRecorder{notify: make(chan struct{})}
+}
+
+// Fake returns a fake service with the given name, as returned by its Name
+// method, whose Start and Stop calls are recorded. Several fakes must be
+// registered with RegisterNamedService, or embedded in distinct types, to be
+// registered in the same registry.
+func (r *LifecycleRecorder) Fake(name string) *FakeService {
+	return &FakeService{name: name, recorder: r}
+}
+
+// Calls returns the recorded calls, such as "Start db", in order.
+func (r *LifecycleRecorder) Calls() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]string(nil), r.calls...)
+}
+
+func (r *LifecycleRecorder) record(name, method string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.calls = append(r.calls, method+" "+name)
+	close(r.notify)
+	r.notify = make(chan struct{})
+}
+
+// index returns the position of the first matching call, or -1 along with
+// a channel closed by the next recorded call.
+func (r *LifecycleRecorder) index(call string) (int, <-chan struct{}) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for i, c := range r.calls {
+		if c == call {
+			return i, nil
+		}
+	}
+	return -1, r.notify
+}
+
+// waitFor returns the position of the first matching call, waiting for it
+// for at most the timeout, and fails the test if it was not made.
+func (r *LifecycleRecorder) waitFor(t testing.TB, call string) int {
+	t.Helper()
+	timeout := time.NewTimer(orderingTimeout)
+	defer timeout.Stop()
+	for {
+		i, next := r.index(call)
+		if i >= 0 {
+			return i
+		}
+		select {
+		case <-next:
+		case <-timeout.C:
+			t.Fatalf("%s was not called within %v, calls: %v", call, orderingTimeout, r.Calls())
+			return -1
+		}
+	}
+}
+
+// AssertStartedBefore waits for both services to be started, and fails the
+// test unless the first one was started before the second one.
+func (r *LifecycleRecorder) AssertStartedBefore(t testing.TB, first, second string) {
+	t.Helper()
+	r.assertBefore(t, "Start "+first, "Start "+second)
+}
+
+// AssertStoppedAfter waits for both services to be stopped, and fails the
+// test unless the first one was stopped after the second one.
+func (r *LifecycleRecorder) AssertStoppedAfter(t testing.TB, first, second string) {
+	t.Helper()
+	r.assertBefore(t, "Stop "+second, "Stop "+first)
+}
+
+func (r *LifecycleRecorder) assertBefore(t testing.TB, before, after string) {
+	t.Helper()
+	i, j := r.waitFor(t, before), r.waitFor(t, after)
+	if i > j {
+		t.Errorf("Expected %q before %q, calls: %v", before, after, r.Calls())
+	}
+}
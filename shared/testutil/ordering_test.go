@@ -0,0 +1,46 @@
+package testutil
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prysmaticlabs/prysm/shared"
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+type fakeDB struct {
+	*FakeService
+}
+
+type fakeSync struct {
+	*FakeService
+}
+
+func TestLifecycleRecorder_DependencyOrder(t *testing.T) {
+	recorder := NewLifecycleRecorder()
+	registry := NewRegistry(t)
+	db := &fakeDB{recorder.Fake("db")}
+	// Registered first, but started after the service it depends on.
+	require.NoError(t, registry.RegisterServiceWithDeps(&fakeSync{recorder.Fake("sync")}, reflect.TypeOf(db)))
+	require.NoError(t, registry.RegisterService(db))
+	require.NoError(t, registry.RegisterNamedService("rpc", recorder.Fake("rpc"), nil))
+
+	require.NoError(t, registry.StartAll())
+	recorder.AssertStartedBefore(t, "db", "sync")
+	require.NoError(t, registry.StopAll(shared.ShutdownNormal))
+	recorder.AssertStoppedAfter(t, "db", "sync")
+	assert.Equal(t, 6, len(recorder.Calls()))
+}
+
+func TestLifecycleRecorder_ReportsWrongOrder(t *testing.T) {
+	recorder := NewLifecycleRecorder()
+	recorder.Fake("sync").Start()
+	recorder.Fake("db").Start()
+
+	tb := &recordingTB{TB: t}
+	recorder.AssertStartedBefore(tb, "db", "sync")
+	require.Equal(t, 1, len(tb.errors))
+	assert.Equal(t, true, strings.Contains(tb.errors[0], `Expected "Start db" before "Start sync"`), tb.errors[0])
+}
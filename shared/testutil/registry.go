@@ -0,0 +1,104 @@
+package testutil
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared"
+)
+
+// leakTimeout is how long NewRegistry waits for the goroutines started by a
+// test to exit once the registry was closed.
+var leakTimeout = 5 * time.Second
+
+const registryPollInterval = 10 * time.Millisecond
+
+// NewRegistry returns a registry configured for tests, which do not need the
+// PreStop pass and its drain period. The registry is closed once the test
+// and its subtests completed, stopping its services unless StopAll already
+// ran, and the test fails if goroutines started since NewRegistry was called
+// are still running by then. Tests using NewRegistry should not run in
+// parallel with other tests, whose goroutines would be counted as leaks.
+func NewRegistry(t testing.TB, opts ...shared.RegistryOption) *shared.ServiceRegistry {
+	t.Helper()
+	before := runtime.NumGoroutine()
+	registry := shared.NewServiceRegistry(append([]shared.RegistryOption{shared.WithoutPreStop()}, opts...)...)
+	t.Cleanup(func() {
+		if err := registry.Close(); err != nil {
+			t.Logf("Could not stop every service: %v", err)
+		}
+		RequireNoGoroutineLeak(t, before, leakTimeout)
+	})
+	return registry
+}
+
+// RequireNoGoroutineLeak waits for the number of running goroutines to go
+// back to the given count, failing the test with the stacks of every
+// goroutine if it does not within the timeout.
+func RequireNoGoroutineLeak(t testing.TB, count int, timeout time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for {
+		n := runtime.NumGoroutine()
+		if n <= count {
+			return
+		}
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<20)
+			t.Errorf("%d goroutines leaked, running goroutines:\n%s", n-count, buf[:runtime.Stack(buf, true)])
+			return
+		}
+		time.Sleep(registryPollInterval)
+	}
+}
+
+// RequireAllHealthy waits for every service of the registry to report a nil
+// status, failing the test with the status of the unhealthy services if they
+// do not within the timeout. Status checks which hang count as unhealthy.
+func RequireAllHealthy(t testing.TB, registry *shared.ServiceRegistry, timeout time.Duration) {
+	t.Helper()
+	var unhealthy []string
+	waitFor(timeout, func() bool {
+		unhealthy = unhealthy[:0]
+		for _, info := range registry.ListServices() {
+			if info.Status != nil {
+				unhealthy = append(unhealthy, fmt.Sprintf("%s: %v", info.Name, info.Status))
+			}
+		}
+		return len(unhealthy) == 0
+	})
+	if len(unhealthy) > 0 {
+		t.Fatalf("Services not healthy within %v: %s", timeout, strings.Join(unhealthy, ", "))
+	}
+}
+
+// RequireStopped waits for every service of the registry to be stopped,
+// failing the test with the state of the other services if they are not
+// within the timeout. Disabled services are ignored.
+func RequireStopped(t testing.TB, registry *shared.ServiceRegistry, timeout time.Duration) {
+	t.Helper()
+	var running []string
+	waitFor(timeout, func() bool {
+		running = running[:0]
+		for _, info := range registry.ListServices() {
+			if info.State != shared.StateStopped && info.State != shared.StateDisabled {
+				running = append(running, fmt.Sprintf("%s: %v", info.Name, info.State))
+			}
+		}
+		return len(running) == 0
+	})
+	if len(running) > 0 {
+		t.Fatalf("Services not stopped within %v: %s", timeout, strings.Join(running, ", "))
+	}
+}
+
+// waitFor polls the condition until it holds or the timeout elapsed.
+func waitFor(timeout time.Duration, condition func() bool) {
+	deadline := time.Now().Add(timeout)
+	for !condition() && time.Now().Before(deadline) {
+		time.Sleep(registryPollInterval)
+	}
+}
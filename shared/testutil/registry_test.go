@@ -0,0 +1,96 @@
+package testutil
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared"
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+// recordingTB records the failures of the helpers under test, and runs the
+// cleanup functions when asked to.
+type recordingTB struct {
+	testing.TB
+	errors   []string
+	cleanups []func()
+}
+
+func (tb *recordingTB) Helper() {}
+
+func (tb *recordingTB) Cleanup(fn func()) {
+	tb.cleanups = append(tb.cleanups, fn)
+}
+
+func (tb *recordingTB) Errorf(format string, args ...interface{}) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+func (tb *recordingTB) Logf(string, ...interface{}) {}
+
+func (tb *recordingTB) cleanup() {
+	for i := len(tb.cleanups) - 1; i >= 0; i-- {
+		tb.cleanups[i]()
+	}
+}
+
+func TestFakeService_WithStatuses(t *testing.T) {
+	fake := (&FakeService{}).WithStatuses(errors.New("syncing"), nil)
+	assert.ErrorContains(t, "syncing", fake.Status())
+	assert.NoError(t, fake.Status())
+	assert.NoError(t, fake.Status())
+	assert.Equal(t, 3, fake.CallCount("Status"))
+}
+
+func TestNewRegistry_ClosedAtCleanup(t *testing.T) {
+	fake := (&FakeService{}).WithStopError(errors.New("db locked"))
+	t.Run("registry", func(t *testing.T) {
+		registry := NewRegistry(t)
+		require.NoError(t, registry.RegisterService(fake))
+		require.NoError(t, registry.StartAll())
+		select {
+		case <-fake.Started():
+		case <-time.After(5 * time.Second):
+			t.Fatal("Fake service was not started")
+		}
+		RequireAllHealthy(t, registry, 5*time.Second)
+	})
+	assert.Equal(t, 1, fake.CallCount("Stop"))
+}
+
+func TestNewRegistry_ReportsLeaks(t *testing.T) {
+	defer func(timeout time.Duration) {
+		leakTimeout = timeout
+	}(leakTimeout)
+	leakTimeout = 50 * time.Millisecond
+	tb := &recordingTB{TB: t}
+	registry := NewRegistry(tb)
+	leaked := make(chan struct{})
+	defer close(leaked)
+	require.NoError(t, registry.RegisterService(&FakeService{StartFunc: func() {
+		go func() {
+			<-leaked
+		}()
+	}}))
+	require.NoError(t, registry.StartAll())
+
+	tb.cleanup()
+	require.Equal(t, 1, len(tb.errors))
+	assert.Equal(t, true, strings.Contains(tb.errors[0], "goroutines leaked"), tb.errors[0])
+}
+
+func TestRequireStopped(t *testing.T) {
+	registry := NewRegistry(t)
+	fake := (&FakeService{}).WithStopDelay(10 * time.Millisecond)
+	require.NoError(t, registry.RegisterService(fake))
+	require.NoError(t, registry.StartAll())
+	<-fake.Started()
+	RequireAllHealthy(t, registry, 5*time.Second)
+
+	require.NoError(t, registry.StopAll(shared.ShutdownNormal))
+	RequireStopped(t, registry, 5*time.Second)
+}
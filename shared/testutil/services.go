@@ -0,0 +1,175 @@
+package testutil
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared"
+)
+
+// OverrideService substitutes the fake for a service of a populated registry,
+// before StartAll is called. The service is designated by a nil pointer: a
+// pointer to the registered type, such as (*sync.Service)(nil), or a pointer
+// to an interface implemented by a single registered service, such as
+// (*p2p.P2P)(nil). The test fails if no such service is registered.
+func OverrideService(t testing.TB, registry *shared.ServiceRegistry, kind interface{}, fake shared.Service) {
+	t.Helper()
+	typ := reflect.TypeOf(kind)
+	if typ.Kind() == reflect.Ptr && typ.Elem().Kind() == reflect.Interface {
+		typ = typ.Elem()
+	}
+	if err := registry.OverrideService(typ, fake); err != nil {
+		t.Fatalf("Could not override service %v: %v", typ, err)
+	}
+}
+
+// FakeService is a service whose lifecycle methods run the configured
+// functions, if any, and are recorded so that tests can assert how the
+// registry used it. It can be embedded in fakes which need to implement
+// additional interfaces.
+//
+// The With methods script the behavior of the fake, and must be called before
+// it is registered.
+type FakeService struct {
+	StartFunc  func()
+	StopFunc   func() error
+	StatusFunc func() error
+
+	lock    sync.Mutex
+	calls   []string
+	started chan struct{}
+	// name and recorder are set for fakes created by LifecycleRecorder.
+	name     string
+	recorder *LifecycleRecorder
+}
+
+// Name returns the name of a fake created by LifecycleRecorder.Fake, or an
+// empty string, which makes the registry name the fake after its type.
+func (s *FakeService) Name() string {
+	return s.name
+}
+
+// WithStatuses makes Status return the given errors in turn, then the last
+// one at every later call.
+func (s *FakeService) WithStatuses(errs ...error) *FakeService {
+	var lock sync.Mutex
+	next := 0
+	s.StatusFunc = func() error {
+		lock.Lock()
+		defer lock.Unlock()
+		if len(errs) == 0 {
+			return nil
+		}
+		err := errs[next]
+		if next < len(errs)-1 {
+			next++
+		}
+		return err
+	}
+	return s
+}
+
+// WithBlockingStatus makes Status block until the given channel is closed,
+// like a service whose status check is stuck, then return nil.
+func (s *FakeService) WithBlockingStatus(release <-chan struct{}) *FakeService {
+	s.StatusFunc = func() error {
+		<-release
+		return nil
+	}
+	return s
+}
+
+// WithStopError makes Stop return the given error.
+func (s *FakeService) WithStopError(err error) *FakeService {
+	s.StopFunc = func() error {
+		return err
+	}
+	return s
+}
+
+// WithStopDelay makes Stop take the given time to return.
+func (s *FakeService) WithStopDelay(delay time.Duration) *FakeService {
+	s.StopFunc = func() error {
+		time.Sleep(delay)
+		return nil
+	}
+	return s
+}
+
+// Started returns a channel which is closed once Start was called.
+func (s *FakeService) Started() <-chan struct{} {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.started == nil {
+		s.started = make(chan struct{})
+	}
+	return s.started
+}
+
+// Start records the call and runs StartFunc.
+func (s *FakeService) Start() {
+	s.record("Start")
+	if s.StartFunc != nil {
+		s.StartFunc()
+	}
+}
+
+// Stop records the call and runs StopFunc.
+func (s *FakeService) Stop() error {
+	s.record("Stop")
+	if s.StopFunc != nil {
+		return s.StopFunc()
+	}
+	return nil
+}
+
+// Status records the call and runs StatusFunc.
+func (s *FakeService) Status() error {
+	s.record("Status")
+	if s.StatusFunc != nil {
+		return s.StatusFunc()
+	}
+	return nil
+}
+
+// Calls returns the names of the lifecycle methods called so far, in order.
+func (s *FakeService) Calls() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]string(nil), s.calls...)
+}
+
+// CallCount returns how many times the given lifecycle method was called.
+func (s *FakeService) CallCount(method string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	count := 0
+	for _, call := range s.calls {
+		if call == method {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *FakeService) record(method string) {
+	if s.recorder != nil && method != "Status" {
+		s.recorder.record(s.name, method)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.calls = append(s.calls, method)
+	if method != "Start" {
+		return
+	}
+	if s.started == nil {
+		s.started = make(chan struct{})
+	}
+	select {
+	case <-s.started:
+	default:
+		close(s.started)
+	}
+}
@@ -0,0 +1,70 @@
+package testutil
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared"
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+type realService struct{}
+
+func (s *realService) Start()        {}
+func (s *realService) Stop() error   { return nil }
+func (s *realService) Status() error { return nil }
+func (s *realService) Head() int     { return 1 }
+
+type headFetcher interface {
+	Head() int
+}
+
+type fakeHeadFetcher struct {
+	*FakeService
+}
+
+func (s *fakeHeadFetcher) Head() int { return 2 }
+
+func TestOverrideService(t *testing.T) {
+	registry := shared.NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&realService{}))
+	started := make(chan struct{})
+	fake := &FakeService{
+		StartFunc:  func() { close(started) },
+		StatusFunc: func() error { return errors.New("fake") },
+	}
+	OverrideService(t, registry, (*realService)(nil), fake)
+
+	require.NoError(t, registry.StartAll())
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Fake service was not started")
+	}
+	assert.ErrorContains(t, "fake", registry.Statuses()[reflect.TypeOf(&realService{})])
+	require.NoError(t, registry.StopAll())
+	assert.Equal(t, 1, fake.CallCount("Start"))
+	assert.Equal(t, 1, fake.CallCount("Stop"))
+	// Status may be called concurrently by the registry, so only the
+	// lifecycle calls are checked for order.
+	var calls []string
+	for _, call := range fake.Calls() {
+		if call != "Status" {
+			calls = append(calls, call)
+		}
+	}
+	assert.DeepEqual(t, []string{"Start", "Stop"}, calls)
+}
+
+func TestOverrideService_ByInterface(t *testing.T) {
+	registry := shared.NewServiceRegistry()
+	require.NoError(t, registry.RegisterService(&realService{}))
+	OverrideService(t, registry, (*headFetcher)(nil), &fakeHeadFetcher{FakeService: &FakeService{}})
+
+	var fetcher headFetcher
+	require.NoError(t, registry.FetchService(&fetcher))
+	assert.Equal(t, 2, fetcher.Head())
+}
@@ -31,9 +31,12 @@ var log = logrus.WithField("prefix", "beaconclient")
 type Notifier interface {
 	BlockFeed() *event.Feed
 	AttestationFeed() *event.Feed
-	ClientReadyFeed() *event.Feed
 }
 
+// ClientReady is published on the event bus of the slasher once the gRPC
+// connection to the beacon node is active and the beacon node is synced.
+type ClientReady struct{}
+
 // ChainFetcher defines a struct which can retrieve
 // chain information from a beacon node such as the latest chain head.
 type ChainFetcher interface {
@@ -50,7 +53,7 @@ type Service struct {
 	beaconClient                ethpb.BeaconChainClient
 	slasherDB                   db.Database
 	nodeClient                  ethpb.NodeClient
-	clientFeed                  *event.Feed
+	eventBus                    *event.Bus
 	blockFeed                   *event.Feed
 	attestationFeed             *event.Feed
 	proposerSlashingsChan       chan *ethpb.ProposerSlashing
@@ -73,6 +76,7 @@ type Config struct {
 	AttesterSlashingsFeed *event.Feed
 	BeaconClient          ethpb.BeaconChainClient
 	NodeClient            ethpb.NodeClient
+	EventBus              *event.Bus
 }
 
 // NewService instantiation.
@@ -84,13 +88,17 @@ func NewService(ctx context.Context, cfg *Config) (*Service, error) {
 		return nil, errors.Wrap(err, "could not create new cache")
 	}
 
+	eventBus := cfg.EventBus
+	if eventBus == nil {
+		eventBus = new(event.Bus)
+	}
 	return &Service{
 		cert:                        cfg.BeaconCert,
 		ctx:                         ctx,
 		cancel:                      cancel,
 		provider:                    cfg.BeaconProvider,
 		blockFeed:                   new(event.Feed),
-		clientFeed:                  new(event.Feed),
+		eventBus:                    eventBus,
 		attestationFeed:             new(event.Feed),
 		slasherDB:                   cfg.SlasherDB,
 		proposerSlashingsChan:       make(chan *ethpb.ProposerSlashing, 1),
@@ -117,12 +125,6 @@ func (bs *Service) AttestationFeed() *event.Feed {
 	return bs.attestationFeed
 }
 
-// ClientReadyFeed returns a feed other services in slasher can subscribe to
-// to indicate when the gRPC connection is ready.
-func (bs *Service) ClientReadyFeed() *event.Feed {
-	return bs.clientFeed
-}
-
 // Stop the beacon client service by closing the gRPC connection.
 func (bs *Service) Stop() error {
 	bs.cancel()
@@ -191,7 +193,7 @@ func (bs *Service) Start() {
 
 	// We notify other services in slasher that the beacon client is ready
 	// and the connection is active.
-	bs.clientFeed.Send(true)
+	bs.eventBus.Publish(ClientReady{})
 
 	// We register subscribers for any detected proposer/attester slashings
 	// in the slasher services that we can submit to the beacon node
@@ -55,6 +55,7 @@ type Service struct {
 	blocksChan            chan *ethpb.SignedBeaconBlock
 	attsChan              chan *ethpb.IndexedAttestation
 	notifier              beaconclient.Notifier
+	clientReady           chan beaconclient.ClientReady
 	chainFetcher          beaconclient.ChainFetcher
 	beaconClient          *beaconclient.Service
 	attesterSlashingsFeed *event.Feed
@@ -68,6 +69,7 @@ type Service struct {
 // Config options for the detection service.
 type Config struct {
 	Notifier              beaconclient.Notifier
+	EventBus              *event.Bus
 	SlasherDB             db.Database
 	ChainFetcher          beaconclient.ChainFetcher
 	BeaconClient          *beaconclient.Service
@@ -79,10 +81,19 @@ type Config struct {
 // NewService instantiation.
 func NewService(ctx context.Context, cfg *Config) *Service {
 	ctx, cancel := context.WithCancel(ctx)
+	// The service subscribes before it is started, so that it cannot miss
+	// the beacon client becoming ready.
+	eventBus := cfg.EventBus
+	if eventBus == nil {
+		eventBus = new(event.Bus)
+	}
+	clientReady := make(chan beaconclient.ClientReady, 1)
+	eventBus.SubscribeWithContext(ctx, clientReady)
 	return &Service{
 		ctx:                   ctx,
 		cancel:                cancel,
 		notifier:              cfg.Notifier,
+		clientReady:           clientReady,
 		chainFetcher:          cfg.ChainFetcher,
 		slasherDB:             cfg.SlasherDB,
 		beaconClient:          cfg.BeaconClient,
@@ -117,11 +128,12 @@ func (ds *Service) Start() {
 	// We wait for the gRPC beacon client to be ready and the beacon node
 	// to be fully synced before proceeding.
 	ds.status = Started
-	ch := make(chan bool)
-	sub := ds.notifier.ClientReadyFeed().Subscribe(ch)
 	ds.status = Syncing
-	<-ch
-	sub.Unsubscribe()
+	select {
+	case <-ds.clientReady:
+	case <-ds.ctx.Done():
+		return
+	}
 
 	if ds.historicalDetection {
 		// The detection service runs detection on all historical
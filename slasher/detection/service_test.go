@@ -0,0 +1,29 @@
+package detection
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/event"
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+	"github.com/prysmaticlabs/prysm/slasher/beaconclient"
+	testDB "github.com/prysmaticlabs/prysm/slasher/db/testing"
+)
+
+func TestNewService_SubscribesToClientReady(t *testing.T) {
+	db := testDB.SetupSlasherDB(t, false)
+	bus := new(event.Bus)
+	ds := NewService(context.Background(), &Config{SlasherDB: db, EventBus: bus})
+
+	assert.Equal(t, 1, bus.Publish(beaconclient.ClientReady{}))
+	<-ds.clientReady
+
+	require.NoError(t, ds.Stop())
+	deadline := time.Now().Add(5 * time.Second)
+	for bus.Publish(beaconclient.ClientReady{}) != 0 {
+		require.Equal(t, true, time.Now().Before(deadline), "Expected the subscription to end once the service stopped")
+		time.Sleep(time.Millisecond)
+	}
+}
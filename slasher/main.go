@@ -69,6 +69,8 @@ var appFlags = []cli.Flag{
 	debug.MemProfileRateFlag,
 	debug.CPUProfileFlag,
 	debug.TraceFlag,
+	debug.LeakCheckFlag,
+	debug.ShuffleStartOrderFlag,
 	flags.RPCPort,
 	flags.RPCHost,
 	flags.CertFlag,
@@ -77,7 +77,7 @@ func NewSlasherNode(cliCtx *cli.Context) (*SlasherNode, error) {
 
 	featureconfig.ConfigureSlasher(cliCtx)
 	cmd.ConfigureSlasher(cliCtx)
-	registry := shared.NewServiceRegistry()
+	registry := shared.NewServiceRegistry(debug.RegistryOptions(cliCtx)...)
 
 	ctx, cancel := context.WithCancel(cliCtx.Context)
 	slasher := &SlasherNode{
@@ -118,7 +118,9 @@ func NewSlasherNode(cliCtx *cli.Context) (*SlasherNode, error) {
 // Start the slasher and kick off every registered service.
 func (s *SlasherNode) Start() {
 	s.lock.Lock()
-	s.services.StartAll()
+	if err := s.services.StartAll(); err != nil {
+		log.WithError(err).Fatal("Could not start services")
+	}
 	s.lock.Unlock()
 
 	log.WithFields(logrus.Fields{
@@ -133,7 +135,7 @@ func (s *SlasherNode) Start() {
 		<-sigc
 		log.Info("Got interrupt, shutting down...")
 		debug.Exit(s.cliCtx) // Ensure trace and CPU profile data are flushed.
-		go s.Close()
+		go s.shutdown(shared.ShutdownSignal)
 		for i := 10; i > 0; i-- {
 			<-sigc
 			if i > 1 {
@@ -143,18 +145,42 @@ func (s *SlasherNode) Start() {
 		panic("Panic closing the beacon node")
 	}()
 
-	// Wait for stop channel to be closed.
-	<-stop
+	// Wait for stop channel to be closed, or for a service to report a fatal
+	// error, in which case the process exits with a non-zero code once the
+	// services are stopped.
+	select {
+	case <-stop:
+	case err := <-s.services.FatalErrors():
+		log.WithError(err).Error("Fatal error reported by a service, shutting down...")
+		s.shutdown(shared.ShutdownFatal)
+		log.WithError(err).Fatal("Slasher stopped after a fatal error")
+	}
 }
 
 // Close handles graceful shutdown of the system.
 func (s *SlasherNode) Close() {
+	s.shutdown(shared.ShutdownNormal)
+}
+
+// shutdown is like Close, but stops the services with the given reason.
+func (s *SlasherNode) shutdown(reason shared.ShutdownReason) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
+	select {
+	case <-s.stop:
+		// Already shut down, by a signal or a fatal error.
+		return
+	default:
+	}
 
 	log.Info("Stopping hash slinging slasher")
 	s.cancel()
-	s.services.StopAll()
+	if err := s.services.StopAll(reason); err != nil {
+		log.WithError(err).Error("Could not stop all services")
+	}
+	if err := s.services.VerifyNoLeaks(); err != nil && !errors.Is(err, shared.ErrLeakCheckDisabled) {
+		log.WithError(err).Warn("Services left goroutines running")
+	}
 	if err := s.db.Close(); err != nil {
 		log.Errorf("Failed to close database: %v", err)
 	}
@@ -167,7 +193,11 @@ func (s *SlasherNode) registerPrometheusService() error {
 		s.services,
 	)
 	logrus.AddHook(prometheus.NewLogrusCollector())
-	return s.services.RegisterService(service)
+	if err := s.services.RegisterHealthCollector(); err != nil {
+		log.WithError(err).Error("Could not register service health metrics")
+	}
+	// The node keeps running without its metrics if the monitoring port is in use.
+	return s.services.RegisterServiceV2(service, nil, &shared.ServiceConfig{Optional: true})
 }
 
 func (s *SlasherNode) startDB() error {
@@ -224,6 +254,7 @@ func (s *SlasherNode) registerBeaconClientService() error {
 		BeaconProvider:        beaconProvider,
 		AttesterSlashingsFeed: s.attesterSlashingsFeed,
 		ProposerSlashingsFeed: s.proposerSlashingsFeed,
+		EventBus:              s.services.EventBus(),
 	})
 	if err != nil {
 		return errors.Wrap(err, "failed to initialize beacon client")
@@ -238,6 +269,7 @@ func (s *SlasherNode) registerDetectionService() error {
 	}
 	ds := detection.NewService(s.ctx, &detection.Config{
 		Notifier:              bs,
+		EventBus:              s.services.EventBus(),
 		SlasherDB:             s.db,
 		BeaconClient:          bs,
 		ChainFetcher:          bs,
@@ -73,6 +73,8 @@ var appHelpFlagGroups = []flagGroup{
 			debug.MemProfileRateFlag,
 			debug.CPUProfileFlag,
 			debug.TraceFlag,
+			debug.LeakCheckFlag,
+			debug.ShuffleStartOrderFlag,
 		},
 	},
 	{
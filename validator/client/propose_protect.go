@@ -1,7 +1,6 @@
 package client
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 
@@ -10,7 +9,6 @@ import (
 	"github.com/prysmaticlabs/prysm/beacon-chain/core/helpers"
 	"github.com/prysmaticlabs/prysm/shared/blockutil"
 	"github.com/prysmaticlabs/prysm/shared/featureconfig"
-	"github.com/prysmaticlabs/prysm/shared/params"
 	"github.com/sirupsen/logrus"
 )
 
@@ -20,20 +18,40 @@ var failedPostBlockSignErr = "made a double proposal, considered slashable by re
 
 func (v *validator) preBlockSignValidations(ctx context.Context, pubKey [48]byte, block *ethpb.BeaconBlock) error {
 	fmtKey := fmt.Sprintf("%#x", pubKey[:])
-	signingRoot, err := v.db.ProposalHistoryForSlot(ctx, pubKey[:], block.Slot)
+	_, exists, err := v.db.ProposalHistoryForSlot(ctx, pubKey, block.Slot)
 	if err != nil {
 		if v.emitAccountMetrics {
 			ValidatorProposeFailVec.WithLabelValues(fmtKey).Inc()
 		}
 		return errors.Wrap(err, "failed to get proposal history")
 	}
-	// If the bit for the current slot is marked, do not propose.
-	if !bytes.Equal(signingRoot, params.BeaconConfig().ZeroHash[:]) {
+	// If a block was already proposed at the current slot, do not propose.
+	if exists {
 		if v.emitAccountMetrics {
 			ValidatorProposeFailVec.WithLabelValues(fmtKey).Inc()
 		}
 		return errors.New(failedPreBlockSignLocalErr)
 	}
+	// Do not propose at or below the lowest slot a block was proposed at, as its history
+	// may have been pruned or imported without older proposals.
+	lowestSignedProposalSlot, lowestExists, err := v.db.LowestSignedProposal(ctx, pubKey)
+	if err != nil {
+		if v.emitAccountMetrics {
+			ValidatorProposeFailVec.WithLabelValues(fmtKey).Inc()
+		}
+		return errors.Wrap(err, "failed to get lowest signed proposal")
+	}
+	if lowestExists && block.Slot <= lowestSignedProposalSlot {
+		if v.emitAccountMetrics {
+			ValidatorProposeFailVec.WithLabelValues(fmtKey).Inc()
+		}
+		return fmt.Errorf(
+			"%s: slot %d is not above the lowest signed proposal slot %d",
+			failedPreBlockSignLocalErr,
+			block.Slot,
+			lowestSignedProposalSlot,
+		)
+	}
 
 	if featureconfig.Get().SlasherProtection && v.protector != nil {
 		blockHdr, err := blockutil.BeaconBlockHeaderFromBlock(block)
@@ -76,7 +94,7 @@ func (v *validator) postBlockSignUpdate(ctx context.Context, pubKey [48]byte, bl
 		}
 		return errors.Wrap(err, "failed to compute signing root for block")
 	}
-	if err := v.db.SaveProposalHistoryForSlot(ctx, pubKey[:], block.Block.Slot, signingRoot[:]); err != nil {
+	if err := v.db.SaveProposalHistoryForSlot(ctx, pubKey, block.Block.Slot, signingRoot[:]); err != nil {
 		if v.emitAccountMetrics {
 			ValidatorProposeFailVec.WithLabelValues(fmtKey).Inc()
 		}
@@ -25,15 +25,36 @@ func TestPreBlockSignLocalValidation(t *testing.T) {
 		Slot:          10,
 		ProposerIndex: 0,
 	}
-	err := validator.db.SaveProposalHistoryForSlot(ctx, validatorKey.PublicKey().Marshal(), 10, []byte{1})
-	require.NoError(t, err)
 	pubKey := [48]byte{}
 	copy(pubKey[:], validatorKey.PublicKey().Marshal())
+	err := validator.db.SaveProposalHistoryForSlot(ctx, pubKey, 10, []byte{1})
+	require.NoError(t, err)
 	err = validator.preBlockSignValidations(context.Background(), pubKey, block)
 	require.ErrorContains(t, failedPreBlockSignLocalErr, err)
-	block.Slot = 9
+	block.Slot = 11
 	err = validator.preBlockSignValidations(context.Background(), pubKey, block)
-	require.NoError(t, err, "Expected allowed attestation not to throw error")
+	require.NoError(t, err, "Expected allowed block not to throw error")
+}
+
+func TestPreBlockSignLocalValidation_LowestSignedProposal(t *testing.T) {
+	ctx := context.Background()
+	config := &featureconfig.Flags{
+		SlasherProtection: false,
+	}
+	reset := featureconfig.InitWithReset(config)
+	defer reset()
+	validator, _, validatorKey, finish := setup(t)
+	defer finish()
+	pubKey := [48]byte{}
+	copy(pubKey[:], validatorKey.PublicKey().Marshal())
+	require.NoError(t, validator.db.SaveProposalHistoryForSlot(ctx, pubKey, 10, []byte{1}))
+	require.NoError(t, validator.db.SaveProposalHistoryForSlot(ctx, pubKey, 20, []byte{2}))
+
+	// Slot 15 was never proposed at, but it is above the lowest signed proposal.
+	err := validator.preBlockSignValidations(ctx, pubKey, &ethpb.BeaconBlock{Slot: 15})
+	require.NoError(t, err)
+	err = validator.preBlockSignValidations(ctx, pubKey, &ethpb.BeaconBlock{Slot: 9})
+	require.ErrorContains(t, "slot 9 is not above the lowest signed proposal slot 10", err)
 }
 
 func TestPreBlockSignValidation(t *testing.T) {
@@ -26,8 +26,9 @@ type ValidatorDB interface {
 	SaveProposalHistoryForEpoch(ctx context.Context, publicKey []byte, epoch uint64, history bitfield.Bitlist) error
 
 	// New data structure methods
-	ProposalHistoryForSlot(ctx context.Context, publicKey []byte, slot uint64) ([]byte, error)
-	SaveProposalHistoryForSlot(ctx context.Context, pubKey []byte, slot uint64, signingRoot []byte) error
+	ProposalHistoryForSlot(ctx context.Context, pubKey [48]byte, slot uint64) ([]byte, bool, error)
+	SaveProposalHistoryForSlot(ctx context.Context, pubKey [48]byte, slot uint64, signingRoot []byte) error
+	LowestSignedProposal(ctx context.Context, pubKey [48]byte) (uint64, bool, error)
 	SaveProposalHistoryForPubKeysV2(ctx context.Context, proposals map[[48]byte]kv.ProposalHistoryForPubkey) error
 
 	// Attester protection related methods.
@@ -38,4 +39,13 @@ type ValidatorDB interface {
 	AttestationHistoryForPubKeysV2(ctx context.Context, publicKeys [][48]byte) (map[[48]byte]kv.EncHistoryData, error)
 	SaveAttestationHistoryForPubKeysV2(ctx context.Context, historyByPubKeys map[[48]byte]kv.EncHistoryData) error
 	SaveAttestationHistoryForPubKeyV2(ctx context.Context, pubKey [48]byte, history kv.EncHistoryData) error
+	SaveAttestationForPubKey(ctx context.Context, pubKey [48]byte, signingRoot [32]byte, source, target uint64) error
+	SaveAttestationsForPubKeys(ctx context.Context, records []kv.AttestationRecord) error
+	CheckSlashableAttestation(ctx context.Context, pubKey [48]byte, signingRoot [32]byte, source, target uint64) (kv.SlashingKind, error)
+	LowestSignedSourceEpoch(ctx context.Context, pubKey [48]byte) (uint64, bool, error)
+	LowestSignedTargetEpoch(ctx context.Context, pubKey [48]byte) (uint64, bool, error)
+
+	// Slashing protection interchange methods.
+	ImportStandardProtectionJSON(ctx context.Context, r io.Reader) error
+	ExportStandardProtectionJSON(ctx context.Context, w io.Writer) error
 }
@@ -128,6 +128,23 @@ func TestSetTargetData(t *testing.T) {
 
 }
 
+func TestMarkAllAsAttestedSinceLatestWrittenEpoch_MarksSkippedTargetsEmpty(t *testing.T) {
+	ctx := context.Background()
+	history, err := MarkAllAsAttestedSinceLatestWrittenEpoch(ctx, NewAttestationHistoryArray(0), 4, &HistoryData{
+		Source:      3,
+		SigningRoot: bytesutil.PadTo([]byte{4}, 32),
+	})
+	require.NoError(t, err)
+	for target := uint64(1); target < 4; target++ {
+		hd, err := history.GetTargetData(ctx, target)
+		require.NoError(t, err)
+		assert.Equal(t, true, hd.IsEmpty(), "Target %d should not be marked as attested", target)
+	}
+	hd, err := history.GetTargetData(ctx, 4)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), hd.Source)
+}
+
 func TestAttestationHistoryForPubKeysNew_EmptyVals(t *testing.T) {
 	pubkeys := [][48]byte{{30}, {25}, {20}}
 	db := setupDB(t, pubkeys)
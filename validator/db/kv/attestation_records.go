@@ -0,0 +1,249 @@
+package kv
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/pkg/errors"
+	"github.com/prysmaticlabs/prysm/shared/bytesutil"
+	"github.com/prysmaticlabs/prysm/shared/params"
+	bolt "go.etcd.io/bbolt"
+	"go.opencensus.io/trace"
+)
+
+// SlashingKind is the kind of slashable offense a validator would commit by signing an attestation.
+type SlashingKind int
+
+const (
+	// NotSlashable means the attestation can safely be signed.
+	NotSlashable SlashingKind = iota
+	// DoubleVote means an attestation with a different signing root was signed for the same target epoch.
+	DoubleVote
+	// SurroundingVote means the attestation would surround a signed attestation.
+	SurroundingVote
+	// SurroundedVote means the attestation would be surrounded by a signed attestation.
+	SurroundedVote
+)
+
+// String returns the name of the slashing kind.
+func (k SlashingKind) String() string {
+	switch k {
+	case NotSlashable:
+		return "not slashable"
+	case DoubleVote:
+		return "double vote"
+	case SurroundingVote:
+		return "surrounding vote"
+	case SurroundedVote:
+		return "surrounded vote"
+	default:
+		return fmt.Sprintf("unknown slashing kind %d", int(k))
+	}
+}
+
+// The attestations signed by a validator are stored in a bucket of its own, keyed by big endian
+// target epoch so that the attestations of a range of target epochs can be read with a cursor,
+// each holding the source epoch and the signing root of the attestation.
+const attestationRecordSize = sourceSize + signingRootSize
+
+// SaveAttestationForPubKey saves an attestation signed by a validator public key. Saving an
+// attestation again is a no-op, while saving another attestation for the same target epoch
+// fails, as the validator would have signed a double vote.
+func (store *Store) SaveAttestationForPubKey(
+	ctx context.Context, pubKey [48]byte, signingRoot [32]byte, source, target uint64,
+) error {
+	ctx, span := trace.StartSpan(ctx, "Validator.SaveAttestationForPubKey")
+	defer span.End()
+
+	return store.update(func(tx *bolt.Tx) error {
+		return saveAttestationRecord(tx, pubKey, signingRoot, source, target)
+	})
+}
+
+// AttestationRecord is an attestation signed by a validator public key, as saved in batches by
+// SaveAttestationsForPubKeys.
+type AttestationRecord struct {
+	PubKey      [48]byte
+	Source      uint64
+	Target      uint64
+	SigningRoot [32]byte
+}
+
+// SaveAttestationsForPubKeys saves a batch of attestations signed by any validator public keys in a
+// single transaction, so that validators signing at the same time do not wait on each other's
+// writes. Each attestation is saved as by SaveAttestationForPubKey, and if any of them fails to be
+// saved, none of the batch is.
+func (store *Store) SaveAttestationsForPubKeys(ctx context.Context, records []AttestationRecord) error {
+	ctx, span := trace.StartSpan(ctx, "Validator.SaveAttestationsForPubKeys")
+	defer span.End()
+
+	if len(records) == 0 {
+		return nil
+	}
+	return store.update(func(tx *bolt.Tx) error {
+		for i, record := range records {
+			if err := saveAttestationRecord(tx, record.PubKey, record.SigningRoot, record.Source, record.Target); err != nil {
+				return errors.Wrapf(err, "could not save attestation %d of the batch", i)
+			}
+		}
+		return nil
+	})
+}
+
+// CheckSlashableAttestation checks whether signing an attestation would be slashable given the
+// attestations a validator public key signed, by looking for a double vote at its target epoch
+// and for signed attestations surrounding it or surrounded by it. A slashable attestation is
+// reported by its kind along with an error describing the offense.
+func (store *Store) CheckSlashableAttestation(
+	ctx context.Context, pubKey [48]byte, signingRoot [32]byte, source, target uint64,
+) (SlashingKind, error) {
+	ctx, span := trace.StartSpan(ctx, "Validator.CheckSlashableAttestation")
+	defer span.End()
+
+	kind := NotSlashable
+	err := store.view(func(tx *bolt.Tx) error {
+		valBucket := tx.Bucket(attestationRecordsBucket).Bucket(pubKey[:])
+		if valBucket == nil {
+			return nil
+		}
+		var err error
+		kind, err = checkSlashableAttestation(valBucket, signingRoot, source, target)
+		return err
+	})
+	return kind, err
+}
+
+func checkSlashableAttestation(valBucket *bolt.Bucket, signingRoot [32]byte, source, target uint64) (SlashingKind, error) {
+	// A signing root which was not known when signing, such as one imported from another
+	// client, cannot prove the attestations are the same.
+	if enc := valBucket.Get(bytesutil.Uint64ToBytesBigEndian(target)); enc != nil {
+		_, existingRoot := decodeAttestationRecord(enc)
+		if !bytes.Equal(existingRoot, signingRoot[:]) || bytes.Equal(existingRoot, params.BeaconConfig().ZeroHash[:]) {
+			return DoubleVote, fmt.Errorf(
+				"attestation with signing root %#x was already signed at target epoch %d", existingRoot, target,
+			)
+		}
+	}
+	c := valBucket.Cursor()
+	// A signed attestation is surrounded if its source is above the incoming source and its
+	// target below the incoming target, which can only be the case for targets in between.
+	for k, v := c.Seek(bytesutil.Uint64ToBytesBigEndian(source + 1)); k != nil; k, v = c.Next() {
+		existingTarget := bytesutil.BytesToUint64BigEndian(k)
+		if existingTarget >= target {
+			break
+		}
+		if existingSource, _ := decodeAttestationRecord(v); existingSource > source {
+			return SurroundingVote, fmt.Errorf(
+				"attestation with source %d and target %d would surround the signed attestation with source %d and target %d",
+				source, target, existingSource, existingTarget,
+			)
+		}
+	}
+	// A signed attestation surrounds the incoming one if its source is below the incoming source
+	// and its target above the incoming target.
+	if target == math.MaxUint64 {
+		return NotSlashable, nil
+	}
+	for k, v := c.Seek(bytesutil.Uint64ToBytesBigEndian(target + 1)); k != nil; k, v = c.Next() {
+		if existingSource, _ := decodeAttestationRecord(v); existingSource < source {
+			return SurroundedVote, fmt.Errorf(
+				"attestation with source %d and target %d would be surrounded by the signed attestation with source %d and target %d",
+				source, target, existingSource, bytesutil.BytesToUint64BigEndian(k),
+			)
+		}
+	}
+	return NotSlashable, nil
+}
+
+// LowestSignedSourceEpoch returns the lowest source epoch of the attestations signed by a validator
+// public key, and whether it signed any attestation at all. Attestations with a lower source epoch
+// must be rejected, even if the other attestations of the validator are unknown.
+func (store *Store) LowestSignedSourceEpoch(ctx context.Context, pubKey [48]byte) (uint64, bool, error) {
+	ctx, span := trace.StartSpan(ctx, "Validator.LowestSignedSourceEpoch")
+	defer span.End()
+	return store.lowestSignedEpoch(lowestSignedSourceBucket, pubKey)
+}
+
+// LowestSignedTargetEpoch returns the lowest target epoch of the attestations signed by a validator
+// public key, and whether it signed any attestation at all. Attestations with a target epoch at or
+// below it must be rejected, even if the other attestations of the validator are unknown.
+func (store *Store) LowestSignedTargetEpoch(ctx context.Context, pubKey [48]byte) (uint64, bool, error) {
+	ctx, span := trace.StartSpan(ctx, "Validator.LowestSignedTargetEpoch")
+	defer span.End()
+	return store.lowestSignedEpoch(lowestSignedTargetBucket, pubKey)
+}
+
+func (store *Store) lowestSignedEpoch(bucket []byte, pubKey [48]byte) (uint64, bool, error) {
+	var epoch uint64
+	var exists bool
+	err := store.view(func(tx *bolt.Tx) error {
+		enc := tx.Bucket(bucket).Get(pubKey[:])
+		if len(enc) == 0 {
+			return nil
+		}
+		exists = true
+		epoch = bytesutil.BytesToUint64BigEndian(enc)
+		return nil
+	})
+	return epoch, exists, err
+}
+
+// updateLowestSignedEpochs lowers the lowest signed source and target epochs of a public key to
+// the epochs of an attestation it signed, if they are lower or if there were none.
+func updateLowestSignedEpochs(tx *bolt.Tx, pubKey [48]byte, source, target uint64) error {
+	if err := lowerEpoch(tx.Bucket(lowestSignedSourceBucket), pubKey, source); err != nil {
+		return errors.Wrapf(err, "could not save lowest signed source epoch for public key %#x", pubKey)
+	}
+	if err := lowerEpoch(tx.Bucket(lowestSignedTargetBucket), pubKey, target); err != nil {
+		return errors.Wrapf(err, "could not save lowest signed target epoch for public key %#x", pubKey)
+	}
+	return nil
+}
+
+func lowerEpoch(bucket *bolt.Bucket, pubKey [48]byte, epoch uint64) error {
+	enc := bucket.Get(pubKey[:])
+	if len(enc) != 0 && bytesutil.BytesToUint64BigEndian(enc) <= epoch {
+		return nil
+	}
+	return bucket.Put(pubKey[:], bytesutil.Uint64ToBytesBigEndian(epoch))
+}
+
+// saveAttestationRecord saves an attestation to the bucket of a validator public key along with
+// its lowest signed epochs, failing if another attestation was saved for the same target epoch.
+func saveAttestationRecord(tx *bolt.Tx, pubKey [48]byte, signingRoot [32]byte, source, target uint64) error {
+	valBucket, err := tx.Bucket(attestationRecordsBucket).CreateBucketIfNotExists(pubKey[:])
+	if err != nil {
+		return errors.Wrapf(err, "could not create attestations bucket for public key %#x", pubKey)
+	}
+	targetKey := bytesutil.Uint64ToBytesBigEndian(target)
+	enc := encodeAttestationRecord(source, signingRoot)
+	if existing := valBucket.Get(targetKey); existing != nil {
+		if bytes.Equal(existing, enc) {
+			return nil
+		}
+		existingSource, existingRoot := decodeAttestationRecord(existing)
+		return fmt.Errorf(
+			"an attestation with source %d and signing root %#x was already saved at target epoch %d for public key %#x",
+			existingSource, existingRoot, target, pubKey,
+		)
+	}
+	if err := valBucket.Put(targetKey, enc); err != nil {
+		return err
+	}
+	return updateLowestSignedEpochs(tx, pubKey, source, target)
+}
+
+func encodeAttestationRecord(source uint64, signingRoot [32]byte) []byte {
+	enc := make([]byte, attestationRecordSize)
+	copy(enc[:sourceSize], bytesutil.Uint64ToBytesBigEndian(source))
+	copy(enc[sourceSize:], signingRoot[:])
+	return enc
+}
+
+func decodeAttestationRecord(enc []byte) (uint64, []byte) {
+	signingRoot := make([]byte, signingRootSize)
+	copy(signingRoot, enc[sourceSize:])
+	return bytesutil.BytesToUint64BigEndian(enc[:sourceSize]), signingRoot
+}
@@ -0,0 +1,390 @@
+package kv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/prysmaticlabs/prysm/shared/params"
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+func TestStore_CheckSlashableAttestation(t *testing.T) {
+	type attestation struct {
+		source      uint64
+		target      uint64
+		signingRoot [32]byte
+	}
+	tests := []struct {
+		name      string
+		existing  []attestation
+		incoming  attestation
+		want      SlashingKind
+		wantError string
+	}{
+		{
+			name:     "no attestation signed",
+			incoming: attestation{source: 1, target: 2, signingRoot: [32]byte{1}},
+			want:     NotSlashable,
+		},
+		{
+			name:     "same attestation signed again",
+			existing: []attestation{{source: 2, target: 5, signingRoot: [32]byte{1}}},
+			incoming: attestation{source: 2, target: 5, signingRoot: [32]byte{1}},
+			want:     NotSlashable,
+		},
+		{
+			name:      "double vote",
+			existing:  []attestation{{source: 2, target: 5, signingRoot: [32]byte{1}}},
+			incoming:  attestation{source: 2, target: 5, signingRoot: [32]byte{2}},
+			want:      DoubleVote,
+			wantError: "already signed at target epoch 5",
+		},
+		{
+			name:      "double vote with another source",
+			existing:  []attestation{{source: 2, target: 5, signingRoot: [32]byte{1}}},
+			incoming:  attestation{source: 3, target: 5, signingRoot: [32]byte{2}},
+			want:      DoubleVote,
+			wantError: "already signed at target epoch 5",
+		},
+		{
+			name:      "double vote against an unknown signing root",
+			existing:  []attestation{{source: 2, target: 5}},
+			incoming:  attestation{source: 2, target: 5},
+			want:      DoubleVote,
+			wantError: "already signed at target epoch 5",
+		},
+		{
+			name:      "surrounding vote",
+			existing:  []attestation{{source: 2, target: 5, signingRoot: [32]byte{1}}},
+			incoming:  attestation{source: 1, target: 6, signingRoot: [32]byte{2}},
+			want:      SurroundingVote,
+			wantError: "would surround the signed attestation with source 2 and target 5",
+		},
+		{
+			name: "surrounding vote over several attestations",
+			existing: []attestation{
+				{source: 0, target: 1, signingRoot: [32]byte{1}},
+				{source: 1, target: 2, signingRoot: [32]byte{2}},
+				{source: 4, target: 8, signingRoot: [32]byte{3}},
+				{source: 8, target: 9, signingRoot: [32]byte{4}},
+			},
+			incoming:  attestation{source: 3, target: 10, signingRoot: [32]byte{5}},
+			want:      SurroundingVote,
+			wantError: "would surround the signed attestation with source 4 and target 8",
+		},
+		{
+			name:      "surrounded vote",
+			existing:  []attestation{{source: 2, target: 5, signingRoot: [32]byte{1}}},
+			incoming:  attestation{source: 3, target: 4, signingRoot: [32]byte{2}},
+			want:      SurroundedVote,
+			wantError: "would be surrounded by the signed attestation with source 2 and target 5",
+		},
+		{
+			name: "surrounded vote by a much later attestation",
+			existing: []attestation{
+				{source: 4, target: 5, signingRoot: [32]byte{1}},
+				{source: 5, target: 6, signingRoot: [32]byte{2}},
+				{source: 1, target: 100, signingRoot: [32]byte{3}},
+			},
+			incoming:  attestation{source: 7, target: 8, signingRoot: [32]byte{4}},
+			want:      SurroundedVote,
+			wantError: "would be surrounded by the signed attestation with source 1 and target 100",
+		},
+		{
+			name:     "later source and target",
+			existing: []attestation{{source: 2, target: 5, signingRoot: [32]byte{1}}},
+			incoming: attestation{source: 3, target: 6, signingRoot: [32]byte{2}},
+			want:     NotSlashable,
+		},
+		{
+			name:     "earlier source and target",
+			existing: []attestation{{source: 2, target: 5, signingRoot: [32]byte{1}}},
+			incoming: attestation{source: 1, target: 4, signingRoot: [32]byte{2}},
+			want:     NotSlashable,
+		},
+		{
+			name:     "same source and later target",
+			existing: []attestation{{source: 2, target: 5, signingRoot: [32]byte{1}}},
+			incoming: attestation{source: 2, target: 6, signingRoot: [32]byte{2}},
+			want:     NotSlashable,
+		},
+		{
+			name:     "same source and earlier target",
+			existing: []attestation{{source: 2, target: 5, signingRoot: [32]byte{1}}},
+			incoming: attestation{source: 2, target: 4, signingRoot: [32]byte{2}},
+			want:     NotSlashable,
+		},
+		{
+			name:     "source at an existing target",
+			existing: []attestation{{source: 2, target: 5, signingRoot: [32]byte{1}}},
+			incoming: attestation{source: 5, target: 7, signingRoot: [32]byte{2}},
+			want:     NotSlashable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			pubKey := [48]byte{1}
+			db := setupDB(t, [][48]byte{pubKey})
+			for _, att := range tt.existing {
+				require.NoError(t, db.SaveAttestationForPubKey(ctx, pubKey, att.signingRoot, att.source, att.target))
+			}
+			kind, err := db.CheckSlashableAttestation(ctx, pubKey, tt.incoming.signingRoot, tt.incoming.source, tt.incoming.target)
+			if tt.wantError != "" {
+				require.ErrorContains(t, tt.wantError, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, kind, "Wrong slashing kind, got %v", kind)
+
+			// Attestations of other validators are never slashable.
+			kind, err = db.CheckSlashableAttestation(ctx, [48]byte{2}, tt.incoming.signingRoot, tt.incoming.source, tt.incoming.target)
+			require.NoError(t, err)
+			assert.Equal(t, NotSlashable, kind)
+		})
+	}
+}
+
+func TestStore_SaveAttestationForPubKey_SameTarget(t *testing.T) {
+	ctx := context.Background()
+	pubKey := [48]byte{1}
+	tests := []struct {
+		name        string
+		source      uint64
+		signingRoot [32]byte
+		wantErr     string
+	}{
+		{
+			name:        "identical attestation",
+			source:      2,
+			signingRoot: [32]byte{1},
+		},
+		{
+			name:        "different signing root",
+			source:      2,
+			signingRoot: [32]byte{2},
+			wantErr:     "was already saved at target epoch 5",
+		},
+		{
+			name:        "different source",
+			source:      3,
+			signingRoot: [32]byte{1},
+			wantErr:     "was already saved at target epoch 5",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupDB(t, [][48]byte{pubKey})
+			require.NoError(t, db.SaveAttestationForPubKey(ctx, pubKey, [32]byte{1}, 2, 5))
+			err := db.SaveAttestationForPubKey(ctx, pubKey, tt.signingRoot, tt.source, 5)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, tt.wantErr, err)
+			} else {
+				require.NoError(t, err)
+			}
+			// The first attestation is kept either way.
+			kind, err := db.CheckSlashableAttestation(ctx, pubKey, [32]byte{1}, 2, 5)
+			require.NoError(t, err)
+			assert.Equal(t, NotSlashable, kind)
+		})
+	}
+}
+
+func TestStore_CheckSlashableAttestation_Imported(t *testing.T) {
+	ctx := context.Background()
+	pubKeys := createRandomPubKeys(t, 1)
+	db := setupDB(t, pubKeys)
+	interchange := &EIPSlashingProtectionFormat{
+		Data: []*ProtectionData{
+			{
+				Pubkey:             fmt.Sprintf("%#x", pubKeys[0]),
+				SignedAttestations: []*SignedAttestation{{SourceEpoch: "2", TargetEpoch: "5"}},
+			},
+		},
+	}
+	interchange.Metadata.InterchangeFormatVersion = InterchangeFormatVersion
+	interchange.Metadata.GenesisValidatorsRoot = fmt.Sprintf("%#x", [32]byte{1})
+	encoded, err := json.Marshal(interchange)
+	require.NoError(t, err)
+	require.NoError(t, db.ImportStandardProtectionJSON(ctx, bytes.NewReader(encoded)))
+
+	kind, err := db.CheckSlashableAttestation(ctx, pubKeys[0], [32]byte{1}, 3, 4)
+	require.ErrorContains(t, "would be surrounded", err)
+	assert.Equal(t, SurroundedVote, kind)
+}
+
+func TestStore_LowestSignedEpochs(t *testing.T) {
+	ctx := context.Background()
+	pubKey := [48]byte{1}
+	db := setupDB(t, [][48]byte{pubKey})
+	_, exists, err := db.LowestSignedSourceEpoch(ctx, pubKey)
+	require.NoError(t, err)
+	assert.Equal(t, false, exists, "Expected no lowest signed source epoch before any attestation")
+	_, exists, err = db.LowestSignedTargetEpoch(ctx, pubKey)
+	require.NoError(t, err)
+	assert.Equal(t, false, exists, "Expected no lowest signed target epoch before any attestation")
+
+	tests := []struct {
+		source       uint64
+		target       uint64
+		wantErr      string
+		lowestSource uint64
+		lowestTarget uint64
+	}{
+		{source: 5, target: 10, lowestSource: 5, lowestTarget: 10},
+		{source: 3, target: 12, lowestSource: 3, lowestTarget: 10},
+		{source: 4, target: 8, lowestSource: 3, lowestTarget: 8},
+		// A failed save leaves the lowest signed epochs untouched.
+		{source: 1, target: 8, wantErr: "already saved at target epoch 8", lowestSource: 3, lowestTarget: 8},
+		{source: 0, target: 1, lowestSource: 0, lowestTarget: 1},
+	}
+	for _, tt := range tests {
+		err := db.SaveAttestationForPubKey(ctx, pubKey, [32]byte{byte(tt.target)}, tt.source, tt.target)
+		if tt.wantErr != "" {
+			require.ErrorContains(t, tt.wantErr, err)
+		} else {
+			require.NoError(t, err)
+		}
+		lowestSource, exists, err := db.LowestSignedSourceEpoch(ctx, pubKey)
+		require.NoError(t, err)
+		require.Equal(t, true, exists)
+		assert.Equal(t, tt.lowestSource, lowestSource, "Wrong lowest source after saving (%d, %d)", tt.source, tt.target)
+		lowestTarget, exists, err := db.LowestSignedTargetEpoch(ctx, pubKey)
+		require.NoError(t, err)
+		require.Equal(t, true, exists)
+		assert.Equal(t, tt.lowestTarget, lowestTarget, "Wrong lowest target after saving (%d, %d)", tt.source, tt.target)
+	}
+}
+
+func TestStore_LowestSignedEpochs_Imported(t *testing.T) {
+	ctx := context.Background()
+	pubKeys := createRandomPubKeys(t, 2)
+	db := setupDB(t, pubKeys)
+	wsPeriod := params.BeaconConfig().WeakSubjectivityPeriod
+	// The attesting history of the first key is too recent to hold the imported attestation.
+	history, err := MarkAllAsAttestedSinceLatestWrittenEpoch(ctx, NewAttestationHistoryArray(0), 2*wsPeriod, &HistoryData{
+		Source:      2*wsPeriod - 1,
+		SigningRoot: make([]byte, 32),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.SaveAttestationHistoryForPubKeyV2(ctx, pubKeys[0], history))
+
+	// A file summarizing the history of each key by its latest attestation, without signing root.
+	interchange := &EIPSlashingProtectionFormat{}
+	for _, pubKey := range pubKeys {
+		interchange.Data = append(interchange.Data, &ProtectionData{
+			Pubkey:             fmt.Sprintf("%#x", pubKey),
+			SignedAttestations: []*SignedAttestation{{SourceEpoch: "100", TargetEpoch: "101"}},
+		})
+	}
+	interchange.Metadata.InterchangeFormatVersion = InterchangeFormatVersion
+	interchange.Metadata.GenesisValidatorsRoot = fmt.Sprintf("%#x", [32]byte{1})
+	encoded, err := json.Marshal(interchange)
+	require.NoError(t, err)
+	require.NoError(t, db.ImportStandardProtectionJSON(ctx, bytes.NewReader(encoded)))
+
+	for _, pubKey := range pubKeys {
+		lowestSource, exists, err := db.LowestSignedSourceEpoch(ctx, pubKey)
+		require.NoError(t, err)
+		require.Equal(t, true, exists)
+		assert.Equal(t, uint64(100), lowestSource)
+		lowestTarget, exists, err := db.LowestSignedTargetEpoch(ctx, pubKey)
+		require.NoError(t, err)
+		require.Equal(t, true, exists)
+		assert.Equal(t, uint64(101), lowestTarget)
+	}
+}
+
+func TestStore_SaveAttestationsForPubKeys(t *testing.T) {
+	ctx := context.Background()
+	pubKeys := [][48]byte{{1}, {2}, {3}}
+	db := setupDB(t, pubKeys)
+	var records []AttestationRecord
+	for i, pubKey := range pubKeys {
+		records = append(records,
+			AttestationRecord{PubKey: pubKey, Source: uint64(i), Target: uint64(i) + 1, SigningRoot: [32]byte{1}},
+			AttestationRecord{PubKey: pubKey, Source: uint64(i) + 1, Target: uint64(i) + 2, SigningRoot: [32]byte{2}},
+		)
+	}
+	require.NoError(t, db.SaveAttestationsForPubKeys(ctx, records))
+	require.NoError(t, db.SaveAttestationsForPubKeys(ctx, nil))
+
+	for i, pubKey := range pubKeys {
+		kind, err := db.CheckSlashableAttestation(ctx, pubKey, [32]byte{3}, uint64(i)+1, uint64(i)+2)
+		require.ErrorContains(t, "already signed", err)
+		assert.Equal(t, DoubleVote, kind)
+		lowestSource, _, err := db.LowestSignedSourceEpoch(ctx, pubKey)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(i), lowestSource)
+		lowestTarget, _, err := db.LowestSignedTargetEpoch(ctx, pubKey)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(i)+1, lowestTarget)
+	}
+}
+
+func TestStore_SaveAttestationsForPubKeys_RollsBackOnFailure(t *testing.T) {
+	ctx := context.Background()
+	pubKeys := [][48]byte{{1}, {2}}
+	db := setupDB(t, pubKeys)
+	require.NoError(t, db.SaveAttestationForPubKey(ctx, pubKeys[1], [32]byte{1}, 5, 6))
+
+	err := db.SaveAttestationsForPubKeys(ctx, []AttestationRecord{
+		{PubKey: pubKeys[0], Source: 1, Target: 2, SigningRoot: [32]byte{1}},
+		{PubKey: pubKeys[1], Source: 0, Target: 1, SigningRoot: [32]byte{1}},
+		// Conflicts with the attestation saved beforehand.
+		{PubKey: pubKeys[1], Source: 5, Target: 6, SigningRoot: [32]byte{2}},
+	})
+	require.ErrorContains(t, "could not save attestation 2 of the batch", err)
+
+	// Neither the attestations nor the lowest signed epochs of the batch were saved.
+	kind, err := db.CheckSlashableAttestation(ctx, pubKeys[0], [32]byte{2}, 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, NotSlashable, kind)
+	_, exists, err := db.LowestSignedTargetEpoch(ctx, pubKeys[0])
+	require.NoError(t, err)
+	assert.Equal(t, false, exists)
+	lowestTarget, _, err := db.LowestSignedTargetEpoch(ctx, pubKeys[1])
+	require.NoError(t, err)
+	assert.Equal(t, uint64(6), lowestTarget)
+	kind, err = db.CheckSlashableAttestation(ctx, pubKeys[1], [32]byte{1}, 5, 6)
+	require.NoError(t, err)
+	assert.Equal(t, NotSlashable, kind)
+}
+
+func BenchmarkStore_SaveAttestations(b *testing.B) {
+	const numValidators = 256
+	ctx := context.Background()
+	pubKeys := make([][48]byte, numValidators)
+	for i := range pubKeys {
+		pubKeys[i] = [48]byte{byte(i), byte(i >> 8)}
+	}
+	recordsForEpoch := func(epoch uint64) []AttestationRecord {
+		records := make([]AttestationRecord, numValidators)
+		for i, pubKey := range pubKeys {
+			records[i] = AttestationRecord{PubKey: pubKey, Source: epoch, Target: epoch + 1, SigningRoot: [32]byte{1}}
+		}
+		return records
+	}
+
+	b.Run("individually", func(b *testing.B) {
+		db := setupDB(b, pubKeys)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			for _, record := range recordsForEpoch(uint64(i)) {
+				err := db.SaveAttestationForPubKey(ctx, record.PubKey, record.SigningRoot, record.Source, record.Target)
+				require.NoError(b, err)
+			}
+		}
+	})
+	b.Run("batch", func(b *testing.B) {
+		db := setupDB(b, pubKeys)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			require.NoError(b, db.SaveAttestationsForPubKeys(ctx, recordsForEpoch(uint64(i))))
+		}
+	})
+}
@@ -90,6 +90,10 @@ func NewKVStore(dirPath string, pubKeys [][48]byte) (*Store, error) {
 			historicAttestationsBucket,
 			newHistoricAttestationsBucket,
 			newhistoricProposalsBucket,
+			lowestSignedProposalsBucket,
+			attestationRecordsBucket,
+			lowestSignedSourceBucket,
+			lowestSignedTargetBucket,
 		)
 	}); err != nil {
 		return nil, err
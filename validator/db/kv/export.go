@@ -0,0 +1,198 @@
+package kv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/prysmaticlabs/prysm/shared/bytesutil"
+	"github.com/prysmaticlabs/prysm/shared/params"
+	bolt "go.etcd.io/bbolt"
+	"go.opencensus.io/trace"
+)
+
+// ExportStandardProtectionJSON writes the slashing protection data of every validator public key
+// known to the database to the given writer as an EIP-3076 compliant JSON file, which can be
+// imported by any eth2 client. For more information, see the EIP document here:
+// https://eips.ethereum.org/EIPS/eip-3076.
+//
+// The export is deterministic: validators are sorted by public key, their signed blocks by slot
+// and their signed attestations by target epoch, so that two exports of the same data are equal.
+// Validators without any signing history are exported with empty lists of blocks and attestations.
+func (store *Store) ExportStandardProtectionJSON(ctx context.Context, w io.Writer) error {
+	ctx, span := trace.StartSpan(ctx, "Validator.ExportStandardProtectionJSON")
+	defer span.End()
+
+	interchangeJSON := &EIPSlashingProtectionFormat{}
+	interchangeJSON.Metadata.InterchangeFormatVersion = InterchangeFormatVersion
+	err := store.view(func(tx *bolt.Tx) error {
+		// A slashing protection file is only valid for the chain it was created on, so it
+		// cannot be exported before the genesis validators root of the chain is known.
+		gvr := tx.Bucket(genesisInfoBucket).Get(genesisValidatorsRootKey)
+		if len(gvr) == 0 {
+			return errors.New("no genesis validators root is stored in the slashing protection db")
+		}
+		interchangeJSON.Metadata.GenesisValidatorsRoot = fmt.Sprintf("%#x", gvr)
+
+		pubKeys, err := exportedPublicKeys(tx)
+		if err != nil {
+			return err
+		}
+		interchangeJSON.Data = make([]*ProtectionData, 0, len(pubKeys))
+		for _, pubKey := range pubKeys {
+			signedBlocks, err := exportSignedBlocks(tx, pubKey)
+			if err != nil {
+				return errors.Wrapf(err, "could not export signed blocks for key %#x", pubKey)
+			}
+			signedAtts, err := exportSignedAttestations(ctx, tx, pubKey)
+			if err != nil {
+				return errors.Wrapf(err, "could not export signed attestations for key %#x", pubKey)
+			}
+			interchangeJSON.Data = append(interchangeJSON.Data, &ProtectionData{
+				Pubkey:             fmt.Sprintf("%#x", pubKey),
+				SignedBlocks:       signedBlocks,
+				SignedAttestations: signedAtts,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "could not export slashing protection data")
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(interchangeJSON); err != nil {
+		return errors.Wrap(err, "could not write slashing protection JSON file")
+	}
+	return nil
+}
+
+// exportedPublicKeys returns the sorted public keys having a proposal history bucket, an
+// attesting history or saved attestations in the database.
+func exportedPublicKeys(tx *bolt.Tx) ([][48]byte, error) {
+	seen := make(map[[48]byte]bool)
+	collect := func(k, _ []byte) error {
+		if len(k) != 48 {
+			return fmt.Errorf("%#x is not a valid public key", k)
+		}
+		seen[bytesutil.ToBytes48(k)] = true
+		return nil
+	}
+	if err := tx.Bucket(newhistoricProposalsBucket).ForEach(collect); err != nil {
+		return nil, err
+	}
+	if err := tx.Bucket(newHistoricAttestationsBucket).ForEach(collect); err != nil {
+		return nil, err
+	}
+	if err := tx.Bucket(attestationRecordsBucket).ForEach(collect); err != nil {
+		return nil, err
+	}
+	pubKeys := make([][48]byte, 0, len(seen))
+	for pubKey := range seen {
+		pubKeys = append(pubKeys, pubKey)
+	}
+	sort.Slice(pubKeys, func(i, j int) bool {
+		return bytes.Compare(pubKeys[i][:], pubKeys[j][:]) < 0
+	})
+	return pubKeys, nil
+}
+
+// exportSignedBlocks returns the signed blocks of a public key sorted by slot, which is the
+// order of the big endian slot keys of its proposal history bucket.
+func exportSignedBlocks(tx *bolt.Tx, pubKey [48]byte) ([]*SignedBlock, error) {
+	signedBlocks := make([]*SignedBlock, 0)
+	valBucket := tx.Bucket(newhistoricProposalsBucket).Bucket(pubKey[:])
+	if valBucket == nil {
+		return signedBlocks, nil
+	}
+	err := valBucket.ForEach(func(k, v []byte) error {
+		if len(k) != 8 {
+			return fmt.Errorf("%#x is not a valid slot", k)
+		}
+		signedBlocks = append(signedBlocks, &SignedBlock{
+			Slot:        fmt.Sprintf("%d", bytesutil.BytesToUint64BigEndian(k)),
+			SigningRoot: exportedSigningRoot(v),
+		})
+		return nil
+	})
+	return signedBlocks, err
+}
+
+// exportSignedAttestations returns the signed attestations of a public key sorted by target
+// epoch, from both its saved attestations and its attesting history. The attesting history holds
+// the targets of one weak subjectivity period up to its latest epoch written, of which the targets
+// not attested for are skipped.
+func exportSignedAttestations(ctx context.Context, tx *bolt.Tx, pubKey [48]byte) ([]*SignedAttestation, error) {
+	signedAttsByTarget := make(map[uint64]*SignedAttestation)
+	if enc := tx.Bucket(newHistoricAttestationsBucket).Get(pubKey[:]); len(enc) != 0 {
+		history := EncHistoryData(enc)
+		latestEpochWritten, err := history.GetLatestEpochWritten(ctx)
+		if err != nil {
+			return nil, err
+		}
+		wsPeriod := params.BeaconConfig().WeakSubjectivityPeriod
+		oldestTarget := uint64(0)
+		if latestEpochWritten >= wsPeriod {
+			oldestTarget = latestEpochWritten - wsPeriod + 1
+		}
+		for target := oldestTarget; target <= latestEpochWritten; target++ {
+			hd, err := history.GetTargetData(ctx, target)
+			if err != nil {
+				return nil, err
+			}
+			if hd.IsEmpty() {
+				continue
+			}
+			signedAttsByTarget[target] = &SignedAttestation{
+				SourceEpoch: fmt.Sprintf("%d", hd.Source),
+				TargetEpoch: fmt.Sprintf("%d", target),
+				SigningRoot: exportedSigningRoot(hd.SigningRoot),
+			}
+		}
+	}
+	if valBucket := tx.Bucket(attestationRecordsBucket).Bucket(pubKey[:]); valBucket != nil {
+		if err := valBucket.ForEach(func(k, v []byte) error {
+			if len(k) != 8 || len(v) != attestationRecordSize {
+				return fmt.Errorf("%#x is not a valid attestation at target %#x", v, k)
+			}
+			target := bytesutil.BytesToUint64BigEndian(k)
+			source, signingRoot := decodeAttestationRecord(v)
+			signedAttsByTarget[target] = &SignedAttestation{
+				SourceEpoch: fmt.Sprintf("%d", source),
+				TargetEpoch: fmt.Sprintf("%d", target),
+				SigningRoot: exportedSigningRoot(signingRoot),
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	targets := make([]uint64, 0, len(signedAttsByTarget))
+	for target := range signedAttsByTarget {
+		targets = append(targets, target)
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i] < targets[j]
+	})
+	signedAtts := make([]*SignedAttestation, len(targets))
+	for i, target := range targets {
+		signedAtts[i] = signedAttsByTarget[target]
+	}
+	return signedAtts, nil
+}
+
+// exportedSigningRoot returns the hex encoding of a stored signing root, or an empty string if
+// none was known when signing, as signing roots are optional in the standard JSON file. Stored
+// values which are not 32 byte roots, such as the placeholder of migrated proposals, are not
+// signing roots.
+func exportedSigningRoot(signingRoot []byte) string {
+	if len(signingRoot) != 32 || bytes.Equal(signingRoot, params.BeaconConfig().ZeroHash[:]) {
+		return ""
+	}
+	return fmt.Sprintf("%#x", signingRoot)
+}
@@ -0,0 +1,171 @@
+package kv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+)
+
+func TestStore_ExportStandardProtectionJSON_NoGenesisValidatorsRoot(t *testing.T) {
+	db := setupDB(t, createRandomPubKeys(t, 1))
+	buf := new(bytes.Buffer)
+	err := db.ExportStandardProtectionJSON(context.Background(), buf)
+	require.ErrorContains(t, "no genesis validators root is stored", err)
+	assert.Equal(t, 0, buf.Len(), "Nothing should have been written")
+}
+
+func TestStore_ExportStandardProtectionJSON_EmptyHistories(t *testing.T) {
+	ctx := context.Background()
+	pubKeys := createRandomPubKeys(t, 2)
+	db := setupDB(t, pubKeys)
+	require.NoError(t, db.SaveGenesisValidatorsRoot(ctx, []byte{1}))
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, db.ExportStandardProtectionJSON(ctx, buf))
+	assert.Equal(t, 2, bytes.Count(buf.Bytes(), []byte(`"signed_blocks": []`)))
+	assert.Equal(t, 2, bytes.Count(buf.Bytes(), []byte(`"signed_attestations": []`)))
+	exported := &EIPSlashingProtectionFormat{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), exported))
+	assert.Equal(t, InterchangeFormatVersion, exported.Metadata.InterchangeFormatVersion)
+	assert.Equal(t, "0x01", exported.Metadata.GenesisValidatorsRoot)
+	require.Equal(t, 2, len(exported.Data))
+}
+
+func TestStore_ExportStandardProtectionJSON_Deterministic(t *testing.T) {
+	ctx := context.Background()
+	pubKeys := createRandomPubKeys(t, 3)
+	db := setupDB(t, nil)
+	require.NoError(t, db.SaveGenesisValidatorsRoot(ctx, bytes.Repeat([]byte{2}, 32)))
+	// Histories are written out of order, and the last key only has attestations.
+	for i := len(pubKeys) - 1; i >= 0; i-- {
+		if i < 2 {
+			for _, slot := range []uint64{9, 3, 6} {
+				require.NoError(t, db.SaveProposalHistoryForSlot(ctx, pubKeys[i], slot, bytes.Repeat([]byte{byte(slot)}, 32)))
+			}
+		}
+		history := NewAttestationHistoryArray(0)
+		var err error
+		for _, target := range []uint64{4, 2, 7} {
+			history, err = MarkAllAsAttestedSinceLatestWrittenEpoch(ctx, history, target, &HistoryData{
+				Source:      target - 1,
+				SigningRoot: bytes.Repeat([]byte{byte(target)}, 32),
+			})
+			require.NoError(t, err)
+		}
+		require.NoError(t, db.SaveAttestationHistoryForPubKeyV2(ctx, pubKeys[i], history))
+	}
+
+	first := new(bytes.Buffer)
+	require.NoError(t, db.ExportStandardProtectionJSON(ctx, first))
+	second := new(bytes.Buffer)
+	require.NoError(t, db.ExportStandardProtectionJSON(ctx, second))
+	assert.DeepEqual(t, first.Bytes(), second.Bytes(), "Exports of the same data differ")
+
+	exported := &EIPSlashingProtectionFormat{}
+	require.NoError(t, json.Unmarshal(first.Bytes(), exported))
+	require.Equal(t, len(pubKeys), len(exported.Data))
+	for i := 1; i < len(exported.Data); i++ {
+		assert.Equal(t, true, exported.Data[i-1].Pubkey < exported.Data[i].Pubkey, "Public keys are not sorted")
+	}
+	for _, data := range exported.Data {
+		var slots []string
+		for _, block := range data.SignedBlocks {
+			slots = append(slots, block.Slot)
+		}
+		if len(slots) != 0 {
+			assert.DeepEqual(t, []string{"3", "6", "9"}, slots)
+		}
+		var targets []string
+		for _, att := range data.SignedAttestations {
+			targets = append(targets, att.TargetEpoch)
+		}
+		assert.DeepEqual(t, []string{"2", "4", "7"}, targets)
+		assert.Equal(t, "1", data.SignedAttestations[0].SourceEpoch)
+		assert.Equal(t, fmt.Sprintf("%#x", bytes.Repeat([]byte{2}, 32)), data.SignedAttestations[0].SigningRoot)
+	}
+
+	// Importing an export into an empty database exports the same file again.
+	imported := setupDB(t, nil)
+	require.NoError(t, imported.ImportStandardProtectionJSON(ctx, bytes.NewReader(first.Bytes())))
+	reexported := new(bytes.Buffer)
+	require.NoError(t, imported.ExportStandardProtectionJSON(ctx, reexported))
+	assert.DeepEqual(t, first.String(), reexported.String())
+}
+
+func TestStore_ExportStandardProtectionJSON_OmitsUnknownSigningRoots(t *testing.T) {
+	ctx := context.Background()
+	pubKeys := createRandomPubKeys(t, 1)
+	db := setupDB(t, pubKeys)
+	require.NoError(t, db.SaveGenesisValidatorsRoot(ctx, []byte{1}))
+	require.NoError(t, db.SaveProposalHistoryForSlot(ctx, pubKeys[0], 1, make([]byte, 32)))
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, db.ExportStandardProtectionJSON(ctx, buf))
+	exported := &EIPSlashingProtectionFormat{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), exported))
+	require.Equal(t, 1, len(exported.Data[0].SignedBlocks))
+	assert.Equal(t, "1", exported.Data[0].SignedBlocks[0].Slot)
+	assert.Equal(t, "", exported.Data[0].SignedBlocks[0].SigningRoot)
+}
+
+func TestStore_ExportStandardProtectionJSON_MigratedProposals(t *testing.T) {
+	ctx := context.Background()
+	pubKeys := createRandomPubKeys(t, 1)
+	db := setupDB(t, pubKeys)
+	require.NoError(t, db.SaveGenesisValidatorsRoot(ctx, bytes.Repeat([]byte{1}, 32)))
+	slotBitlist, err := db.ProposalHistoryForEpoch(ctx, pubKeys[0][:], 0)
+	require.NoError(t, err)
+	slotBitlist.SetBitAt(3, true)
+	require.NoError(t, db.SaveProposalHistoryForEpoch(ctx, pubKeys[0][:], 0, slotBitlist))
+	require.NoError(t, db.MigrateV2ProposalFormat(ctx))
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, db.ExportStandardProtectionJSON(ctx, buf))
+	exported := &EIPSlashingProtectionFormat{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), exported))
+	require.Equal(t, 1, len(exported.Data[0].SignedBlocks))
+	assert.Equal(t, "3", exported.Data[0].SignedBlocks[0].Slot)
+	assert.Equal(t, "", exported.Data[0].SignedBlocks[0].SigningRoot, "The placeholder of a migrated proposal was exported")
+
+	// The exported file can be imported again.
+	imported := setupDB(t, nil)
+	require.NoError(t, imported.ImportStandardProtectionJSON(ctx, bytes.NewReader(buf.Bytes())))
+	_, exists, err := imported.ProposalHistoryForSlot(ctx, pubKeys[0], 3)
+	require.NoError(t, err)
+	assert.Equal(t, true, exists)
+	lowest, exists, err := imported.LowestSignedProposal(ctx, pubKeys[0])
+	require.NoError(t, err)
+	require.Equal(t, true, exists)
+	assert.Equal(t, uint64(3), lowest)
+}
+
+func TestStore_ExportStandardProtectionJSON_SavedAttestations(t *testing.T) {
+	ctx := context.Background()
+	pubKeys := createRandomPubKeys(t, 1)
+	db := setupDB(t, nil)
+	require.NoError(t, db.SaveGenesisValidatorsRoot(ctx, []byte{1}))
+	// Saved attestations are merged with the attesting history, and take precedence over it.
+	history, err := MarkAllAsAttestedSinceLatestWrittenEpoch(ctx, NewAttestationHistoryArray(0), 3, &HistoryData{
+		Source:      2,
+		SigningRoot: bytes.Repeat([]byte{9}, 32),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.SaveAttestationHistoryForPubKeyV2(ctx, pubKeys[0], history))
+	require.NoError(t, db.SaveAttestationForPubKey(ctx, pubKeys[0], [32]byte{3}, 2, 3))
+	require.NoError(t, db.SaveAttestationForPubKey(ctx, pubKeys[0], [32]byte{1}, 0, 1))
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, db.ExportStandardProtectionJSON(ctx, buf))
+	exported := &EIPSlashingProtectionFormat{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), exported))
+	require.Equal(t, 1, len(exported.Data))
+	require.DeepEqual(t, []*SignedAttestation{
+		{SourceEpoch: "0", TargetEpoch: "1", SigningRoot: fmt.Sprintf("%#x", [32]byte{1})},
+		{SourceEpoch: "2", TargetEpoch: "3", SigningRoot: fmt.Sprintf("%#x", [32]byte{3})},
+	}, exported.Data[0].SignedAttestations)
+}
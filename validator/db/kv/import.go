@@ -0,0 +1,356 @@
+package kv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/prysmaticlabs/prysm/shared/bytesutil"
+	"github.com/prysmaticlabs/prysm/shared/hashutil"
+	"github.com/prysmaticlabs/prysm/shared/params"
+	log "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+	"go.opencensus.io/trace"
+)
+
+// ImportStandardProtectionJSON takes in EIP-3076 compliant JSON file used for slashing protection
+// by eth2 validators and imports its data into Prysm's internal representation of slashing
+// protection in the validator client's database. For more information, see the EIP document here:
+// https://eips.ethereum.org/EIPS/eip-3076.
+//
+// The whole file is parsed and validated before anything is written, and its data is written
+// in a single transaction along with the genesis validators root if none was stored yet, so
+// that a malformed file or a file from another chain leaves the database untouched. Imported
+// histories are merged with the histories already stored.
+func (store *Store) ImportStandardProtectionJSON(ctx context.Context, r io.Reader) error {
+	ctx, span := trace.StartSpan(ctx, "Validator.ImportStandardProtectionJSON")
+	defer span.End()
+
+	encodedJSON, err := ioutil.ReadAll(r)
+	if err != nil {
+		return errors.Wrap(err, "could not read slashing protection JSON file")
+	}
+	interchangeJSON := &EIPSlashingProtectionFormat{}
+	if err := json.Unmarshal(encodedJSON, interchangeJSON); err != nil {
+		return errors.Wrap(err, "could not unmarshal slashing protection JSON file")
+	}
+	if interchangeJSON.Data == nil {
+		log.Warn("No slashing protection data to import")
+		return nil
+	}
+
+	// We validate the `Metadata` field of the slashing protection JSON file.
+	genesisValidatorsRoot, err := validateMetadata(interchangeJSON)
+	if err != nil {
+		return errors.Wrap(err, "slashing protection JSON metadata was incorrect")
+	}
+
+	// We need to handle duplicate public keys in the JSON file, with potentially
+	// different signing histories for both attestations and blocks.
+	signedBlocksByPubKey, err := parseUniqueSignedBlocksByPubKey(interchangeJSON.Data)
+	if err != nil {
+		return errors.Wrap(err, "could not parse unique entries for blocks by public key")
+	}
+	signedAttsByPubKey, err := parseUniqueSignedAttestationsByPubKey(interchangeJSON.Data)
+	if err != nil {
+		return errors.Wrap(err, "could not parse unique entries for attestations by public key")
+	}
+
+	proposalHistoryByPubKey := make(map[[48]byte]ProposalHistoryForPubkey)
+	for pubKey, signedBlocks := range signedBlocksByPubKey {
+		// Transform the processed signed blocks data from the JSON
+		// file into the internal Prysm representation of proposal history.
+		proposalHistory, err := transformSignedBlocks(signedBlocks)
+		if err != nil {
+			return errors.Wrapf(err, "could not parse signed blocks in JSON file for key %#x", pubKey)
+		}
+		proposalHistoryByPubKey[pubKey] = *proposalHistory
+	}
+	attestationsByPubKey := make(map[[48]byte][]*importedAttestation)
+	for pubKey, signedAtts := range signedAttsByPubKey {
+		atts, err := transformSignedAttestations(signedAtts)
+		if err != nil {
+			return errors.Wrapf(err, "could not parse signed attestations in JSON file for key %#x", pubKey)
+		}
+		attestationsByPubKey[pubKey] = atts
+	}
+
+	// We save the histories to disk as a single atomic operation, ensuring that this only occurs
+	// after we successfully parsed all data from the JSON file and that the file was created
+	// on our chain. If anything fails, nothing is written.
+	return store.update(func(tx *bolt.Tx) error {
+		if err := checkGenesisValidatorsRoot(tx, genesisValidatorsRoot); err != nil {
+			return err
+		}
+		if err := saveImportedProposals(tx, proposalHistoryByPubKey); err != nil {
+			return errors.Wrap(err, "could not save proposal history from imported JSON to database")
+		}
+		if err := saveImportedAttestations(ctx, tx, attestationsByPubKey); err != nil {
+			return errors.Wrap(err, "could not save attesting history from imported JSON to database")
+		}
+		return nil
+	})
+}
+
+// importedAttestation is a signed attestation parsed from a slashing protection JSON file.
+type importedAttestation struct {
+	source      uint64
+	target      uint64
+	signingRoot [32]byte
+}
+
+// validateMetadata checks the interchange format version of the slashing protection JSON file,
+// and returns the genesis validators root it was created for.
+func validateMetadata(interchangeJSON *EIPSlashingProtectionFormat) ([32]byte, error) {
+	// We need to ensure the version in the metadata field matches the one we support.
+	version := interchangeJSON.Metadata.InterchangeFormatVersion
+	if version != InterchangeFormatVersion {
+		return [32]byte{}, fmt.Errorf(
+			"slashing protection JSON version '%s' is not supported, wanted '%s'",
+			version,
+			InterchangeFormatVersion,
+		)
+	}
+	gvr, err := rootFromHex(interchangeJSON.Metadata.GenesisValidatorsRoot)
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("%s is not a valid genesis validators root: %v", interchangeJSON.Metadata.GenesisValidatorsRoot, err)
+	}
+	return gvr, nil
+}
+
+// checkGenesisValidatorsRoot verifies the genesis validators root of an imported file matches that
+// of our chain data, otherwise the imported slashing protection JSON was created on a different
+// chain. The root is saved if the database does not hold one yet.
+func checkGenesisValidatorsRoot(tx *bolt.Tx, gvr [32]byte) error {
+	bkt := tx.Bucket(genesisInfoBucket)
+	dbGvr := bkt.Get(genesisValidatorsRootKey)
+	if len(dbGvr) == 0 {
+		if err := bkt.Put(genesisValidatorsRootKey, gvr[:]); err != nil {
+			return errors.Wrap(err, "could not save genesis validator root to db")
+		}
+		return nil
+	}
+	if !bytes.Equal(dbGvr, gvr[:]) {
+		return fmt.Errorf("genesis validator root doesnt match the one that is stored in slashing protection db, "+
+			"imported %#x but stored %#x. Please make sure you import the protection data that is relevant "+
+			"to the chain you are on", gvr, dbGvr)
+	}
+	return nil
+}
+
+// We create a map of pubKey -> []*SignedBlock. Then, we keep a map of observed hashes of
+// signed blocks. If we observe a new hash, we insert those signed blocks for processing.
+func parseUniqueSignedBlocksByPubKey(data []*ProtectionData) (map[[48]byte][]*SignedBlock, error) {
+	seenHashes := make(map[[32]byte]bool)
+	signedBlocksByPubKey := make(map[[48]byte][]*SignedBlock)
+	for _, validatorData := range data {
+		if validatorData == nil {
+			continue
+		}
+		pubKey, err := pubKeyFromHex(validatorData.Pubkey)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a valid public key: %v", validatorData.Pubkey, err)
+		}
+		for _, sBlock := range validatorData.SignedBlocks {
+			if sBlock == nil {
+				continue
+			}
+			encoded, err := json.Marshal(sBlock)
+			if err != nil {
+				return nil, err
+			}
+			// Namespace the hash by the public key and the encoded block.
+			h := hashutil.Hash(append(pubKey[:], encoded...))
+			if _, ok := seenHashes[h]; ok {
+				continue
+			}
+			seenHashes[h] = true
+			signedBlocksByPubKey[pubKey] = append(signedBlocksByPubKey[pubKey], sBlock)
+		}
+	}
+	return signedBlocksByPubKey, nil
+}
+
+// We create a map of pubKey -> []*SignedAttestation. Then, we keep a map of observed hashes of
+// signed attestations. If we observe a new hash, we insert those signed attestations for processing.
+func parseUniqueSignedAttestationsByPubKey(data []*ProtectionData) (map[[48]byte][]*SignedAttestation, error) {
+	seenHashes := make(map[[32]byte]bool)
+	signedAttestationsByPubKey := make(map[[48]byte][]*SignedAttestation)
+	for _, validatorData := range data {
+		if validatorData == nil {
+			continue
+		}
+		pubKey, err := pubKeyFromHex(validatorData.Pubkey)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a valid public key: %v", validatorData.Pubkey, err)
+		}
+		for _, sAtt := range validatorData.SignedAttestations {
+			if sAtt == nil {
+				continue
+			}
+			encoded, err := json.Marshal(sAtt)
+			if err != nil {
+				return nil, err
+			}
+			// Namespace the hash by the public key and the encoded block.
+			h := hashutil.Hash(append(pubKey[:], encoded...))
+			if _, ok := seenHashes[h]; ok {
+				continue
+			}
+			seenHashes[h] = true
+			signedAttestationsByPubKey[pubKey] = append(signedAttestationsByPubKey[pubKey], sAtt)
+		}
+	}
+	return signedAttestationsByPubKey, nil
+}
+
+func transformSignedBlocks(signedBlocks []*SignedBlock) (*ProposalHistoryForPubkey, error) {
+	proposals := make([]Proposal, len(signedBlocks))
+	for i, proposal := range signedBlocks {
+		slot, err := uint64FromString(proposal.Slot)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a valid slot: %v", proposal.Slot, err)
+		}
+		var signingRoot [32]byte
+		// Signing roots are optional in the standard JSON file.
+		if proposal.SigningRoot != "" {
+			signingRoot, err = rootFromHex(proposal.SigningRoot)
+			if err != nil {
+				return nil, fmt.Errorf("%s is not a valid root: %v", proposal.SigningRoot, err)
+			}
+		}
+		proposals[i] = Proposal{
+			Slot:        slot,
+			SigningRoot: signingRoot[:],
+		}
+	}
+	return &ProposalHistoryForPubkey{
+		Proposals: proposals,
+	}, nil
+}
+
+// transformSignedAttestations parses the signed attestations of a public key, sorted by
+// target epoch so that they can be applied in order to an attesting history.
+func transformSignedAttestations(atts []*SignedAttestation) ([]*importedAttestation, error) {
+	imported := make([]*importedAttestation, len(atts))
+	for i, attestation := range atts {
+		target, err := uint64FromString(attestation.TargetEpoch)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a valid epoch: %v", attestation.TargetEpoch, err)
+		}
+		source, err := uint64FromString(attestation.SourceEpoch)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a valid epoch: %v", attestation.SourceEpoch, err)
+		}
+		if source > target {
+			return nil, fmt.Errorf("source epoch %d is greater than target epoch %d", source, target)
+		}
+		var signingRoot [32]byte
+		// Signing roots are optional in the standard JSON file.
+		if attestation.SigningRoot != "" {
+			signingRoot, err = rootFromHex(attestation.SigningRoot)
+			if err != nil {
+				return nil, fmt.Errorf("%s is not a valid root: %v", attestation.SigningRoot, err)
+			}
+		}
+		imported[i] = &importedAttestation{source: source, target: target, signingRoot: signingRoot}
+	}
+	sort.SliceStable(imported, func(i, j int) bool {
+		return imported[i].target < imported[j].target
+	})
+	return imported, nil
+}
+
+// saveImportedProposals saves the imported proposals of each public key unless a proposal was
+// already saved at their slot, in which case the saved proposal is kept, and lowers the lowest
+// signed proposal slot of the public key.
+func saveImportedProposals(tx *bolt.Tx, historyByPubKeys map[[48]byte]ProposalHistoryForPubkey) error {
+	bucket := tx.Bucket(newhistoricProposalsBucket)
+	for pubKey, history := range historyByPubKeys {
+		valBucket, err := bucket.CreateBucketIfNotExists(pubKey[:])
+		if err != nil {
+			return fmt.Errorf("could not create bucket for public key %#x", pubKey)
+		}
+		for _, proposal := range history.Proposals {
+			if err := updateLowestSignedProposal(tx, pubKey, proposal.Slot); err != nil {
+				return err
+			}
+			slotKey := bytesutil.Uint64ToBytesBigEndian(proposal.Slot)
+			if valBucket.Get(slotKey) != nil {
+				continue
+			}
+			if err := valBucket.Put(slotKey, proposal.SigningRoot); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// saveImportedAttestations saves the imported attestations of each public key, and marks them as
+// attested in its attesting history, starting from the history already stored, if any. Attestations
+// older than the weak subjectivity period of the history are left out of the history.
+func saveImportedAttestations(ctx context.Context, tx *bolt.Tx, attsByPubKey map[[48]byte][]*importedAttestation) error {
+	bucket := tx.Bucket(newHistoricAttestationsBucket)
+	for pubKey, atts := range attsByPubKey {
+		var history EncHistoryData
+		if enc := bucket.Get(pubKey[:]); len(enc) != 0 {
+			history = make(EncHistoryData, len(enc))
+			copy(history, enc)
+		} else {
+			history = NewAttestationHistoryArray(0)
+		}
+		wsPeriod := params.BeaconConfig().WeakSubjectivityPeriod
+		for _, att := range atts {
+			if err := saveImportedAttestationRecord(tx, pubKey, att); err != nil {
+				return err
+			}
+			latestEpochWritten, err := history.GetLatestEpochWritten(ctx)
+			if err != nil {
+				return errors.Wrapf(err, "could not get latest epoch written for key %#x", pubKey)
+			}
+			// The history only holds one weak subjectivity period of targets, so that
+			// older targets would overwrite newer ones.
+			if att.target+wsPeriod <= latestEpochWritten {
+				continue
+			}
+			signingRoot := att.signingRoot
+			history, err = MarkAllAsAttestedSinceLatestWrittenEpoch(ctx, history, att.target, &HistoryData{
+				Source:      att.source,
+				SigningRoot: signingRoot[:],
+			})
+			if err != nil {
+				return errors.Wrapf(err, "could not mark epoch %d as attested for key %#x", att.target, pubKey)
+			}
+		}
+		if err := bucket.Put(pubKey[:], history); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveImportedAttestationRecord saves an imported attestation unless an attestation was already
+// saved for its target epoch, in which case the saved attestation is kept, and lowers the lowest
+// signed epochs of the public key.
+func saveImportedAttestationRecord(tx *bolt.Tx, pubKey [48]byte, att *importedAttestation) error {
+	valBucket, err := tx.Bucket(attestationRecordsBucket).CreateBucketIfNotExists(pubKey[:])
+	if err != nil {
+		return errors.Wrapf(err, "could not create attestations bucket for public key %#x", pubKey)
+	}
+	// The lowest signed epochs are lowered by every imported attestation, which is all a file
+	// summarizing the history of a validator by its latest attestation provides.
+	if err := updateLowestSignedEpochs(tx, pubKey, att.source, att.target); err != nil {
+		return err
+	}
+	targetKey := bytesutil.Uint64ToBytesBigEndian(att.target)
+	if valBucket.Get(targetKey) != nil {
+		return nil
+	}
+	return valBucket.Put(targetKey, encodeAttestationRecord(att.source, att.signingRoot))
+}
@@ -0,0 +1,979 @@
+package kv
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/prysmaticlabs/prysm/shared/bls"
+	"github.com/prysmaticlabs/prysm/shared/bytesutil"
+	"github.com/prysmaticlabs/prysm/shared/hashutil"
+	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
+	"github.com/prysmaticlabs/prysm/shared/testutil/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+// interchangeTestCase is a test case in the format of the EIP-3076 interchange test vectors: the
+// genesis validators root of the database, then interchange files imported one after the other.
+type interchangeTestCase struct {
+	Name                  string `json:"name"`
+	GenesisValidatorsRoot string `json:"genesis_validators_root"`
+	Steps                 []struct {
+		ShouldSucceed bool                          `json:"should_succeed"`
+		Interchange   *EIPSlashingProtectionFormat  `json:"interchange"`
+		Blocks        []*interchangeTestBlock       `json:"blocks"`
+		Attestations  []*interchangeTestAttestation `json:"attestations"`
+	} `json:"steps"`
+}
+
+// interchangeTestBlock is a block the validator tries to sign once a step was imported.
+type interchangeTestBlock struct {
+	Pubkey        string `json:"pubkey"`
+	Slot          string `json:"slot"`
+	SigningRoot   string `json:"signing_root"`
+	ShouldSucceed bool   `json:"should_succeed"`
+}
+
+// interchangeTestAttestation is an attestation the validator tries to sign once a step was imported.
+type interchangeTestAttestation struct {
+	Pubkey        string `json:"pubkey"`
+	SourceEpoch   string `json:"source_epoch"`
+	TargetEpoch   string `json:"target_epoch"`
+	SigningRoot   string `json:"signing_root"`
+	ShouldSucceed bool   `json:"should_succeed"`
+}
+
+func TestStore_ImportStandardProtectionJSON_TestVectors(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "interchange", "*.json"))
+	require.NoError(t, err)
+	require.NotEqual(t, 0, len(files), "No interchange test vectors found")
+	for _, file := range files {
+		enc, err := ioutil.ReadFile(file)
+		require.NoError(t, err)
+		tc := &interchangeTestCase{}
+		require.NoError(t, json.Unmarshal(enc, tc))
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := context.Background()
+			db := setupDB(t, nil)
+			gvr, err := rootFromHex(tc.GenesisValidatorsRoot)
+			require.NoError(t, err)
+			require.NoError(t, db.SaveGenesisValidatorsRoot(ctx, gvr[:]))
+			for i, step := range tc.Steps {
+				encoded, err := json.Marshal(step.Interchange)
+				require.NoError(t, err)
+				err = db.ImportStandardProtectionJSON(ctx, bytes.NewReader(encoded))
+				if !step.ShouldSucceed {
+					require.NotNil(t, err, "Step %d should have failed", i)
+					continue
+				}
+				require.NoError(t, err, "Step %d should have succeeded", i)
+				assertImported(t, db, step.Interchange)
+				for _, block := range step.Blocks {
+					assert.Equal(t, block.ShouldSucceed, canSignBlock(t, db, block), "Wrong result for block %+v", block)
+				}
+				for _, att := range step.Attestations {
+					assert.Equal(t, att.ShouldSucceed, canSignAttestation(t, db, att), "Wrong result for attestation %+v", att)
+				}
+			}
+		})
+	}
+}
+
+// canSignBlock applies the EIP-3076 protection rules to a block: a validator may not sign any
+// block at a slot it proposed at, nor at or below the lowest slot it proposed at.
+func canSignBlock(t *testing.T, db *Store, block *interchangeTestBlock) bool {
+	ctx := context.Background()
+	pubKey, err := pubKeyFromHex(block.Pubkey)
+	require.NoError(t, err)
+	slot, err := uint64FromString(block.Slot)
+	require.NoError(t, err)
+	_, exists, err := db.ProposalHistoryForSlot(ctx, pubKey, slot)
+	require.NoError(t, err)
+	if exists {
+		return false
+	}
+	lowest, exists, err := db.LowestSignedProposal(ctx, pubKey)
+	require.NoError(t, err)
+	return !exists || slot > lowest
+}
+
+// canSignAttestation applies the EIP-3076 protection rules to an attestation: a validator may
+// not sign a slashable attestation, nor one with a source below its lowest signed source epoch
+// or a target at or below its lowest signed target epoch.
+func canSignAttestation(t *testing.T, db *Store, att *interchangeTestAttestation) bool {
+	ctx := context.Background()
+	pubKey, err := pubKeyFromHex(att.Pubkey)
+	require.NoError(t, err)
+	source, err := uint64FromString(att.SourceEpoch)
+	require.NoError(t, err)
+	target, err := uint64FromString(att.TargetEpoch)
+	require.NoError(t, err)
+	signingRoot, err := rootFromHex(att.SigningRoot)
+	require.NoError(t, err)
+	if kind, _ := db.CheckSlashableAttestation(ctx, pubKey, signingRoot, source, target); kind != NotSlashable {
+		return false
+	}
+	lowestSource, exists, err := db.LowestSignedSourceEpoch(ctx, pubKey)
+	require.NoError(t, err)
+	if exists && source < lowestSource {
+		return false
+	}
+	lowestTarget, exists, err := db.LowestSignedTargetEpoch(ctx, pubKey)
+	require.NoError(t, err)
+	return !exists || target > lowestTarget
+}
+
+// assertImported checks every signed block and attestation of the interchange file is stored.
+func assertImported(t *testing.T, db *Store, interchange *EIPSlashingProtectionFormat) {
+	ctx := context.Background()
+	for _, data := range interchange.Data {
+		pubKey, err := pubKeyFromHex(data.Pubkey)
+		require.NoError(t, err)
+		for _, block := range data.SignedBlocks {
+			slot, err := uint64FromString(block.Slot)
+			require.NoError(t, err)
+			want := make([]byte, 32)
+			if block.SigningRoot != "" {
+				root, err := rootFromHex(block.SigningRoot)
+				require.NoError(t, err)
+				want = root[:]
+			}
+			signingRoot, _, err := db.ProposalHistoryForSlot(ctx, pubKey, slot)
+			require.NoError(t, err)
+			assert.DeepEqual(t, want, signingRoot, "Wrong signing root for slot %d", slot)
+		}
+		histories, err := db.AttestationHistoryForPubKeysV2(ctx, [][48]byte{pubKey})
+		require.NoError(t, err)
+		for _, att := range data.SignedAttestations {
+			source, err := uint64FromString(att.SourceEpoch)
+			require.NoError(t, err)
+			target, err := uint64FromString(att.TargetEpoch)
+			require.NoError(t, err)
+			want := make([]byte, 32)
+			if att.SigningRoot != "" {
+				root, err := rootFromHex(att.SigningRoot)
+				require.NoError(t, err)
+				want = root[:]
+			}
+			hd, err := histories[pubKey].GetTargetData(ctx, target)
+			require.NoError(t, err)
+			require.NotNil(t, hd, "No attestation for target %d", target)
+			assert.Equal(t, source, hd.Source, "Wrong source for target %d", target)
+			assert.DeepEqual(t, want, hd.SigningRoot, "Wrong signing root for target %d", target)
+		}
+	}
+}
+
+func TestStore_ImportStandardProtectionJSON_FailuresLeaveDBUntouched(t *testing.T) {
+	pubKeys := createRandomPubKeys(t, 1)
+	goodRoot := fmt.Sprintf("%#x", [32]byte{1})
+	tests := []struct {
+		name      string
+		version   string
+		gvr       string
+		data      []*ProtectionData
+		wantError string
+	}{
+		{
+			name:      "unknown version",
+			version:   "4",
+			gvr:       goodRoot,
+			wantError: "slashing protection JSON version '4' is not supported, wanted '5'",
+		},
+		{
+			name:      "malformed genesis validators root",
+			version:   InterchangeFormatVersion,
+			gvr:       "0x1234",
+			wantError: "0x1234 is not a valid genesis validators root",
+		},
+		{
+			name:    "malformed public key",
+			version: InterchangeFormatVersion,
+			gvr:     goodRoot,
+			data: []*ProtectionData{
+				{Pubkey: "0xabcd"},
+			},
+			wantError: "0xabcd is not a valid public key",
+		},
+		{
+			name:    "malformed slot",
+			version: InterchangeFormatVersion,
+			gvr:     goodRoot,
+			data: []*ProtectionData{
+				{
+					Pubkey:       fmt.Sprintf("%#x", pubKeys[0]),
+					SignedBlocks: []*SignedBlock{{Slot: "1"}, {Slot: "-2"}},
+				},
+			},
+			wantError: "-2 is not a valid slot",
+		},
+		{
+			name:    "malformed signing root",
+			version: InterchangeFormatVersion,
+			gvr:     goodRoot,
+			data: []*ProtectionData{
+				{
+					Pubkey:             fmt.Sprintf("%#x", pubKeys[0]),
+					SignedAttestations: []*SignedAttestation{{SourceEpoch: "1", TargetEpoch: "2", SigningRoot: "0xzz"}},
+				},
+			},
+			wantError: "0xzz is not a valid root",
+		},
+		{
+			name:    "source after target",
+			version: InterchangeFormatVersion,
+			gvr:     goodRoot,
+			data: []*ProtectionData{
+				{
+					Pubkey:             fmt.Sprintf("%#x", pubKeys[0]),
+					SignedBlocks:       []*SignedBlock{{Slot: "1"}},
+					SignedAttestations: []*SignedAttestation{{SourceEpoch: "3", TargetEpoch: "2"}},
+				},
+			},
+			wantError: "source epoch 3 is greater than target epoch 2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			db := setupDB(t, nil)
+			interchange := &EIPSlashingProtectionFormat{Data: tt.data}
+			if interchange.Data == nil {
+				interchange.Data = []*ProtectionData{}
+			}
+			interchange.Metadata.InterchangeFormatVersion = tt.version
+			interchange.Metadata.GenesisValidatorsRoot = tt.gvr
+			encoded, err := json.Marshal(interchange)
+			require.NoError(t, err)
+
+			err = db.ImportStandardProtectionJSON(ctx, bytes.NewReader(encoded))
+			require.ErrorContains(t, tt.wantError, err)
+
+			// Not even the genesis validators root was saved.
+			gvr, err := db.GenesisValidatorsRoot(ctx)
+			require.NoError(t, err)
+			assert.DeepEqual(t, []byte(nil), gvr)
+			require.NoError(t, db.view(func(tx *bolt.Tx) error {
+				assert.Equal(t, (*bolt.Bucket)(nil), tx.Bucket(newhistoricProposalsBucket).Bucket(pubKeys[0][:]))
+				assert.DeepEqual(t, []byte(nil), tx.Bucket(newHistoricAttestationsBucket).Get(pubKeys[0][:]))
+				return nil
+			}))
+		})
+	}
+}
+
+func TestStore_ImportStandardProtectionJSON_KeepsStoredProposal(t *testing.T) {
+	ctx := context.Background()
+	pubKeys := createRandomPubKeys(t, 1)
+	db := setupDB(t, pubKeys)
+	storedRoot := bytesutil.PadTo([]byte{5}, 32)
+	require.NoError(t, db.SaveProposalHistoryForSlot(ctx, pubKeys[0], 5, storedRoot))
+
+	interchange := &EIPSlashingProtectionFormat{
+		Data: []*ProtectionData{
+			{
+				Pubkey: fmt.Sprintf("%#x", pubKeys[0]),
+				SignedBlocks: []*SignedBlock{
+					{Slot: "5", SigningRoot: fmt.Sprintf("%#x", [32]byte{6})},
+					{Slot: "4", SigningRoot: fmt.Sprintf("%#x", [32]byte{4})},
+				},
+			},
+		},
+	}
+	interchange.Metadata.InterchangeFormatVersion = InterchangeFormatVersion
+	interchange.Metadata.GenesisValidatorsRoot = fmt.Sprintf("%#x", [32]byte{1})
+	encoded, err := json.Marshal(interchange)
+	require.NoError(t, err)
+	require.NoError(t, db.ImportStandardProtectionJSON(ctx, bytes.NewReader(encoded)))
+
+	// The conflicting imported proposal does not overwrite the stored one.
+	signingRoot, exists, err := db.ProposalHistoryForSlot(ctx, pubKeys[0], 5)
+	require.NoError(t, err)
+	require.Equal(t, true, exists)
+	assert.DeepEqual(t, storedRoot, signingRoot)
+	signingRoot, exists, err = db.ProposalHistoryForSlot(ctx, pubKeys[0], 4)
+	require.NoError(t, err)
+	require.Equal(t, true, exists)
+	assert.Equal(t, byte(4), signingRoot[0])
+	lowest, _, err := db.LowestSignedProposal(ctx, pubKeys[0])
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), lowest)
+}
+
+func TestStore_ImportStandardProtectionJSON_MergesWithStoredHistory(t *testing.T) {
+	ctx := context.Background()
+	pubKeys := createRandomPubKeys(t, 1)
+	db := setupDB(t, pubKeys)
+	require.NoError(t, db.SaveProposalHistoryForSlot(ctx, pubKeys[0], 5, []byte{5}))
+	history, err := MarkAllAsAttestedSinceLatestWrittenEpoch(ctx, NewAttestationHistoryArray(0), 10, &HistoryData{Source: 9, SigningRoot: []byte{10}})
+	require.NoError(t, err)
+	require.NoError(t, db.SaveAttestationHistoryForPubKeyV2(ctx, pubKeys[0], history))
+
+	interchange := &EIPSlashingProtectionFormat{
+		Data: []*ProtectionData{
+			{
+				Pubkey:             fmt.Sprintf("%#x", pubKeys[0]),
+				SignedBlocks:       []*SignedBlock{{Slot: "7", SigningRoot: fmt.Sprintf("%#x", [32]byte{7})}, {Slot: "3"}},
+				SignedAttestations: []*SignedAttestation{{SourceEpoch: "4", TargetEpoch: "6"}},
+			},
+		},
+	}
+	interchange.Metadata.InterchangeFormatVersion = InterchangeFormatVersion
+	interchange.Metadata.GenesisValidatorsRoot = fmt.Sprintf("%#x", [32]byte{1})
+	encoded, err := json.Marshal(interchange)
+	require.NoError(t, err)
+	require.NoError(t, db.ImportStandardProtectionJSON(ctx, bytes.NewReader(encoded)))
+
+	signingRoot, _, err := db.ProposalHistoryForSlot(ctx, pubKeys[0], 5)
+	require.NoError(t, err)
+	assert.Equal(t, byte(5), signingRoot[0])
+	signingRoot, _, err = db.ProposalHistoryForSlot(ctx, pubKeys[0], 7)
+	require.NoError(t, err)
+	assert.Equal(t, byte(7), signingRoot[0])
+	lowest, _, err := db.LowestSignedProposal(ctx, pubKeys[0])
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), lowest)
+
+	histories, err := db.AttestationHistoryForPubKeysV2(ctx, pubKeys)
+	require.NoError(t, err)
+	latest, err := histories[pubKeys[0]].GetLatestEpochWritten(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), latest)
+	hd, err := histories[pubKeys[0]].GetTargetData(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(9), hd.Source)
+	hd, err = histories[pubKeys[0]].GetTargetData(ctx, 6)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), hd.Source)
+
+	gvr, err := db.GenesisValidatorsRoot(ctx)
+	require.NoError(t, err)
+	assert.DeepEqual(t, []byte{1}, gvr[:1])
+}
+
+func Test_validateMetadata(t *testing.T) {
+	goodRoot := [32]byte{1}
+	goodStr := make([]byte, hex.EncodedLen(len(goodRoot)))
+	hex.Encode(goodStr, goodRoot[:])
+	tests := []struct {
+		name                   string
+		interchangeJSON        *EIPSlashingProtectionFormat
+		dbGenesisValidatorRoot []byte
+		wantErr                bool
+		wantFatal              string
+	}{
+		{
+			name: "Incorrect version for EIP format should fail",
+			interchangeJSON: &EIPSlashingProtectionFormat{
+				Metadata: struct {
+					InterchangeFormatVersion string `json:"interchange_format_version"`
+					GenesisValidatorsRoot    string `json:"genesis_validators_root"`
+				}{
+					InterchangeFormatVersion: "1",
+					GenesisValidatorsRoot:    string(goodStr),
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Junk data for version should fail",
+			interchangeJSON: &EIPSlashingProtectionFormat{
+				Metadata: struct {
+					InterchangeFormatVersion string `json:"interchange_format_version"`
+					GenesisValidatorsRoot    string `json:"genesis_validators_root"`
+				}{
+					InterchangeFormatVersion: "asdljas$d",
+					GenesisValidatorsRoot:    string(goodStr),
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Proper version field should pass",
+			interchangeJSON: &EIPSlashingProtectionFormat{
+				Metadata: struct {
+					InterchangeFormatVersion string `json:"interchange_format_version"`
+					GenesisValidatorsRoot    string `json:"genesis_validators_root"`
+				}{
+					InterchangeFormatVersion: InterchangeFormatVersion,
+					GenesisValidatorsRoot:    string(goodStr),
+				},
+			},
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := validateMetadata(tt.interchangeJSON); (err != nil) != tt.wantErr {
+				t.Errorf("validateMetadata() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_checkGenesisValidatorsRoot(t *testing.T) {
+	goodRoot := [32]byte{1}
+	goodStr := make([]byte, hex.EncodedLen(len(goodRoot)))
+	hex.Encode(goodStr, goodRoot[:])
+	secondRoot := [32]byte{2}
+	secondStr := make([]byte, hex.EncodedLen(len(secondRoot)))
+	hex.Encode(secondStr, secondRoot[:])
+
+	tests := []struct {
+		name                   string
+		interchangeJSON        *EIPSlashingProtectionFormat
+		dbGenesisValidatorRoot []byte
+		wantErr                bool
+	}{
+		{
+			name: "Same genesis roots should not fail",
+			interchangeJSON: &EIPSlashingProtectionFormat{
+				Metadata: struct {
+					InterchangeFormatVersion string `json:"interchange_format_version"`
+					GenesisValidatorsRoot    string `json:"genesis_validators_root"`
+				}{
+					InterchangeFormatVersion: InterchangeFormatVersion,
+					GenesisValidatorsRoot:    string(goodStr),
+				},
+			},
+			dbGenesisValidatorRoot: goodRoot[:],
+			wantErr:                false,
+		},
+		{
+			name: "Different genesis roots should fail",
+			interchangeJSON: &EIPSlashingProtectionFormat{
+				Metadata: struct {
+					InterchangeFormatVersion string `json:"interchange_format_version"`
+					GenesisValidatorsRoot    string `json:"genesis_validators_root"`
+				}{
+					InterchangeFormatVersion: InterchangeFormatVersion,
+					GenesisValidatorsRoot:    string(secondStr),
+				},
+			},
+			dbGenesisValidatorRoot: goodRoot[:],
+			wantErr:                true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validatorDB := setupDB(t, nil)
+			ctx := context.Background()
+			require.NoError(t, validatorDB.SaveGenesisValidatorsRoot(ctx, tt.dbGenesisValidatorRoot))
+			gvr, err := validateMetadata(tt.interchangeJSON)
+			require.NoError(t, err)
+			err = validatorDB.update(func(tx *bolt.Tx) error {
+				return checkGenesisValidatorsRoot(tx, gvr)
+			})
+			if tt.wantErr {
+				require.ErrorContains(t, "genesis validator root doesnt match the one that is stored", err)
+			} else {
+				require.NoError(t, err)
+			}
+
+		})
+	}
+}
+
+func Test_parseUniqueSignedBlocksByPubKey(t *testing.T) {
+	numValidators := 4
+	pubKeys := createRandomPubKeys(t, numValidators)
+	roots := createRandomRoots(t, numValidators)
+	tests := []struct {
+		name    string
+		data    []*ProtectionData
+		want    map[[48]byte][]*SignedBlock
+		wantErr bool
+	}{
+		{
+			name: "nil values are skipped",
+			data: []*ProtectionData{
+				{
+					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
+					SignedBlocks: []*SignedBlock{
+						{
+							Slot:        "1",
+							SigningRoot: fmt.Sprintf("%x", roots[0]),
+						},
+						nil,
+					},
+				},
+				{
+					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
+					SignedBlocks: []*SignedBlock{
+						{
+							Slot:        "3",
+							SigningRoot: fmt.Sprintf("%x", roots[2]),
+						},
+					},
+				},
+			},
+			want: map[[48]byte][]*SignedBlock{
+				pubKeys[0]: {
+					{
+						Slot:        "1",
+						SigningRoot: fmt.Sprintf("%x", roots[0]),
+					},
+					{
+						Slot:        "3",
+						SigningRoot: fmt.Sprintf("%x", roots[2]),
+					},
+				},
+			},
+		},
+		{
+			name: "same blocks but different public keys are parsed correctly",
+			data: []*ProtectionData{
+				{
+					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
+					SignedBlocks: []*SignedBlock{
+						{
+							Slot:        "1",
+							SigningRoot: fmt.Sprintf("%x", roots[0]),
+						},
+						{
+							Slot:        "2",
+							SigningRoot: fmt.Sprintf("%x", roots[1]),
+						},
+					},
+				},
+				{
+					Pubkey: fmt.Sprintf("%x", pubKeys[1]),
+					SignedBlocks: []*SignedBlock{
+						{
+							Slot:        "1",
+							SigningRoot: fmt.Sprintf("%x", roots[0]),
+						},
+						{
+							Slot:        "2",
+							SigningRoot: fmt.Sprintf("%x", roots[1]),
+						},
+					},
+				},
+			},
+			want: map[[48]byte][]*SignedBlock{
+				pubKeys[0]: {
+					{
+						Slot:        "1",
+						SigningRoot: fmt.Sprintf("%x", roots[0]),
+					},
+					{
+						Slot:        "2",
+						SigningRoot: fmt.Sprintf("%x", roots[1]),
+					},
+				},
+				pubKeys[1]: {
+					{
+						Slot:        "1",
+						SigningRoot: fmt.Sprintf("%x", roots[0]),
+					},
+					{
+						Slot:        "2",
+						SigningRoot: fmt.Sprintf("%x", roots[1]),
+					},
+				},
+			},
+		},
+		{
+			name: "disjoint sets of signed blocks by the same public key are parsed correctly",
+			data: []*ProtectionData{
+				{
+					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
+					SignedBlocks: []*SignedBlock{
+						{
+							Slot:        "1",
+							SigningRoot: fmt.Sprintf("%x", roots[0]),
+						},
+						{
+							Slot:        "2",
+							SigningRoot: fmt.Sprintf("%x", roots[1]),
+						},
+					},
+				},
+				{
+					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
+					SignedBlocks: []*SignedBlock{
+						{
+							Slot:        "3",
+							SigningRoot: fmt.Sprintf("%x", roots[2]),
+						},
+					},
+				},
+			},
+			want: map[[48]byte][]*SignedBlock{
+				pubKeys[0]: {
+					{
+						Slot:        "1",
+						SigningRoot: fmt.Sprintf("%x", roots[0]),
+					},
+					{
+						Slot:        "2",
+						SigningRoot: fmt.Sprintf("%x", roots[1]),
+					},
+					{
+						Slot:        "3",
+						SigningRoot: fmt.Sprintf("%x", roots[2]),
+					},
+				},
+			},
+		},
+		{
+			name: "full duplicate entries are uniquely parsed",
+			data: []*ProtectionData{
+				{
+					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
+					SignedBlocks: []*SignedBlock{
+						{
+							Slot:        "1",
+							SigningRoot: fmt.Sprintf("%x", roots[0]),
+						},
+					},
+				},
+				{
+					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
+					SignedBlocks: []*SignedBlock{
+						{
+							Slot:        "1",
+							SigningRoot: fmt.Sprintf("%x", roots[0]),
+						},
+					},
+				},
+			},
+			want: map[[48]byte][]*SignedBlock{
+				pubKeys[0]: {
+					{
+						Slot:        "1",
+						SigningRoot: fmt.Sprintf("%x", roots[0]),
+					},
+				},
+			},
+		},
+		{
+			name: "intersecting duplicate public key entries are handled properly",
+			data: []*ProtectionData{
+				{
+					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
+					SignedBlocks: []*SignedBlock{
+						{
+							Slot:        "1",
+							SigningRoot: fmt.Sprintf("%x", roots[0]),
+						},
+						{
+							Slot:        "2",
+							SigningRoot: fmt.Sprintf("%x", roots[1]),
+						},
+					},
+				},
+				{
+					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
+					SignedBlocks: []*SignedBlock{
+						{
+							Slot:        "2",
+							SigningRoot: fmt.Sprintf("%x", roots[1]),
+						},
+						{
+							Slot:        "3",
+							SigningRoot: fmt.Sprintf("%x", roots[2]),
+						},
+					},
+				},
+			},
+			want: map[[48]byte][]*SignedBlock{
+				pubKeys[0]: {
+					{
+						Slot:        "1",
+						SigningRoot: fmt.Sprintf("%x", roots[0]),
+					},
+					{
+						Slot:        "2",
+						SigningRoot: fmt.Sprintf("%x", roots[1]),
+					},
+					{
+						Slot:        "3",
+						SigningRoot: fmt.Sprintf("%x", roots[2]),
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseUniqueSignedBlocksByPubKey(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseUniqueSignedBlocksByPubKey() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseUniqueSignedBlocksByPubKey() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseUniqueSignedAttestationsByPubKey(t *testing.T) {
+	numValidators := 4
+	pubKeys := createRandomPubKeys(t, numValidators)
+	roots := createRandomRoots(t, numValidators)
+	tests := []struct {
+		name    string
+		data    []*ProtectionData
+		want    map[[48]byte][]*SignedAttestation
+		wantErr bool
+	}{
+		{
+			name: "nil values are skipped",
+			data: []*ProtectionData{
+				{
+					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
+					SignedAttestations: []*SignedAttestation{
+						{
+							SourceEpoch: "1",
+							TargetEpoch: "3",
+							SigningRoot: fmt.Sprintf("%x", roots[0]),
+						},
+						nil,
+					},
+				},
+				{
+					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
+					SignedAttestations: []*SignedAttestation{
+						{
+							SourceEpoch: "3",
+							TargetEpoch: "5",
+							SigningRoot: fmt.Sprintf("%x", roots[2]),
+						},
+					},
+				},
+			},
+			want: map[[48]byte][]*SignedAttestation{
+				pubKeys[0]: {
+					{
+						SourceEpoch: "1",
+						TargetEpoch: "3",
+						SigningRoot: fmt.Sprintf("%x", roots[0]),
+					},
+					{
+						SourceEpoch: "3",
+						TargetEpoch: "5",
+						SigningRoot: fmt.Sprintf("%x", roots[2]),
+					},
+				},
+			},
+		},
+		{
+			name: "same blocks but different public keys are parsed correctly",
+			data: []*ProtectionData{
+				{
+					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
+					SignedAttestations: []*SignedAttestation{
+						{
+							SourceEpoch: "1",
+							SigningRoot: fmt.Sprintf("%x", roots[0]),
+						},
+						{
+							SourceEpoch: "2",
+							SigningRoot: fmt.Sprintf("%x", roots[1]),
+						},
+					},
+				},
+				{
+					Pubkey: fmt.Sprintf("%x", pubKeys[1]),
+					SignedAttestations: []*SignedAttestation{
+						{
+							SourceEpoch: "1",
+							SigningRoot: fmt.Sprintf("%x", roots[0]),
+						},
+						{
+							SourceEpoch: "2",
+							SigningRoot: fmt.Sprintf("%x", roots[1]),
+						},
+					},
+				},
+			},
+			want: map[[48]byte][]*SignedAttestation{
+				pubKeys[0]: {
+					{
+						SourceEpoch: "1",
+						SigningRoot: fmt.Sprintf("%x", roots[0]),
+					},
+					{
+						SourceEpoch: "2",
+						SigningRoot: fmt.Sprintf("%x", roots[1]),
+					},
+				},
+				pubKeys[1]: {
+					{
+						SourceEpoch: "1",
+						SigningRoot: fmt.Sprintf("%x", roots[0]),
+					},
+					{
+						SourceEpoch: "2",
+						SigningRoot: fmt.Sprintf("%x", roots[1]),
+					},
+				},
+			},
+		},
+		{
+			name: "disjoint sets of signed blocks by the same public key are parsed correctly",
+			data: []*ProtectionData{
+				{
+					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
+					SignedAttestations: []*SignedAttestation{
+						{
+							SourceEpoch: "1",
+							TargetEpoch: "3",
+							SigningRoot: fmt.Sprintf("%x", roots[0]),
+						},
+						{
+							SourceEpoch: "2",
+							TargetEpoch: "4",
+							SigningRoot: fmt.Sprintf("%x", roots[1]),
+						},
+					},
+				},
+				{
+					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
+					SignedAttestations: []*SignedAttestation{
+						{
+							SourceEpoch: "3",
+							TargetEpoch: "5",
+							SigningRoot: fmt.Sprintf("%x", roots[2]),
+						},
+					},
+				},
+			},
+			want: map[[48]byte][]*SignedAttestation{
+				pubKeys[0]: {
+					{
+						SourceEpoch: "1",
+						TargetEpoch: "3",
+						SigningRoot: fmt.Sprintf("%x", roots[0]),
+					},
+					{
+						SourceEpoch: "2",
+						TargetEpoch: "4",
+						SigningRoot: fmt.Sprintf("%x", roots[1]),
+					},
+					{
+						SourceEpoch: "3",
+						TargetEpoch: "5",
+						SigningRoot: fmt.Sprintf("%x", roots[2]),
+					},
+				},
+			},
+		},
+		{
+			name: "full duplicate entries are uniquely parsed",
+			data: []*ProtectionData{
+				{
+					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
+					SignedAttestations: []*SignedAttestation{
+						{
+							SourceEpoch: "1",
+							SigningRoot: fmt.Sprintf("%x", roots[0]),
+						},
+					},
+				},
+				{
+					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
+					SignedAttestations: []*SignedAttestation{
+						{
+							SourceEpoch: "1",
+							SigningRoot: fmt.Sprintf("%x", roots[0]),
+						},
+					},
+				},
+			},
+			want: map[[48]byte][]*SignedAttestation{
+				pubKeys[0]: {
+					{
+						SourceEpoch: "1",
+						SigningRoot: fmt.Sprintf("%x", roots[0]),
+					},
+				},
+			},
+		},
+		{
+			name: "intersecting duplicate public key entries are handled properly",
+			data: []*ProtectionData{
+				{
+					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
+					SignedAttestations: []*SignedAttestation{
+						{
+							SourceEpoch: "1",
+							SigningRoot: fmt.Sprintf("%x", roots[0]),
+						},
+						{
+							SourceEpoch: "2",
+							SigningRoot: fmt.Sprintf("%x", roots[1]),
+						},
+					},
+				},
+				{
+					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
+					SignedAttestations: []*SignedAttestation{
+						{
+							SourceEpoch: "2",
+							SigningRoot: fmt.Sprintf("%x", roots[1]),
+						},
+						{
+							SourceEpoch: "3",
+							SigningRoot: fmt.Sprintf("%x", roots[2]),
+						},
+					},
+				},
+			},
+			want: map[[48]byte][]*SignedAttestation{
+				pubKeys[0]: {
+					{
+						SourceEpoch: "1",
+						SigningRoot: fmt.Sprintf("%x", roots[0]),
+					},
+					{
+						SourceEpoch: "2",
+						SigningRoot: fmt.Sprintf("%x", roots[1]),
+					},
+					{
+						SourceEpoch: "3",
+						SigningRoot: fmt.Sprintf("%x", roots[2]),
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseUniqueSignedAttestationsByPubKey(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseUniqueSignedAttestationsByPubKey() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseUniqueSignedAttestationsByPubKey() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func createRandomRoots(t *testing.T, numRoots int) [][32]byte {
+	roots := make([][32]byte, numRoots)
+	for i := 0; i < numRoots; i++ {
+		roots[i] = hashutil.Hash([]byte(fmt.Sprintf("%d", i)))
+	}
+	return roots
+}
+
+func createRandomPubKeys(t *testing.T, numValidators int) [][48]byte {
+	pubKeys := make([][48]byte, numValidators)
+	for i := 0; i < numValidators; i++ {
+		randKey, err := bls.RandKey()
+		require.NoError(t, err)
+		copy(pubKeys[i][:], randKey.PublicKey().Marshal())
+	}
+	return pubKeys
+}
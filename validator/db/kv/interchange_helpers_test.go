@@ -1,4 +1,4 @@
-package interchangeformat
+package kv
 
 import (
 	"math"
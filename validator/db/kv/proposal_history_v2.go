@@ -1,6 +1,7 @@
 package kv
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 
@@ -14,31 +15,57 @@ import (
 	"go.opencensus.io/trace"
 )
 
-// ProposalHistoryForSlot accepts a validator public key and returns the corresponding signing root.
-// Returns nil if there is no proposal history for the validator at this slot.
-func (store *Store) ProposalHistoryForSlot(ctx context.Context, publicKey []byte, slot uint64) ([]byte, error) {
+// ProposalHistoryForSlot accepts a validator public key and returns the signing root of the
+// block it proposed at the given slot, and whether it proposed a block at this slot at all.
+func (store *Store) ProposalHistoryForSlot(ctx context.Context, pubKey [48]byte, slot uint64) ([]byte, bool, error) {
 	ctx, span := trace.StartSpan(ctx, "Validator.ProposalHistoryForSlot")
 	defer span.End()
 
-	var err error
-	signingRoot := make([]byte, 32)
-	err = store.view(func(tx *bolt.Tx) error {
+	var signingRoot []byte
+	var exists bool
+	err := store.view(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket(newhistoricProposalsBucket)
-		valBucket := bucket.Bucket(publicKey)
+		valBucket := bucket.Bucket(pubKey[:])
 		if valBucket == nil {
-			return fmt.Errorf("validator history empty for public key: %#x", publicKey)
+			return nil
 		}
 		sr := valBucket.Get(bytesutil.Uint64ToBytesBigEndian(slot))
-		if len(sr) == 0 {
+		if sr == nil {
 			return nil
 		}
+		exists = true
+		signingRoot = make([]byte, 32)
 		copy(signingRoot, sr)
 		return nil
 	})
-	return signingRoot, err
+	return signingRoot, exists, err
+}
+
+// LowestSignedProposal returns the lowest slot at which a validator public key proposed a block,
+// and whether it proposed any block at all. Proposals at or below this slot must be rejected, even
+// once their history was pruned.
+func (store *Store) LowestSignedProposal(ctx context.Context, pubKey [48]byte) (uint64, bool, error) {
+	ctx, span := trace.StartSpan(ctx, "Validator.LowestSignedProposal")
+	defer span.End()
+
+	var lowestSignedProposalSlot uint64
+	var exists bool
+	err := store.view(func(tx *bolt.Tx) error {
+		enc := tx.Bucket(lowestSignedProposalsBucket).Get(pubKey[:])
+		if len(enc) == 0 {
+			return nil
+		}
+		exists = true
+		lowestSignedProposalSlot = bytesutil.BytesToUint64BigEndian(enc)
+		return nil
+	})
+	return lowestSignedProposalSlot, exists, err
 }
 
-// SaveProposalHistoryForPubKeysV2 saves the proposal histories for the provided validator public keys.
+// SaveProposalHistoryForPubKeysV2 saves the proposal histories for the provided validator public keys
+// in a single transaction. Each proposal is saved as by SaveProposalHistoryForSlot, lowering the
+// lowest signed proposal slot of its public key if needed, and if any of them fails to be saved,
+// none of the histories are.
 func (store *Store) SaveProposalHistoryForPubKeysV2(
 	ctx context.Context,
 	historyByPubKeys map[[48]byte]ProposalHistoryForPubkey,
@@ -54,7 +81,7 @@ func (store *Store) SaveProposalHistoryForPubKeysV2(
 				return fmt.Errorf("could not create bucket for public key %#x", pubKey)
 			}
 			for _, proposal := range history.Proposals {
-				if err := valBucket.Put(bytesutil.Uint64ToBytesBigEndian(proposal.Slot), proposal.SigningRoot); err != nil {
+				if err := saveProposal(tx, valBucket, pubKey, proposal.Slot, proposal.SigningRoot); err != nil {
 					return err
 				}
 			}
@@ -64,18 +91,21 @@ func (store *Store) SaveProposalHistoryForPubKeysV2(
 	return err
 }
 
-// SaveProposalHistoryForSlot saves the proposal history for the requested validator public key.
-func (store *Store) SaveProposalHistoryForSlot(ctx context.Context, pubKey []byte, slot uint64, signingRoot []byte) error {
-	ctx, span := trace.StartSpan(ctx, "Validator.SaveProposalHistoryForEpoch")
+// SaveProposalHistoryForSlot saves the signing root of the block proposed by a validator public
+// key at the given slot, and lowers its lowest signed proposal slot if needed. Saving a slot again
+// with the same signing root is a no-op, while saving it with a different signing root fails, as
+// the validator would have proposed two different blocks at the same slot.
+func (store *Store) SaveProposalHistoryForSlot(ctx context.Context, pubKey [48]byte, slot uint64, signingRoot []byte) error {
+	ctx, span := trace.StartSpan(ctx, "Validator.SaveProposalHistoryForSlot")
 	defer span.End()
 
 	err := store.update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket(newhistoricProposalsBucket)
-		valBucket, err := bucket.CreateBucketIfNotExists(pubKey)
+		valBucket, err := bucket.CreateBucketIfNotExists(pubKey[:])
 		if err != nil {
 			return fmt.Errorf("could not create bucket for public key %#x", pubKey)
 		}
-		if err := valBucket.Put(bytesutil.Uint64ToBytesBigEndian(slot), signingRoot); err != nil {
+		if err := saveProposal(tx, valBucket, pubKey, slot, signingRoot); err != nil {
 			return err
 		}
 		return pruneProposalHistoryBySlot(valBucket, slot)
@@ -83,6 +113,43 @@ func (store *Store) SaveProposalHistoryForSlot(ctx context.Context, pubKey []byt
 	return err
 }
 
+// saveProposal saves the signing root of the block proposed by a public key at the given slot to
+// its proposal history bucket along with its lowest signed proposal slot, failing if a block with
+// a different signing root was saved at the same slot.
+func saveProposal(tx *bolt.Tx, valBucket *bolt.Bucket, pubKey [48]byte, slot uint64, signingRoot []byte) error {
+	slotKey := bytesutil.Uint64ToBytesBigEndian(slot)
+	if sr := valBucket.Get(slotKey); sr != nil && !bytes.Equal(sr, signingRoot) {
+		return fmt.Errorf(
+			"a block with signing root %#x was already proposed at slot %d for public key %#x",
+			sr,
+			slot,
+			pubKey,
+		)
+	}
+	if err := valBucket.Put(slotKey, signingRoot); err != nil {
+		return err
+	}
+	return updateLowestSignedProposal(tx, pubKey, slot)
+}
+
+// updateLowestSignedProposal lowers the lowest signed proposal slot of a public key to the given
+// slot, if it is lower or if there was none.
+func updateLowestSignedProposal(tx *bolt.Tx, pubKey [48]byte, slot uint64) error {
+	bucket := tx.Bucket(lowestSignedProposalsBucket)
+	enc := bucket.Get(pubKey[:])
+	if len(enc) != 0 && bytesutil.BytesToUint64BigEndian(enc) <= slot {
+		return nil
+	}
+	if err := bucket.Put(pubKey[:], bytesutil.Uint64ToBytesBigEndian(slot)); err != nil {
+		return errors.Wrapf(err, "could not save lowest signed proposal slot for public key %#x", pubKey)
+	}
+	return nil
+}
+
+// migratedProposalSigningRoot is saved as the signing root of the proposals migrated from the
+// proposal history by epoch, which did not keep their signing roots.
+var migratedProposalSigningRoot = []byte{1}
+
 // MigrateV2ProposalFormat accepts a validator public key and returns the corresponding signing root.
 // Returns nil if there is no proposal history for the validator at this slot.
 func (store *Store) MigrateV2ProposalFormat(ctx context.Context) error {
@@ -142,7 +209,10 @@ func (store *Store) MigrateV2ProposalFormat(ctx context.Context) error {
 						if err != nil {
 							return errors.Wrapf(err, "failed to get start slot of epoch: %d", epochProposals.Epoch)
 						}
-						if err := valBucket.Put(bytesutil.Uint64ToBytesBigEndian(ss+i), []byte{1}); err != nil {
+						if err := valBucket.Put(bytesutil.Uint64ToBytesBigEndian(ss+i), migratedProposalSigningRoot); err != nil {
+							return err
+						}
+						if err := updateLowestSignedProposal(tx, pr.PubKey, ss+i); err != nil {
 							return err
 						}
 					}
@@ -15,10 +15,10 @@ func TestProposalHistoryForSlot_InitializesNewPubKeys(t *testing.T) {
 	db := setupDB(t, pubkeys)
 
 	for _, pub := range pubkeys {
-		signingRoot, err := db.ProposalHistoryForSlot(context.Background(), pub[:], 0)
+		signingRoot, exists, err := db.ProposalHistoryForSlot(context.Background(), pub, 0)
 		require.NoError(t, err)
-		expected := bytesutil.PadTo([]byte{}, 32)
-		require.DeepEqual(t, expected, signingRoot, "Expected proposal history slot signing root to be empty")
+		require.Equal(t, false, exists, "Expected no proposal at slot 0")
+		require.DeepEqual(t, []byte(nil), signingRoot, "Expected proposal history slot signing root to be empty")
 	}
 }
 
@@ -26,8 +26,9 @@ func TestNewProposalHistoryForSlot_NilDB(t *testing.T) {
 	valPubkey := [48]byte{1, 2, 3}
 	db := setupDB(t, [][48]byte{})
 
-	_, err := db.ProposalHistoryForSlot(context.Background(), valPubkey[:], 0)
-	require.ErrorContains(t, "validator history empty for public key", err, "Unexpected error for nil DB")
+	_, exists, err := db.ProposalHistoryForSlot(context.Background(), valPubkey, 0)
+	require.NoError(t, err)
+	require.Equal(t, false, exists, "Expected no proposal for an unknown public key")
 }
 
 func TestSaveProposalHistoryForSlot_OK(t *testing.T) {
@@ -36,12 +37,12 @@ func TestSaveProposalHistoryForSlot_OK(t *testing.T) {
 
 	slot := uint64(2)
 
-	err := db.SaveProposalHistoryForSlot(context.Background(), pubkey[:], slot, []byte{1})
+	err := db.SaveProposalHistoryForSlot(context.Background(), pubkey, slot, []byte{1})
 	require.NoError(t, err, "Saving proposal history failed: %v")
-	signingRoot, err := db.ProposalHistoryForSlot(context.Background(), pubkey[:], slot)
+	signingRoot, exists, err := db.ProposalHistoryForSlot(context.Background(), pubkey, slot)
 	require.NoError(t, err, "Failed to get proposal history")
 
-	require.NotNil(t, signingRoot)
+	require.Equal(t, true, exists)
 	require.DeepEqual(t, bytesutil.PadTo([]byte{1}, 32), signingRoot, "Expected DB to keep object the same")
 }
 
@@ -51,13 +52,13 @@ func TestSaveProposalHistoryForSlot_Empty(t *testing.T) {
 
 	slot := uint64(2)
 	emptySlot := uint64(120)
-	err := db.SaveProposalHistoryForSlot(context.Background(), pubkey[:], slot, []byte{1})
+	err := db.SaveProposalHistoryForSlot(context.Background(), pubkey, slot, []byte{1})
 	require.NoError(t, err, "Saving proposal history failed: %v")
-	signingRoot, err := db.ProposalHistoryForSlot(context.Background(), pubkey[:], emptySlot)
+	signingRoot, exists, err := db.ProposalHistoryForSlot(context.Background(), pubkey, emptySlot)
 	require.NoError(t, err, "Failed to get proposal history")
 
-	require.NotNil(t, signingRoot)
-	require.DeepEqual(t, bytesutil.PadTo([]byte{}, 32), signingRoot, "Expected DB to keep object the same")
+	require.Equal(t, false, exists)
+	require.DeepEqual(t, []byte(nil), signingRoot, "Expected DB to keep object the same")
 }
 
 func TestSaveProposalHistoryForSlot_Overwrites(t *testing.T) {
@@ -82,9 +83,9 @@ func TestSaveProposalHistoryForSlot_Overwrites(t *testing.T) {
 
 	for _, tt := range tests {
 		db := setupDB(t, [][48]byte{pubkey})
-		err := db.SaveProposalHistoryForSlot(context.Background(), pubkey[:], 0, tt.signingRoot)
+		err := db.SaveProposalHistoryForSlot(context.Background(), pubkey, 0, tt.signingRoot)
 		require.NoError(t, err, "Saving proposal history failed")
-		signingRoot, err := db.ProposalHistoryForSlot(context.Background(), pubkey[:], 0)
+		signingRoot, _, err := db.ProposalHistoryForSlot(context.Background(), pubkey, 0)
 		require.NoError(t, err, "Failed to get proposal history")
 
 		require.NotNil(t, signingRoot)
@@ -92,6 +93,132 @@ func TestSaveProposalHistoryForSlot_Overwrites(t *testing.T) {
 	}
 }
 
+func TestSaveProposalHistoryForSlot_SameSlot(t *testing.T) {
+	pubkey := [48]byte{4}
+	signingRoot := bytesutil.PadTo([]byte{1}, 32)
+	tests := []struct {
+		name        string
+		signingRoot []byte
+		wantErr     string
+	}{
+		{
+			name:        "identical signing root",
+			signingRoot: bytesutil.PadTo([]byte{1}, 32),
+		},
+		{
+			name:        "different signing root",
+			signingRoot: bytesutil.PadTo([]byte{2}, 32),
+			wantErr:     "was already proposed at slot 5",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			db := setupDB(t, [][48]byte{pubkey})
+			require.NoError(t, db.SaveProposalHistoryForSlot(ctx, pubkey, 5, signingRoot))
+
+			err := db.SaveProposalHistoryForSlot(ctx, pubkey, 5, tt.signingRoot)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, tt.wantErr, err)
+			} else {
+				require.NoError(t, err)
+			}
+			// The first signing root is kept either way.
+			sr, exists, err := db.ProposalHistoryForSlot(ctx, pubkey, 5)
+			require.NoError(t, err)
+			require.Equal(t, true, exists)
+			require.DeepEqual(t, signingRoot, sr)
+		})
+	}
+}
+
+func TestLowestSignedProposal(t *testing.T) {
+	ctx := context.Background()
+	pubkey := [48]byte{5}
+	db := setupDB(t, [][48]byte{pubkey})
+	_, exists, err := db.LowestSignedProposal(ctx, pubkey)
+	require.NoError(t, err)
+	require.Equal(t, false, exists, "Expected no lowest signed proposal before any proposal")
+
+	slotsPerEpoch := params.BeaconConfig().SlotsPerEpoch
+	wsPeriod := params.BeaconConfig().WeakSubjectivityPeriod
+	tests := []struct {
+		slot   uint64
+		lowest uint64
+	}{
+		{slot: 10, lowest: 10},
+		{slot: 20, lowest: 10},
+		{slot: 5, lowest: 5},
+		// Pruning the history of slot 5 does not raise the lowest signed proposal.
+		{slot: (wsPeriod + 1) * slotsPerEpoch, lowest: 5},
+	}
+	for _, tt := range tests {
+		require.NoError(t, db.SaveProposalHistoryForSlot(ctx, pubkey, tt.slot, bytesutil.PadTo([]byte{1}, 32)))
+		lowest, exists, err := db.LowestSignedProposal(ctx, pubkey)
+		require.NoError(t, err)
+		require.Equal(t, true, exists)
+		require.Equal(t, tt.lowest, lowest, "Wrong lowest signed proposal after saving slot %d", tt.slot)
+	}
+	_, exists, err = db.ProposalHistoryForSlot(ctx, pubkey, 5)
+	require.NoError(t, err)
+	require.Equal(t, false, exists, "Expected slot 5 to be pruned")
+}
+
+func TestSaveProposalHistoryForPubKeysV2_LowestSignedProposal(t *testing.T) {
+	ctx := context.Background()
+	pubkey := [48]byte{6}
+	db := setupDB(t, [][48]byte{pubkey})
+	signingRoot := bytesutil.PadTo([]byte{1}, 32)
+	history := ProposalHistoryForPubkey{
+		Proposals: []Proposal{
+			{Slot: 5, SigningRoot: signingRoot},
+			{Slot: 3, SigningRoot: signingRoot},
+			{Slot: 9, SigningRoot: signingRoot},
+		},
+	}
+	require.NoError(t, db.SaveProposalHistoryForPubKeysV2(ctx, map[[48]byte]ProposalHistoryForPubkey{pubkey: history}))
+
+	lowest, exists, err := db.LowestSignedProposal(ctx, pubkey)
+	require.NoError(t, err)
+	require.Equal(t, true, exists)
+	require.Equal(t, uint64(3), lowest)
+	for _, proposal := range history.Proposals {
+		sr, exists, err := db.ProposalHistoryForSlot(ctx, pubkey, proposal.Slot)
+		require.NoError(t, err)
+		require.Equal(t, true, exists, "Expected a proposal at slot %d", proposal.Slot)
+		require.DeepEqual(t, signingRoot, sr)
+	}
+}
+
+func TestSaveProposalHistoryForPubKeysV2_DifferentSigningRoot(t *testing.T) {
+	ctx := context.Background()
+	pubkey := [48]byte{7}
+	db := setupDB(t, [][48]byte{pubkey})
+	signingRoot := bytesutil.PadTo([]byte{1}, 32)
+	require.NoError(t, db.SaveProposalHistoryForSlot(ctx, pubkey, 5, signingRoot))
+
+	history := ProposalHistoryForPubkey{
+		Proposals: []Proposal{
+			{Slot: 2, SigningRoot: signingRoot},
+			{Slot: 5, SigningRoot: bytesutil.PadTo([]byte{2}, 32)},
+		},
+	}
+	err := db.SaveProposalHistoryForPubKeysV2(ctx, map[[48]byte]ProposalHistoryForPubkey{pubkey: history})
+	require.ErrorContains(t, "was already proposed at slot 5", err)
+
+	// Nothing of the failed batch is saved.
+	sr, exists, err := db.ProposalHistoryForSlot(ctx, pubkey, 5)
+	require.NoError(t, err)
+	require.Equal(t, true, exists)
+	require.DeepEqual(t, signingRoot, sr)
+	_, exists, err = db.ProposalHistoryForSlot(ctx, pubkey, 2)
+	require.NoError(t, err)
+	require.Equal(t, false, exists)
+	lowest, _, err := db.LowestSignedProposal(ctx, pubkey)
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), lowest)
+}
+
 func TestPruneProposalHistoryBySlot_OK(t *testing.T) {
 	slotsPerEpoch := params.BeaconConfig().SlotsPerEpoch
 	wsPeriod := params.BeaconConfig().WeakSubjectivityPeriod
@@ -137,17 +264,17 @@ func TestPruneProposalHistoryBySlot_OK(t *testing.T) {
 	for _, tt := range tests {
 		db := setupDB(t, [][48]byte{pubKey})
 		for _, slot := range tt.slots {
-			err := db.SaveProposalHistoryForSlot(context.Background(), pubKey[:], slot, signedRoot)
+			err := db.SaveProposalHistoryForSlot(context.Background(), pubKey, slot, signedRoot)
 			require.NoError(t, err, "Saving proposal history failed")
 		}
 
 		for _, slot := range tt.removedSlots {
-			sr, err := db.ProposalHistoryForSlot(context.Background(), pubKey[:], slot)
+			_, exists, err := db.ProposalHistoryForSlot(context.Background(), pubKey, slot)
 			require.NoError(t, err, "Failed to get proposal history")
-			require.DeepEqual(t, bytesutil.PadTo([]byte{}, 32), sr, "Unexpected difference in bytes for epoch %d", slot)
+			require.Equal(t, false, exists, "Expected slot %d to be pruned", slot)
 		}
 		for _, slot := range tt.storedSlots {
-			sr, err := db.ProposalHistoryForSlot(context.Background(), pubKey[:], slot)
+			sr, _, err := db.ProposalHistoryForSlot(context.Background(), pubKey, slot)
 			require.NoError(t, err, "Failed to get proposal history")
 			require.DeepEqual(t, signedRoot, sr, "Unexpected difference in bytes for epoch %d", slot)
 		}
@@ -182,15 +309,19 @@ func TestStore_ImportProposalHistory(t *testing.T) {
 
 	for slot := uint64(0); slot <= lastIndex; slot++ {
 		if _, ok := proposedSlots[slot]; ok {
-			root, err := db.ProposalHistoryForSlot(ctx, pubkey[:], slot)
+			root, _, err := db.ProposalHistoryForSlot(ctx, pubkey, slot)
 			require.NoError(t, err)
 			require.DeepEqual(t, bytesutil.PadTo([]byte{1}, 32), root, "slot: %d", slot)
 			continue
 		}
-		root, err := db.ProposalHistoryForSlot(ctx, pubkey[:], slot)
+		_, exists, err := db.ProposalHistoryForSlot(ctx, pubkey, slot)
 		require.NoError(t, err)
-		require.DeepEqual(t, bytesutil.PadTo([]byte{}, 32), root)
+		require.Equal(t, false, exists, "slot: %d", slot)
 	}
+	lowest, exists, err := db.LowestSignedProposal(ctx, pubkey)
+	require.NoError(t, err)
+	require.Equal(t, true, exists)
+	require.Equal(t, uint64(0), lowest)
 }
 
 func TestShouldImportProposals(t *testing.T) {
@@ -10,8 +10,15 @@ var (
 	historicProposalsBucket = []byte("proposal-history-bucket")
 	// Validator slashing protection from double proposals.
 	newhistoricProposalsBucket = []byte("proposal-history-bucket-interchange")
+	// Lowest slot at which each validator proposed a block.
+	lowestSignedProposalsBucket = []byte("lowest-signed-proposals-bucket")
 	// Validator slashing protection from slashable attestations.
 	historicAttestationsBucket = []byte("attestation-history-bucket")
 	// New Validator slashing protection from slashable attestations.
 	newHistoricAttestationsBucket = []byte("attestation-history-bucket-interchange")
+	// Attestations signed by each validator, keyed by target epoch.
+	attestationRecordsBucket = []byte("attestation-records-bucket")
+	// Lowest source and target epochs of the attestations signed by each validator.
+	lowestSignedSourceBucket = []byte("lowest-signed-source-bucket")
+	lowestSignedTargetBucket = []byte("lowest-signed-target-bucket")
 )
@@ -89,6 +89,8 @@ var appFlags = []cli.Flag{
 	debug.MemProfileRateFlag,
 	debug.CPUProfileFlag,
 	debug.TraceFlag,
+	debug.LeakCheckFlag,
+	debug.ShuffleStartOrderFlag,
 	cmd.AcceptTosFlag,
 }
 
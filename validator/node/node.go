@@ -73,7 +73,7 @@ func NewValidatorClient(cliCtx *cli.Context) (*ValidatorClient, error) {
 	// Warn if user's platform is not supported
 	prereq.WarnIfNotSupported(cliCtx.Context)
 
-	registry := shared.NewServiceRegistry()
+	registry := shared.NewServiceRegistry(debug.RegistryOptions(cliCtx)...)
 	ValidatorClient := &ValidatorClient{
 		cliCtx:            cliCtx,
 		services:          registry,
@@ -118,7 +118,9 @@ func (s *ValidatorClient) Start() {
 		"version": version.GetVersion(),
 	}).Info("Starting validator node")
 
-	s.services.StartAll()
+	if err := s.services.StartAll(); err != nil {
+		log.WithError(err).Fatal("Could not start services")
+	}
 
 	stop := s.stop
 	s.lock.Unlock()
@@ -130,7 +132,7 @@ func (s *ValidatorClient) Start() {
 		<-sigc
 		log.Info("Got interrupt, shutting down...")
 		debug.Exit(s.cliCtx) // Ensure trace and CPU profile data are flushed.
-		go s.Close()
+		go s.shutdown(shared.ShutdownSignal)
 		for i := 10; i > 0; i-- {
 			<-sigc
 			if i > 1 {
@@ -140,16 +142,40 @@ func (s *ValidatorClient) Start() {
 		panic("Panic closing the validator client")
 	}()
 
-	// Wait for stop channel to be closed.
-	<-stop
+	// Wait for stop channel to be closed, or for a service to report a fatal
+	// error, in which case the process exits with a non-zero code once the
+	// services are stopped.
+	select {
+	case <-stop:
+	case err := <-s.services.FatalErrors():
+		log.WithError(err).Error("Fatal error reported by a service, shutting down...")
+		s.shutdown(shared.ShutdownFatal)
+		log.WithError(err).Fatal("Validator client stopped after a fatal error")
+	}
 }
 
 // Close handles graceful shutdown of the system.
 func (s *ValidatorClient) Close() {
+	s.shutdown(shared.ShutdownNormal)
+}
+
+// shutdown is like Close, but stops the services with the given reason.
+func (s *ValidatorClient) shutdown(reason shared.ShutdownReason) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
+	select {
+	case <-s.stop:
+		// Already shut down, by a signal or a fatal error.
+		return
+	default:
+	}
 
-	s.services.StopAll()
+	if err := s.services.StopAll(reason); err != nil {
+		log.WithError(err).Error("Could not stop all services")
+	}
+	if err := s.services.VerifyNoLeaks(); err != nil && !errors.Is(err, shared.ErrLeakCheckDisabled) {
+		log.WithError(err).Warn("Services left goroutines running")
+	}
 	log.Info("Stopping Prysm validator")
 	close(s.stop)
 }
@@ -337,7 +363,11 @@ func (s *ValidatorClient) registerPrometheusService() error {
 		s.services,
 	)
 	logrus.AddHook(prometheus.NewLogrusCollector())
-	return s.services.RegisterService(service)
+	if err := s.services.RegisterHealthCollector(); err != nil {
+		log.WithError(err).Error("Could not register service health metrics")
+	}
+	// The node keeps running without its metrics if the monitoring port is in use.
+	return s.services.RegisterServiceV2(service, nil, &shared.ServiceConfig{Optional: true})
 }
 
 func (s *ValidatorClient) registerClientService(
@@ -0,0 +1,16 @@
+package interchangeformat
+
+import (
+	"context"
+	"io"
+
+	"github.com/prysmaticlabs/prysm/validator/db"
+)
+
+// ExportStandardProtectionJSON writes the slashing protection data of the validator client's
+// database to the given writer as an EIP-3076 compliant JSON file, which can be imported by any
+// eth2 client. For more information, see the EIP document here:
+// https://eips.ethereum.org/EIPS/eip-3076.
+func ExportStandardProtectionJSON(ctx context.Context, validatorDB db.Database, w io.Writer) error {
+	return validatorDB.ExportStandardProtectionJSON(ctx, w)
+}
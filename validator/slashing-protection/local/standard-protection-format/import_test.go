@@ -6,13 +6,11 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"reflect"
 	"strconv"
 	"testing"
 
 	"github.com/prysmaticlabs/prysm/shared/bls"
 	"github.com/prysmaticlabs/prysm/shared/bytesutil"
-	"github.com/prysmaticlabs/prysm/shared/hashutil"
 	"github.com/prysmaticlabs/prysm/shared/params"
 	"github.com/prysmaticlabs/prysm/shared/rand"
 	"github.com/prysmaticlabs/prysm/shared/testutil/assert"
@@ -86,14 +84,9 @@ func TestStore_ImportInterchangeData_BadFormat_PreventsDBWrites(t *testing.T) {
 		)
 		proposals := proposalHistory[i].Proposals
 		for _, proposal := range proposals {
-			receivedProposalSigningRoot, err := validatorDB.ProposalHistoryForSlot(ctx, publicKeys[i][:], proposal.Slot)
+			_, exists, err := validatorDB.ProposalHistoryForSlot(ctx, publicKeys[i], proposal.Slot)
 			require.NoError(t, err)
-			require.DeepEqual(
-				t,
-				params.BeaconConfig().ZeroHash[:],
-				receivedProposalSigningRoot,
-				"Imported proposal signing root is different than the empty default",
-			)
+			require.Equal(t, false, exists, "Imported proposal should not have been saved")
 		}
 	}
 }
@@ -131,7 +124,7 @@ func TestStore_ImportInterchangeData_OK(t *testing.T) {
 		)
 		proposals := proposalHistory[i].Proposals
 		for _, proposal := range proposals {
-			receivedProposalSigningRoot, err := validatorDB.ProposalHistoryForSlot(ctx, publicKeys[i][:], proposal.Slot)
+			receivedProposalSigningRoot, _, err := validatorDB.ProposalHistoryForSlot(ctx, publicKeys[i], proposal.Slot)
 			require.NoError(t, err)
 			require.DeepEqual(
 				t,
@@ -143,608 +136,6 @@ func TestStore_ImportInterchangeData_OK(t *testing.T) {
 	}
 }
 
-func Test_validateMetadata(t *testing.T) {
-	goodRoot := [32]byte{1}
-	goodStr := make([]byte, hex.EncodedLen(len(goodRoot)))
-	hex.Encode(goodStr, goodRoot[:])
-	tests := []struct {
-		name                   string
-		interchangeJSON        *EIPSlashingProtectionFormat
-		dbGenesisValidatorRoot []byte
-		wantErr                bool
-		wantFatal              string
-	}{
-		{
-			name: "Incorrect version for EIP format should fail",
-			interchangeJSON: &EIPSlashingProtectionFormat{
-				Metadata: struct {
-					InterchangeFormatVersion string `json:"interchange_format_version"`
-					GenesisValidatorsRoot    string `json:"genesis_validators_root"`
-				}{
-					InterchangeFormatVersion: "1",
-					GenesisValidatorsRoot:    string(goodStr),
-				},
-			},
-			wantErr: true,
-		},
-		{
-			name: "Junk data for version should fail",
-			interchangeJSON: &EIPSlashingProtectionFormat{
-				Metadata: struct {
-					InterchangeFormatVersion string `json:"interchange_format_version"`
-					GenesisValidatorsRoot    string `json:"genesis_validators_root"`
-				}{
-					InterchangeFormatVersion: "asdljas$d",
-					GenesisValidatorsRoot:    string(goodStr),
-				},
-			},
-			wantErr: true,
-		},
-		{
-			name: "Proper version field should pass",
-			interchangeJSON: &EIPSlashingProtectionFormat{
-				Metadata: struct {
-					InterchangeFormatVersion string `json:"interchange_format_version"`
-					GenesisValidatorsRoot    string `json:"genesis_validators_root"`
-				}{
-					InterchangeFormatVersion: INTERCHANGE_FORMAT_VERSION,
-					GenesisValidatorsRoot:    string(goodStr),
-				},
-			},
-			wantErr: false,
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			validatorDB := dbtest.SetupDB(t, nil)
-			ctx := context.Background()
-			if err := validateMetadata(ctx, validatorDB, tt.interchangeJSON); (err != nil) != tt.wantErr {
-				t.Errorf("validateMetadata() error = %v, wantErr %v", err, tt.wantErr)
-			}
-
-		})
-	}
-}
-
-func Test_validateMetadataGenesisValidatorRoot(t *testing.T) {
-	goodRoot := [32]byte{1}
-	goodStr := make([]byte, hex.EncodedLen(len(goodRoot)))
-	hex.Encode(goodStr, goodRoot[:])
-	secondRoot := [32]byte{2}
-	secondStr := make([]byte, hex.EncodedLen(len(secondRoot)))
-	hex.Encode(secondStr, secondRoot[:])
-
-	tests := []struct {
-		name                   string
-		interchangeJSON        *EIPSlashingProtectionFormat
-		dbGenesisValidatorRoot []byte
-		wantErr                bool
-	}{
-		{
-			name: "Same genesis roots should not fail",
-			interchangeJSON: &EIPSlashingProtectionFormat{
-				Metadata: struct {
-					InterchangeFormatVersion string `json:"interchange_format_version"`
-					GenesisValidatorsRoot    string `json:"genesis_validators_root"`
-				}{
-					InterchangeFormatVersion: INTERCHANGE_FORMAT_VERSION,
-					GenesisValidatorsRoot:    string(goodStr),
-				},
-			},
-			dbGenesisValidatorRoot: goodRoot[:],
-			wantErr:                false,
-		},
-		{
-			name: "Different genesis roots should not fail",
-			interchangeJSON: &EIPSlashingProtectionFormat{
-				Metadata: struct {
-					InterchangeFormatVersion string `json:"interchange_format_version"`
-					GenesisValidatorsRoot    string `json:"genesis_validators_root"`
-				}{
-					InterchangeFormatVersion: INTERCHANGE_FORMAT_VERSION,
-					GenesisValidatorsRoot:    string(secondStr),
-				},
-			},
-			dbGenesisValidatorRoot: goodRoot[:],
-			wantErr:                true,
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			validatorDB := dbtest.SetupDB(t, nil)
-			ctx := context.Background()
-			require.NoError(t, validatorDB.SaveGenesisValidatorsRoot(ctx, tt.dbGenesisValidatorRoot))
-			err := validateMetadata(ctx, validatorDB, tt.interchangeJSON)
-			if tt.wantErr {
-				require.ErrorContains(t, "genesis validator root doesnt match the one that is stored", err)
-			} else {
-				require.NoError(t, err)
-			}
-
-		})
-	}
-}
-
-func Test_parseUniqueSignedBlocksByPubKey(t *testing.T) {
-	numValidators := 4
-	pubKeys := createRandomPubKeys(t, numValidators)
-	roots := createRandomRoots(t, numValidators)
-	tests := []struct {
-		name    string
-		data    []*ProtectionData
-		want    map[[48]byte][]*SignedBlock
-		wantErr bool
-	}{
-		{
-			name: "nil values are skipped",
-			data: []*ProtectionData{
-				{
-					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
-					SignedBlocks: []*SignedBlock{
-						{
-							Slot:        "1",
-							SigningRoot: fmt.Sprintf("%x", roots[0]),
-						},
-						nil,
-					},
-				},
-				{
-					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
-					SignedBlocks: []*SignedBlock{
-						{
-							Slot:        "3",
-							SigningRoot: fmt.Sprintf("%x", roots[2]),
-						},
-					},
-				},
-			},
-			want: map[[48]byte][]*SignedBlock{
-				pubKeys[0]: {
-					{
-						Slot:        "1",
-						SigningRoot: fmt.Sprintf("%x", roots[0]),
-					},
-					{
-						Slot:        "3",
-						SigningRoot: fmt.Sprintf("%x", roots[2]),
-					},
-				},
-			},
-		},
-		{
-			name: "same blocks but different public keys are parsed correctly",
-			data: []*ProtectionData{
-				{
-					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
-					SignedBlocks: []*SignedBlock{
-						{
-							Slot:        "1",
-							SigningRoot: fmt.Sprintf("%x", roots[0]),
-						},
-						{
-							Slot:        "2",
-							SigningRoot: fmt.Sprintf("%x", roots[1]),
-						},
-					},
-				},
-				{
-					Pubkey: fmt.Sprintf("%x", pubKeys[1]),
-					SignedBlocks: []*SignedBlock{
-						{
-							Slot:        "1",
-							SigningRoot: fmt.Sprintf("%x", roots[0]),
-						},
-						{
-							Slot:        "2",
-							SigningRoot: fmt.Sprintf("%x", roots[1]),
-						},
-					},
-				},
-			},
-			want: map[[48]byte][]*SignedBlock{
-				pubKeys[0]: {
-					{
-						Slot:        "1",
-						SigningRoot: fmt.Sprintf("%x", roots[0]),
-					},
-					{
-						Slot:        "2",
-						SigningRoot: fmt.Sprintf("%x", roots[1]),
-					},
-				},
-				pubKeys[1]: {
-					{
-						Slot:        "1",
-						SigningRoot: fmt.Sprintf("%x", roots[0]),
-					},
-					{
-						Slot:        "2",
-						SigningRoot: fmt.Sprintf("%x", roots[1]),
-					},
-				},
-			},
-		},
-		{
-			name: "disjoint sets of signed blocks by the same public key are parsed correctly",
-			data: []*ProtectionData{
-				{
-					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
-					SignedBlocks: []*SignedBlock{
-						{
-							Slot:        "1",
-							SigningRoot: fmt.Sprintf("%x", roots[0]),
-						},
-						{
-							Slot:        "2",
-							SigningRoot: fmt.Sprintf("%x", roots[1]),
-						},
-					},
-				},
-				{
-					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
-					SignedBlocks: []*SignedBlock{
-						{
-							Slot:        "3",
-							SigningRoot: fmt.Sprintf("%x", roots[2]),
-						},
-					},
-				},
-			},
-			want: map[[48]byte][]*SignedBlock{
-				pubKeys[0]: {
-					{
-						Slot:        "1",
-						SigningRoot: fmt.Sprintf("%x", roots[0]),
-					},
-					{
-						Slot:        "2",
-						SigningRoot: fmt.Sprintf("%x", roots[1]),
-					},
-					{
-						Slot:        "3",
-						SigningRoot: fmt.Sprintf("%x", roots[2]),
-					},
-				},
-			},
-		},
-		{
-			name: "full duplicate entries are uniquely parsed",
-			data: []*ProtectionData{
-				{
-					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
-					SignedBlocks: []*SignedBlock{
-						{
-							Slot:        "1",
-							SigningRoot: fmt.Sprintf("%x", roots[0]),
-						},
-					},
-				},
-				{
-					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
-					SignedBlocks: []*SignedBlock{
-						{
-							Slot:        "1",
-							SigningRoot: fmt.Sprintf("%x", roots[0]),
-						},
-					},
-				},
-			},
-			want: map[[48]byte][]*SignedBlock{
-				pubKeys[0]: {
-					{
-						Slot:        "1",
-						SigningRoot: fmt.Sprintf("%x", roots[0]),
-					},
-				},
-			},
-		},
-		{
-			name: "intersecting duplicate public key entries are handled properly",
-			data: []*ProtectionData{
-				{
-					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
-					SignedBlocks: []*SignedBlock{
-						{
-							Slot:        "1",
-							SigningRoot: fmt.Sprintf("%x", roots[0]),
-						},
-						{
-							Slot:        "2",
-							SigningRoot: fmt.Sprintf("%x", roots[1]),
-						},
-					},
-				},
-				{
-					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
-					SignedBlocks: []*SignedBlock{
-						{
-							Slot:        "2",
-							SigningRoot: fmt.Sprintf("%x", roots[1]),
-						},
-						{
-							Slot:        "3",
-							SigningRoot: fmt.Sprintf("%x", roots[2]),
-						},
-					},
-				},
-			},
-			want: map[[48]byte][]*SignedBlock{
-				pubKeys[0]: {
-					{
-						Slot:        "1",
-						SigningRoot: fmt.Sprintf("%x", roots[0]),
-					},
-					{
-						Slot:        "2",
-						SigningRoot: fmt.Sprintf("%x", roots[1]),
-					},
-					{
-						Slot:        "3",
-						SigningRoot: fmt.Sprintf("%x", roots[2]),
-					},
-				},
-			},
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got, err := parseUniqueSignedBlocksByPubKey(tt.data)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("parseUniqueSignedBlocksByPubKey() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("parseUniqueSignedBlocksByPubKey() got = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
-func Test_parseUniqueSignedAttestationsByPubKey(t *testing.T) {
-	numValidators := 4
-	pubKeys := createRandomPubKeys(t, numValidators)
-	roots := createRandomRoots(t, numValidators)
-	tests := []struct {
-		name    string
-		data    []*ProtectionData
-		want    map[[48]byte][]*SignedAttestation
-		wantErr bool
-	}{
-		{
-			name: "nil values are skipped",
-			data: []*ProtectionData{
-				{
-					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
-					SignedAttestations: []*SignedAttestation{
-						{
-							SourceEpoch: "1",
-							TargetEpoch: "3",
-							SigningRoot: fmt.Sprintf("%x", roots[0]),
-						},
-						nil,
-					},
-				},
-				{
-					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
-					SignedAttestations: []*SignedAttestation{
-						{
-							SourceEpoch: "3",
-							TargetEpoch: "5",
-							SigningRoot: fmt.Sprintf("%x", roots[2]),
-						},
-					},
-				},
-			},
-			want: map[[48]byte][]*SignedAttestation{
-				pubKeys[0]: {
-					{
-						SourceEpoch: "1",
-						TargetEpoch: "3",
-						SigningRoot: fmt.Sprintf("%x", roots[0]),
-					},
-					{
-						SourceEpoch: "3",
-						TargetEpoch: "5",
-						SigningRoot: fmt.Sprintf("%x", roots[2]),
-					},
-				},
-			},
-		},
-		{
-			name: "same blocks but different public keys are parsed correctly",
-			data: []*ProtectionData{
-				{
-					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
-					SignedAttestations: []*SignedAttestation{
-						{
-							SourceEpoch: "1",
-							SigningRoot: fmt.Sprintf("%x", roots[0]),
-						},
-						{
-							SourceEpoch: "2",
-							SigningRoot: fmt.Sprintf("%x", roots[1]),
-						},
-					},
-				},
-				{
-					Pubkey: fmt.Sprintf("%x", pubKeys[1]),
-					SignedAttestations: []*SignedAttestation{
-						{
-							SourceEpoch: "1",
-							SigningRoot: fmt.Sprintf("%x", roots[0]),
-						},
-						{
-							SourceEpoch: "2",
-							SigningRoot: fmt.Sprintf("%x", roots[1]),
-						},
-					},
-				},
-			},
-			want: map[[48]byte][]*SignedAttestation{
-				pubKeys[0]: {
-					{
-						SourceEpoch: "1",
-						SigningRoot: fmt.Sprintf("%x", roots[0]),
-					},
-					{
-						SourceEpoch: "2",
-						SigningRoot: fmt.Sprintf("%x", roots[1]),
-					},
-				},
-				pubKeys[1]: {
-					{
-						SourceEpoch: "1",
-						SigningRoot: fmt.Sprintf("%x", roots[0]),
-					},
-					{
-						SourceEpoch: "2",
-						SigningRoot: fmt.Sprintf("%x", roots[1]),
-					},
-				},
-			},
-		},
-		{
-			name: "disjoint sets of signed blocks by the same public key are parsed correctly",
-			data: []*ProtectionData{
-				{
-					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
-					SignedAttestations: []*SignedAttestation{
-						{
-							SourceEpoch: "1",
-							TargetEpoch: "3",
-							SigningRoot: fmt.Sprintf("%x", roots[0]),
-						},
-						{
-							SourceEpoch: "2",
-							TargetEpoch: "4",
-							SigningRoot: fmt.Sprintf("%x", roots[1]),
-						},
-					},
-				},
-				{
-					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
-					SignedAttestations: []*SignedAttestation{
-						{
-							SourceEpoch: "3",
-							TargetEpoch: "5",
-							SigningRoot: fmt.Sprintf("%x", roots[2]),
-						},
-					},
-				},
-			},
-			want: map[[48]byte][]*SignedAttestation{
-				pubKeys[0]: {
-					{
-						SourceEpoch: "1",
-						TargetEpoch: "3",
-						SigningRoot: fmt.Sprintf("%x", roots[0]),
-					},
-					{
-						SourceEpoch: "2",
-						TargetEpoch: "4",
-						SigningRoot: fmt.Sprintf("%x", roots[1]),
-					},
-					{
-						SourceEpoch: "3",
-						TargetEpoch: "5",
-						SigningRoot: fmt.Sprintf("%x", roots[2]),
-					},
-				},
-			},
-		},
-		{
-			name: "full duplicate entries are uniquely parsed",
-			data: []*ProtectionData{
-				{
-					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
-					SignedAttestations: []*SignedAttestation{
-						{
-							SourceEpoch: "1",
-							SigningRoot: fmt.Sprintf("%x", roots[0]),
-						},
-					},
-				},
-				{
-					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
-					SignedAttestations: []*SignedAttestation{
-						{
-							SourceEpoch: "1",
-							SigningRoot: fmt.Sprintf("%x", roots[0]),
-						},
-					},
-				},
-			},
-			want: map[[48]byte][]*SignedAttestation{
-				pubKeys[0]: {
-					{
-						SourceEpoch: "1",
-						SigningRoot: fmt.Sprintf("%x", roots[0]),
-					},
-				},
-			},
-		},
-		{
-			name: "intersecting duplicate public key entries are handled properly",
-			data: []*ProtectionData{
-				{
-					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
-					SignedAttestations: []*SignedAttestation{
-						{
-							SourceEpoch: "1",
-							SigningRoot: fmt.Sprintf("%x", roots[0]),
-						},
-						{
-							SourceEpoch: "2",
-							SigningRoot: fmt.Sprintf("%x", roots[1]),
-						},
-					},
-				},
-				{
-					Pubkey: fmt.Sprintf("%x", pubKeys[0]),
-					SignedAttestations: []*SignedAttestation{
-						{
-							SourceEpoch: "2",
-							SigningRoot: fmt.Sprintf("%x", roots[1]),
-						},
-						{
-							SourceEpoch: "3",
-							SigningRoot: fmt.Sprintf("%x", roots[2]),
-						},
-					},
-				},
-			},
-			want: map[[48]byte][]*SignedAttestation{
-				pubKeys[0]: {
-					{
-						SourceEpoch: "1",
-						SigningRoot: fmt.Sprintf("%x", roots[0]),
-					},
-					{
-						SourceEpoch: "2",
-						SigningRoot: fmt.Sprintf("%x", roots[1]),
-					},
-					{
-						SourceEpoch: "3",
-						SigningRoot: fmt.Sprintf("%x", roots[2]),
-					},
-				},
-			},
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got, err := parseUniqueSignedAttestationsByPubKey(tt.data)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("parseUniqueSignedAttestationsByPubKey() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("parseUniqueSignedAttestationsByPubKey() got = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
 func mockSlashingProtectionJSON(
 	t *testing.T,
 	publicKeys [][48]byte,
@@ -764,6 +155,9 @@ func mockSlashingProtectionJSON(
 		for target := uint64(0); target <= highestEpochWritten; target++ {
 			hd, err := attestingHistories[i].GetTargetData(ctx, target)
 			require.NoError(t, err)
+			if hd.IsEmpty() {
+				continue
+			}
 			data.SignedAttestations = append(data.SignedAttestations, &SignedAttestation{
 				TargetEpoch: strconv.FormatUint(target, 10),
 				SourceEpoch: strconv.FormatUint(hd.Source, 10),
@@ -795,12 +189,12 @@ func mockAttestingAndProposalHistories(t *testing.T, numValidators int) ([]kv.En
 		latestTarget := gen.Intn(int(params.BeaconConfig().WeakSubjectivityPeriod) / 100)
 		hd := kv.NewAttestationHistoryArray(uint64(latestTarget))
 		proposals := make([]kv.Proposal, 0)
-		for i := 1; i < latestTarget; i++ {
+		for i := 1; i <= latestTarget; i++ {
 			signingRoot := [32]byte{}
 			signingRootStr := fmt.Sprintf("%d", i)
 			copy(signingRoot[:], signingRootStr)
 			historyData := &kv.HistoryData{
-				Source:      uint64(gen.Intn(100000)),
+				Source:      uint64(gen.Intn(i + 1)),
 				SigningRoot: signingRoot[:],
 			}
 			hd, err = hd.SetTargetData(ctx, uint64(i), historyData)
@@ -832,11 +226,3 @@ func createRandomPubKeys(t *testing.T, numValidators int) [][48]byte {
 	}
 	return pubKeys
 }
-
-func createRandomRoots(t *testing.T, numRoots int) [][32]byte {
-	roots := make([][32]byte, numRoots)
-	for i := 0; i < numRoots; i++ {
-		roots[i] = hashutil.Hash([]byte(fmt.Sprintf("%d", i)))
-	}
-	return roots
-}
@@ -74,6 +74,8 @@ var appHelpFlagGroups = []flagGroup{
 			debug.MemProfileRateFlag,
 			debug.CPUProfileFlag,
 			debug.TraceFlag,
+			debug.LeakCheckFlag,
+			debug.ShuffleStartOrderFlag,
 		},
 	},
 	{